@@ -0,0 +1,67 @@
+// Package archiver archives the completed todos of users according
+// to their archive rules.
+//
+// Archiving is idempotent, so every instance of the server may run an
+// Archiver without coordinating with the others.
+package archiver
+
+import (
+	"log"
+	"models"
+	"time"
+)
+
+// Archiver periodically applies the archive rules of every user.
+type Archiver struct {
+	Interval time.Duration // Time between runs of the rules
+}
+
+func NewArchiver() *Archiver {
+	return &Archiver{Interval: time.Hour}
+}
+
+// Run applies the rules every Interval until stop is closed.
+func (a *Archiver) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(a.Interval)
+	defer ticker.Stop()
+
+	for {
+		a.RunRules(time.Now())
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunRules applies the archive rules of every user at the time now and
+// returns the number of rules applied. Failures are logged and the
+// remaining rules are still applied.
+func (a *Archiver) RunRules(now time.Time) int {
+	uds := models.NewUserStorage()
+	defer uds.Close()
+
+	us, err := uds.GetUsersWithArchiveRules()
+	if err != nil {
+		log.Printf("RunRules: GetUsersWithArchiveRules failure: %s\n", err)
+		return 0
+	}
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	applied := 0
+	for _, u := range us {
+		for _, rule := range u.ArchiveRules {
+			if err := tds.ArchiveTodos(u.Id.Hex(), rule, now); err != nil {
+				log.Printf("RunRules: Failure to archive 2Dos of user %s: %s\n", u.Id.Hex(), err)
+				continue
+			}
+			applied++
+		}
+	}
+
+	return applied
+}
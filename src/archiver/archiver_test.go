@@ -0,0 +1,59 @@
+package archiver
+
+import (
+	"models"
+	"testing"
+	"time"
+)
+
+func init() {
+	models.TODO_STORE_TYPE = models.Test
+	models.USER_STORE_TYPE = models.Test
+}
+
+func TestRunRules(t *testing.T) {
+	now := time.Now()
+	week := now.AddDate(0, 0, -7)
+	day := now.AddDate(0, 0, -1)
+
+	u := models.NewUser()
+	u.Username = "archiver-TestRunRules"
+	u.ArchiveRules = []models.ArchiveRule{{Days: 7}, {Days: 0, ListId: "someday"}}
+	models.NewUserStorage().InsertUser(u)
+
+	todo := func(completed bool, at time.Time, listId string) models.Todo {
+		t0 := models.NewTodo()
+		t0.Ownerid = u.Id.Hex()
+		t0.Completed = completed
+		t0.CompletedAt = &at
+		t0.ListId = listId
+		models.NewTodoStorage().InsertTodo(t0)
+		return t0
+	}
+
+	old := todo(true, week, "")
+	recent := todo(true, day, "")
+	open := todo(false, week, "")
+	someday := todo(true, now, "someday")
+
+	a := NewArchiver()
+	if n := a.RunRules(now); n != 2 {
+		t.Errorf("Both rules should be applied: %d", n)
+	}
+
+	tds := models.NewTodoStorage()
+	for _, tc := range []struct {
+		todo     models.Todo
+		archived bool
+	}{{old, true}, {recent, false}, {open, false}, {someday, true}} {
+		t0, _ := tds.GetTodoById(tc.todo.Id)
+		if t0.Archived != tc.archived || (t0.ArchivedAt != nil) != tc.archived {
+			t.Errorf("Incorrect archived state of %s: %v %v", t0.Id, t0.Archived, t0.ArchivedAt)
+		}
+	}
+
+	ts, _ := tds.GetTodosForUserId(u.Id.Hex())
+	if len(ts) != 2 {
+		t.Errorf("Archived 2Dos should be left out: %v", ts)
+	}
+}
@@ -0,0 +1,134 @@
+// Package authz is the authorization service deciding what a user may
+// do with todos and lists. Access is granted by owning an item or by
+// the item, or the list of a todo, being shared with the user.
+package authz
+
+import (
+	"errors"
+	"log"
+	"models"
+)
+
+// Action is something a user does with a todo or list.
+type Action int
+
+const (
+	View Action = iota
+	Comment
+	Edit
+	Delete
+	Share
+	// Complete is marking a todo as completed, which its assignee may
+	// do along with the editors of the todo.
+	Complete
+	// TrackTime is logging time on a todo, allowed like Complete.
+	TrackTime
+)
+
+// minimumRoles maps each action to the least role allowed to perform it.
+var minimumRoles = map[Action]models.Role{
+	View:      models.RoleViewer,
+	Comment:   models.RoleViewer,
+	Edit:      models.RoleEditor,
+	Delete:    models.RoleOwner,
+	Share:     models.RoleOwner,
+	Complete:  models.RoleEditor,
+	TrackTime: models.RoleEditor,
+}
+
+// ErrForbidden is returned when the user may view an item but may not
+// perform the action on it.
+var ErrForbidden = errors.New("Insufficient permissions")
+
+// Allows reports whether the role allows the action.
+func Allows(r models.Role, a Action) bool {
+	min, ok := minimumRoles[a]
+	return ok && r.Level() >= min.Level()
+}
+
+// TodoRole returns the role of the user on the todo, the highest of
+// the role granted on the todo itself and on its list. The assignee
+// of a todo may at least view it.
+func TodoRole(userId string, t models.Todo) models.Role {
+	r := t.RoleFor(userId)
+	if t.AssigneeId == userId {
+		r = models.MaxRole(r, models.RoleViewer)
+	}
+
+	if r == models.RoleOwner || t.ListId == "" {
+		return r
+	}
+
+	lds := models.NewListStorage()
+	defer lds.Close()
+
+	l, err := lds.GetListById(t.ListId)
+	if err != nil {
+		log.Printf("TodoRole: GetListById failed for: %s reason: %s\n", t.ListId, err)
+		return r
+	}
+
+	return models.MaxRole(r, l.RoleFor(userId))
+}
+
+// Todo returns the todo with the id if the user may perform the action
+// on it. models.TodoNotFoundError is returned when the todo doesn't
+// exist or is not visible to the user so that its existence isn't
+// revealed, ErrForbidden when the user may only view it.
+func Todo(tds models.TodoStorage, userId, todoId string, a Action) (*models.Todo, error) {
+	t, err := tds.GetTodoById(todoId)
+	if err != nil {
+		return nil, models.TodoNotFoundError
+	}
+
+	r := TodoRole(userId, *t)
+	if !Allows(r, View) {
+		return nil, models.TodoNotFoundError
+	}
+
+	assigneeAction := a == Complete || a == TrackTime
+	if !Allows(r, a) && !(assigneeAction && t.AssigneeId == userId) {
+		return t, ErrForbidden
+	}
+
+	return t, nil
+}
+
+// List returns the list with the id if the user may perform the action
+// on it. The errors are those of Todo with models.ListNotFoundError.
+func List(lds models.ListStorage, userId, listId string, a Action) (*models.List, error) {
+	l, err := lds.GetListById(listId)
+	if err != nil {
+		return nil, models.ListNotFoundError
+	}
+
+	r := l.RoleFor(userId)
+	if !Allows(r, View) {
+		return nil, models.ListNotFoundError
+	}
+
+	if !Allows(r, a) {
+		return l, ErrForbidden
+	}
+
+	return l, nil
+}
+
+// SharedListIds returns the ids of the lists other users share with
+// the user and of the lists the user shares with others, the todos
+// other users add to them are visible to the user.
+func SharedListIds(lds models.ListStorage, userId string) ([]string, error) {
+	ls, err := lds.GetListsForUserId(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0)
+	for _, l := range ls {
+		if l.Ownerid != userId || len(l.Shares) > 0 {
+			ids = append(ids, l.Id.Hex())
+		}
+	}
+
+	return ids, nil
+}
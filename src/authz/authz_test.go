@@ -0,0 +1,93 @@
+package authz
+
+import (
+	"models"
+	"testing"
+)
+
+func init() {
+	models.TODO_STORE_TYPE = models.Test
+	models.LIST_STORE_TYPE = models.Test
+}
+
+func TestTodo(t *testing.T) {
+	ownerId, editorId, viewerId := "owner", "editor", "viewer"
+
+	l := models.NewList()
+	l.Ownerid = ownerId
+	l.Shares = []models.Share{{UserId: editorId, Role: models.RoleEditor}}
+	lds := models.NewListStorage()
+	lds.InsertList(l)
+
+	t0 := models.NewTodo()
+	t0.Ownerid = ownerId
+	t0.ListId = l.Id.Hex()
+	t0.Shares = []models.Share{{UserId: viewerId, Role: models.RoleViewer}}
+	tds := models.NewTodoStorage()
+	tds.InsertTodo(t0)
+
+	tests := []struct {
+		userId string
+		action Action
+		err    error
+	}{
+		{ownerId, Delete, nil},
+		{editorId, Edit, nil},
+		{editorId, Delete, ErrForbidden},
+		{viewerId, Comment, nil},
+		{viewerId, Edit, ErrForbidden},
+		{"stranger", View, models.TodoNotFoundError},
+	}
+
+	for _, test := range tests {
+		_, err := Todo(tds, test.userId, t0.Id, test.action)
+		if err != test.err {
+			t.Errorf("Incorrect authorization of %s for %d: want %v got %v", test.userId, test.action, test.err, err)
+		}
+	}
+
+	if _, err := Todo(tds, ownerId, "missing", View); err != models.TodoNotFoundError {
+		t.Errorf("Missing 2Do should not be found: got %v", err)
+	}
+}
+
+func TestSharedListIds(t *testing.T) {
+	l0 := models.NewList()
+	l0.Ownerid = "alice"
+	l0.Shares = []models.Share{{UserId: "bob", Role: models.RoleViewer}}
+	l1 := models.NewList()
+	l1.Ownerid = "bob"
+
+	lds := models.NewListStorage()
+	lds.InsertList(l0)
+	lds.InsertList(l1)
+
+	ids, err := SharedListIds(lds, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ids) != 1 || ids[0] != l0.Id.Hex() {
+		t.Errorf("Incorrect shared lists: %v", ids)
+	}
+}
+
+func TestTodoAssignee(t *testing.T) {
+	t0 := models.NewTodo()
+	t0.Ownerid = "owner"
+	t0.AssigneeId = "assignee"
+	tds := models.NewTodoStorage()
+	tds.InsertTodo(t0)
+
+	for _, a := range []Action{View, Comment, Complete} {
+		if _, err := Todo(tds, "assignee", t0.Id, a); err != nil {
+			t.Errorf("Assignee should be allowed %d: got %v", a, err)
+		}
+	}
+
+	for _, a := range []Action{Edit, Delete} {
+		if _, err := Todo(tds, "assignee", t0.Id, a); err != ErrForbidden {
+			t.Errorf("Assignee should not be allowed %d: got %v", a, err)
+		}
+	}
+}
@@ -10,6 +10,12 @@ type Configuration struct {
 	MongodbHostname string `json:"mongodb_hostname"`
 	Secret          string `json:"secret"`
 	JWTIssuer       string `json:"jwt_issuer"`
+	SMTPHostname    string `json:"smtp_hostname"` // host:port of the mail server for email notifications
+	SMTPFrom        string `json:"smtp_from"`
+	BlobStore       string `json:"blob_store"` // "gridfs" (default) or "local"
+	BlobDir         string `json:"blob_dir"`   // directory of the local blob store
+	MaxUploadSize   int64  `json:"max_upload_size"`
+	UploadQuota     int64  `json:"upload_quota"` // total attachment bytes per user
 }
 
 const configFile = "conf.json"
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"auth"
+	"encoding/json"
+	"fmt"
+	"log"
+	"models"
+	"net/http"
+	"net/mail"
+	"net/url"
+)
+
+// AccountHandler is a handler function for the /api/account endpoint
+// it acts as a multiplexer to a respective http method handler.
+func AccountHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		AccountGetHandler(w, r)
+	case "PUT":
+		AccountPutHandler(w, r)
+	}
+}
+
+// AccountGetHandler returns the account of the user along with
+// their preferences.
+func AccountGetHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	uds := models.NewUserStorage()
+	defer uds.Close()
+
+	u, err := uds.GetUserById(claims.UserId)
+	if err != nil {
+		NotFoundHandler(w, r, "Account not found.")
+		log.Println("AccountGetHandler: " + err.Error())
+		return
+	}
+
+	weights := u.Weights()
+	u.SmartWeights = &weights
+
+	data, err := json.Marshal(u)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("AccountGetHandler: " + err.Error())
+		return
+	}
+
+	w.WriteHeader(StatusSuccess)
+	w.Header().Set(ContentType, ApplicationJSON)
+	w.Write(data)
+}
+
+// accountChanges are the preferences a user may modify.
+type accountChanges struct {
+	SmartWeights *models.SmartWeights  `json:"smart_weights"`
+	Email        *string               `json:"email"`
+	WebhookURL   *string               `json:"webhook_url"`
+	TimeZone     *string               `json:"time_zone"`
+	Locale       *string               `json:"locale"`
+	ArchiveRules *[]models.ArchiveRule `json:"archive_rules"`
+}
+
+// AccountPutHandler modifies the preferences of the user.
+func AccountPutHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	var ac accountChanges
+	err = json.NewDecoder(r.Body).Decode(&ac)
+	if err != nil {
+		BadRequestHandler(w, r, "Body format incorrect for account. Try: { \"smart_weights\": { \"priority\": 1, \"due_soon\": 1, \"overdue\": 1, \"age\": 0.25 } }")
+		return
+	}
+
+	change := make(map[string]interface{})
+	if ac.SmartWeights != nil {
+		if err := ac.SmartWeights.Validate(); err != nil {
+			BadRequestHandler(w, r, err.Error())
+			return
+		}
+		change["smart_weights"] = ac.SmartWeights
+	}
+
+	if ac.Email != nil {
+		if *ac.Email != "" {
+			if _, err := mail.ParseAddress(*ac.Email); err != nil {
+				BadRequestHandler(w, r, "Invalid email address.")
+				return
+			}
+		}
+		change["email"] = *ac.Email
+	}
+
+	if ac.WebhookURL != nil {
+		if *ac.WebhookURL != "" {
+			u, err := url.Parse(*ac.WebhookURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				BadRequestHandler(w, r, "Invalid webhook_url, it must be an http or https url.")
+				return
+			}
+		}
+		change["webhook_url"] = *ac.WebhookURL
+	}
+
+	if ac.TimeZone != nil {
+		if err := models.ValidateTimeZone(*ac.TimeZone); err != nil {
+			BadRequestHandler(w, r, err.Error())
+			return
+		}
+		change["time_zone"] = *ac.TimeZone
+	}
+
+	if ac.Locale != nil {
+		if err := models.ValidateLocale(*ac.Locale); err != nil {
+			BadRequestHandler(w, r, err.Error())
+			return
+		}
+		change["locale"] = *ac.Locale
+	}
+
+	if ac.ArchiveRules != nil {
+		if err := models.ValidateArchiveRules(*ac.ArchiveRules); err != nil {
+			BadRequestHandler(w, r, err.Error())
+			return
+		}
+
+		for _, rule := range *ac.ArchiveRules {
+			if rule.ListId != "" && rule.ListId != models.InboxListId && !userCanEditList(rule.ListId, claims.UserId) {
+				BadRequestHandler(w, r, fmt.Sprintf("List not found: %s", rule.ListId))
+				return
+			}
+		}
+		change["archive_rules"] = *ac.ArchiveRules
+	}
+
+	if len(change) == 0 {
+		BadRequestHandler(w, r, "No account changes given.")
+		return
+	}
+
+	uds := models.NewUserStorage()
+	defer uds.Close()
+
+	err = uds.ModifyUser(claims.UserId, change)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to modify account")
+		log.Println("AccountPutHandler: " + err.Error())
+		return
+	}
+
+	res := jsonResponse{Result: "Successfully modified account"}
+	msg, err := json.Marshal(res)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to modify account")
+		log.Println("AccountPutHandler: " + err.Error())
+		return
+	}
+
+	w.WriteHeader(StatusSuccess)
+	w.Header().Set(ContentType, ApplicationJSON)
+	w.Write(msg)
+}
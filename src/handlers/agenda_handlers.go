@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"auth"
+	"authz"
+	"fmt"
+	"github.com/gorilla/mux"
+	"ical"
+	"log"
+	"models"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultAgendaDays is the number of days of an agenda without ?to=.
+	defaultAgendaDays = 7
+
+	// The feed spans feedPastDays before today and feedDays after.
+	feedPastDays = 30
+	feedDays     = 180
+)
+
+// agendaTodos returns the todos of the user which the agenda from start
+// until end is made of: those due in the range, and for the overdue
+// carry-over and the projections of recurring todos, the open todos due
+// before it.
+func agendaTodos(tds models.TodoStorage, userId string, f models.TodoFilter, start, end time.Time) ([]models.Todo, error) {
+	lds := models.NewListStorage()
+	defer lds.Close()
+
+	var err error
+	f.SharedListIds, err = authz.SharedListIds(lds, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	inRange := f
+	inRange.DueFrom, inRange.DueBefore = start, end
+	ts, err := tds.GetTodosForFilter(userId, inRange)
+	if err != nil {
+		return nil, err
+	}
+
+	open := false
+	before := f
+	before.Completed, before.DueBefore = &open, start
+	earlier, err := tds.GetTodosForFilter(userId, before)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(ts, earlier...), nil
+}
+
+// AgendaHandler returns the todos due from ?from= to ?to=, both days
+// included, grouped by the days of the user's time zone, see
+// models.Agenda. Days are dates or relative to today e.g. today+7d, see
+// models.ParseRelativeDate, from today for a week by default. Upcoming
+// occurrences of recurring todos are projected and open overdue todos
+// are carried over to today. The todos can be narrowed down like those
+// of TodosGetHandler with ?list_id=, ?tag= and ?include=deferred.
+func AgendaHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	q := r.URL.Query()
+	f, err := todoFilterFromQuery(q)
+	if err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+	if f.Due != "" {
+		BadRequestHandler(w, r, "due is not supported by the agenda, try from and to")
+		return
+	}
+
+	switch q.Get("include") {
+	case "":
+		f.HideDeferred = true
+	case "deferred":
+	default:
+		BadRequestHandler(w, r, "include must be deferred")
+		return
+	}
+
+	now := time.Now()
+	loc := userLocation(claims.UserId)
+
+	day := func(param, def string) (time.Time, bool) {
+		// The + of today+7d is decoded as a space unless escaped
+		s := strings.Replace(q.Get(param), " ", "+", -1)
+		if s == "" {
+			s = def
+		}
+
+		t, _, err := models.ParseRelativeDate(s, now, loc)
+		if err != nil {
+			BadRequestHandler(w, r, fmt.Sprintf("%s: %s", param, err))
+			return t, false
+		}
+
+		start, _ := models.DayBounds(t, loc)
+		return start, true
+	}
+
+	start, ok := day("from", "today")
+	if !ok {
+		return
+	}
+	last, ok := day("to", start.AddDate(0, 0, defaultAgendaDays-1).Format(models.DateFormat))
+	if !ok {
+		return
+	}
+	end := last.AddDate(0, 0, 1)
+
+	if !start.Before(end) || start.AddDate(0, 0, models.MaxAgendaDays).Before(end) {
+		BadRequestHandler(w, r, fmt.Sprintf("to must be from the day of from up to %d days after", models.MaxAgendaDays-1))
+		return
+	}
+
+	f.Now = now
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	ts, err := agendaTodos(tds, claims.UserId, f, start, end)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("Failed to get agenda: " + err.Error())
+		return
+	}
+	markBlocked(tds, ts)
+	markLogged(ts)
+
+	writeJSON(w, r, StatusSuccess, models.Agenda(ts, start, end, now, loc))
+}
+
+// feedURL returns the URL of the calendar feed with the token.
+func feedURL(r *http.Request, token string) string {
+	scheme := "https"
+	if r.TLS == nil {
+		scheme = "http"
+	}
+
+	return fmt.Sprintf("%s://%s/api/agenda/feed/%s.ics", scheme, r.Host, token)
+}
+
+// AgendaFeedHandler is the handler function for the /api/agenda/feed
+// endpoint. POST creates the user's calendar feed, replacing the
+// token of the former one, and returns its URL which calendar apps can
+// subscribe to. DELETE removes the feed.
+func AgendaFeedHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	token := ""
+	if r.Method == "POST" {
+		token = models.NewFeedToken()
+	}
+
+	uds := models.NewUserStorage()
+	defer uds.Close()
+
+	err = uds.ModifyUser(claims.UserId, map[string]interface{}{"feed_token": token})
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to modify calendar feed")
+		log.Println("Failure to modify calendar feed: " + err.Error())
+		return
+	}
+
+	if token == "" {
+		writeJSON(w, r, StatusSuccess, jsonResponse{Result: "Successfully removed calendar feed"})
+		return
+	}
+
+	writeJSON(w, r, StatusCreation, jsonResponse{
+		Result: "Successfully created calendar feed",
+		Data:   map[string]string{"url": feedURL(r, token)},
+	})
+}
+
+// AgendaFeedGetHandler serves the calendar feed of the user with the
+// token of the URL in the iCalendar format, which calendar apps poll.
+// The token authorizes the request, which is read-only. The feed has
+// the todos due from feedPastDays ago to feedDays ahead, including the
+// projected occurrences of recurring todos, as all day events or
+// events at their due time. Completed todos are checked.
+func AgendaFeedGetHandler(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+
+	uds := models.NewUserStorage()
+	defer uds.Close()
+
+	u, err := uds.GetUserByFeedToken(token)
+	if err != nil || u.Blocked {
+		NotFoundHandler(w, r, "")
+		return
+	}
+	userId := u.Id.Hex()
+
+	now := time.Now()
+	loc := u.Location()
+	today, _ := models.DayBounds(now, loc)
+	start, end := today.AddDate(0, 0, -feedPastDays), today.AddDate(0, 0, feedDays)
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	ts, err := agendaTodos(tds, userId, models.TodoFilter{Now: now}, start, end)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("Failed to get calendar feed: " + err.Error())
+		return
+	}
+
+	c := ical.Calendar{Name: "2Do: " + u.Username, Stamp: now}
+	for _, item := range models.AgendaItems(ts, start, end, now) {
+		e := ical.Event{
+			UID:         item.Id + "@2do",
+			Summary:     item.Title,
+			Description: item.Note,
+			Categories:  item.Tags,
+			Start:       item.Due.In(loc),
+			AllDay:      item.AllDay,
+		}
+		if item.Projected {
+			e.UID = fmt.Sprintf("%s-%s@2do", item.Id, item.Due.In(loc).Format(models.DateFormat))
+		}
+		if item.Completed {
+			e.Summary = "✓ " + e.Summary
+		}
+		c.Events = append(c.Events, e)
+	}
+
+	w.Header().Set(ContentType, TextCalendar)
+	w.WriteHeader(StatusSuccess)
+	if err := c.Write(w); err != nil {
+		log.Println("Failed to write calendar feed: " + err.Error())
+	}
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAgenda(t *testing.T) {
+	u, token := shareSetup("agenda-TestAgenda")
+	u.TimeZone = "Asia/Kolkata"
+	models.NewUserStorage().InsertUser(u)
+	loc := u.Location()
+
+	today, _ := models.DayBounds(time.Now(), loc)
+	tds := models.NewTodoStorage()
+	add := func(title string, due time.Time, rc *models.Recurrence) {
+		td := models.NewTodo()
+		td.Ownerid = u.Id.Hex()
+		td.Title = title
+		td.Due = due
+		td.AllDay = true
+		td.Recurrence = rc
+		tds.InsertTodo(td)
+	}
+	add("overdue", today.AddDate(0, 0, -3), nil)
+	add("tomorrow", today.AddDate(0, 0, 1), nil)
+	add("weekly", today.AddDate(0, 0, 2), &models.Recurrence{Rule: "FREQ=WEEKLY", TimeZone: u.TimeZone})
+	add("far", today.AddDate(0, 0, 60), nil)
+
+	agenda := func(query string) ([]models.AgendaDay, int) {
+		req, rr := handlersSetup("GET", "api/agenda"+query, "")
+		req.Header.Set("Authorization", "Bearer "+token)
+		ValidatePath(AgendaHandler).ServeHTTP(rr, req)
+
+		var days []models.AgendaDay
+		json.Unmarshal(rr.Body.Bytes(), &days)
+		return days, rr.Code
+	}
+
+	for _, query := range []string{"?from=soon", "?from=today&to=yesterday", "?to=today%2B400d", "?due=today"} {
+		if _, code := agenda(query); code != StatusBadRequest {
+			t.Errorf("Expected a bad request for %s: got %d", query, code)
+		}
+	}
+
+	days, code := agenda("?to=today+13d")
+	if code != StatusSuccess || len(days) != 14 {
+		t.Fatalf("Incorrect agenda: %d %v", code, days)
+	}
+	if days[0].Date != today.Format(models.DateFormat) {
+		t.Errorf("Agenda should start today: %s", days[0].Date)
+	}
+	if len(days[0].Todos) != 1 || !days[0].Todos[0].Overdue || days[0].Todos[0].Title != "overdue" {
+		t.Errorf("Overdue todos should be carried over to today: %v", days[0].Todos)
+	}
+	if len(days[1].Todos) != 1 || days[1].Todos[0].Title != "tomorrow" {
+		t.Errorf("Incorrect todos of tomorrow: %v", days[1].Todos)
+	}
+	if len(days[9].Todos) != 1 || !days[9].Todos[0].Projected || days[9].Todos[0].Title != "weekly" {
+		t.Errorf("Recurring todos should be projected: %v", days[9].Todos)
+	}
+
+	if days, _ := agenda("?from=today+60d&to=today+60d"); len(days) != 1 || len(days[0].Todos) != 1 || days[0].Todos[0].Title != "far" {
+		t.Errorf("Incorrect agenda of a later day: %v", days)
+	}
+}
+
+func TestAgendaFeed(t *testing.T) {
+	u, token := shareSetup("agenda-TestAgendaFeed")
+
+	req, rr := handlersSetup("POST", "api/agenda/feed", "")
+	req.Header.Set("Authorization", "Bearer "+token)
+	ValidatePath(AgendaFeedHandler).ServeHTTP(rr, req)
+	testStatus(StatusCreation, rr, t)
+
+	var res struct {
+		Data struct {
+			URL string `json:"url"`
+		} `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &res)
+	if !strings.Contains(res.Data.URL, "/api/agenda/feed/") || !strings.HasSuffix(res.Data.URL, ".ics") {
+		t.Errorf("Incorrect feed url: %s", res.Data.URL)
+	}
+
+	u.FeedToken = models.NewFeedToken()
+	models.NewUserStorage().InsertUser(u)
+
+	td := models.NewTodo()
+	td.Ownerid = u.Id.Hex()
+	td.Title = "Standup, daily"
+	td.Due = time.Now().Add(time.Hour)
+	models.NewTodoStorage().InsertTodo(td)
+
+	feed := func(feedToken string) (string, int) {
+		req, rr := handlersSetup("GET", "api/agenda/feed/"+feedToken+".ics", "")
+		req = mux.SetURLVars(req, map[string]string{"token": feedToken})
+		AgendaFeedGetHandler(rr, req)
+		return rr.Body.String(), rr.Code
+	}
+
+	if _, code := feed(models.NewFeedToken()); code != StatusNotFound {
+		t.Errorf("Feeds should not be found without their token: got %d", code)
+	}
+
+	body, code := feed(u.FeedToken)
+	if code != StatusSuccess {
+		t.Fatalf("Failure to get feed: got %d", code)
+	}
+	if !strings.HasPrefix(body, "BEGIN:VCALENDAR\r\n") || !strings.Contains(body, "UID:"+td.Id+"@2do\r\n") || !strings.Contains(body, "SUMMARY:Standup\\, daily\r\n") {
+		t.Errorf("Incorrect feed:\n%s", body)
+	}
+}
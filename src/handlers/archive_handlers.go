@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"auth"
+	"authz"
+	"log"
+	"models"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultArchived = 50
+	maxArchived     = 200
+)
+
+// archivePage is a page of the archived todos of a user.
+type archivePage struct {
+	Todos  []models.Todo `json:"todos"`
+	Total  int           `json:"total"`
+	Offset int           `json:"offset"`
+	Limit  int           `json:"limit"`
+}
+
+// ArchiveHandler returns the archived todos accessible to the user,
+// most recently archived first and paginated with ?offset= and
+// ?limit=. ?q= searches their titles and notes, they are filtered
+// like the todos endpoint otherwise. Todos are archived with
+// {"archived": true} and by the user's archive rules.
+func ArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	q := r.URL.Query()
+	offset, limit, err := pageFromQuery(q, defaultArchived, maxArchived)
+	if err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+
+	f, err := todoFilterFromQuery(q)
+	if err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+	f.Search = q.Get("q")
+	f.Archived = true
+	if f.Due != "" {
+		f.Now = time.Now()
+		f.Location = userLocation(claims.UserId)
+	}
+
+	lds := models.NewListStorage()
+	defer lds.Close()
+
+	f.SharedListIds, err = authz.SharedListIds(lds, claims.UserId)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("Failed to get shared lists: " + err.Error())
+		return
+	}
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	page := archivePage{Offset: offset, Limit: limit}
+	page.Total, err = tds.CountTodosForFilter(claims.UserId, f)
+	if err == nil {
+		page.Todos, err = tds.GetArchivedTodos(claims.UserId, f, offset, limit)
+	}
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("Failed to get archived 2Dos: " + err.Error())
+		return
+	}
+	markBlocked(tds, page.Todos)
+	markLogged(page.Todos)
+
+	writeJSON(w, r, StatusSuccess, page)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"models"
+	"testing"
+	"time"
+)
+
+func TestArchiveHandler(t *testing.T) {
+	u, token := shareSetup("archive-TestArchiveHandler")
+	tds := models.NewTodoStorage()
+
+	var ids []string
+	for _, title := range []string{"File taxes", "Pay taxes", "Buy milk"} {
+		t0 := models.NewTodo()
+		t0.Ownerid = u.Id.Hex()
+		t0.Title = title
+		t0.Completed = true
+		tds.InsertTodo(t0)
+		ids = append(ids, t0.Id)
+
+		req, rr := handlersSetup("PUT", "api/todos/"+t0.Id, "{\"archived\": true}")
+		req.Header.Set("Authorization", "Bearer "+token)
+		req = mux.SetURLVars(req, map[string]string{"id": t0.Id})
+		ValidatePath(TodoHandler).ServeHTTP(rr, req)
+		testStatus(StatusSuccess, rr, t)
+	}
+
+	archived, _ := tds.GetTodoById(ids[0])
+	if !archived.Archived || archived.ArchivedAt == nil {
+		t.Errorf("2Do should be archived: %v %v", archived.Archived, archived.ArchivedAt)
+	}
+
+	req, rr := handlersSetup("GET", "api/todos", "")
+	req.Header.Set("Authorization", "Bearer "+token)
+	ValidatePath(TodosHandler).ServeHTTP(rr, req)
+	testStatus(StatusSuccess, rr, t)
+
+	var ts []models.Todo
+	json.Unmarshal(rr.Body.Bytes(), &ts)
+	if len(ts) != 0 {
+		t.Errorf("Archived 2Dos should be left out of the 2Dos: %v", ts)
+	}
+
+	get := func(query string) archivePage {
+		req, rr := handlersSetup("GET", "api/archive"+query, "")
+		req.Header.Set("Authorization", "Bearer "+token)
+		ValidatePath(ArchiveHandler).ServeHTTP(rr, req)
+		testStatus(StatusSuccess, rr, t)
+
+		var page archivePage
+		json.Unmarshal(rr.Body.Bytes(), &page)
+		return page
+	}
+
+	if page := get(""); page.Total != 3 || len(page.Todos) != 3 {
+		t.Errorf("Every archived 2Do should be returned: %+v", page)
+	}
+
+	page := get("?q=TAXES&limit=1")
+	if page.Total != 2 || len(page.Todos) != 1 || page.Limit != 1 {
+		t.Errorf("Search should be paginated: %+v", page)
+	}
+
+	next := get("?q=taxes&limit=1&offset=1")
+	if len(next.Todos) != 1 || next.Todos[0].Id == page.Todos[0].Id {
+		t.Errorf("Second page should hold the other 2Do: %+v %+v", page, next)
+	}
+
+	req, rr = handlersSetup("PUT", "api/todos/"+ids[2], "{\"archived\": false}")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req = mux.SetURLVars(req, map[string]string{"id": ids[2]})
+	ValidatePath(TodoHandler).ServeHTTP(rr, req)
+	testStatus(StatusSuccess, rr, t)
+
+	unarchived, _ := tds.GetTodoById(ids[2])
+	if unarchived.Archived || unarchived.ArchivedAt != nil {
+		t.Errorf("2Do should be unarchived: %v %v", unarchived.Archived, unarchived.ArchivedAt)
+	}
+
+	req, rr = handlersSetup("GET", "api/archive?limit=0", "")
+	req.Header.Set("Authorization", "Bearer "+token)
+	ValidatePath(ArchiveHandler).ServeHTTP(rr, req)
+	testStatus(StatusBadRequest, rr, t)
+}
+
+func TestTodosPostHandlerArchivedAt(t *testing.T) {
+	_, token := shareSetup("archive-TestTodosPostHandlerArchivedAt")
+
+	for _, body := range []string{
+		"{\"title\": \"Old\", \"archived\": true, \"archived_at\": \"2001-01-01T00:00:00Z\"}",
+		"{\"title\": \"New\", \"archived_at\": \"2001-01-01T00:00:00Z\"}",
+	} {
+		before := time.Now()
+		req, rr := handlersSetup("POST", "api/todos", body)
+		req.Header.Set("Authorization", "Bearer "+token)
+		ValidatePath(TodosHandler).ServeHTTP(rr, req)
+		testStatus(StatusCreation, rr, t)
+
+		var res struct {
+			Data models.Todo `json:"data"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &res)
+		at := res.Data.ArchivedAt
+		if res.Data.Archived != (at != nil) || (at != nil && at.Before(before.Truncate(time.Second))) {
+			t.Errorf("2Do should be archived when it is created: %s %v %v", body, res.Data.Archived, at)
+		}
+	}
+}
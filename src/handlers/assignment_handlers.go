@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"models"
+	"notify"
+)
+
+// AssignedKind is the kind of the notification sent to the
+// assignee of a todo.
+const AssignedKind = "assigned"
+
+// validateAssignee checks that the user a todo is assigned to exists.
+// An empty id leaves the todo unassigned.
+func validateAssignee(assigneeId string) error {
+	if assigneeId == "" {
+		return nil
+	}
+
+	uds := models.NewUserStorage()
+	defer uds.Close()
+
+	if _, err := uds.GetUserById(assigneeId); err != nil {
+		return fmt.Errorf("Assignee not found: %s", assigneeId)
+	}
+
+	return nil
+}
+
+// onlyCompletion reports whether the changes to a todo only complete
+// or reopen it, or change its status, which its assignee may do.
+func onlyCompletion(changes map[string]interface{}) bool {
+	for k := range changes {
+		if k != "completed" && k != "status" {
+			return false
+		}
+	}
+
+	return len(changes) > 0
+}
+
+// notifyAssignee notifies the assignee of the todo that it was
+// assigned to them by the user assignerId. Users aren't notified
+// of assigning todos to themselves. Failures are only logged since
+// the assignment itself has been stored.
+func notifyAssignee(t models.Todo, assignerId string) {
+	if t.AssigneeId == "" || t.AssigneeId == assignerId {
+		return
+	}
+
+	uds := models.NewUserStorage()
+	defer uds.Close()
+
+	u, err := uds.GetUserById(t.AssigneeId)
+	if err != nil {
+		log.Printf("notifyAssignee: GetUserById failed for: %s reason: %s\n", t.AssigneeId, err)
+		return
+	}
+
+	assigner := "Someone"
+	if a, err := uds.GetUserById(assignerId); err == nil {
+		assigner = a.Username
+	}
+
+	n := models.NewNotification()
+	n.Kind = AssignedKind
+	n.TodoId = t.Id
+	n.Title = fmt.Sprintf("Assigned: %s", t.Title)
+	n.Message = fmt.Sprintf("%s assigned the 2Do \"%s\" to you.", assigner, t.Title)
+
+	_, err = notify.GetDispatcher().Dispatch(*u, n, models.Channels)
+	if err != nil {
+		log.Printf("notifyAssignee: Failure to notify %s: %s\n", t.AssigneeId, err)
+	}
+}
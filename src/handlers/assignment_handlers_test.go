@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"models"
+	"net/http"
+	"testing"
+)
+
+func TestTodoAssignment(t *testing.T) {
+	ownerToken, t0 := attachmentSetup()
+	bob, bobToken := shareSetup("bob-TestTodoAssignment")
+	t0.Title = "Do the thing"
+	models.NewTodoStorage().InsertTodo(t0)
+
+	serve := func(token, method, body string) int {
+		req, rr := handlersSetup(method, "api/todos/"+t0.Id, body)
+		req = mux.SetURLVars(req, map[string]string{"id": t0.Id})
+		req.Header.Set("Authorization", "Bearer "+token)
+		ValidatePath(http.HandlerFunc(TodoHandler)).ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := serve(ownerToken, "PUT", "{\"assignee_id\": \"missing\"}"); code != StatusBadRequest {
+		t.Errorf("Assignee should be validated: got %d", code)
+	}
+
+	if code := serve(ownerToken, "PUT", "{\"assignee_id\": \""+bob.Id.Hex()+"\"}"); code != StatusSuccess {
+		t.Fatalf("Failure to assign 2Do: got %d", code)
+	}
+
+	ns, _ := models.NewNotificationStorage().GetNotificationsForUserId(bob.Id.Hex(), true, 0)
+	if len(ns) != 1 || ns[0].Kind != AssignedKind || ns[0].TodoId != t0.Id {
+		t.Errorf("Assignee should be notified: %v", ns)
+	}
+
+	// The assignee sees the 2Do in the assigned to me view
+	req, rr := handlersSetup("GET", "api/todos?assigned=me", "")
+	req.Header.Set("Authorization", "Bearer "+bobToken)
+	ValidatePath(TodosHandler).ServeHTTP(rr, req)
+
+	var ts []models.Todo
+	if err := json.Unmarshal(rr.Body.Bytes(), &ts); err != nil {
+		t.Fatal(err)
+	}
+	if len(ts) != 1 || ts[0].Id != t0.Id {
+		t.Errorf("Assigned 2Do should be listed: %v", ts)
+	}
+
+	if code := serve(bobToken, "PUT", "{\"title\": \"Something else\"}"); code != StatusForbidden {
+		t.Errorf("Assignee should not modify the 2Do: got %d", code)
+	}
+
+	if code := serve(bobToken, "PUT", "{\"completed\": true}"); code != StatusSuccess {
+		t.Errorf("Assignee should complete the 2Do: got %d", code)
+	}
+
+	if code := serve(bobToken, "DELETE", ""); code != StatusForbidden {
+		t.Errorf("Assignee should not delete the 2Do: got %d", code)
+	}
+
+	req, rr = handlersSetup("POST", "api/todos/"+t0.Id+"/comments", "{\"body\": \"Done!\"}")
+	req = mux.SetURLVars(req, map[string]string{"id": t0.Id})
+	req.Header.Set("Authorization", "Bearer "+bobToken)
+	ValidatePath(CommentsHandler).ServeHTTP(rr, req)
+	testStatus(StatusCreation, rr, t)
+}
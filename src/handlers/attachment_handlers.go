@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"auth"
+	"authz"
+	"bufio"
+	"config"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"io"
+	"log"
+	"mime"
+	"models"
+	"net/http"
+	"strconv"
+)
+
+const (
+	attachmentFormField = "file"
+	sniffLen            = 512     // bytes used by http.DetectContentType
+	multipartOverhead   = 1 << 20 // allowance for the multipart headers
+)
+
+// uploadLimits returns the configured maximum size of an attachment
+// and the quota of attachment bytes per user.
+func uploadLimits() (int64, int64) {
+	c := config.GetConfig()
+	maxSize, quota := c.MaxUploadSize, c.UploadQuota
+	if maxSize <= 0 {
+		maxSize = models.DefaultMaxUploadSize
+	}
+	if quota <= 0 {
+		quota = models.DefaultUploadQuota
+	}
+
+	return maxSize, quota
+}
+
+// removeAttachments deletes the blobs of the attachments of deleted
+// todos. Failures are only logged since the todos are already gone.
+func removeAttachments(ts ...models.Todo) {
+	bs := models.NewBlobStore()
+	defer bs.Close()
+
+	for _, t := range ts {
+		for _, a := range t.Attachments {
+			err := bs.DeleteBlob(a.Id.Hex())
+			if err != nil && err != models.BlobNotFoundError {
+				log.Printf("removeAttachments: Failure to delete attachment %s: %s\n", a.Id.Hex(), err)
+			}
+		}
+	}
+}
+
+// AttachmentsHandler is the handler function for the
+// /api/todos/{id}/attachments endpoint.
+func AttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		AttachmentsGetHandler(w, r)
+	case "POST":
+		AttachmentsPostHandler(w, r)
+	}
+}
+
+// AttachmentHandler is the handler function for the
+// /api/todos/{id}/attachments/{attachment_id} endpoint.
+func AttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		AttachmentGetHandler(w, r)
+	case "DELETE":
+		AttachmentDeleteHandler(w, r)
+	}
+}
+
+// AttachmentsGetHandler returns the metadata of the attachments of a todo.
+func AttachmentsGetHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	t, ok := authorizeTodo(w, r, tds, claims.UserId, id, authz.View)
+	if !ok {
+		return
+	}
+
+	as := t.Attachments
+	if as == nil {
+		as = make([]models.Attachment, 0)
+	}
+
+	data, err := json.Marshal(as)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("AttachmentsGetHandler: " + err.Error())
+		return
+	}
+
+	w.WriteHeader(StatusSuccess)
+	w.Header().Set(ContentType, ApplicationJSON)
+	w.Write(data)
+}
+
+// filePart returns the file part of a multipart upload.
+func filePart(r *http.Request) (io.Reader, string, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, "", err
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			return nil, "", fmt.Errorf("Missing %s field", attachmentFormField)
+		}
+
+		if part.FormName() == attachmentFormField {
+			return part, part.FileName(), nil
+		}
+	}
+}
+
+// AttachmentsPostHandler attaches the file of a multipart upload, in
+// the file field, to a todo. The content type is sniffed from the
+// contents, only the AttachmentContentTypes are accepted. The file must
+// fit within both the maximum upload size and the quota of the owner
+// of the todo.
+func AttachmentsPostHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	t, ok := authorizeTodo(w, r, tds, claims.UserId, id, authz.Edit)
+	if !ok {
+		return
+	}
+
+	maxSize, quota := uploadLimits()
+	used, err := tds.GetAttachmentsSize(t.Ownerid)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to add attachment")
+		log.Println("Failure to compute attachments size: " + err.Error())
+		return
+	}
+
+	limit, tooLarge := maxSize, fmt.Sprintf("Attachments are limited to %d bytes.", maxSize)
+	if quota-used < limit {
+		limit, tooLarge = quota-used, "Attachment quota exceeded."
+	}
+	if limit <= 0 {
+		RequestTooLargeHandler(w, r, tooLarge)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, limit+multipartOverhead)
+	part, name, err := filePart(r)
+	if err != nil {
+		BadRequestHandler(w, r, fmt.Sprintf("Invalid multipart upload: %s", err))
+		return
+	}
+
+	br := bufio.NewReaderSize(part, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF {
+		BadRequestHandler(w, r, fmt.Sprintf("Failure to read upload: %s", err))
+		return
+	}
+
+	a := models.NewAttachment()
+	a.Name = name
+	a.ContentType = http.DetectContentType(head)
+	if !models.AllowedContentType(a.ContentType) {
+		BadRequestHandler(w, r, fmt.Sprintf("Unsupported attachment type: %s", a.ContentType))
+		return
+	}
+
+	bs := models.NewBlobStore()
+	defer bs.Close()
+
+	a.Size, err = bs.PutBlob(a.Id.Hex(), io.LimitReader(br, limit+1))
+	if err != nil || a.Size > limit {
+		bs.DeleteBlob(a.Id.Hex())
+		if err != nil {
+			InternalErrorHandler(w, r, "Failure to add attachment")
+			log.Println("Failure to store attachment: " + err.Error())
+		} else {
+			RequestTooLargeHandler(w, r, tooLarge)
+		}
+		return
+	}
+
+	// The quota checked above may since have been used by concurrent
+	// uploads, it is checked again as the attachment is added
+	err = tds.AddAttachment(id, a, quota)
+	if err != nil {
+		bs.DeleteBlob(a.Id.Hex())
+		if err == models.ErrAttachmentQuota {
+			RequestTooLargeHandler(w, r, "Attachment quota exceeded.")
+		} else {
+			NotFoundHandler(w, r, "2Do not found.")
+		}
+		return
+	}
+
+	res := jsonResponse{Result: fmt.Sprintf("Successfully added attachment: %s", a.Id.Hex()), Data: a}
+	msg, err := json.Marshal(res)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to add attachment")
+		log.Println("Failure to add attachment: " + err.Error())
+		return
+	}
+
+	w.WriteHeader(StatusCreation)
+	w.Header().Set(ContentType, ApplicationJSON)
+	w.Write(msg)
+}
+
+// AttachmentGetHandler downloads the contents of an attachment.
+func AttachmentGetHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, attachmentId := vars["id"], vars["attachment_id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	t, ok := authorizeTodo(w, r, tds, claims.UserId, id, authz.View)
+	if !ok {
+		return
+	}
+
+	a, ok := t.Attachment(attachmentId)
+	if !ok {
+		NotFoundHandler(w, r, "Attachment not found.")
+		return
+	}
+
+	bs := models.NewBlobStore()
+	defer bs.Close()
+
+	blob, err := bs.GetBlob(a.Id.Hex())
+	if err != nil {
+		NotFoundHandler(w, r, "Attachment not found.")
+		log.Printf("AttachmentGetHandler: Failure to open attachment %s: %s\n", a.Id.Hex(), err)
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set(ContentType, a.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(a.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(StatusSuccess)
+
+	_, err = io.Copy(w, blob)
+	if err != nil {
+		log.Printf("AttachmentGetHandler: Failure to send attachment %s: %s\n", a.Id.Hex(), err)
+	}
+}
+
+// AttachmentDeleteHandler removes an attachment from a todo.
+func AttachmentDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, attachmentId := vars["id"], vars["attachment_id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	if _, ok := authorizeTodo(w, r, tds, claims.UserId, id, authz.Edit); !ok {
+		return
+	}
+
+	err = tds.RemoveAttachment(id, attachmentId)
+	if err != nil {
+		NotFoundHandler(w, r, "Attachment not found.")
+		return
+	}
+
+	bs := models.NewBlobStore()
+	defer bs.Close()
+
+	err = bs.DeleteBlob(attachmentId)
+	if err != nil && err != models.BlobNotFoundError {
+		log.Printf("AttachmentDeleteHandler: Failure to delete attachment %s: %s\n", attachmentId, err)
+	}
+
+	res := jsonResponse{Result: fmt.Sprintf("Successfully deleted attachment: %s", attachmentId)}
+	msg, err := json.Marshal(res)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to delete attachment")
+		log.Println("Failure to delete attachment: " + err.Error())
+		return
+	}
+
+	w.WriteHeader(StatusSuccess)
+	w.Header().Set(ContentType, ApplicationJSON)
+	w.Write(msg)
+}
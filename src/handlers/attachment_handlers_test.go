@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"auth"
+	"bytes"
+	"github.com/gorilla/mux"
+	"log"
+	"mime/multipart"
+	"models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func init() {
+	models.BLOB_STORE_TYPE = models.Test
+}
+
+var pngHeader = []byte("\x89PNG\x0D\x0A\x1A\x0A\x00\x00\x00\x0DIHDR")
+
+// attachmentSetup stores a user owning a todo.
+func attachmentSetup() (string, models.Todo) {
+	u := models.NewUser()
+	models.NewUserStorage().InsertUser(u)
+	token, err := auth.CreateToken(u)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	t0 := models.NewTodo()
+	t0.Ownerid = u.Id.Hex()
+	models.NewTodoStorage().InsertTodo(t0)
+
+	return token, t0
+}
+
+func serveAttachment(token, method string, vars map[string]string, name string, contents []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	contentType := ""
+	if contents != nil {
+		mw := multipart.NewWriter(&body)
+		fw, err := mw.CreateFormFile("file", name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fw.Write(contents)
+		mw.Close()
+		contentType = mw.FormDataContentType()
+	}
+
+	req, rr := handlersSetup(method, "api/todos/"+vars["id"]+"/attachments", body.String())
+	req = mux.SetURLVars(req, vars)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if contentType != "" {
+		req.Header.Set(ContentType, contentType)
+	}
+
+	var handler http.HandlerFunc = AttachmentsHandler
+	if _, ok := vars["attachment_id"]; ok {
+		handler = AttachmentHandler
+	}
+
+	ValidatePath(handler).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestAttachments(t *testing.T) {
+	token, t0 := attachmentSetup()
+	vars := map[string]string{"id": t0.Id}
+	tds := models.NewTodoStorage()
+
+	rr := serveAttachment(token, "POST", vars, "screenshot.png", pngHeader)
+	testStatus(StatusCreation, rr, t)
+
+	t1, _ := tds.GetTodoById(t0.Id)
+	if len(t1.Attachments) != 1 {
+		t.Fatalf("Attachment was not linked to 2Do: %v", t1.Attachments)
+	}
+
+	a := t1.Attachments[0]
+	if a.Name != "screenshot.png" || a.ContentType != "image/png" || a.Size != int64(len(pngHeader)) {
+		t.Errorf("Incorrect attachment metadata: %v", a)
+	}
+
+	vars["attachment_id"] = a.Id.Hex()
+	rr = serveAttachment(token, "GET", vars, "", nil)
+	testStatus(StatusSuccess, rr, t)
+
+	if !bytes.Equal(rr.Body.Bytes(), pngHeader) || rr.Header().Get(ContentType) != "image/png" {
+		t.Errorf("Incorrect attachment download: %q", rr.Body.String())
+	}
+
+	rr = serveAttachment(token, "DELETE", vars, "", nil)
+	testStatus(StatusSuccess, rr, t)
+
+	t1, _ = tds.GetTodoById(t0.Id)
+	if len(t1.Attachments) != 0 {
+		t.Errorf("Attachment was not removed from 2Do: %v", t1.Attachments)
+	}
+
+	if _, err := models.NewBlobStore().GetBlob(a.Id.Hex()); err != models.BlobNotFoundError {
+		t.Errorf("Attachment contents were not deleted: %v", err)
+	}
+}
+
+func TestAttachmentsPostHandlerUnsupportedType(t *testing.T) {
+	token, t0 := attachmentSetup()
+	vars := map[string]string{"id": t0.Id}
+
+	rr := serveAttachment(token, "POST", vars, "screenshot.png", []byte("MZ\x90\x00\x03\x00\x00\x00"))
+	testStatus(StatusBadRequest, rr, t)
+}
+
+func TestAttachmentsPostHandlerQuota(t *testing.T) {
+	token, t0 := attachmentSetup()
+	vars := map[string]string{"id": t0.Id}
+
+	_, quota := uploadLimits()
+	full := models.NewAttachment()
+	full.Size = quota
+	models.NewTodoStorage().AddAttachment(t0.Id, full, quota)
+
+	rr := serveAttachment(token, "POST", vars, "screenshot.png", pngHeader)
+	testStatus(StatusTooLarge, rr, t)
+}
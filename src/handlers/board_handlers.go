@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"auth"
+	"authz"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"gopkg.in/mgo.v2/bson"
+	"log"
+	"models"
+	"net/http"
+	"time"
+)
+
+const (
+	// boardLockLease is how long a list is claimed while a todo moves
+	// into a state with a WIP limit. Requests wait up to boardLockWait
+	// for the claim of another request, retrying every boardLockRetry.
+	boardLockLease = 10 * time.Second
+	boardLockWait  = 3 * time.Second
+	boardLockRetry = 25 * time.Millisecond
+)
+
+var errBoardBusy = errors.New("The board is busy, try again")
+
+// lockList claims the list so that the WIP limits of its workflow can
+// be checked and kept by a single request at a time, across instances
+// of the server. It returns the function releasing the list.
+func lockList(listId string) (func(), error) {
+	holder := bson.NewObjectId().Hex()
+	lds := models.NewListStorage()
+
+	deadline := time.Now().Add(boardLockWait)
+	for {
+		ok, err := lds.LockList(listId, holder, time.Now(), boardLockLease)
+		if err != nil {
+			lds.Close()
+			return nil, err
+		}
+
+		if ok {
+			return func() {
+				if err := lds.UnlockList(listId, holder); err != nil {
+					log.Printf("lockList: Failure to unlock list %s: %s\n", listId, err)
+				}
+				lds.Close()
+			}, nil
+		}
+
+		if time.Now().After(deadline) {
+			lds.Close()
+			return nil, errBoardBusy
+		}
+		time.Sleep(boardLockRetry)
+	}
+}
+
+// listTodos returns every todo of the list, including those of other
+// users, which isn't archived.
+func listTodos(tds models.TodoStorage, userId, listId string) ([]models.Todo, error) {
+	return tds.GetTodosForFilter(userId, models.TodoFilter{ListId: listId, SharedListIds: []string{listId}})
+}
+
+// enforceWIPLimit refuses the changes if they move the todo into a
+// state whose WIP limit has been reached. The list is claimed while
+// the limit is checked, the returned function releases it once the
+// changes are written so that concurrent requests can't exceed the
+// limit. Otherwise it writes the error response.
+func enforceWIPLimit(w http.ResponseWriter, r *http.Request, tds models.TodoStorage, wf *models.Workflow, changes map[string]interface{}, t models.Todo, userId string) (func(), bool) {
+	noop := func() {}
+	if _, moved := changes["status_history"]; wf == nil || !moved {
+		return noop, true
+	}
+
+	to, _ := changes["status"].(string)
+	limit := wf.WIPLimit(to)
+	if limit == 0 {
+		return noop, true
+	}
+
+	listId := t.ListId
+	if id, ok := changes["list_id"].(string); ok {
+		listId = id
+	}
+
+	unlock, err := lockList(listId)
+	if err == errBoardBusy {
+		ConflictHandler(w, r, err.Error())
+		return nil, false
+	}
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to modify 2Do")
+		log.Println("Failure to lock list: " + err.Error())
+		return nil, false
+	}
+
+	ts, err := listTodos(tds, userId, listId)
+	if err != nil {
+		unlock()
+		InternalErrorHandler(w, r, "Failure to modify 2Do")
+		log.Println("Failure to get 2Dos of list: " + err.Error())
+		return nil, false
+	}
+
+	if wf.CountInState(ts, to, t.Id) >= limit {
+		unlock()
+		ConflictHandler(w, r, fmt.Sprintf("WIP limit of %d reached for state: %s", limit, to))
+		return nil, false
+	}
+
+	return unlock, true
+}
+
+// BoardHandler returns the board of a list with a workflow, a column
+// of todos per state in workflow order. The todos of a column are in
+// their manual order.
+func BoardHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	lds := models.NewListStorage()
+	defer lds.Close()
+
+	l, ok := authorizeList(w, r, lds, claims.UserId, id, authz.View)
+	if !ok {
+		return
+	}
+
+	if l.Workflow == nil {
+		BadRequestHandler(w, r, fmt.Sprintf("List has no workflow: %s", id))
+		return
+	}
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	ts, err := listTodos(tds, claims.UserId, id)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("Failed to get 2Dos of list: " + err.Error())
+		return
+	}
+	markBlocked(tds, ts)
+	markLogged(ts)
+
+	writeJSON(w, r, StatusSuccess, models.NewBoard(id, *l.Workflow, ts))
+}
+
+type transitionRequest struct {
+	Status string `json:"status"`
+	moveRequest
+}
+
+// TodoTransitionHandler moves a card of a board: the todo moves to the
+// status, as its workflow allows, and optionally between the before and
+// after todos of that column. Both are written at once and the WIP
+// limit of the status is enforced, see enforceWIPLimit.
+func TodoTransitionHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	var tr transitionRequest
+	err = json.NewDecoder(r.Body).Decode(&tr)
+	if err != nil || tr.Status == "" {
+		BadRequestHandler(w, r, "Body format incorrect for transition. Try: { \"status\": \"review\", \"after\": \"{id}\", \"before\": \"{id}\" }")
+		return
+	}
+	reorder := tr.Before != "" || tr.After != ""
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	// Reordering is editing, like moving a todo
+	action := authz.Complete
+	if reorder {
+		action = authz.Edit
+	}
+
+	t, ok := authorizeTodo(w, r, tds, claims.UserId, id, action)
+	if !ok {
+		return
+	}
+
+	wf := listWorkflow(t.ListId)
+	if wf == nil {
+		BadRequestHandler(w, r, models.ErrNoWorkflow.Error())
+		return
+	}
+
+	m := map[string]interface{}{"status": tr.Status}
+
+	var rebalanced map[string]float64
+	if reorder {
+		for _, nid := range []string{tr.Before, tr.After} {
+			if nid == "" {
+				continue
+			}
+
+			n, err := tds.GetTodoById(nid)
+			if err != nil || n.ListId != t.ListId || wf.StatusOf(*n) != tr.Status {
+				BadRequestHandler(w, r, fmt.Sprintf("2Do not found in column %s: %s", tr.Status, nid))
+				return
+			}
+		}
+
+		var position float64
+		position, rebalanced, ok = newPosition(w, r, tds, claims.UserId, *t, tr.moveRequest)
+		if !ok {
+			return
+		}
+		m["position"] = position
+		delete(rebalanced, id)
+	}
+
+	res, ok := modifyTodo(w, r, tds, *t, m, claims.UserId)
+	if !ok {
+		return
+	}
+
+	if len(rebalanced) > 0 {
+		if err := tds.SetTodoPositions(rebalanced); err != nil {
+			log.Println("Failure to rebalance 2Dos: " + err.Error())
+		}
+	}
+
+	if res.Data == nil {
+		if t, err := tds.GetTodoById(id); err == nil {
+			res.Data = t
+		}
+	}
+
+	writeJSON(w, r, StatusSuccess, res)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"models"
+	"testing"
+)
+
+func TestBoard(t *testing.T) {
+	u, token := shareSetup("board-TestBoard")
+	l := workflowSetup(t, u, token)
+
+	lds := models.NewListStorage()
+	wl, _ := lds.GetListById(l.Id.Hex())
+	wf := *wl.Workflow
+	wf.States[2].WIPLimit = 1 // review
+	lds.SetListWorkflow(l.Id.Hex(), &wf)
+
+	tds := models.NewTodoStorage()
+	ts := []models.Todo{models.NewTodo(), models.NewTodo(), models.NewTodo()}
+	for i := range ts {
+		ts[i].Ownerid = u.Id.Hex()
+		ts[i].ListId = l.Id.Hex()
+		ts[i].Status = "in progress"
+		ts[i].Position = float64(i + 1)
+		tds.InsertTodo(ts[i])
+	}
+	a, b, c := ts[0].Id, ts[1].Id, ts[2].Id
+
+	transition := func(id, body string) int {
+		req, rr := handlersSetup("POST", "api/todos/"+id+"/transition", body)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		ValidatePath(TodoTransitionHandler).ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := transition(a, `{"status": "review"}`); code != StatusSuccess {
+		t.Fatalf("Failure to transition 2Do: got %d", code)
+	}
+	if code := transition(b, `{"status": "review"}`); code != StatusConflict {
+		t.Errorf("WIP limit should be enforced: got %d", code)
+	}
+	if code := transition(b, `{"status": "done"}`); code != StatusConflict {
+		t.Errorf("Workflow transitions should be enforced: got %d", code)
+	}
+	if code := transition(b, `{"status": "blocked", "before": "`+a+`"}`); code != StatusBadRequest {
+		t.Errorf("Neighbours should be in the target column: got %d", code)
+	}
+
+	if code := transition(c, `{"status": "blocked"}`); code != StatusSuccess {
+		t.Fatalf("Failure to transition 2Do: got %d", code)
+	}
+	if code := transition(b, `{"status": "blocked", "before": "`+c+`"}`); code != StatusSuccess {
+		t.Fatalf("Failure to transition 2Do: got %d", code)
+	}
+
+	req, rr := handlersSetup("GET", "api/lists/"+l.Id.Hex()+"/board", "")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req = mux.SetURLVars(req, map[string]string{"id": l.Id.Hex()})
+	ValidatePath(BoardHandler).ServeHTTP(rr, req)
+	testStatus(StatusSuccess, rr, t)
+
+	var board models.Board
+	json.Unmarshal(rr.Body.Bytes(), &board)
+	if len(board.Columns) != 5 {
+		t.Fatalf("Expected a column per state: %+v", board)
+	}
+	review, blocked := board.Columns[2], board.Columns[3]
+	if review.Count != 1 || review.WIPLimit != 1 || review.Todos[0].Id != a {
+		t.Errorf("Incorrect review column: %+v", review)
+	}
+	if blocked.Count != 2 || blocked.Todos[0].Id != b || blocked.Todos[1].Id != c {
+		t.Errorf("Incorrect blocked column: %+v", blocked)
+	}
+
+	inbox := models.NewList()
+	inbox.Ownerid = u.Id.Hex()
+	lds.InsertList(inbox)
+
+	req, rr = handlersSetup("GET", "api/lists/"+inbox.Id.Hex()+"/board", "")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req = mux.SetURLVars(req, map[string]string{"id": inbox.Id.Hex()})
+	ValidatePath(BoardHandler).ServeHTTP(rr, req)
+	testStatus(StatusBadRequest, rr, t)
+}
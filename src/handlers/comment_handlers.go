@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"auth"
+	"authz"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"log"
+	"models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultComments = 50
+	maxComments     = 200
+)
+
+// pageFromQuery returns the number of objects to skip and the number
+// to return as set by ?offset= and ?limit=.
+func pageFromQuery(q url.Values, defaultLimit, maxLimit int) (int, int, error) {
+	offset, limit := 0, defaultLimit
+
+	if o := q.Get("offset"); o != "" {
+		var err error
+		offset, err = strconv.Atoi(o)
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("offset must be a positive number")
+		}
+	}
+
+	if l := q.Get("limit"); l != "" {
+		var err error
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > maxLimit {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+		}
+	}
+
+	return offset, limit, nil
+}
+
+// commentableTodo returns the todo with the id if the user may take
+// part in its discussion, otherwise it writes the error response.
+func commentableTodo(w http.ResponseWriter, r *http.Request, id, userId string) (*models.Todo, bool) {
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	return authorizeTodo(w, r, tds, userId, id, authz.Comment)
+}
+
+// removeComments deletes the comments of deleted todos. Failures are
+// only logged since the todos are already gone.
+func removeComments(ts ...models.Todo) {
+	cs := models.NewCommentStorage()
+	defer cs.Close()
+
+	for _, t := range ts {
+		err := cs.DeleteCommentsForTodoId(t.Id)
+		if err != nil {
+			log.Printf("removeComments: Failure to delete comments of 2Do %s: %s\n", t.Id, err)
+		}
+	}
+}
+
+// CommentsHandler is the handler function for the
+// /api/todos/{id}/comments endpoint.
+func CommentsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		CommentsGetHandler(w, r)
+	case "POST":
+		CommentsPostHandler(w, r)
+	}
+}
+
+// CommentHandler is the handler function for the
+// /api/todos/{id}/comments/{comment_id} endpoint.
+func CommentHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "PUT":
+		CommentPutHandler(w, r)
+	case "DELETE":
+		CommentDeleteHandler(w, r)
+	}
+}
+
+// CommentsGetHandler returns the comments of a todo oldest first,
+// paginated with ?offset= and ?limit=.
+func CommentsGetHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	offset, limit, err := pageFromQuery(r.URL.Query(), defaultComments, maxComments)
+	if err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+
+	if _, ok := commentableTodo(w, r, id, claims.UserId); !ok {
+		return
+	}
+
+	cs := models.NewCommentStorage()
+	defer cs.Close()
+
+	comments, err := cs.GetCommentsForTodoId(id, offset, limit)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to retrieve comments")
+		log.Println("Failed to get comments: " + err.Error())
+		return
+	}
+
+	data, err := json.Marshal(comments)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("Failed to get comments: " + err.Error())
+		return
+	}
+
+	w.WriteHeader(StatusSuccess)
+	w.Header().Set(ContentType, ApplicationJSON)
+	w.Write(data)
+}
+
+// commentBody decodes the body of a comment from the request.
+func commentBody(r *http.Request) (string, error) {
+	v := struct {
+		Body string `json:"body"`
+	}{}
+
+	err := json.NewDecoder(r.Body).Decode(&v)
+	if err != nil {
+		return "", err
+	}
+
+	return models.ValidateCommentBody(v.Body)
+}
+
+// CommentsPostHandler adds a comment by the user to a todo.
+func CommentsPostHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	body, err := commentBody(r)
+	if err != nil {
+		BadRequestHandler(w, r, fmt.Sprintf("Invalid comment: %s", err))
+		return
+	}
+
+	if _, ok := commentableTodo(w, r, id, claims.UserId); !ok {
+		return
+	}
+
+	c := models.NewComment()
+	c.TodoId = id
+	c.Authorid = claims.UserId
+	c.Body = body
+
+	cs := models.NewCommentStorage()
+	defer cs.Close()
+
+	err = cs.InsertComment(c)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to add comment")
+		log.Println("Failure to add comment: " + err.Error())
+		return
+	}
+
+	res := jsonResponse{Result: fmt.Sprintf("Successfully added comment: %s", c.Id.Hex()), Data: c}
+	msg, err := json.Marshal(res)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to add comment")
+		log.Println("Failure to add comment: " + err.Error())
+		return
+	}
+
+	w.WriteHeader(StatusCreation)
+	w.Header().Set(ContentType, ApplicationJSON)
+	w.Write(msg)
+}
+
+// todoComment returns the comment with the id if it belongs to the
+// todo and the user may take part in the discussion of the todo,
+// otherwise it writes the error response.
+func todoComment(w http.ResponseWriter, r *http.Request, cs models.CommentStorage, todoId, commentId, userId string) (*models.Todo, *models.Comment, bool) {
+	t, ok := commentableTodo(w, r, todoId, userId)
+	if !ok {
+		return nil, nil, false
+	}
+
+	c, err := cs.GetCommentById(commentId)
+	if err != nil || c.TodoId != todoId {
+		NotFoundHandler(w, r, "Comment not found.")
+		return nil, nil, false
+	}
+
+	return t, c, true
+}
+
+// CommentPutHandler edits the body of a comment. Only the author
+// of a comment may edit it.
+func CommentPutHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, commentId := vars["id"], vars["comment_id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	body, err := commentBody(r)
+	if err != nil {
+		BadRequestHandler(w, r, fmt.Sprintf("Invalid comment: %s", err))
+		return
+	}
+
+	cs := models.NewCommentStorage()
+	defer cs.Close()
+
+	_, c, ok := todoComment(w, r, cs, id, commentId, claims.UserId)
+	if !ok {
+		return
+	}
+
+	if c.Authorid != claims.UserId {
+		ForbiddenHandler(w, r, "Only the author may edit a comment.")
+		return
+	}
+
+	err = cs.EditComment(commentId, claims.UserId, body, time.Now())
+	if err != nil {
+		NotFoundHandler(w, r, "Comment not found.")
+		return
+	}
+
+	res := jsonResponse{Result: fmt.Sprintf("Successfully modified comment: %s", commentId)}
+	msg, err := json.Marshal(res)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to modify comment")
+		log.Println("Failure to modify comment: " + err.Error())
+		return
+	}
+
+	w.WriteHeader(StatusSuccess)
+	w.Header().Set(ContentType, ApplicationJSON)
+	w.Write(msg)
+}
+
+// CommentDeleteHandler deletes a comment. Comments may be deleted by
+// their author or by the users who may delete the todo.
+func CommentDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, commentId := vars["id"], vars["comment_id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	cs := models.NewCommentStorage()
+	defer cs.Close()
+
+	t, c, ok := todoComment(w, r, cs, id, commentId, claims.UserId)
+	if !ok {
+		return
+	}
+
+	if c.Authorid != claims.UserId && !authz.Allows(authz.TodoRole(claims.UserId, *t), authz.Delete) {
+		ForbiddenHandler(w, r, "Only the author may delete a comment.")
+		return
+	}
+
+	err = cs.DeleteComment(commentId)
+	if err != nil {
+		NotFoundHandler(w, r, "Comment not found.")
+		return
+	}
+
+	res := jsonResponse{Result: fmt.Sprintf("Successfully deleted comment: %s", commentId)}
+	msg, err := json.Marshal(res)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to delete comment")
+		log.Println("Failure to delete comment: " + err.Error())
+		return
+	}
+
+	w.WriteHeader(StatusSuccess)
+	w.Header().Set(ContentType, ApplicationJSON)
+	w.Write(msg)
+}
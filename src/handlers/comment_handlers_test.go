@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"auth"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"log"
+	"models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func init() {
+	models.COMMENT_STORE_TYPE = models.Test
+}
+
+func serveComments(token, method, body string, vars map[string]string) *httptest.ResponseRecorder {
+	req, rr := handlersSetup(method, "api/todos/"+vars["id"]+"/comments", body)
+	req = mux.SetURLVars(req, vars)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	var handler http.HandlerFunc = CommentsHandler
+	if _, ok := vars["comment_id"]; ok {
+		handler = CommentHandler
+	}
+
+	ValidatePath(handler).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestComments(t *testing.T) {
+	token, t0 := attachmentSetup()
+	vars := map[string]string{"id": t0.Id}
+
+	for _, body := range []string{"First", "Second", "Third"} {
+		rr := serveComments(token, "POST", "{\"body\": \""+body+"\"}", vars)
+		testStatus(StatusCreation, rr, t)
+	}
+
+	rr := serveComments(token, "POST", "{\"body\": \"  \"}", vars)
+	testStatus(StatusBadRequest, rr, t)
+
+	req, rr := handlersSetup("GET", "api/todos/"+t0.Id+"/comments?offset=1&limit=1", "")
+	req = mux.SetURLVars(req, vars)
+	req.Header.Set("Authorization", "Bearer "+token)
+	ValidatePath(CommentsHandler).ServeHTTP(rr, req)
+	testStatus(StatusSuccess, rr, t)
+
+	var cs []models.Comment
+	if err := json.Unmarshal(rr.Body.Bytes(), &cs); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cs) != 1 || cs[0].Body != "Second" || cs[0].Authorid != t0.Ownerid {
+		t.Fatalf("Incorrect page of comments: %v", cs)
+	}
+
+	vars["comment_id"] = cs[0].Id.Hex()
+	rr = serveComments(token, "PUT", "{\"body\": \"Edited\"}", vars)
+	testStatus(StatusSuccess, rr, t)
+
+	c, _ := models.NewCommentStorage().GetCommentById(cs[0].Id.Hex())
+	if c.Body != "Edited" || c.Edited == nil {
+		t.Errorf("Comment was not edited: %v", c)
+	}
+
+	// Deleting the 2Do deletes its comments
+	req, rr = handlersSetup("DELETE", "api/todos/"+t0.Id, "")
+	req = mux.SetURLVars(req, map[string]string{"id": t0.Id})
+	req.Header.Set("Authorization", "Bearer "+token)
+	ValidatePath(TodoHandler).ServeHTTP(rr, req)
+	testStatus(StatusSuccess, rr, t)
+
+	left, _ := models.NewCommentStorage().GetCommentsForTodoId(t0.Id, 0, 0)
+	if len(left) != 0 {
+		t.Errorf("Comments of deleted 2Do were not deleted: %v", left)
+	}
+}
+
+func TestCommentsOtherUser(t *testing.T) {
+	_, t0 := attachmentSetup()
+	vars := map[string]string{"id": t0.Id}
+
+	c := models.NewComment()
+	c.TodoId = t0.Id
+	c.Authorid = t0.Ownerid
+	c.Body = "Mine"
+	models.NewCommentStorage().InsertComment(c)
+
+	u := models.NewUser()
+	models.NewUserStorage().InsertUser(u)
+	token, err := auth.CreateToken(u)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rr := serveComments(token, "POST", "{\"body\": \"Not mine\"}", vars)
+	testStatus(StatusNotFound, rr, t)
+
+	vars["comment_id"] = c.Id.Hex()
+	rr = serveComments(token, "DELETE", "", vars)
+	testStatus(StatusNotFound, rr, t)
+}
@@ -10,6 +10,7 @@ import (
 const (
 	ContentType     = "Content-Type"
 	ApplicationJSON = "application/json"
+	TextCalendar    = "text/calendar; charset=utf-8"
 )
 
 const (
@@ -17,7 +18,10 @@ const (
 	StatusCreation      = 201
 	StatusBadRequest    = 400
 	StatusUnauthorized  = 401
+	StatusForbidden     = 403
 	StatusNotFound      = 404
+	StatusConflict      = 409
+	StatusTooLarge      = 413
 	StatusInternalError = 500
 )
 
@@ -92,3 +96,68 @@ func BadRequestHandler(w http.ResponseWriter, r *http.Request, errMsg string) {
 
 	w.Write(msg)
 }
+
+func RequestTooLargeHandler(w http.ResponseWriter, r *http.Request, errMsg string) {
+	w.WriteHeader(StatusTooLarge)
+	w.Header().Set(ContentType, ApplicationJSON)
+	v := jsonResponse{ErrorMessage: errMsg}
+
+	msg, err := json.Marshal(v)
+	if err != nil || errMsg == "" {
+		w.Write([]byte("{ \"error_message\": \"Request Entity Too Large.\"}"))
+		if err != nil {
+			log.Println(fmt.Sprintf("Failure to marshal jsonResponse: %v", err))
+		}
+		return
+	}
+
+	w.Write(msg)
+}
+
+func ForbiddenHandler(w http.ResponseWriter, r *http.Request, errMsg string) {
+	w.WriteHeader(StatusForbidden)
+	w.Header().Set(ContentType, ApplicationJSON)
+	v := jsonResponse{ErrorMessage: errMsg}
+
+	msg, err := json.Marshal(v)
+	if err != nil || errMsg == "" {
+		w.Write([]byte("{ \"error_message\": \"Insufficient permissions.\"}"))
+		if err != nil {
+			log.Println(fmt.Sprintf("Failure to marshal jsonResponse: %v", err))
+		}
+		return
+	}
+
+	w.Write(msg)
+}
+
+func ConflictHandler(w http.ResponseWriter, r *http.Request, errMsg string) {
+	w.WriteHeader(StatusConflict)
+	w.Header().Set(ContentType, ApplicationJSON)
+	v := jsonResponse{ErrorMessage: errMsg}
+
+	msg, err := json.Marshal(v)
+	if err != nil || errMsg == "" {
+		w.Write([]byte("{ \"error_message\": \"Conflict.\"}"))
+		if err != nil {
+			log.Println(fmt.Sprintf("Failure to marshal jsonResponse: %v", err))
+		}
+		return
+	}
+
+	w.Write(msg)
+}
+
+// writeJSON writes v as json with the status.
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	msg, err := json.Marshal(v)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println(fmt.Sprintf("Failure to marshal response: %v", err))
+		return
+	}
+
+	w.WriteHeader(status)
+	w.Header().Set(ContentType, ApplicationJSON)
+	w.Write(msg)
+}
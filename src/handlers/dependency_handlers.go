@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"auth"
+	"authz"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"log"
+	"models"
+	"net/http"
+	"strings"
+)
+
+// maxGraphNodes bounds the size of the graph returned by TodoGraphHandler.
+const maxGraphNodes = 500
+
+// blockersFromRequest validates the blockers of the todo with the id
+// todoId, which may be empty for a new todo. Every blocker must be
+// viewable by the user and must not create a cycle. It writes the
+// response itself on failure.
+func blockersFromRequest(w http.ResponseWriter, r *http.Request, tds models.TodoStorage, userId, todoId string, ids []string) ([]string, bool) {
+	ids, err := models.NormalizeBlockedBy(ids)
+	if err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return nil, false
+	}
+
+	for _, id := range ids {
+		if _, err := authz.Todo(tds, userId, id, authz.View); err != nil {
+			BadRequestHandler(w, r, fmt.Sprintf("Blocker not found: %s", id))
+			return nil, false
+		}
+	}
+
+	if todoId != "" {
+		err = models.CheckDependencies(tds, todoId, ids)
+		if err == models.ErrDependencyCycle {
+			BadRequestHandler(w, r, err.Error())
+			return nil, false
+		}
+		if err != nil {
+			InternalErrorHandler(w, r, "")
+			log.Println("Failure to check 2Do dependencies: " + err.Error())
+			return nil, false
+		}
+	}
+
+	return ids, true
+}
+
+// stringsFromJSON converts a decoded json array into strings.
+func stringsFromJSON(v interface{}) ([]string, bool) {
+	vs, ok := v.([]interface{})
+	if !ok {
+		return nil, v == nil
+	}
+
+	ss := make([]string, 0, len(vs))
+	for _, v := range vs {
+		s, ok := v.(string)
+		if !ok {
+			return nil, false
+		}
+		ss = append(ss, s)
+	}
+
+	return ss, true
+}
+
+// refuseBlockedCompletion writes a conflict response if the todo still
+// has open blockers, unless the request is forced with ?force=true.
+func refuseBlockedCompletion(w http.ResponseWriter, r *http.Request, tds models.TodoStorage, t models.Todo) bool {
+	if t.Completed || r.URL.Query().Get("force") == "true" {
+		return false
+	}
+
+	open, err := models.BlockersOpen(tds, t)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("Failure to get 2Do blockers: " + err.Error())
+		return true
+	}
+
+	if len(open) == 0 {
+		return false
+	}
+
+	ids := make([]string, len(open))
+	for i, b := range open {
+		ids[i] = b.Id
+	}
+	ConflictHandler(w, r, fmt.Sprintf("2Do is blocked by: %s. Complete them first or use ?force=true", strings.Join(ids, ", ")))
+
+	return true
+}
+
+// markBlocked sets the computed blocked flag of the todos.
+func markBlocked(tds models.TodoStorage, ts []models.Todo) {
+	if err := models.MarkBlocked(tds, ts); err != nil {
+		log.Println("Failure to mark blocked 2Dos: " + err.Error())
+	}
+}
+
+// removeBlocker removes the deleted todo from the blockers of other todos.
+func removeBlocker(tds models.TodoStorage, id string) {
+	if err := tds.RemoveBlocker(id); err != nil {
+		log.Println(fmt.Sprintf("Failure to remove blocker %s: %s", id, err))
+	}
+}
+
+type graphNode struct {
+	Id        string `json:"id"`
+	Title     string `json:"title"`
+	Completed bool   `json:"completed"`
+	Blocked   bool   `json:"blocked"`
+}
+
+// graphEdge is the dependency of To on From i.e. From blocks To.
+type graphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type dependencyGraph struct {
+	Nodes []graphNode `json:"nodes"`
+	Edges []graphEdge `json:"edges"`
+}
+
+// TodoGraphHandler returns the dependency graph of a todo, that is
+// every todo which transitively blocks it or is blocked by it. Todos
+// the user can't view are left out.
+func TodoGraphHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	t, ok := authorizeTodo(w, r, tds, claims.UserId, id, authz.View)
+	if !ok {
+		return
+	}
+
+	todos := map[string]models.Todo{id: *t}
+	order := []string{id}
+	queue := []string{id}
+	for len(queue) > 0 && len(order) < maxGraphNodes {
+		current := todos[queue[0]]
+		queue = queue[1:]
+
+		neighbours := append([]string{}, current.BlockedBy...)
+		blocked, err := tds.GetTodosBlockedBy(current.Id)
+		if err != nil {
+			InternalErrorHandler(w, r, "")
+			log.Println("Failure to get 2Do graph: " + err.Error())
+			return
+		}
+		for _, b := range blocked {
+			neighbours = append(neighbours, b.Id)
+		}
+
+		for _, n := range neighbours {
+			if _, seen := todos[n]; seen || len(order) >= maxGraphNodes {
+				continue
+			}
+
+			nt, err := authz.Todo(tds, claims.UserId, n, authz.View)
+			if err != nil {
+				continue
+			}
+
+			todos[n] = *nt
+			order = append(order, n)
+			queue = append(queue, n)
+		}
+	}
+
+	ts := make([]models.Todo, len(order))
+	for i, n := range order {
+		ts[i] = todos[n]
+	}
+	markBlocked(tds, ts)
+
+	g := dependencyGraph{Nodes: make([]graphNode, 0, len(ts)), Edges: make([]graphEdge, 0)}
+	for _, t := range ts {
+		g.Nodes = append(g.Nodes, graphNode{Id: t.Id, Title: t.Title, Completed: t.Completed, Blocked: t.Blocked})
+		for _, b := range t.BlockedBy {
+			if _, ok := todos[b]; ok {
+				g.Edges = append(g.Edges, graphEdge{From: b, To: t.Id})
+			}
+		}
+	}
+
+	data, err := json.Marshal(g)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("Failure to get 2Do graph: " + err.Error())
+		return
+	}
+
+	w.WriteHeader(StatusSuccess)
+	w.Header().Set(ContentType, ApplicationJSON)
+	w.Write(data)
+}
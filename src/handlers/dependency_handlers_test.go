@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTodoDependencies(t *testing.T) {
+	token, t0 := attachmentSetup()
+	blocker := models.NewTodo()
+	blocker.Ownerid = t0.Ownerid
+	blocker.Title = "First"
+	tds := models.NewTodoStorage()
+	tds.InsertTodo(t0)
+	tds.InsertTodo(blocker)
+
+	serve := func(h http.HandlerFunc, id, method, path, body string) *httptest.ResponseRecorder {
+		req, rr := handlersSetup(method, path, body)
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		req.Header.Set("Authorization", "Bearer "+token)
+		ValidatePath(h).ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve(TodoHandler, t0.Id, "PUT", "api/todos/"+t0.Id, "{\"blocked_by\": [\""+blocker.Id+"\"]}")
+	testStatus(StatusSuccess, rr, t)
+
+	// The blocker can't in turn be blocked by the 2Do
+	rr = serve(TodoHandler, blocker.Id, "PUT", "api/todos/"+blocker.Id, "{\"blocked_by\": [\""+t0.Id+"\"]}")
+	testStatus(StatusBadRequest, rr, t)
+
+	rr = serve(TodoHandler, t0.Id, "GET", "api/todos/"+t0.Id, "")
+	var got models.Todo
+	json.Unmarshal(rr.Body.Bytes(), &got)
+	if !got.Blocked {
+		t.Error("2Do should be blocked")
+	}
+
+	rr = serve(TodoHandler, t0.Id, "PUT", "api/todos/"+t0.Id, "{\"completed\": true}")
+	testStatus(StatusConflict, rr, t)
+
+	rr = serve(TodoGraphHandler, t0.Id, "GET", "api/todos/"+t0.Id+"/graph", "")
+	testStatus(StatusSuccess, rr, t)
+	var g dependencyGraph
+	json.Unmarshal(rr.Body.Bytes(), &g)
+	if len(g.Nodes) != 2 || len(g.Edges) != 1 || g.Edges[0].From != blocker.Id || g.Edges[0].To != t0.Id {
+		t.Errorf("Incorrect dependency graph: %v", g)
+	}
+
+	rr = serve(TodoHandler, t0.Id, "PUT", "api/todos/"+t0.Id+"?force=true", "{\"completed\": true}")
+	testStatus(StatusSuccess, rr, t)
+}
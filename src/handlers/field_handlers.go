@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"auth"
+	"authz"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"log"
+	"models"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// fieldsParam prefixes the query parameters filtering and sorting the
+// todos by a custom field e.g. ?fields.estimate.lt=3&sort=-fields.estimate
+const fieldsParam = "fields."
+
+// listFields returns the custom fields of the list, none when there is
+// no list.
+func listFields(listId string) ([]models.CustomField, error) {
+	if listId == "" {
+		return nil, nil
+	}
+
+	lds := models.NewListStorage()
+	defer lds.Close()
+
+	l, err := lds.GetListById(listId)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.Fields, nil
+}
+
+// validateFieldUsers checks that the values of the user fields are
+// the ids of users.
+func validateFieldUsers(fields []models.CustomField, values map[string]interface{}) error {
+	for k, v := range values {
+		if f := models.FindField(fields, k); f != nil && f.Type == models.FieldUser {
+			if err := validateAssignee(v.(string)); err != nil {
+				return fmt.Errorf("User of %s not found: %s", k, v)
+			}
+		}
+	}
+
+	return nil
+}
+
+// todoFieldValues returns the custom field values of a todo of the list
+// with the changes applied, see models.FieldValues.
+func todoFieldValues(listId string, values, changes map[string]interface{}) (map[string]interface{}, error) {
+	fields, err := listFields(listId)
+	if err != nil {
+		return nil, fmt.Errorf("List not found: %s", listId)
+	}
+
+	values, err = models.FieldValues(fields, values, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	return values, validateFieldUsers(fields, values)
+}
+
+// applyFields adds the custom field values of the todo to the changes
+// when they set some of them, null removing a value, or move the todo
+// to another list. Otherwise it writes the error response.
+func applyFields(w http.ResponseWriter, r *http.Request, changes map[string]interface{}, t models.Todo) bool {
+	v, hasFields := changes["fields"]
+	listId, moved := changes["list_id"].(string)
+	moved = moved && listId != t.ListId
+	if !hasFields && !moved {
+		return true
+	}
+
+	if !moved {
+		listId = t.ListId
+	}
+
+	fieldChanges, ok := v.(map[string]interface{})
+	if v != nil && !ok {
+		BadRequestHandler(w, r, "fields must be an object of values by field key")
+		return false
+	}
+
+	values, err := todoFieldValues(listId, t.Fields, fieldChanges)
+	if err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return false
+	}
+
+	changes["fields"] = values
+	return true
+}
+
+// fieldQuery narrows the filter down by the custom field conditions of
+// the query e.g. ?fields.customer=Acme or ?fields.due.gte=2024-01-31 and
+// returns the field to sort by from ?sort=fields.{key}, descending with
+// ?sort=-fields.{key}. Fields are those of the list the todos are
+// filtered by.
+func fieldQuery(q url.Values, f *models.TodoFilter) (sortBy *models.CustomField, desc bool, err error) {
+	sort := q.Get("sort")
+	desc = strings.HasPrefix(sort, "-"+fieldsParam)
+	sort = strings.TrimPrefix(sort, "-")
+
+	var conditions []string
+	for param := range q {
+		if strings.HasPrefix(param, fieldsParam) {
+			conditions = append(conditions, param)
+		}
+	}
+
+	if len(conditions) == 0 && !strings.HasPrefix(sort, fieldsParam) {
+		return nil, false, nil
+	}
+
+	if f.ListId == "" || f.ListId == models.InboxListId {
+		return nil, false, fmt.Errorf("Filtering or sorting by fields requires a list_id")
+	}
+
+	fields, err := listFields(f.ListId)
+	if err != nil {
+		return nil, false, fmt.Errorf("List not found: %s", f.ListId)
+	}
+
+	for _, param := range conditions {
+		key, op := strings.TrimPrefix(param, fieldsParam), ""
+		if i := strings.Index(key, "."); i >= 0 {
+			key, op = key[:i], key[i+1:]
+		}
+
+		field := models.FindField(fields, key)
+		if field == nil {
+			return nil, false, fmt.Errorf("%s: %s", models.ErrUnknownField, key)
+		}
+
+		c, err := models.ParseFieldCondition(*field, op, q.Get(param))
+		if err != nil {
+			return nil, false, err
+		}
+		f.Fields = append(f.Fields, c)
+	}
+
+	if strings.HasPrefix(sort, fieldsParam) {
+		key := strings.TrimPrefix(sort, fieldsParam)
+		if sortBy = models.FindField(fields, key); sortBy == nil {
+			return nil, false, fmt.Errorf("%s: %s", models.ErrUnknownField, key)
+		}
+	}
+
+	return sortBy, desc, nil
+}
+
+// FieldsHandler is the handler function for the
+// /api/lists/{id}/fields endpoint.
+func FieldsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		FieldsGetHandler(w, r)
+	case "PUT":
+		FieldsPutHandler(w, r)
+	}
+}
+
+// FieldsGetHandler returns the custom fields of a list.
+func FieldsGetHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	lds := models.NewListStorage()
+	defer lds.Close()
+
+	l, ok := authorizeList(w, r, lds, claims.UserId, id, authz.View)
+	if !ok {
+		return
+	}
+
+	fields := l.Fields
+	if fields == nil {
+		fields = []models.CustomField{}
+	}
+
+	writeJSON(w, r, StatusSuccess, fields)
+}
+
+// FieldsPutHandler replaces the custom fields of a list. The body is an
+// array of fields such as [{ "key": "estimate", "type": "number" },
+// { "key": "size", "type": "enum", "options": ["S", "M", "L"] }]. Values
+// of the todos of the list which no longer fit the fields are dropped
+// when their values are next modified.
+func FieldsPutHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	var fields []models.CustomField
+	err = json.NewDecoder(r.Body).Decode(&fields)
+	if err != nil {
+		BadRequestHandler(w, r, "Body format incorrect for fields. Try: [{ \"key\": \"estimate\", \"name\": \"Estimate\", \"type\": \"number\" }]")
+		return
+	}
+
+	if err := models.ValidateCustomFields(fields); err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+
+	lds := models.NewListStorage()
+	defer lds.Close()
+
+	if _, ok := authorizeList(w, r, lds, claims.UserId, id, authz.Edit); !ok {
+		return
+	}
+
+	err = lds.SetListFields(id, fields)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to modify fields")
+		log.Println("Failure to modify fields: " + err.Error())
+		return
+	}
+
+	writeJSON(w, r, StatusSuccess, jsonResponse{
+		Result: fmt.Sprintf("Successfully modified fields of list: %s", id),
+		Data:   fields,
+	})
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"models"
+	"testing"
+)
+
+const teamFields = `[{"key": "customer", "type": "text"}, {"key": "estimate", "name": "Estimate", "type": "number"},
+	{"key": "size", "type": "enum", "options": ["S", "M", "L"]}, {"key": "reviewer", "type": "user"}]`
+
+func TestFields(t *testing.T) {
+	u, token := shareSetup("fields-TestFields")
+
+	l := models.NewList()
+	l.Ownerid = u.Id.Hex()
+	models.NewListStorage().InsertList(l)
+	listId := l.Id.Hex()
+
+	putFields := func(body string) int {
+		req, rr := handlersSetup("PUT", "api/lists/"+listId+"/fields", body)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req = mux.SetURLVars(req, map[string]string{"id": listId})
+		ValidatePath(FieldsHandler).ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := putFields(`[{"key": "size", "type": "enum"}]`); code != StatusBadRequest {
+		t.Errorf("Enum fields without options should be rejected: got %d", code)
+	}
+	if code := putFields(teamFields); code != StatusSuccess {
+		t.Fatalf("Failure to set fields: got %d", code)
+	}
+
+	post := func(body string) (int, models.Todo) {
+		req, rr := handlersSetup("POST", "api/todos", body)
+		req.Header.Set("Authorization", "Bearer "+token)
+		ValidatePath(TodosHandler).ServeHTTP(rr, req)
+
+		var created struct {
+			Data models.Todo `json:"data"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &created)
+		return rr.Code, created.Data
+	}
+
+	if code, _ := post(`{"title": "x", "list_id": "` + listId + `", "fields": {"size": "XL"}}`); code != StatusBadRequest {
+		t.Errorf("Values outside the options should be rejected: got %d", code)
+	}
+	if code, _ := post(`{"title": "x", "list_id": "` + listId + `", "fields": {"reviewer": "nobody"}}`); code != StatusBadRequest {
+		t.Errorf("Unknown users should be rejected: got %d", code)
+	}
+	if code, _ := post(`{"title": "x", "fields": {"size": "S"}}`); code != StatusBadRequest {
+		t.Errorf("Todos of the inbox have no fields: got %d", code)
+	}
+
+	ids := make([]string, 3)
+	for i, body := range []string{
+		`{"customer": "Acme", "estimate": 8, "size": "L", "reviewer": "` + u.Id.Hex() + `"}`,
+		`{"customer": "Initech", "estimate": 2}`,
+		`{"customer": "Acme"}`,
+	} {
+		code, created := post(`{"title": "x", "list_id": "` + listId + `", "fields": ` + body + `}`)
+		if code != StatusCreation {
+			t.Fatalf("Failure to create 2Do with fields: got %d", code)
+		}
+		ids[i] = created.Id
+	}
+
+	put := func(id, body string) int {
+		req, rr := handlersSetup("PUT", "api/todos/"+id, body)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		ValidatePath(TodoHandler).ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := put(ids[2], `{"fields": {"estimate": "a lot"}}`); code != StatusBadRequest {
+		t.Errorf("Values of the wrong type should be rejected: got %d", code)
+	}
+	if code := put(ids[2], `{"fields": {"estimate": 5, "customer": null}}`); code != StatusSuccess {
+		t.Fatalf("Failure to modify fields: got %d", code)
+	}
+
+	tds := models.NewTodoStorage()
+	modified, _ := tds.GetTodoById(ids[2])
+	if _, ok := modified.Fields["customer"]; ok || modified.Fields["estimate"] != 5.0 {
+		t.Errorf("Incorrect fields after modifying: %v", modified.Fields)
+	}
+
+	get := func(query string) (int, []string) {
+		req, rr := handlersSetup("GET", "api/todos?"+query, "")
+		req.Header.Set("Authorization", "Bearer "+token)
+		ValidatePath(TodosHandler).ServeHTTP(rr, req)
+
+		var ts []models.Todo
+		json.Unmarshal(rr.Body.Bytes(), &ts)
+		got := make([]string, len(ts))
+		for i, t := range ts {
+			got[i] = t.Id
+		}
+		return rr.Code, got
+	}
+
+	if code, got := get("list_id=" + listId + "&fields.customer=Acme"); code != StatusSuccess || len(got) != 1 || got[0] != ids[0] {
+		t.Errorf("Incorrect 2Dos for customer filter: %d %v", code, got)
+	}
+	if code, got := get("list_id=" + listId + "&fields.estimate.gte=5&sort=fields.estimate"); code != StatusSuccess || len(got) != 2 || got[0] != ids[2] || got[1] != ids[0] {
+		t.Errorf("Incorrect 2Dos for estimate filter: %d %v", code, got)
+	}
+	if code, got := get("list_id=" + listId + "&sort=-fields.estimate"); code != StatusSuccess || len(got) != 3 || got[0] != ids[0] || got[2] != ids[1] {
+		t.Errorf("Incorrect order by estimate: %d %v", code, got)
+	}
+	if code, _ := get("fields.customer=Acme"); code != StatusBadRequest {
+		t.Errorf("Field filters without a list should be rejected: got %d", code)
+	}
+	if code, _ := get("list_id=" + listId + "&fields.color=red"); code != StatusBadRequest {
+		t.Errorf("Unknown fields should be rejected: got %d", code)
+	}
+
+	// Moving to the inbox drops the values
+	if code := put(ids[0], `{"list_id": ""}`); code != StatusSuccess {
+		t.Fatalf("Failure to move 2Do: got %d", code)
+	}
+	moved, _ := tds.GetTodoById(ids[0])
+	if moved.Fields != nil {
+		t.Errorf("Fields should be dropped when moving to a list without them: %v", moved.Fields)
+	}
+}
@@ -2,12 +2,15 @@ package handlers
 
 import (
 	"auth"
+	"authz"
 	"encoding/json"
 	"fmt"
 	"github.com/gorilla/mux"
 	"log"
 	"models"
 	"net/http"
+	"net/url"
+	"time"
 )
 
 // jsonResponse is the struct for almost all responses
@@ -46,8 +49,59 @@ func TodoHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// todoFilterFromQuery builds the filter for the todos endpoint
+// from the url query parameters.
+func todoFilterFromQuery(q url.Values) (models.TodoFilter, error) {
+	f := models.TodoFilter{
+		ListId:  q.Get("list_id"),
+		Tags:    models.NormalizeTags(q["tag"]),
+		TagMode: q.Get("tag_mode"),
+	}
+
+	switch f.TagMode {
+	case "", models.TagModeAny, models.TagModeAll:
+	default:
+		return f, fmt.Errorf("tag_mode must be either %s or %s", models.TagModeAny, models.TagModeAll)
+	}
+
+	f.Due = q.Get("due")
+	switch f.Due {
+	case "", models.DueToday, models.DueOverdue:
+	default:
+		return f, fmt.Errorf("due must be either %s or %s", models.DueToday, models.DueOverdue)
+	}
+
+	return f, nil
+}
+
+// userLocation returns the time zone of the user, UTC if the user
+// can't be found.
+func userLocation(userId string) *time.Location {
+	uds := models.NewUserStorage()
+	defer uds.Close()
+
+	u, err := uds.GetUserById(userId)
+	if err != nil {
+		log.Printf("userLocation: GetUserById failed for: %s reason: %s\n", userId, err)
+		return time.UTC
+	}
+
+	return u.Location()
+}
+
 // TodosGetHandler is the handler function which returns all the
-// respective todos for a user.
+// respective todos for a user, including those shared with the
+// user directly or through a list. The todos can be narrowed down to
+// a single list with ?list_id={id} or ?list_id=inbox and to tags
+// with ?tag=a&tag=b&tag_mode=any|all. The todos are in their manual
+// order unless ?sort=smart orders them by how pressing they are, see
+// models.SmartWeights.
+// ?assigned=me only returns the todos assigned to the user and
+// ?due=today|overdue those due on the user's current day or past due.
+// The todos of a list can be filtered and sorted by its custom fields,
+// see fieldQuery. Todos deferred until later are left out unless
+// ?include=deferred. ?render=html adds the rendered notes and their
+// links.
 func TodosGetHandler(w http.ResponseWriter, r *http.Request) {
 	claims, err := auth.GetClaims(r)
 	if err != nil {
@@ -56,14 +110,91 @@ func TodosGetHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	render, err := renderParam(r.URL.Query())
+	if err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+
+	f, err := todoFilterFromQuery(r.URL.Query())
+	if err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+
+	sortField, desc, err := fieldQuery(r.URL.Query(), &f)
+	if err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+
+	switch r.URL.Query().Get("assigned") {
+	case "":
+	case "me":
+		f.AssigneeId = claims.UserId
+	default:
+		BadRequestHandler(w, r, "assigned must be me")
+		return
+	}
+
+	switch r.URL.Query().Get("include") {
+	case "":
+		f.HideDeferred = true
+	case "deferred":
+	default:
+		BadRequestHandler(w, r, "include must be deferred")
+		return
+	}
+
+	f.Now = time.Now()
+	if f.Due != "" {
+		f.Location = userLocation(claims.UserId)
+	}
+
+	lds := models.NewListStorage()
+	defer lds.Close()
+
+	f.SharedListIds, err = authz.SharedListIds(lds, claims.UserId)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("Failed to get shared lists: " + err.Error())
+		return
+	}
+
 	tds := models.NewTodoStorage()
 
-	ts, err := tds.GetTodosForUserId(claims.UserId)
+	ts, err := tds.GetTodosForFilter(claims.UserId, f)
 	if err != nil {
 		NotFoundHandler(w, r, "No 2Dos found.")
 		log.Println("Failed to get Todos: " + err.Error())
 		return
 	}
+	markBlocked(tds, ts)
+	markLogged(ts)
+	if render {
+		models.RenderNotes(ts)
+	}
+
+	switch sort := r.URL.Query().Get("sort"); {
+	case sortField != nil:
+		models.SortByField(ts, sortField.Key, desc)
+	case sort == "":
+	case sort == "smart":
+		uds := models.NewUserStorage()
+		defer uds.Close()
+
+		u, err := uds.GetUserById(claims.UserId)
+		if err != nil {
+			InternalErrorHandler(w, r, "")
+			log.Println("Failed to get Todos: " + err.Error())
+			return
+		}
+
+		models.SmartSort(ts, u.Weights(), time.Now())
+	default:
+		BadRequestHandler(w, r, "sort must be smart or a field e.g. fields.estimate")
+		return
+	}
 
 	data, err := json.Marshal(ts)
 	if err != nil {
@@ -78,35 +209,153 @@ func TodosGetHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // TodosPostHandler is the handler function so that a user
-// can insert new todos.
+// can insert new todos. The due_date is either an RFC 3339 time or
+// a date, which is all day in the user's time zone, as is defer_until.
 func TodosPostHandler(w http.ResponseWriter, r *http.Request) {
-	t := models.NewTodo()
 	claims, err := auth.GetClaims(r)
 	if err != nil {
 		UnauthorizedHandler(w, r, "")
 		return
 	}
 
-	err = json.NewDecoder(r.Body).Decode(&t)
+	// Due and DeferUntil shadow those of the todo so that dates are accepted
+	body := struct {
+		models.Todo
+		Due        string `json:"due_date"`
+		DeferUntil string `json:"defer_until"`
+	}{Todo: models.NewTodo()}
+	err = json.NewDecoder(r.Body).Decode(&body)
 	if err != nil {
 		BadRequestHandler(w, r, "Body format incorrect for 2Do. Try: { \"title\": \"Some Title\", \"note\": \"Example note\" }")
 		log.Println(err)
 		return
 	}
 
+	t := body.Todo
+	loc := userLocation(claims.UserId)
+	t.Due, t.AllDay, err = models.ParseDue(body.Due, loc)
+	if err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+
+	t.DeferUntil, err = models.ParseDeferUntil(body.DeferUntil, loc)
+	if err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+
+	// The id is the server's, not one of the body
+	t.Id = models.NewPublicId()
 	t.Ownerid = claims.UserId
+	t.Shares = nil
+	t.Attachments = nil
+	t.Tags = models.NormalizeTags(t.Tags)
+	if t.Priority != "" {
+		t.Priority, err = models.ParsePriority(string(t.Priority))
+		if err != nil {
+			BadRequestHandler(w, r, err.Error())
+			return
+		}
+	}
+
+	if rc := t.Recurrence; rc != nil {
+		if t.Due.IsZero() {
+			BadRequestHandler(w, r, models.ErrNoDueDate.Error())
+			return
+		}
+
+		if err := rc.Validate(); err != nil {
+			BadRequestHandler(w, r, fmt.Sprintf("Invalid recurrence: %s", err))
+			return
+		}
+
+		rc.Start = t.Due
+		rc.Index = 1
+	}
+
+	for _, rm := range t.Reminders {
+		if err := rm.Validate(); err != nil {
+			BadRequestHandler(w, r, err.Error())
+			return
+		}
+	}
+
+	if t.ListId != "" && !userCanEditList(t.ListId, claims.UserId) {
+		BadRequestHandler(w, r, fmt.Sprintf("List not found: %s", t.ListId))
+		return
+	}
+
+	t.Fields, err = todoFieldValues(t.ListId, nil, t.Fields)
+	if err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+
+	if err := setInitialStatus(&t, claims.UserId); err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+
+	t.CompletedAt, t.CompletedBy = nil, ""
+	if t.Completed {
+		now := time.Now()
+		t.CompletedAt, t.CompletedBy = &now, claims.UserId
+	}
+
+	t.ArchivedAt = nil
+	if t.Archived {
+		now := time.Now()
+		t.ArchivedAt = &now
+	}
+
+	if err := validateAssignee(t.AssigneeId); err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	if t.ParentId != "" {
+		if _, err := authz.Todo(tds, claims.UserId, t.ParentId, authz.Edit); err != nil {
+			BadRequestHandler(w, r, fmt.Sprintf("Parent 2Do not found: %s", t.ParentId))
+			return
+		}
+	}
+
+	if len(t.BlockedBy) > 0 {
+		var ok bool
+		t.BlockedBy, ok = blockersFromRequest(w, r, tds, claims.UserId, "", t.BlockedBy)
+		if !ok {
+			return
+		}
+	}
+	markBlocked(tds, []models.Todo{t})
+
+	// New 2Dos are placed after the user's others
+	if t.Position == 0 {
+		last, err := tds.LastPosition(claims.UserId)
+		if err != nil {
+			InternalErrorHandler(w, r, "Failure to add 2Do")
+			log.Println("Failure to add 2Do: " + err.Error())
+			return
+		}
+		t.Position, _ = models.PositionBetween(&last, nil)
+	}
 
-	tds := models.NewTodoDataStore()
 	err = tds.InsertTodo(t)
 	if err != nil {
 		InternalErrorHandler(w, r, "Failure to add 2Do")
 		log.Println("Failure to add 2Do: " + err.Error())
 		return
 	}
-	log.Println("2Do: " + t.Id.String() + " created")
+	log.Println("2Do: " + t.Id + " created")
+	syncReminders(t)
+	notifyAssignee(t, claims.UserId)
 
 	res := jsonResponse{
-		Result: fmt.Sprintf("Successfully created 2Do: %s", t.Id.String()),
+		Result: fmt.Sprintf("Successfully created 2Do: %s", t.Id),
 		Data:   t,
 	}
 
@@ -123,7 +372,7 @@ func TodosPostHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // TodoGetHandler is the handler function in order to retrieve a
-// specific todo with an ID.
+// specific todo with an ID, with its note rendered with ?render=html.
 func TodoGetHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -135,22 +384,28 @@ func TodoGetHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tds := models.NewTodoDataStore()
-
-	t, err := tds.GetTodoById(id)
+	render, err := renderParam(r.URL.Query())
 	if err != nil {
-		NotFoundHandler(w, r, fmt.Sprintf("Failed to retrieve 2Do with id: %s", id))
-		log.Println(fmt.Sprintf("TodoGetHandler Failure: 2Do (%s) not found", id))
+		BadRequestHandler(w, r, err.Error())
 		return
 	}
 
-	if t.Ownerid != claims.UserId {
-		NotFoundHandler(w, r, fmt.Sprintf("Failed to retrieve 2Do with id: %s", id))
-		log.Println(fmt.Sprintf("TodoGetHandler Failure: t.Ownerid (%s) != claims.UserId (%s)", t.Ownerid, claims.UserId))
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	t, ok := authorizeTodo(w, r, tds, claims.UserId, id, authz.View)
+	if !ok {
+		log.Println(fmt.Sprintf("TodoGetHandler Failure: 2Do (%s) not accessible to %s", id, claims.UserId))
 		return
 	}
+	ts := []models.Todo{*t}
+	markBlocked(tds, ts)
+	markLogged(ts)
+	if render {
+		models.RenderNotes(ts)
+	}
 
-	data, err := json.Marshal(t)
+	data, err := json.Marshal(ts[0])
 	if err != nil {
 		NotFoundHandler(w, r, fmt.Sprintf("Failed to retrieve 2Do with id: %s", id))
 		log.Println("TodoHandler Error: " + err.Error())
@@ -163,7 +418,12 @@ func TodoGetHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // TodoPutHandler is the handler function which allows a user
-// to modify an existing todo.
+// to modify an existing todo. The assignee of a todo may only
+// complete it. A todo with open blockers can only be completed
+// with ?force=true. When and by whom a todo was completed, and when
+// it was archived, is recorded by the server. The status of todos of
+// lists with a workflow may only change as the workflow allows, see
+// applyWorkflow.
 func TodoPutHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -182,19 +442,66 @@ func TodoPutHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tds := models.NewTodoDataStore()
+	delete(m, "completed_at")
+	delete(m, "completed_by")
+	delete(m, "archived_at")
+	delete(m, "status_history")
 
-	err = tds.ModifyTodo(id, claims.UserId, m)
-	if err != nil {
-		if err == models.TodoNotFoundError {
-			NotFoundHandler(w, r, "2Do not found.")
+	if listId, ok := m["list_id"].(string); ok && listId != "" && !userCanEditList(listId, claims.UserId) {
+		BadRequestHandler(w, r, fmt.Sprintf("List not found: %s", listId))
+		return
+	}
+
+	if v, ok := m["due_date"]; ok {
+		s, _ := v.(string)
+		due, allDay, err := models.ParseDue(s, userLocation(claims.UserId))
+		if err != nil {
+			BadRequestHandler(w, r, err.Error())
+			return
+		}
+		if due.IsZero() {
+			// Cleared, stored as null so that it is never overdue
+			m["due_date"], m["all_day"] = nil, false
 		} else {
-			BadRequestHandler(w, r, "Error modifiying 2Do. Please check the formatting of the parameters.")
+			m["due_date"], m["all_day"] = due, allDay
+		}
+	}
+
+	if v, ok := m["defer_until"]; ok {
+		s, _ := v.(string)
+		until, err := models.ParseDeferUntil(s, userLocation(claims.UserId))
+		if err != nil {
+			BadRequestHandler(w, r, err.Error())
+			return
 		}
+		m["defer_until"] = until
+	}
+
+	if assigneeId, ok := m["assignee_id"].(string); ok {
+		if err := validateAssignee(assigneeId); err != nil {
+			BadRequestHandler(w, r, err.Error())
+			return
+		}
+	}
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	action := authz.Edit
+	if onlyCompletion(m) {
+		action = authz.Complete
+	}
+
+	t, ok := authorizeTodo(w, r, tds, claims.UserId, id, action)
+	if !ok {
+		return
+	}
+
+	res, ok := modifyTodo(w, r, tds, *t, m, claims.UserId)
+	if !ok {
 		return
 	}
 
-	res := jsonResponse{Result: fmt.Sprintf("Successfully modified 2Do: %s", id)}
 	msg, err := json.Marshal(res)
 	if err != nil {
 		InternalErrorHandler(w, r, "Failure to modify 2Do")
@@ -207,6 +514,149 @@ func TodoPutHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(msg)
 }
 
+// modifyTodo applies the changes, as decoded from the body of a
+// request, to the todo which the user is authorized to modify. It
+// returns the response, otherwise it writes the error response.
+func modifyTodo(w http.ResponseWriter, r *http.Request, tds models.TodoStorage, t models.Todo, m map[string]interface{}, userId string) (jsonResponse, bool) {
+	id := t.Id
+	assigneeId, reassigned := m["assignee_id"].(string)
+	reassigned = reassigned && assigneeId != t.AssigneeId
+
+	if v, ok := m["blocked_by"]; ok {
+		ids, valid := stringsFromJSON(v)
+		if !valid {
+			BadRequestHandler(w, r, "blocked_by must be an array of 2Do ids")
+			return jsonResponse{}, false
+		}
+
+		m["blocked_by"], ok = blockersFromRequest(w, r, tds, userId, id, ids)
+		if !ok {
+			return jsonResponse{}, false
+		}
+	}
+
+	if !applyFields(w, r, m, t) {
+		return jsonResponse{}, false
+	}
+
+	if !applyRecurrence(w, r, m, t) {
+		return jsonResponse{}, false
+	}
+
+	wf, ok := applyWorkflow(w, r, m, t, userId)
+	if !ok {
+		return jsonResponse{}, false
+	}
+
+	unlock, ok := enforceWIPLimit(w, r, tds, wf, m, t, userId)
+	if !ok {
+		return jsonResponse{}, false
+	}
+	defer unlock()
+
+	// Completing a recurring 2Do creates its next instance
+	var next *models.Todo
+	if completed, _ := m["completed"].(bool); completed {
+		if refuseBlockedCompletion(w, r, tds, t) {
+			return jsonResponse{}, false
+		}
+
+		var err error
+		next, err = nextRecurringInstance(t)
+		if err != nil {
+			InternalErrorHandler(w, r, "Failure to modify 2Do")
+			log.Println("Failure to create next instance of 2Do: " + err.Error())
+			return jsonResponse{}, false
+		}
+	}
+
+	recordCompletion(m, t, userId)
+	recordArchival(m, t)
+
+	err := tds.ModifyTodo(id, m)
+	if err != nil {
+		if err == models.TodoNotFoundError {
+			NotFoundHandler(w, r, "2Do not found.")
+		} else {
+			BadRequestHandler(w, r, "Error modifiying 2Do. Please check the formatting of the parameters.")
+		}
+		return jsonResponse{}, false
+	}
+
+	res := jsonResponse{Result: fmt.Sprintf("Successfully modified 2Do: %s", id)}
+	if next != nil {
+		if wf != nil {
+			next.Status = wf.Initial
+			next.StatusHistory = []models.StatusChange{{To: next.Status, At: time.Now(), By: userId}}
+		}
+		err = tds.InsertTodo(*next)
+		if err != nil {
+			InternalErrorHandler(w, r, "Failure to add next 2Do")
+			log.Println("Failure to add next instance of 2Do: " + err.Error())
+			return jsonResponse{}, false
+		}
+
+		res.Result += fmt.Sprintf(", next 2Do: %s", next.Id)
+		res.Data = next
+		syncReminders(*next)
+	}
+
+	if rescheduled(m) || reassigned {
+		if t, err := tds.GetTodoById(id); err == nil {
+			if rescheduled(m) {
+				syncReminders(*t)
+			}
+			if reassigned {
+				notifyAssignee(*t, userId)
+			}
+		}
+	}
+
+	return res, true
+}
+
+// recordCompletion adds when and by whom the todo is completed to the
+// changes when they complete it, or clears them when they reopen it.
+func recordCompletion(changes map[string]interface{}, t models.Todo, userId string) {
+	completed, ok := changes["completed"].(bool)
+	if !ok || completed == t.Completed {
+		return
+	}
+
+	if completed {
+		changes["completed_at"], changes["completed_by"] = time.Now(), userId
+	} else {
+		changes["completed_at"], changes["completed_by"] = nil, ""
+	}
+}
+
+// recordArchival adds when the todo is archived to the changes when
+// they archive it, or clears it when they unarchive it.
+func recordArchival(changes map[string]interface{}, t models.Todo) {
+	archived, ok := changes["archived"].(bool)
+	if !ok || archived == t.Archived {
+		return
+	}
+
+	if archived {
+		changes["archived_at"] = time.Now()
+	} else {
+		changes["archived_at"] = nil
+	}
+}
+
+// rescheduled reports whether the changes to a todo affect
+// when its reminders are due, or whether they are.
+func rescheduled(changes map[string]interface{}) bool {
+	for _, k := range []string{"reminders", "due_date", "completed", "archived"} {
+		if _, ok := changes[k]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
 func TodoDeleteHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -217,12 +667,24 @@ func TodoDeleteHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tds := models.NewTodoDataStore()
-	err = tds.DeleteTodo(id, claims.UserId)
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	t, ok := authorizeTodo(w, r, tds, claims.UserId, id, authz.Delete)
+	if !ok {
+		return
+	}
+
+	err = tds.DeleteTodo(id)
 	if err != nil {
 		NotFoundHandler(w, r, "2Do not found.")
 		return
 	}
+	cancelReminders(id)
+	removeAttachments(*t)
+	removeComments(*t)
+	removeTimeEntries(*t)
+	removeBlocker(tds, id)
 
 	res := jsonResponse{Result: fmt.Sprintf("Successfully deleted 2Do: %s", id)}
 	msg, err := json.Marshal(res)
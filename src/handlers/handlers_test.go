@@ -105,7 +105,7 @@ func TestTodosHandler1(t *testing.T) {
 
 	testBody(fmt.Sprintf("[%s]", string(b)), rr, t)
 
-	tds.DeleteTodo(t0.Id.Hex(), u.Id.Hex())
+	tds.DeleteTodo(t0.Id)
 	tus.DeleteUser(u.Id.Hex())
 }
 
@@ -142,8 +142,19 @@ func TestTodosHandler2(t *testing.T) {
 
 	testStatus(StatusCreation, rr, t)
 
+	// The id and position are assigned by the server
+	var created struct {
+		Data models.Todo `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	if created.Data.Id == t0.Id || !models.IsPublicId(created.Data.Id) {
+		t.Errorf("The 2Do should have a new id: %q", created.Data.Id)
+	}
+	t0.Id = created.Data.Id
+	t0.Position = models.PositionStep
+
 	res := jsonResponse{
-		Result: fmt.Sprintf("Successfully created 2Do: %s", t0.Id.String()),
+		Result: fmt.Sprintf("Successfully created 2Do: %s", t0.Id),
 		Data:   t0,
 	}
 
@@ -154,7 +165,77 @@ func TestTodosHandler2(t *testing.T) {
 
 	testBody(string(msg), rr, t)
 
-	tds := models.NewTodoDataStore()
-	tds.DeleteTodo(t0.Id.Hex(), u.Id.Hex())
+	tds := models.NewTodoStorage()
+	tds.DeleteTodo(t0.Id)
+	tus.DeleteUser(u.Id.Hex())
+}
+
+func TestTodosHandlerSmartSort(t *testing.T) {
+	req, rr := handlersSetup("GET", "api/todos?sort=smart", "")
+
+	u := models.NewUser()
+	tus := models.NewUserStorage()
+	tus.InsertUser(u)
+	token, err := auth.CreateToken(u)
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	t0 := models.NewTodo()
+	t0.Ownerid = u.Id.Hex()
+	t1 := models.NewTodo()
+	t1.Ownerid = u.Id.Hex()
+	t1.Priority = models.PriorityHigh
+	tds := models.NewTodoStorage()
+	tds.InsertTodo(t0)
+	tds.InsertTodo(t1)
+
+	var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+		TodosHandler(w, r)
+	}
+
+	ValidatePath(handler).ServeHTTP(rr, req)
+
+	testStatus(StatusSuccess, rr, t)
+
+	b, err := json.Marshal([]models.Todo{t1, t0})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	testBody(string(b), rr, t)
+
+	tds.DeleteTodo(t0.Id)
+	tds.DeleteTodo(t1.Id)
 	tus.DeleteUser(u.Id.Hex())
 }
+
+func TestTodosPostHandlerIgnoresId(t *testing.T) {
+	u, token := shareSetup("TestTodosPostHandlerIgnoresId")
+
+	tds := models.NewTodoStorage()
+	t0 := models.NewTodo()
+	t0.Ownerid = u.Id.Hex()
+	t0.Title = "Existing"
+	tds.InsertTodo(t0)
+
+	for _, id := range []string{"", "not/a public id", t0.Id} {
+		req, rr := handlersSetup("POST", "api/todos", fmt.Sprintf("{\"id\": %q, \"title\": \"New\"}", id))
+		req.Header.Set("Authorization", "Bearer "+token)
+		ValidatePath(TodosHandler).ServeHTTP(rr, req)
+		testStatus(StatusCreation, rr, t)
+
+		var res struct {
+			Data models.Todo `json:"data"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &res)
+		if res.Data.Id == id || !models.IsPublicId(res.Data.Id) {
+			t.Errorf("The 2Do should have a new id instead of %q: %q", id, res.Data.Id)
+		}
+	}
+
+	if t1, err := tds.GetTodoById(t0.Id); err != nil || t1.Title != "Existing" {
+		t.Errorf("The existing 2Do should be unchanged: %v %v", t1, err)
+	}
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"auth"
+	"authz"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"log"
+	"models"
+	"net/http"
+	"time"
+)
+
+// ListsHandler is a handler function for the /api/lists endpoint
+// it acts as a multiplexer to a respective http method handler.
+func ListsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		ListsGetHandler(w, r)
+	case "POST":
+		ListsPostHandler(w, r)
+	}
+}
+
+// ListHandler is a handler function for the /api/lists/{id} endpoint
+// it acts as a multiplexer to a respective http method handler.
+func ListHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		ListGetHandler(w, r)
+	case "PUT":
+		ListPutHandler(w, r)
+	case "DELETE":
+		ListDeleteHandler(w, r)
+	}
+}
+
+// userCanEditList reports whether the list with the id exists
+// and the user may add todos to it.
+func userCanEditList(listId, userId string) bool {
+	lds := models.NewListStorage()
+	defer lds.Close()
+
+	_, err := authz.List(lds, userId, listId, authz.Edit)
+	return err == nil
+}
+
+// ListsGetHandler is the handler function which returns all the
+// lists of a user, including the lists shared with the user.
+func ListsGetHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	lds := models.NewListStorage()
+	defer lds.Close()
+
+	ls, err := lds.GetListsForUserId(claims.UserId)
+	if err != nil {
+		NotFoundHandler(w, r, "No lists found.")
+		log.Println("Failed to get lists: " + err.Error())
+		return
+	}
+
+	data, err := json.Marshal(ls)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("Failed to get lists: " + err.Error())
+		return
+	}
+
+	w.WriteHeader(StatusSuccess)
+	w.Header().Set(ContentType, ApplicationJSON)
+	w.Write(data)
+}
+
+// ListsPostHandler is the handler function so that a user
+// can create new lists.
+func ListsPostHandler(w http.ResponseWriter, r *http.Request) {
+	l := models.NewList()
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&l)
+	if err != nil || l.Name == "" {
+		BadRequestHandler(w, r, "Body format incorrect for list. Try: { \"name\": \"Groceries\" }")
+		return
+	}
+
+	l.Ownerid = claims.UserId
+	if l.Created.IsZero() {
+		l.Created = time.Now()
+	}
+
+	lds := models.NewListStorage()
+	defer lds.Close()
+
+	err = lds.InsertList(l)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to add list")
+		log.Println("Failure to add list: " + err.Error())
+		return
+	}
+
+	res := jsonResponse{
+		Result: fmt.Sprintf("Successfully created list: %s", l.Id.String()),
+		Data:   l,
+	}
+
+	msg, err := json.Marshal(res)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println(fmt.Sprintf("ListsPostHandler: %s", err.Error()))
+		return
+	}
+
+	w.WriteHeader(StatusCreation)
+	w.Header().Set(ContentType, ApplicationJSON)
+	w.Write(msg)
+}
+
+// ListGetHandler is the handler function in order to retrieve a
+// specific list with an ID.
+func ListGetHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	lds := models.NewListStorage()
+	defer lds.Close()
+
+	l, ok := authorizeList(w, r, lds, claims.UserId, id, authz.View)
+	if !ok {
+		return
+	}
+
+	data, err := json.Marshal(l)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("ListGetHandler Error: " + err.Error())
+		return
+	}
+
+	w.WriteHeader(StatusSuccess)
+	w.Header().Set(ContentType, ApplicationJSON)
+	w.Write(data)
+}
+
+// ListPutHandler is the handler function which allows a user
+// to rename an existing list.
+func ListPutHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	m := make(map[string]interface{})
+	err = json.NewDecoder(r.Body).Decode(&m)
+	if err != nil {
+		BadRequestHandler(w, r, "Body format incorrect for list. Try: { \"name\": \"Groceries\" }")
+		return
+	}
+
+	lds := models.NewListStorage()
+	defer lds.Close()
+
+	if _, ok := authorizeList(w, r, lds, claims.UserId, id, authz.Edit); !ok {
+		return
+	}
+
+	err = lds.ModifyList(id, m)
+	if err != nil {
+		if err == models.ListNotFoundError {
+			NotFoundHandler(w, r, "List not found.")
+		} else {
+			BadRequestHandler(w, r, "Error modifiying list. Please check the formatting of the parameters.")
+		}
+		return
+	}
+
+	res := jsonResponse{Result: fmt.Sprintf("Successfully modified list: %s", id)}
+	msg, err := json.Marshal(res)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to modify list")
+		log.Println("Failure to modify list: " + err.Error())
+		return
+	}
+
+	w.WriteHeader(StatusSuccess)
+	w.Header().Set(ContentType, ApplicationJSON)
+	w.Write(msg)
+}
+
+// ListDeleteHandler deletes a list. By default the todos of the
+// list are moved to the inbox, with ?cascade=true they are
+// deleted along with the list.
+func ListDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	cascade := false
+	switch r.URL.Query().Get("cascade") {
+	case "", "false":
+	case "true":
+		cascade = true
+	default:
+		BadRequestHandler(w, r, "cascade must be either true or false")
+		return
+	}
+
+	lds := models.NewListStorage()
+	defer lds.Close()
+
+	if _, ok := authorizeList(w, r, lds, claims.UserId, id, authz.Delete); !ok {
+		return
+	}
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	if cascade {
+		var ts, archived []models.Todo
+		// Every todo of the list, including those of other users
+		f := models.TodoFilter{ListId: id, SharedListIds: []string{id}}
+		ts, err = tds.GetTodosForFilter(claims.UserId, f)
+		if err == nil {
+			archived, err = tds.GetArchivedTodos(claims.UserId, f, 0, 0)
+			ts = append(ts, archived...)
+		}
+		if err == nil {
+			err = tds.DeleteTodosInList(id)
+		}
+		if err == nil {
+			removeAttachments(ts...)
+			removeComments(ts...)
+			removeTimeEntries(ts...)
+			for _, t := range ts {
+				removeBlocker(tds, t.Id)
+			}
+		}
+	} else {
+		err = tds.MoveTodosToList(id, "")
+	}
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to delete list")
+		log.Println("Failure to delete list: " + err.Error())
+		return
+	}
+
+	err = lds.DeleteList(id)
+	if err != nil {
+		NotFoundHandler(w, r, "List not found.")
+		return
+	}
+
+	res := jsonResponse{Result: fmt.Sprintf("Successfully deleted list: %s", id)}
+	msg, err := json.Marshal(res)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to delete list")
+		log.Println("Failure to delete list: " + err.Error())
+		return
+	}
+
+	w.WriteHeader(StatusSuccess)
+	w.Header().Set(ContentType, ApplicationJSON)
+	w.Write(msg)
+}
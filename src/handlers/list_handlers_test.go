@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"auth"
+	"github.com/gorilla/mux"
+	"log"
+	"models"
+	"net/http"
+	"testing"
+)
+
+func init() {
+	models.LIST_STORE_TYPE = models.Test
+}
+
+func TestListDeleteHandler(t *testing.T) {
+	u := models.NewUser()
+	tus := models.NewUserStorage()
+	tus.InsertUser(u)
+	token, err := auth.CreateToken(u)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	l := models.NewList()
+	l.Ownerid = u.Id.Hex()
+	lds := models.NewListStorage()
+	lds.InsertList(l)
+
+	t0 := models.NewTodo()
+	t0.Ownerid = u.Id.Hex()
+	t0.ListId = l.Id.Hex()
+	tds := models.NewTodoStorage()
+	tds.InsertTodo(t0)
+
+	req, rr := handlersSetup("DELETE", "api/lists/"+l.Id.Hex(), "")
+	req = mux.SetURLVars(req, map[string]string{"id": l.Id.Hex()})
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+		ListHandler(w, r)
+	}
+
+	ValidatePath(handler).ServeHTTP(rr, req)
+
+	testStatus(StatusSuccess, rr, t)
+
+	if _, err := lds.GetListById(l.Id.Hex()); err == nil {
+		t.Error("List was not deleted")
+	}
+
+	ts, err := tds.GetTodosForFilter(u.Id.Hex(), models.TodoFilter{ListId: models.InboxListId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ts) != 1 {
+		t.Errorf("Todo was not moved to the inbox: %v", ts)
+	}
+
+	tds.DeleteTodo(t0.Id)
+	tus.DeleteUser(u.Id.Hex())
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"auth"
+	"authz"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"log"
+	"markdown"
+	"models"
+	"net/http"
+	"net/url"
+)
+
+// renderParam reports whether the notes of the todos are to be
+// rendered with ?render=html, see models.RenderNotes.
+func renderParam(q url.Values) (bool, error) {
+	switch q.Get("render") {
+	case "":
+		return false, nil
+	case "html":
+		return true, nil
+	}
+
+	return false, fmt.Errorf("render must be html")
+}
+
+type checklistRequest struct {
+	Index   *int  `json:"index"`
+	Checked *bool `json:"checked"`
+}
+
+// TodoChecklistHandler checks or unchecks a task list item, - [ ] or
+// - [x], of the note of a todo. The body has the index of the item in
+// the note, as in the data-task attribute of note_html, and whether it
+// is checked, the item is toggled when left out. The todo is returned
+// with its note rendered.
+func TodoChecklistHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	var cr checklistRequest
+	err = json.NewDecoder(r.Body).Decode(&cr)
+	if err != nil || cr.Index == nil {
+		BadRequestHandler(w, r, "Body format incorrect for checklist. Try: { \"index\": 0, \"checked\": true }")
+		return
+	}
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	t, ok := authorizeTodo(w, r, tds, claims.UserId, id, authz.Complete)
+	if !ok {
+		return
+	}
+
+	tasks := markdown.Render(t.Note).Tasks
+	if *cr.Index < 0 || *cr.Index >= len(tasks) {
+		NotFoundHandler(w, r, fmt.Sprintf("%s: %d", markdown.ErrNoTask, *cr.Index))
+		return
+	}
+
+	checked := !tasks[*cr.Index].Checked
+	if cr.Checked != nil {
+		checked = *cr.Checked
+	}
+
+	note, err := markdown.ToggleTask(t.Note, *cr.Index, checked)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to modify checklist")
+		log.Println("Failure to modify checklist: " + err.Error())
+		return
+	}
+
+	err = tds.ModifyTodo(id, map[string]interface{}{"note": note})
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to modify checklist")
+		log.Println("Failure to modify checklist: " + err.Error())
+		return
+	}
+	t.Note = note
+
+	ts := []models.Todo{*t}
+	models.RenderNotes(ts)
+
+	writeJSON(w, r, StatusSuccess, jsonResponse{
+		Result: fmt.Sprintf("Successfully modified checklist of 2Do: %s", id),
+		Data:   ts[0],
+	})
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"models"
+	"strings"
+	"testing"
+)
+
+func TestTodoNoteRendering(t *testing.T) {
+	u, token := shareSetup("note-TestTodoNoteRendering")
+
+	tds := models.NewTodoStorage()
+	t0 := models.NewTodo()
+	t0.Ownerid = u.Id.Hex()
+	t0.Note = "See [the docs](https://example.com/docs)\n\n- [ ] milk\n- [x] eggs\n\n<script>alert(1)</script>"
+	tds.InsertTodo(t0)
+	id := t0.Id
+
+	get := func(query string) (models.Todo, int) {
+		req, rr := handlersSetup("GET", "api/todos/"+id+query, "")
+		req.Header.Set("Authorization", "Bearer "+token)
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		ValidatePath(TodoHandler).ServeHTTP(rr, req)
+
+		var td models.Todo
+		json.Unmarshal(rr.Body.Bytes(), &td)
+		return td, rr.Code
+	}
+
+	if td, _ := get(""); td.NoteHTML != "" || td.Links != nil {
+		t.Errorf("Notes should only be rendered on demand: %q", td.NoteHTML)
+	}
+
+	if _, code := get("?render=pdf"); code != StatusBadRequest {
+		t.Errorf("Expected a bad request for an unknown rendering: got %d", code)
+	}
+
+	td, _ := get("?render=html")
+	if strings.Contains(td.NoteHTML, "<script>") || !strings.Contains(td.NoteHTML, "&lt;script&gt;") {
+		t.Errorf("Raw html should be escaped: %s", td.NoteHTML)
+	}
+	if len(td.Links) != 1 || td.Links[0].URL != "https://example.com/docs" || td.Links[0].Text != "the docs" {
+		t.Errorf("Incorrect links: %v", td.Links)
+	}
+
+	toggle := func(body string) (models.Todo, int) {
+		req, rr := handlersSetup("POST", "api/todos/"+id+"/checklist", body)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		ValidatePath(TodoChecklistHandler).ServeHTTP(rr, req)
+
+		var res struct{ Data models.Todo }
+		json.Unmarshal(rr.Body.Bytes(), &res)
+		return res.Data, rr.Code
+	}
+
+	if _, code := toggle(`{}`); code != StatusBadRequest {
+		t.Errorf("Expected a bad request without an index: got %d", code)
+	}
+	if _, code := toggle(`{"index": 2}`); code != StatusNotFound {
+		t.Errorf("Expected not found for a missing item: got %d", code)
+	}
+
+	td, code := toggle(`{"index": 0}`)
+	if code != StatusSuccess {
+		t.Fatalf("Failure to toggle checklist item: got %d", code)
+	}
+	if !strings.Contains(td.NoteHTML, `data-task="0" checked`) {
+		t.Errorf("Toggled item should be rendered checked: %s", td.NoteHTML)
+	}
+
+	toggle(`{"index": 1, "checked": true}`)
+	stored, _ := tds.GetTodoById(id)
+	if !strings.Contains(stored.Note, "- [x] milk\n- [x] eggs\n") {
+		t.Errorf("Incorrect note after toggling: %q", stored.Note)
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"auth"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"log"
+	"models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultNotifications = 50
+	maxNotifications     = 200
+)
+
+// syncReminders schedules the reminders of the todo, replacing any
+// previously scheduled reminders. Failures are only logged since the
+// todo itself has been stored.
+func syncReminders(t models.Todo) {
+	rjs := models.NewReminderJobStorage()
+	defer rjs.Close()
+
+	err := rjs.ReplaceJobsForTodo(t.Id, t.ReminderJobs(time.Now()))
+	if err != nil {
+		log.Printf("syncReminders: Failure to schedule reminders of 2Do %s: %s\n", t.Id, err)
+	}
+}
+
+// cancelReminders removes the scheduled reminders of a deleted todo.
+func cancelReminders(todoId string) {
+	rjs := models.NewReminderJobStorage()
+	defer rjs.Close()
+
+	err := rjs.ReplaceJobsForTodo(todoId, nil)
+	if err != nil {
+		log.Printf("cancelReminders: Failure to cancel reminders of 2Do %s: %s\n", todoId, err)
+	}
+}
+
+// NotificationsGetHandler is the handler function for the
+// /api/notifications endpoint. It returns the in-app notifications
+// of the user, newest first. ?unread=true only returns unread
+// notifications and ?limit= sets how many are returned.
+func NotificationsGetHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	q := r.URL.Query()
+	unread := q.Get("unread") == "true"
+
+	limit := defaultNotifications
+	if l := q.Get("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > maxNotifications {
+			BadRequestHandler(w, r, fmt.Sprintf("limit must be between 1 and %d", maxNotifications))
+			return
+		}
+	}
+
+	nds := models.NewNotificationStorage()
+	defer nds.Close()
+
+	ns, err := nds.GetNotificationsForUserId(claims.UserId, unread, limit)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to retrieve notifications")
+		log.Println("Failed to get notifications: " + err.Error())
+		return
+	}
+
+	data, err := json.Marshal(ns)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("Failed to get notifications: " + err.Error())
+		return
+	}
+
+	w.WriteHeader(StatusSuccess)
+	w.Header().Set(ContentType, ApplicationJSON)
+	w.Write(data)
+}
+
+// NotificationReadHandler is the handler function for the
+// /api/notifications/{id}/read endpoint. It marks a notification
+// as read.
+func NotificationReadHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	nds := models.NewNotificationStorage()
+	defer nds.Close()
+
+	err = nds.MarkNotificationRead(id, claims.UserId)
+	if err != nil {
+		NotFoundHandler(w, r, "Notification not found.")
+		return
+	}
+
+	res := jsonResponse{Result: fmt.Sprintf("Successfully read notification: %s", id)}
+	msg, err := json.Marshal(res)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("NotificationReadHandler: " + err.Error())
+		return
+	}
+
+	w.WriteHeader(StatusSuccess)
+	w.Header().Set(ContentType, ApplicationJSON)
+	w.Write(msg)
+}
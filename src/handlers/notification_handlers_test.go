@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"auth"
+	"encoding/json"
+	"log"
+	"models"
+	"net/http"
+	"testing"
+)
+
+func init() {
+	models.REMINDER_STORE_TYPE = models.Test
+	models.NOTIFICATION_STORE_TYPE = models.Test
+}
+
+func TestNotificationsGetHandler(t *testing.T) {
+	req, rr := handlersSetup("GET", "api/notifications?unread=true", "")
+
+	u := models.NewUser()
+	tus := models.NewUserStorage()
+	tus.InsertUser(u)
+	token, err := auth.CreateToken(u)
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	nds := models.NewNotificationStorage()
+	n0 := models.NewNotification()
+	n0.Ownerid = u.Id.Hex()
+	n1 := models.NewNotification()
+	n1.Ownerid = u.Id.Hex()
+	n1.Read = true
+	nds.InsertNotification(n0)
+	nds.InsertNotification(n1)
+
+	var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+		NotificationsGetHandler(w, r)
+	}
+
+	ValidatePath(handler).ServeHTTP(rr, req)
+
+	testStatus(StatusSuccess, rr, t)
+
+	b, err := json.Marshal([]models.Notification{n0})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	testBody(string(b), rr, t)
+
+	tus.DeleteUser(u.Id.Hex())
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"auth"
+	"authz"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"log"
+	"models"
+	"net/http"
+)
+
+type moveRequest struct {
+	Before string `json:"before"` // The todo to place the moved todo before
+	After  string `json:"after"`  // The todo to place the moved todo after
+}
+
+// positionOf returns the index of the todo with the id in ts or -1.
+func positionOf(ts []models.Todo, id string) int {
+	for i, t := range ts {
+		if t.Id == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// newPosition returns the position of the todo moved as the request
+// asks among the todos accessible to the user. When the positions have
+// become too dense the rebalanced positions of the user's todos, the
+// moved todo's included, are returned as well. When only the todos of
+// other users are around the move and too dense, the move conflicts.
+// Otherwise it writes the error response.
+func newPosition(w http.ResponseWriter, r *http.Request, tds models.TodoStorage, userId string, t models.Todo, mr moveRequest) (float64, map[string]float64, bool) {
+	id := t.Id
+	if mr.Before == id || mr.After == id {
+		BadRequestHandler(w, r, "A 2Do can't be moved relative to itself")
+		return 0, nil, false
+	}
+
+	lds := models.NewListStorage()
+	defer lds.Close()
+
+	var err error
+	f := models.TodoFilter{}
+	f.SharedListIds, err = authz.SharedListIds(lds, userId)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("Failed to get shared lists: " + err.Error())
+		return 0, nil, false
+	}
+
+	ts, err := tds.GetTodosForFilter(userId, f)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to move 2Do")
+		log.Println("Failure to move 2Do: " + err.Error())
+		return 0, nil, false
+	}
+	if i := positionOf(ts, id); i >= 0 {
+		ts = append(ts[:i], ts[i+1:]...)
+	}
+
+	// The moved todo goes between ts[index-1] and ts[index]
+	index := -1
+	var lo, hi *float64
+	if mr.After != "" {
+		i := positionOf(ts, mr.After)
+		if i < 0 {
+			BadRequestHandler(w, r, fmt.Sprintf("2Do not found: %s", mr.After))
+			return 0, nil, false
+		}
+		index = i + 1
+		lo = &ts[i].Position
+	}
+	if mr.Before != "" {
+		i := positionOf(ts, mr.Before)
+		if i < 0 {
+			BadRequestHandler(w, r, fmt.Sprintf("2Do not found: %s", mr.Before))
+			return 0, nil, false
+		}
+		if index >= 0 && i < index {
+			BadRequestHandler(w, r, "The after 2Do must precede the before 2Do")
+			return 0, nil, false
+		}
+		if index < 0 {
+			index = i
+		}
+		hi = &ts[i].Position
+	}
+	if lo == nil && index > 0 {
+		lo = &ts[index-1].Position
+	}
+	if hi == nil && index < len(ts) {
+		hi = &ts[index].Position
+	}
+
+	if position, ok := models.PositionBetween(lo, hi); ok {
+		return position, nil, true
+	}
+
+	// The todos of other users, as those of lists shared with the user,
+	// are never rebalanced
+	moved := append(append(append([]models.Todo{}, ts[:index]...), t), ts[index:]...)
+	positions, ok := models.Rebalance(moved, func(o models.Todo) bool {
+		return o.Ownerid != userId && o.Id != id
+	})
+	if !ok {
+		ConflictHandler(w, r, "There is no room to move the 2Do between the 2Dos of other users.")
+		return 0, nil, false
+	}
+	log.Println(fmt.Sprintf("Rebalanced the positions of %d 2Dos", len(positions)))
+
+	return positions[id], positions, true
+}
+
+// TodoMoveHandler moves a todo between two others, given by the before
+// and after ids. Only one of them is required, the todo is then placed
+// directly before or after it. Moving writes only the moved todo unless
+// the positions have become too dense, then the user's todos are
+// rebalanced.
+func TodoMoveHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	var mr moveRequest
+	err = json.NewDecoder(r.Body).Decode(&mr)
+	if err != nil || (mr.Before == "" && mr.After == "") {
+		BadRequestHandler(w, r, "Body format incorrect for move. Try: { \"after\": \"{id}\", \"before\": \"{id}\" }")
+		return
+	}
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	t, ok := authorizeTodo(w, r, tds, claims.UserId, id, authz.Edit)
+	if !ok {
+		return
+	}
+
+	position, rebalanced, ok := newPosition(w, r, tds, claims.UserId, *t, mr)
+	if !ok {
+		return
+	}
+
+	if rebalanced != nil {
+		err = tds.SetTodoPositions(rebalanced)
+	} else {
+		err = tds.ModifyTodo(id, map[string]interface{}{"position": position})
+	}
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to move 2Do")
+		log.Println("Failure to move 2Do: " + err.Error())
+		return
+	}
+	t.Position = position
+
+	res := jsonResponse{Result: fmt.Sprintf("Successfully moved 2Do: %s", id), Data: t}
+	msg, err := json.Marshal(res)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to move 2Do")
+		log.Println("Failure to move 2Do: " + err.Error())
+		return
+	}
+
+	w.WriteHeader(StatusSuccess)
+	w.Header().Set(ContentType, ApplicationJSON)
+	w.Write(msg)
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"github.com/gorilla/mux"
+	"models"
+	"net/http"
+	"testing"
+)
+
+func TestTodoMove(t *testing.T) {
+	token, t0 := attachmentSetup()
+	tds := models.NewTodoStorage()
+	ts := []models.Todo{t0, models.NewTodo(), models.NewTodo()}
+	for i := range ts {
+		ts[i].Ownerid = t0.Ownerid
+		ts[i].Position = float64(i + 1)
+		tds.InsertTodo(ts[i])
+	}
+
+	move := func(id, body string) int {
+		req, rr := handlersSetup("POST", "api/todos/"+id+"/move", body)
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		req.Header.Set("Authorization", "Bearer "+token)
+		ValidatePath(http.HandlerFunc(TodoMoveHandler)).ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	order := func() []string {
+		got, _ := tds.GetTodosForUserId(t0.Ownerid)
+		ids := make([]string, len(got))
+		for i, t := range got {
+			ids[i] = t.Id
+		}
+		return ids
+	}
+
+	a, b, c := ts[0].Id, ts[1].Id, ts[2].Id
+
+	if code := move(a, "{\"after\": \""+c+"\"}"); code != StatusSuccess {
+		t.Fatalf("Failure to move 2Do: got %d", code)
+	}
+	if got := order(); got[0] != b || got[1] != c || got[2] != a {
+		t.Errorf("Incorrect order after move: %v", got)
+	}
+
+	if code := move(a, "{\"after\": \""+b+"\", \"before\": \""+c+"\"}"); code != StatusSuccess {
+		t.Fatalf("Failure to move 2Do: got %d", code)
+	}
+	if got := order(); got[0] != b || got[1] != a || got[2] != c {
+		t.Errorf("Incorrect order after move: %v", got)
+	}
+
+	if code := move(a, "{\"after\": \""+c+"\", \"before\": \""+b+"\"}"); code != StatusBadRequest {
+		t.Errorf("Neighbours out of order should be rejected: got %d", code)
+	}
+
+	// Dense positions are rebalanced
+	tds.SetTodoPositions(map[string]float64{a: 1, b: 1 + 1e-9, c: 1 + 2e-9})
+	if code := move(c, "{\"after\": \""+a+"\"}"); code != StatusSuccess {
+		t.Fatalf("Failure to move 2Do: got %d", code)
+	}
+	got, _ := tds.GetTodosForUserId(t0.Ownerid)
+	if got[1].Id != c || got[1].Position-got[0].Position != models.PositionStep {
+		t.Errorf("2Dos should be rebalanced: %v", got)
+	}
+}
+
+func TestTodoMoveBetweenDenseSharedTodos(t *testing.T) {
+	owner, _ := shareSetup("alice-TestTodoMoveBetweenDenseSharedTodos")
+	bob, bobToken := shareSetup("bob-TestTodoMoveBetweenDenseSharedTodos")
+
+	l := models.NewList()
+	l.Ownerid = owner.Id.Hex()
+	l.Shares = []models.Share{{UserId: bob.Id.Hex(), Username: bob.Username, Role: models.RoleEditor}}
+	models.NewListStorage().InsertList(l)
+
+	tds := models.NewTodoStorage()
+	positions := map[string]float64{}
+	ts := []models.Todo{models.NewTodo(), models.NewTodo(), models.NewTodo()}
+	for i := range ts {
+		ts[i].Ownerid = owner.Id.Hex()
+		ts[i].ListId = l.Id.Hex()
+		ts[i].Position = 2 + float64(i)*1e-9
+		positions[ts[i].Id] = ts[i].Position
+	}
+	ts[0].Ownerid, ts[0].ListId, ts[0].Position = bob.Id.Hex(), "", 1
+	for _, t0 := range ts {
+		tds.InsertTodo(t0)
+	}
+
+	// The only room for bob's 2Do is between alice's
+	req, rr := handlersSetup("POST", "api/todos/"+ts[0].Id+"/move", "{\"after\": \""+ts[1].Id+"\"}")
+	req = mux.SetURLVars(req, map[string]string{"id": ts[0].Id})
+	req.Header.Set("Authorization", "Bearer "+bobToken)
+	ValidatePath(http.HandlerFunc(TodoMoveHandler)).ServeHTTP(rr, req)
+	testStatus(StatusConflict, rr, t)
+
+	for _, t0 := range ts[1:] {
+		if t1, _ := tds.GetTodoById(t0.Id); t1.Position != positions[t0.Id] {
+			t.Errorf("The 2Dos of other users should keep their positions: %v", t1)
+		}
+	}
+}
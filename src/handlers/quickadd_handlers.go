@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"auth"
+	"encoding/json"
+	"fmt"
+	"log"
+	"models"
+	"net/http"
+	"quickadd"
+	"time"
+)
+
+type quickAddRequest struct {
+	Text     string `json:"text"`
+	TimeZone string `json:"time_zone"` // IANA time zone, the user's by default
+	ListId   string `json:"list_id"`
+	// Preview only returns the interpretation without adding the todo.
+	Preview bool `json:"preview"`
+}
+
+// TodoQuickAddHandler adds a todo from a single line of text such as
+// "Pay rent tomorrow 9am #home !high every month", see the quickadd
+// package. The response contains the todo and how the text was
+// interpreted so that clients can confirm it.
+func TodoQuickAddHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	var qr quickAddRequest
+	err = json.NewDecoder(r.Body).Decode(&qr)
+	if err != nil {
+		BadRequestHandler(w, r, "Body format incorrect for quick add. Try: { \"text\": \"Pay rent tomorrow 9am #home\", \"time_zone\": \"Europe/London\" }")
+		return
+	}
+
+	loc := userLocation(claims.UserId)
+	if qr.TimeZone != "" {
+		loc, err = time.LoadLocation(qr.TimeZone)
+		if err != nil {
+			BadRequestHandler(w, r, fmt.Sprintf("Unknown time zone: %s", qr.TimeZone))
+			return
+		}
+	}
+
+	now := time.Now()
+	res, err := quickadd.Parse(qr.Text, now, loc)
+	if err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+
+	t := &res.Todo
+	t.Ownerid = claims.UserId
+	t.Created = now
+	t.ListId = qr.ListId
+	if t.ListId != "" && !userCanEditList(t.ListId, claims.UserId) {
+		BadRequestHandler(w, r, fmt.Sprintf("List not found: %s", t.ListId))
+		return
+	}
+
+	if err := setInitialStatus(t, claims.UserId); err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+
+	if qr.Preview {
+		writeJSON(w, r, StatusSuccess, jsonResponse{Result: "2Do not added, preview only", Data: res})
+		return
+	}
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	last, err := tds.LastPosition(claims.UserId)
+	if err == nil {
+		t.Position, _ = models.PositionBetween(&last, nil)
+		err = tds.InsertTodo(*t)
+	}
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to add 2Do")
+		log.Println("Failure to add 2Do: " + err.Error())
+		return
+	}
+	log.Println("2Do: " + t.Id + " created")
+
+	writeJSON(w, r, StatusCreation, jsonResponse{
+		Result: fmt.Sprintf("Successfully created 2Do: %s", t.Id),
+		Data:   res,
+	})
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"models"
+	"quickadd"
+	"testing"
+	"time"
+)
+
+func TestTodoQuickAdd(t *testing.T) {
+	token, _ := attachmentSetup()
+
+	serve := func(body string) *quickadd.Result {
+		req, rr := handlersSetup("POST", "api/todos/quick", body)
+		req.Header.Set("Authorization", "Bearer "+token)
+		ValidatePath(TodoQuickAddHandler).ServeHTTP(rr, req)
+		if rr.Code != StatusSuccess && rr.Code != StatusCreation {
+			t.Fatalf("Failure to quick add %s: got %d", body, rr.Code)
+		}
+
+		var res struct {
+			Data quickadd.Result `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		return &res.Data
+	}
+
+	res := serve("{\"text\": \"Pay rent tomorrow 9am #home !high every month\", \"time_zone\": \"Europe/London\", \"preview\": true}")
+	if res.Todo.Title != "Pay rent" || len(res.Tokens) != 4 {
+		t.Errorf("Incorrect interpretation: %v", res)
+	}
+	if _, err := models.NewTodoStorage().GetTodoById(res.Todo.Id); err == nil {
+		t.Error("Preview should not add the 2Do")
+	}
+
+	before := time.Now()
+	res = serve("{\"text\": \"Pay rent tomorrow 9am #home\", \"time_zone\": \"Europe/London\"}")
+	td, err := models.NewTodoStorage().GetTodoById(res.Todo.Id)
+	if err != nil || td.Title != "Pay rent" || td.Due.IsZero() {
+		t.Errorf("2Do should be added: %v %v", td, err)
+	}
+	if td != nil && (td.Created.Before(before.Truncate(time.Millisecond)) || td.Created.After(time.Now())) {
+		t.Errorf("2Do should be created now: %v", td.Created)
+	}
+
+	for _, body := range []string{"{\"text\": \"#home\"}", "{\"text\": \"Pay rent\", \"time_zone\": \"Mars/Base\"}"} {
+		req, rr := handlersSetup("POST", "api/todos/quick", body)
+		req.Header.Set("Authorization", "Bearer "+token)
+		ValidatePath(TodoQuickAddHandler).ServeHTTP(rr, req)
+		testStatus(StatusBadRequest, rr, t)
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"auth"
+	"authz"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"log"
+	"models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultOccurrences = 5
+	maxOccurrences     = 100
+)
+
+// nextRecurringInstance returns the instance which follows the todo
+// once it is completed. It returns nil if the todo isn't recurring
+// or has already been completed.
+func nextRecurringInstance(t models.Todo) (*models.Todo, error) {
+	if t.Completed || t.Recurrence == nil {
+		return nil, nil
+	}
+
+	return t.NextInstance(time.Now())
+}
+
+// applyRecurrence validates the recurrence which the changes set on the
+// todo, and checks that the todo keeps a due date when it recurs.
+// Otherwise it writes the error response. Where an instance is in its
+// series is not the client's to set: a recurrence starts its series at
+// the due date unless it only repeats the rule the todo has.
+func applyRecurrence(w http.ResponseWriter, r *http.Request, changes map[string]interface{}, t models.Todo) bool {
+	due := t.Due
+	if v, ok := changes["due_date"]; ok {
+		// Either a time or nil when it is cleared, see TodoPutHandler
+		due, _ = v.(time.Time)
+	}
+
+	rc := t.Recurrence
+	if v, ok := changes["recurrence"]; ok {
+		rc = nil
+		if v != nil {
+			b, err := json.Marshal(v)
+			rc = &models.Recurrence{}
+			if err == nil {
+				err = json.Unmarshal(b, rc)
+			}
+			if err == nil {
+				err = rc.Validate()
+			}
+			if err != nil {
+				BadRequestHandler(w, r, fmt.Sprintf("Invalid recurrence: %s", err))
+				return false
+			}
+
+			if old := t.Recurrence; old != nil && old.Rule == rc.Rule && old.TimeZone == rc.TimeZone && old.FromCompletion == rc.FromCompletion {
+				rc.Start, rc.Index = old.Start, old.Index
+			} else {
+				rc.Start, rc.Index = due, 1
+			}
+			changes["recurrence"] = *rc
+		}
+	}
+
+	if rc != nil && due.IsZero() {
+		BadRequestHandler(w, r, models.ErrNoDueDate.Error())
+		return false
+	}
+
+	return true
+}
+
+// TodoOccurrencesHandler is the handler function for the
+// /api/todos/{id}/occurrences endpoint. It previews the due dates of
+// the upcoming instances of a recurring todo, ?count= sets how many.
+func TodoOccurrencesHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	count := defaultOccurrences
+	if c := r.URL.Query().Get("count"); c != "" {
+		count, err = strconv.Atoi(c)
+		if err != nil || count < 1 || count > maxOccurrences {
+			BadRequestHandler(w, r, fmt.Sprintf("count must be between 1 and %d", maxOccurrences))
+			return
+		}
+	}
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	t, ok := authorizeTodo(w, r, tds, claims.UserId, id, authz.View)
+	if !ok {
+		return
+	}
+
+	if t.Recurrence == nil {
+		BadRequestHandler(w, r, "2Do is not recurring.")
+		return
+	}
+
+	ts, err := t.Occurrences(count)
+	if err != nil {
+		BadRequestHandler(w, r, fmt.Sprintf("Invalid recurrence: %s", err))
+		return
+	}
+
+	data, err := json.Marshal(ts)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("TodoOccurrencesHandler: " + err.Error())
+		return
+	}
+
+	w.WriteHeader(StatusSuccess)
+	w.Header().Set(ContentType, ApplicationJSON)
+	w.Write(data)
+}
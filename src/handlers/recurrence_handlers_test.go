@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"auth"
+	"github.com/gorilla/mux"
+	"log"
+	"models"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTodoPutHandlerCompletesRecurringTodo(t *testing.T) {
+	u := models.NewUser()
+	tus := models.NewUserStorage()
+	tus.InsertUser(u)
+	token, err := auth.CreateToken(u)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	t0 := models.NewTodo()
+	t0.Ownerid = u.Id.Hex()
+	t0.Due = time.Date(2017, time.March, 1, 9, 0, 0, 0, time.UTC)
+	t0.Recurrence = &models.Recurrence{Rule: "FREQ=DAILY"}
+	tds := models.NewTodoStorage()
+	tds.InsertTodo(t0)
+
+	req, rr := handlersSetup("PUT", "api/todos/"+t0.Id, "{\"completed\": true}")
+	req = mux.SetURLVars(req, map[string]string{"id": t0.Id})
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+		TodoHandler(w, r)
+	}
+
+	ValidatePath(handler).ServeHTTP(rr, req)
+
+	testStatus(StatusSuccess, rr, t)
+
+	ts, err := tds.GetTodosForUserId(u.Id.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ts) != 2 {
+		t.Fatalf("Next instance was not created: %v", ts)
+	}
+
+	for _, todo := range ts {
+		if todo.Id == t0.Id {
+			if !todo.Completed {
+				t.Error("2Do was not completed")
+			}
+		} else if !todo.Due.Equal(t0.Due.AddDate(0, 0, 1)) || todo.Completed {
+			t.Errorf("Incorrect next instance: %v", todo)
+		}
+		tds.DeleteTodo(todo.Id)
+	}
+
+	tus.DeleteUser(u.Id.Hex())
+}
+
+func TestTodoPutHandlerRecurrence(t *testing.T) {
+	u, token := shareSetup("TestTodoPutHandlerRecurrence")
+
+	tds := models.NewTodoStorage()
+	t0 := models.NewTodo()
+	t0.Ownerid = u.Id.Hex()
+	tds.InsertTodo(t0)
+
+	put := func(body string) int {
+		req, rr := handlersSetup("PUT", "api/todos/"+t0.Id, body)
+		req = mux.SetURLVars(req, map[string]string{"id": t0.Id})
+		req.Header.Set("Authorization", "Bearer "+token)
+		ValidatePath(TodoHandler).ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := put("{\"recurrence\": {\"rrule\": \"FREQ=DAILY\"}}"); code != StatusBadRequest {
+		t.Errorf("A recurrence without a due date should be refused: %d", code)
+	}
+
+	if code := put("{\"recurrence\": {\"rrule\": \"FREQ=SOMETIMES\"}, \"due_date\": \"2017-03-01\"}"); code != StatusBadRequest {
+		t.Errorf("An invalid recurrence should be refused: %d", code)
+	}
+
+	body := "{\"recurrence\": {\"rrule\": \"FREQ=DAILY;COUNT=3\", \"dtstart\": \"2000-01-01T00:00:00Z\", \"index\": 3}, \"due_date\": \"2017-03-01T09:00:00Z\"}"
+	if code := put(body); code != StatusSuccess {
+		t.Fatalf("The recurrence should be set: %d", code)
+	}
+
+	t1, _ := tds.GetTodoById(t0.Id)
+	due := time.Date(2017, time.March, 1, 9, 0, 0, 0, time.UTC)
+	if rc := t1.Recurrence; rc == nil || !rc.Start.Equal(due) || rc.Index != 1 {
+		t.Errorf("The series should start at the due date: %v", rc)
+	}
+
+	if code := put("{\"due_date\": null}"); code != StatusBadRequest {
+		t.Errorf("The due date of a recurring 2Do should not be cleared: %d", code)
+	}
+
+	if code := put("{\"due_date\": null, \"recurrence\": null}"); code != StatusSuccess {
+		t.Errorf("The recurrence and due date should be cleared: %d", code)
+	}
+}
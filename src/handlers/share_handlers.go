@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"auth"
+	"authz"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"log"
+	"models"
+	"net/http"
+)
+
+// authorizeTodo returns the todo with the id if the user may perform
+// the action on it, otherwise it writes the error response.
+func authorizeTodo(w http.ResponseWriter, r *http.Request, tds models.TodoStorage, userId, id string, a authz.Action) (*models.Todo, bool) {
+	t, err := authz.Todo(tds, userId, id, a)
+	switch err {
+	case nil:
+		return t, true
+	case authz.ErrForbidden:
+		ForbiddenHandler(w, r, fmt.Sprintf("Insufficient permissions for 2Do: %s", id))
+	default:
+		NotFoundHandler(w, r, fmt.Sprintf("Failed to retrieve 2Do with id: %s", id))
+	}
+
+	return nil, false
+}
+
+// authorizeList returns the list with the id if the user may perform
+// the action on it, otherwise it writes the error response.
+func authorizeList(w http.ResponseWriter, r *http.Request, lds models.ListStorage, userId, id string, a authz.Action) (*models.List, bool) {
+	l, err := authz.List(lds, userId, id, a)
+	switch err {
+	case nil:
+		return l, true
+	case authz.ErrForbidden:
+		ForbiddenHandler(w, r, fmt.Sprintf("Insufficient permissions for list: %s", id))
+	default:
+		NotFoundHandler(w, r, fmt.Sprintf("Failed to retrieve list with id: %s", id))
+	}
+
+	return nil, false
+}
+
+// shareRequest is the body of a request to share a todo or list.
+type shareRequest struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// shareFromRequest decodes the share in the request body and looks up
+// the user it is for. The owner of the item can't be given a share.
+func shareFromRequest(r *http.Request, ownerId string) (models.Share, error) {
+	sr := shareRequest{}
+	err := json.NewDecoder(r.Body).Decode(&sr)
+	if err != nil {
+		return models.Share{}, fmt.Errorf("Body format incorrect for share. Try: { \"username\": \"alice\", \"role\": \"%s\" }", models.RoleEditor)
+	}
+
+	role, err := models.ParseRole(sr.Role)
+	if err != nil {
+		return models.Share{}, err
+	}
+
+	uds := models.NewUserStorage()
+	defer uds.Close()
+
+	u, err := uds.GetUserByName(sr.Username)
+	if err != nil {
+		return models.Share{}, fmt.Errorf("User not found: %s", sr.Username)
+	}
+
+	if u.Id.Hex() == ownerId {
+		return models.Share{}, fmt.Errorf("%s already owns it", sr.Username)
+	}
+
+	return models.Share{UserId: u.Id.Hex(), Username: u.Username, Role: role}, nil
+}
+
+// sharesResponse writes the shares of a todo or list.
+func sharesResponse(w http.ResponseWriter, r *http.Request, shares []models.Share) {
+	if shares == nil {
+		shares = make([]models.Share, 0)
+	}
+
+	data, err := json.Marshal(shares)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("sharesResponse: " + err.Error())
+		return
+	}
+
+	w.WriteHeader(StatusSuccess)
+	w.Header().Set(ContentType, ApplicationJSON)
+	w.Write(data)
+}
+
+// removedShare returns the shares without the share of the user with
+// the username in the request path.
+func removedShare(r *http.Request, shares []models.Share) ([]models.Share, bool) {
+	username := mux.Vars(r)["username"]
+	for _, s := range shares {
+		if s.Username == username {
+			return models.RemoveShare(shares, s.UserId)
+		}
+	}
+
+	return shares, false
+}
+
+// TodoSharesHandler is the handler function for the
+// /api/todos/{id}/shares endpoint. GET lists the shares of the todo
+// and PUT shares it with a user, or changes the role of the user.
+func TodoSharesHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	action := authz.Share
+	if r.Method == "GET" {
+		action = authz.View
+	}
+
+	t, ok := authorizeTodo(w, r, tds, claims.UserId, id, action)
+	if !ok {
+		return
+	}
+
+	if r.Method == "PUT" {
+		share, err := shareFromRequest(r, t.Ownerid)
+		if err != nil {
+			BadRequestHandler(w, r, err.Error())
+			return
+		}
+
+		t.Shares = models.SetShare(t.Shares, share)
+		err = tds.SetTodoShares(id, t.Shares)
+		if err != nil {
+			InternalErrorHandler(w, r, "Failure to share 2Do")
+			log.Println("Failure to share 2Do: " + err.Error())
+			return
+		}
+	}
+
+	sharesResponse(w, r, t.Shares)
+}
+
+// TodoShareDeleteHandler is the handler function for the
+// /api/todos/{id}/shares/{username} endpoint. It stops sharing
+// the todo with the user.
+func TodoShareDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	t, ok := authorizeTodo(w, r, tds, claims.UserId, id, authz.Share)
+	if !ok {
+		return
+	}
+
+	shares, ok := removedShare(r, t.Shares)
+	if !ok {
+		NotFoundHandler(w, r, "Share not found.")
+		return
+	}
+
+	err = tds.SetTodoShares(id, shares)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to unshare 2Do")
+		log.Println("Failure to unshare 2Do: " + err.Error())
+		return
+	}
+
+	sharesResponse(w, r, shares)
+}
+
+// ListSharesHandler is the handler function for the
+// /api/lists/{id}/shares endpoint. Sharing a list grants the role on
+// every todo of the list.
+func ListSharesHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	lds := models.NewListStorage()
+	defer lds.Close()
+
+	action := authz.Share
+	if r.Method == "GET" {
+		action = authz.View
+	}
+
+	l, ok := authorizeList(w, r, lds, claims.UserId, id, action)
+	if !ok {
+		return
+	}
+
+	if r.Method == "PUT" {
+		share, err := shareFromRequest(r, l.Ownerid)
+		if err != nil {
+			BadRequestHandler(w, r, err.Error())
+			return
+		}
+
+		l.Shares = models.SetShare(l.Shares, share)
+		err = lds.SetListShares(id, l.Shares)
+		if err != nil {
+			InternalErrorHandler(w, r, "Failure to share list")
+			log.Println("Failure to share list: " + err.Error())
+			return
+		}
+	}
+
+	sharesResponse(w, r, l.Shares)
+}
+
+// ListShareDeleteHandler is the handler function for the
+// /api/lists/{id}/shares/{username} endpoint. It stops sharing
+// the list with the user.
+func ListShareDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	lds := models.NewListStorage()
+	defer lds.Close()
+
+	l, ok := authorizeList(w, r, lds, claims.UserId, id, authz.Share)
+	if !ok {
+		return
+	}
+
+	shares, ok := removedShare(r, l.Shares)
+	if !ok {
+		NotFoundHandler(w, r, "Share not found.")
+		return
+	}
+
+	err = lds.SetListShares(id, shares)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to unshare list")
+		log.Println("Failure to unshare list: " + err.Error())
+		return
+	}
+
+	sharesResponse(w, r, shares)
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"auth"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"log"
+	"models"
+	"net/http"
+	"testing"
+)
+
+// shareSetup stores a user with the username and returns its token.
+func shareSetup(username string) (models.User, string) {
+	u := models.NewUser()
+	u.Username = username
+	models.NewUserStorage().InsertUser(u)
+	token, err := auth.CreateToken(u)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return u, token
+}
+
+func TestTodoSharing(t *testing.T) {
+	ownerToken, t0 := attachmentSetup()
+	bob, bobToken := shareSetup("bob-TestTodoSharing")
+	vars := map[string]string{"id": t0.Id}
+
+	serve := func(token, method, body string, handler http.HandlerFunc) int {
+		req, rr := handlersSetup(method, "api/todos/"+t0.Id, body)
+		req = mux.SetURLVars(req, vars)
+		req.Header.Set("Authorization", "Bearer "+token)
+		ValidatePath(handler).ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := serve(bobToken, "GET", "", TodoHandler); code != StatusNotFound {
+		t.Errorf("Unshared 2Do should not be found: got %d", code)
+	}
+
+	body := "{\"username\": \"" + bob.Username + "\", \"role\": \"viewer\"}"
+	if code := serve(bobToken, "PUT", body, TodoSharesHandler); code != StatusNotFound {
+		t.Errorf("Only owners may share a 2Do: got %d", code)
+	}
+
+	if code := serve(ownerToken, "PUT", body, TodoSharesHandler); code != StatusSuccess {
+		t.Fatalf("Failure to share 2Do: got %d", code)
+	}
+
+	if code := serve(bobToken, "GET", "", TodoHandler); code != StatusSuccess {
+		t.Errorf("Viewer should see the 2Do: got %d", code)
+	}
+
+	if code := serve(bobToken, "PUT", "{\"title\": \"Mine now\"}", TodoHandler); code != StatusForbidden {
+		t.Errorf("Viewer should not modify the 2Do: got %d", code)
+	}
+
+	// The shared 2Do is listed for the recipient
+	req, rr := handlersSetup("GET", "api/todos", "")
+	req.Header.Set("Authorization", "Bearer "+bobToken)
+	ValidatePath(TodosHandler).ServeHTTP(rr, req)
+
+	var ts []models.Todo
+	if err := json.Unmarshal(rr.Body.Bytes(), &ts); err != nil {
+		t.Fatal(err)
+	}
+	if len(ts) != 1 || ts[0].Id != t0.Id {
+		t.Errorf("Shared 2Do should be listed: %v", ts)
+	}
+
+	body = "{\"username\": \"" + bob.Username + "\", \"role\": \"editor\"}"
+	serve(ownerToken, "PUT", body, TodoSharesHandler)
+	if code := serve(bobToken, "PUT", "{\"title\": \"Edited\"}", TodoHandler); code != StatusSuccess {
+		t.Errorf("Editor should modify the 2Do: got %d", code)
+	}
+
+	if code := serve(bobToken, "DELETE", "", TodoHandler); code != StatusForbidden {
+		t.Errorf("Editor should not delete the 2Do: got %d", code)
+	}
+
+	vars["username"] = bob.Username
+	if code := serve(ownerToken, "DELETE", "", TodoShareDeleteHandler); code != StatusSuccess {
+		t.Fatalf("Failure to unshare 2Do: got %d", code)
+	}
+
+	delete(vars, "username")
+	if code := serve(bobToken, "GET", "", TodoHandler); code != StatusNotFound {
+		t.Errorf("Unshared 2Do should not be found: got %d", code)
+	}
+}
+
+func TestListSharing(t *testing.T) {
+	owner, ownerToken := shareSetup("alice-TestListSharing")
+	bob, bobToken := shareSetup("bob-TestListSharing")
+
+	l := models.NewList()
+	l.Ownerid = owner.Id.Hex()
+	l.Shares = []models.Share{{UserId: bob.Id.Hex(), Username: bob.Username, Role: models.RoleEditor}}
+	models.NewListStorage().InsertList(l)
+
+	t0 := models.NewTodo()
+	t0.Ownerid = owner.Id.Hex()
+	t0.ListId = l.Id.Hex()
+	models.NewTodoStorage().InsertTodo(t0)
+
+	// Editors of a list may add 2Dos to it and edit its 2Dos
+	req, rr := handlersSetup("POST", "api/todos", "{\"title\": \"Mine\", \"list_id\": \""+l.Id.Hex()+"\"}")
+	req.Header.Set("Authorization", "Bearer "+bobToken)
+	ValidatePath(TodosHandler).ServeHTTP(rr, req)
+	testStatus(StatusCreation, rr, t)
+
+	req, rr = handlersSetup("PUT", "api/todos/"+t0.Id, "{\"completed\": true}")
+	req = mux.SetURLVars(req, map[string]string{"id": t0.Id})
+	req.Header.Set("Authorization", "Bearer "+bobToken)
+	ValidatePath(TodoHandler).ServeHTTP(rr, req)
+	testStatus(StatusSuccess, rr, t)
+
+	// But may not delete the list
+	req, rr = handlersSetup("DELETE", "api/lists/"+l.Id.Hex(), "")
+	req = mux.SetURLVars(req, map[string]string{"id": l.Id.Hex()})
+	req.Header.Set("Authorization", "Bearer "+bobToken)
+	ValidatePath(ListHandler).ServeHTTP(rr, req)
+	testStatus(StatusForbidden, rr, t)
+
+	// The owner sees the 2Do added by the editor
+	req, rr = handlersSetup("GET", "api/todos?list_id="+l.Id.Hex(), "")
+	req.Header.Set("Authorization", "Bearer "+ownerToken)
+	ValidatePath(TodosHandler).ServeHTTP(rr, req)
+
+	var ts []models.Todo
+	if err := json.Unmarshal(rr.Body.Bytes(), &ts); err != nil {
+		t.Fatal(err)
+	}
+	if len(ts) != 2 {
+		t.Errorf("2Dos of the list should be listed: %v", ts)
+	}
+}
@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"auth"
+	"authz"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"log"
+	"models"
+	"net/http"
+	"time"
+)
+
+// SmartListsHandler is a handler function for the /api/smart-lists
+// endpoint it acts as a multiplexer to a respective http method handler.
+func SmartListsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		SmartListsGetHandler(w, r)
+	case "POST":
+		SmartListsPostHandler(w, r)
+	}
+}
+
+// SmartListHandler is a handler function for the /api/smart-lists/{id}
+// endpoint it acts as a multiplexer to a respective http method handler.
+func SmartListHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		SmartListGetHandler(w, r)
+	case "PUT":
+		SmartListPutHandler(w, r)
+	case "DELETE":
+		SmartListDeleteHandler(w, r)
+	}
+}
+
+const smartListFormat = "Body format incorrect for smart list. Try: { \"name\": \"Due this week\", \"filter\": { \"tags\": [\"work\"], \"min_priority\": \"high\", \"due_to\": \"today+7d\" }, \"sort\": \"due\" }"
+
+// ownSmartList returns the smart list with the id if the user owns it,
+// otherwise it writes the error response. Smart lists are private so
+// those of other users are not found.
+func ownSmartList(w http.ResponseWriter, r *http.Request, sds models.SmartListStorage, userId, id string) (*models.SmartList, bool) {
+	s, err := sds.GetSmartListById(id)
+	if err != nil || s.Ownerid != userId {
+		NotFoundHandler(w, r, fmt.Sprintf("Failed to retrieve smart list with id: %s", id))
+		return nil, false
+	}
+
+	return s, true
+}
+
+// smartListFilter returns the filter of the todos of the smart list for
+// the user, see models.SmartList.TodoFilter. The list of the smart list
+// must be visible to the user.
+func smartListFilter(s models.SmartList, userId string, now time.Time) (models.TodoFilter, error) {
+	var fields []models.CustomField
+	if listId := s.Filter.ListId; listId != "" && listId != models.InboxListId {
+		lds := models.NewListStorage()
+		defer lds.Close()
+
+		l, err := authz.List(lds, userId, listId, authz.View)
+		if err != nil {
+			return models.TodoFilter{}, fmt.Errorf("List not found: %s", listId)
+		}
+		fields = l.Fields
+	}
+
+	return s.TodoFilter(userId, fields, now, userLocation(userId))
+}
+
+// smartListFromRequest decodes and validates the smart list of the
+// request for the user.
+func smartListFromRequest(r *http.Request, s *models.SmartList, userId string) error {
+	if err := json.NewDecoder(r.Body).Decode(s); err != nil {
+		return errors.New(smartListFormat)
+	}
+
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	_, err := smartListFilter(*s, userId, time.Now())
+	return err
+}
+
+// SmartListsGetHandler returns the smart lists of the user by name.
+func SmartListsGetHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	sds := models.NewSmartListStorage()
+	defer sds.Close()
+
+	ss, err := sds.GetSmartListsForUserId(claims.UserId)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("Failed to get smart lists: " + err.Error())
+		return
+	}
+
+	writeJSON(w, r, StatusSuccess, ss)
+}
+
+// SmartListsPostHandler creates a smart list, a saved search of todos.
+// Its filter narrows the todos down by list_id, tags and tag_mode,
+// min_priority, assigned (me), due (today or overdue), the due_from and
+// due_to dates, completed, search and conditions on the custom fields of
+// its list: [{ "key": "estimate", "op": "lt", "value": "3" }]. Dates
+// may be relative to the day the todos are asked for e.g. today+7d, see
+// models.ParseRelativeDate. Its todos are sorted by sort, one of smart,
+// due, priority, created, title or fields.{key}, - for descending.
+func SmartListsPostHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	s := models.SmartList{}
+	if err := smartListFromRequest(r, &s, claims.UserId); err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+	n := models.NewSmartList()
+	s.Id, s.Ownerid, s.Created = n.Id, claims.UserId, n.Created
+
+	sds := models.NewSmartListStorage()
+	defer sds.Close()
+
+	err = sds.InsertSmartList(s)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to add smart list")
+		log.Println("Failure to add smart list: " + err.Error())
+		return
+	}
+
+	writeJSON(w, r, StatusCreation, jsonResponse{
+		Result: fmt.Sprintf("Successfully created smart list: %s", s.Id.Hex()),
+		Data:   s,
+	})
+}
+
+// SmartListGetHandler returns a smart list of the user.
+func SmartListGetHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	sds := models.NewSmartListStorage()
+	defer sds.Close()
+
+	s, ok := ownSmartList(w, r, sds, claims.UserId, id)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, r, StatusSuccess, s)
+}
+
+// SmartListPutHandler replaces the name and definition of a smart list.
+func SmartListPutHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	sds := models.NewSmartListStorage()
+	defer sds.Close()
+
+	old, ok := ownSmartList(w, r, sds, claims.UserId, id)
+	if !ok {
+		return
+	}
+
+	s := models.SmartList{}
+	if err := smartListFromRequest(r, &s, claims.UserId); err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+	s.Id, s.Ownerid, s.Created = old.Id, old.Ownerid, old.Created
+
+	err = sds.ReplaceSmartList(s)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to modify smart list")
+		log.Println("Failure to modify smart list: " + err.Error())
+		return
+	}
+
+	writeJSON(w, r, StatusSuccess, jsonResponse{
+		Result: fmt.Sprintf("Successfully modified smart list: %s", id),
+		Data:   s,
+	})
+}
+
+// SmartListDeleteHandler deletes a smart list, its todos are kept.
+func SmartListDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	sds := models.NewSmartListStorage()
+	defer sds.Close()
+
+	if _, ok := ownSmartList(w, r, sds, claims.UserId, id); !ok {
+		return
+	}
+
+	err = sds.DeleteSmartList(id)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to delete smart list")
+		log.Println("Failure to delete smart list: " + err.Error())
+		return
+	}
+
+	writeJSON(w, r, StatusSuccess, jsonResponse{Result: fmt.Sprintf("Successfully deleted smart list: %s", id)})
+}
+
+// SmartListTodosHandler returns the todos of a smart list, evaluating
+// its filter at the time of the request in the user's time zone. The
+// todos accessible to the user are those of TodosGetHandler and their
+// notes are rendered with ?render=html.
+func SmartListTodosHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	render, err := renderParam(r.URL.Query())
+	if err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+
+	sds := models.NewSmartListStorage()
+	defer sds.Close()
+
+	s, ok := ownSmartList(w, r, sds, claims.UserId, id)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	f, err := smartListFilter(*s, claims.UserId, now)
+	if err != nil {
+		BadRequestHandler(w, r, fmt.Sprintf("Smart list no longer valid: %s", err))
+		return
+	}
+
+	lds := models.NewListStorage()
+	defer lds.Close()
+
+	f.SharedListIds, err = authz.SharedListIds(lds, claims.UserId)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("Failed to get shared lists: " + err.Error())
+		return
+	}
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	ts, err := tds.GetTodosForFilter(claims.UserId, f)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("Failed to get Todos of smart list: " + err.Error())
+		return
+	}
+	markBlocked(tds, ts)
+	markLogged(ts)
+	if render {
+		models.RenderNotes(ts)
+	}
+
+	switch by, desc := s.SortBy(); {
+	case s.SortField() != "":
+		models.SortByField(ts, s.SortField(), desc)
+	case by == models.SortSmart:
+		uds := models.NewUserStorage()
+		defer uds.Close()
+
+		u, err := uds.GetUserById(claims.UserId)
+		if err != nil {
+			InternalErrorHandler(w, r, "")
+			log.Println("Failed to get Todos of smart list: " + err.Error())
+			return
+		}
+
+		models.SmartSort(ts, u.Weights(), now)
+	default:
+		s.SortTodos(ts)
+	}
+
+	writeJSON(w, r, StatusSuccess, ts)
+}
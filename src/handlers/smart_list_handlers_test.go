@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"models"
+	"testing"
+	"time"
+)
+
+func init() {
+	models.SMART_LIST_STORE_TYPE = models.Test
+}
+
+func TestSmartLists(t *testing.T) {
+	u, token := shareSetup("smart-TestSmartLists")
+	_, other := shareSetup("smart-TestSmartLists-other")
+
+	tds := models.NewTodoStorage()
+	add := func(title string, p models.Priority, due time.Time) {
+		td := models.NewTodo()
+		td.Ownerid = u.Id.Hex()
+		td.Title = title
+		td.Tags = []string{"work"}
+		td.Priority = p
+		td.Due = due
+		tds.InsertTodo(td)
+	}
+	now := time.Now()
+	add("soon", models.PriorityHigh, now.Add(48*time.Hour))
+	add("sooner", models.PriorityUrgent, now.Add(24*time.Hour))
+	add("later", models.PriorityHigh, now.AddDate(0, 0, 30))
+	add("unimportant", models.PriorityLow, now.Add(24*time.Hour))
+
+	for _, body := range []string{
+		`{"filter": {}}`,
+		`{"name": "x", "filter": {"due_to": "someday"}}`,
+		`{"name": "x", "filter": {"list_id": "nope"}}`,
+		`{"name": "x", "sort": "size"}`,
+	} {
+		req, rr := handlersSetup("POST", "api/smart-lists", body)
+		req.Header.Set("Authorization", "Bearer "+token)
+		ValidatePath(SmartListsHandler).ServeHTTP(rr, req)
+		if rr.Code != StatusBadRequest {
+			t.Errorf("Expected a bad request for %s: got %d", body, rr.Code)
+		}
+	}
+
+	req, rr := handlersSetup("POST", "api/smart-lists", `{"name": "This week", "filter": {"tags": ["work"], "min_priority": "high", "due_to": "today+7d"}, "sort": "due"}`)
+	req.Header.Set("Authorization", "Bearer "+token)
+	ValidatePath(SmartListsHandler).ServeHTTP(rr, req)
+	testStatus(StatusCreation, rr, t)
+
+	var created struct {
+		Data models.SmartList `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	id := created.Data.Id.Hex()
+
+	todos := func(token string) ([]models.Todo, int) {
+		req, rr := handlersSetup("GET", "api/smart-lists/"+id+"/todos", "")
+		req.Header.Set("Authorization", "Bearer "+token)
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		ValidatePath(SmartListTodosHandler).ServeHTTP(rr, req)
+
+		var ts []models.Todo
+		json.Unmarshal(rr.Body.Bytes(), &ts)
+		return ts, rr.Code
+	}
+
+	if _, code := todos(other); code != StatusNotFound {
+		t.Errorf("Smart lists of other users should not be found: got %d", code)
+	}
+
+	ts, code := todos(token)
+	if code != StatusSuccess || len(ts) != 2 || ts[0].Title != "sooner" || ts[1].Title != "soon" {
+		t.Errorf("Incorrect todos of smart list: %d %v", code, ts)
+	}
+
+	req, rr = handlersSetup("PUT", "api/smart-lists/"+id, `{"name": "Five weeks", "filter": {"tags": ["work"], "due_from": "now", "due_to": "today+5w"}, "sort": "-priority"}`)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req = mux.SetURLVars(req, map[string]string{"id": id})
+	ValidatePath(SmartListHandler).ServeHTTP(rr, req)
+	testStatus(StatusSuccess, rr, t)
+
+	ts, _ = todos(token)
+	if len(ts) != 4 || ts[0].Title != "sooner" || ts[3].Title != "unimportant" {
+		t.Errorf("Incorrect todos of modified smart list: %v", ts)
+	}
+
+	req, rr = handlersSetup("GET", "api/smart-lists", "")
+	req.Header.Set("Authorization", "Bearer "+token)
+	ValidatePath(SmartListsHandler).ServeHTTP(rr, req)
+	var ss []models.SmartList
+	json.Unmarshal(rr.Body.Bytes(), &ss)
+	if len(ss) != 1 || ss[0].Name != "Five weeks" {
+		t.Errorf("Incorrect smart lists: %v", ss)
+	}
+
+	req, rr = handlersSetup("DELETE", "api/smart-lists/"+id, "")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req = mux.SetURLVars(req, map[string]string{"id": id})
+	ValidatePath(SmartListHandler).ServeHTTP(rr, req)
+	testStatus(StatusSuccess, rr, t)
+
+	if _, code := todos(token); code != StatusNotFound {
+		t.Errorf("Deleted smart list should not be found: got %d", code)
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"auth"
+	"authz"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"log"
+	"models"
+	"net/http"
+	"time"
+)
+
+type snoozeRequest struct {
+	Preset string `json:"preset"`
+	Until  string `json:"until"`
+}
+
+// TodoSnoozeHandler defers a todo, hiding it from the todos of the
+// user until then. The body either has a preset, one of later_today,
+// tomorrow or next_week in the user's time zone, see
+// models.SnoozeUntil, or the time or date until which the todo is
+// deferred. Snoozing is undone by setting defer_until to null.
+func TodoSnoozeHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	var sr snoozeRequest
+	err = json.NewDecoder(r.Body).Decode(&sr)
+	if err != nil || (sr.Preset == "") == (sr.Until == "") {
+		BadRequestHandler(w, r, "Body format incorrect for snooze. Try: { \"preset\": \"tomorrow\" } or { \"until\": \"2017-01-02\" }")
+		return
+	}
+
+	loc := userLocation(claims.UserId)
+
+	var until *time.Time
+	if sr.Preset != "" {
+		var at time.Time
+		at, err = models.SnoozeUntil(sr.Preset, time.Now(), loc)
+		until = &at
+	} else {
+		until, err = models.ParseDeferUntil(sr.Until, loc)
+	}
+	if err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	t, ok := authorizeTodo(w, r, tds, claims.UserId, id, authz.Edit)
+	if !ok {
+		return
+	}
+
+	err = tds.ModifyTodo(id, map[string]interface{}{"defer_until": *until})
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to snooze 2Do")
+		log.Println("Failure to snooze 2Do: " + err.Error())
+		return
+	}
+	t.DeferUntil = until
+
+	writeJSON(w, r, StatusSuccess, jsonResponse{
+		Result: fmt.Sprintf("Successfully snoozed 2Do: %s", id),
+		Data:   t,
+	})
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"models"
+	"testing"
+	"time"
+)
+
+func TestTodoSnooze(t *testing.T) {
+	u, token := shareSetup("snooze-TestTodoSnooze")
+	u.TimeZone = "Asia/Kolkata"
+	models.NewUserStorage().InsertUser(u)
+
+	tds := models.NewTodoStorage()
+	t0 := models.NewTodo()
+	t0.Ownerid = u.Id.Hex()
+	tds.InsertTodo(t0)
+	id := t0.Id
+
+	snooze := func(body string) int {
+		req, rr := handlersSetup("POST", "api/todos/"+id+"/snooze", body)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		ValidatePath(TodoSnoozeHandler).ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	get := func(query string) int {
+		req, rr := handlersSetup("GET", "api/todos"+query, "")
+		req.Header.Set("Authorization", "Bearer "+token)
+		ValidatePath(TodosHandler).ServeHTTP(rr, req)
+		testStatus(StatusSuccess, rr, t)
+
+		var ts []models.Todo
+		json.Unmarshal(rr.Body.Bytes(), &ts)
+		return len(ts)
+	}
+
+	for _, body := range []string{`{}`, `{"preset": "someday"}`, `{"preset": "tomorrow", "until": "2030-01-01"}`, `{"until": "soon"}`} {
+		if code := snooze(body); code != StatusBadRequest {
+			t.Errorf("Expected a bad request for %s: got %d", body, code)
+		}
+	}
+
+	if code := snooze(`{"preset": "tomorrow"}`); code != StatusSuccess {
+		t.Fatalf("Failure to snooze 2Do: got %d", code)
+	}
+
+	snoozed, _ := tds.GetTodoById(id)
+	want, _ := models.SnoozeUntil(models.SnoozeTomorrow, time.Now(), u.Location())
+	if snoozed.DeferUntil == nil || !snoozed.DeferUntil.Equal(want) {
+		t.Errorf("2Do should be deferred until tomorrow morning in the user's time zone: %v", snoozed.DeferUntil)
+	}
+
+	if n := get(""); n != 0 {
+		t.Errorf("Deferred 2Dos should be hidden: got %d", n)
+	}
+	if n := get("?include=deferred"); n != 1 {
+		t.Errorf("Deferred 2Dos should be included on demand: got %d", n)
+	}
+
+	req, rr := handlersSetup("PUT", "api/todos/"+id, `{"defer_until": null}`)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req = mux.SetURLVars(req, map[string]string{"id": id})
+	ValidatePath(TodoHandler).ServeHTTP(rr, req)
+	testStatus(StatusSuccess, rr, t)
+
+	if n := get(""); n != 1 {
+		t.Errorf("2Dos should resurface once no longer deferred: got %d", n)
+	}
+
+	past := time.Now().Add(-time.Minute).Format(time.RFC3339)
+	if code := snooze(`{"until": "` + past + `"}`); code != StatusSuccess {
+		t.Fatalf("Failure to snooze 2Do: got %d", code)
+	}
+	if n := get(""); n != 1 {
+		t.Errorf("2Dos deferred until a past time should be shown: got %d", n)
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"auth"
+	"authz"
+	"fmt"
+	"log"
+	"models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultStatsDays and defaultStatsWeeks are the lengths of the
+	// series of the statistics without ?days= and ?weeks=.
+	defaultStatsDays  = 30
+	defaultStatsWeeks = 12
+	maxStatsDays      = 366
+	maxStatsWeeks     = 104
+)
+
+// statsPeriod parses the query parameter key as a number of periods
+// between 1 and max, def when it is missing.
+func statsPeriod(r *http.Request, key string, def, max int) (int, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > max {
+		return 0, fmt.Errorf("%s must be a number between 1 and %d", key, max)
+	}
+
+	return n, nil
+}
+
+// StatsHandler returns the productivity statistics of the user: the
+// todos they completed per day and per week, their streaks, the
+// average time to complete a todo and the number of overdue todos.
+// Days are those of the user's time zone.
+func StatsHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	days, err := statsPeriod(r, "days", defaultStatsDays, maxStatsDays)
+	if err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+
+	weeks, err := statsPeriod(r, "weeks", defaultStatsWeeks, maxStatsWeeks)
+	if err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+
+	loc := userLocation(claims.UserId)
+	now := time.Now()
+
+	lds := models.NewListStorage()
+	defer lds.Close()
+
+	f := models.TodoFilter{Due: models.DueOverdue, Now: now, Location: loc}
+	f.SharedListIds, err = authz.SharedListIds(lds, claims.UserId)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("Failed to get shared lists: " + err.Error())
+		return
+	}
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	overdue, err := tds.CountTodosForFilter(claims.UserId, f)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("Failure to count overdue 2Dos: " + err.Error())
+		return
+	}
+
+	cs, err := tds.GetCompletionStats(claims.UserId, loc)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("Failure to get completion stats: " + err.Error())
+		return
+	}
+
+	writeJSON(w, r, StatusSuccess, models.NewStats(cs, overdue, now, loc, days, weeks))
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"models"
+	"testing"
+	"time"
+)
+
+func TestStatsHandler(t *testing.T) {
+	u, token := shareSetup("stats-TestStatsHandler")
+	tds := models.NewTodoStorage()
+
+	late := models.NewTodo()
+	late.Ownerid = u.Id.Hex()
+	late.Due = time.Now().Add(-time.Hour)
+	tds.InsertTodo(late)
+
+	done := models.NewTodo()
+	done.Ownerid = u.Id.Hex()
+	done.Created = time.Now().Add(-time.Hour)
+	tds.InsertTodo(done)
+
+	req, rr := handlersSetup("PUT", "api/todos/"+done.Id, "{\"completed\": true, \"completed_by\": \"someone\"}")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req = mux.SetURLVars(req, map[string]string{"id": done.Id})
+	ValidatePath(TodoHandler).ServeHTTP(rr, req)
+	testStatus(StatusSuccess, rr, t)
+
+	completed, _ := tds.GetTodoById(done.Id)
+	if completed.CompletedAt == nil || completed.CompletedBy != u.Id.Hex() {
+		t.Errorf("Completion should be recorded for the user: %v %q", completed.CompletedAt, completed.CompletedBy)
+	}
+
+	req, rr = handlersSetup("GET", "api/stats?days=7&weeks=2", "")
+	req.Header.Set("Authorization", "Bearer "+token)
+	ValidatePath(StatsHandler).ServeHTTP(rr, req)
+	testStatus(StatusSuccess, rr, t)
+
+	var s models.Stats
+	json.Unmarshal(rr.Body.Bytes(), &s)
+	if len(s.CompletedPerDay) != 7 || len(s.CompletedPerWeek) != 2 {
+		t.Fatalf("Incorrect series: %v %v", s.CompletedPerDay, s.CompletedPerWeek)
+	}
+	if s.CompletedPerDay[6].Count != 1 || s.CurrentStreak != 1 || s.LongestStreak != 1 {
+		t.Errorf("Completion should count today: %+v", s)
+	}
+	if s.AverageCompletionSeconds == nil || *s.AverageCompletionSeconds < 3600 {
+		t.Errorf("Incorrect average completion: %v", s.AverageCompletionSeconds)
+	}
+	if s.Overdue != 1 {
+		t.Errorf("Only the late 2Do should be overdue: %d", s.Overdue)
+	}
+
+	req, rr = handlersSetup("PUT", "api/todos/"+done.Id, "{\"completed\": false}")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req = mux.SetURLVars(req, map[string]string{"id": done.Id})
+	ValidatePath(TodoHandler).ServeHTTP(rr, req)
+	testStatus(StatusSuccess, rr, t)
+
+	reopened, _ := tds.GetTodoById(done.Id)
+	if reopened.CompletedAt != nil || reopened.CompletedBy != "" {
+		t.Errorf("Reopening should clear the completion: %v %q", reopened.CompletedAt, reopened.CompletedBy)
+	}
+
+	req, rr = handlersSetup("GET", "api/stats?days=0", "")
+	req.Header.Set("Authorization", "Bearer "+token)
+	ValidatePath(StatsHandler).ServeHTTP(rr, req)
+	testStatus(StatusBadRequest, rr, t)
+}
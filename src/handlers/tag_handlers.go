@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"auth"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"log"
+	"models"
+	"net/http"
+)
+
+// TagsGetHandler is the handler function for the /api/tags endpoint
+// it returns every tag of the user along with its usage count.
+func TagsGetHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	tcs, err := tds.GetTagsForUserId(claims.UserId)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to retrieve tags")
+		log.Println("Failed to get tags: " + err.Error())
+		return
+	}
+
+	data, err := json.Marshal(tcs)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("Failed to get tags: " + err.Error())
+		return
+	}
+
+	w.WriteHeader(StatusSuccess)
+	w.Header().Set(ContentType, ApplicationJSON)
+	w.Write(data)
+}
+
+// TagPutHandler is the handler function for the /api/tags/{tag}
+// endpoint. It renames the tag on every todo of the user, renaming
+// to an already existing tag merges the two.
+func TagPutHandler(w http.ResponseWriter, r *http.Request) {
+	tag := models.NormalizeTag(mux.Vars(r)["tag"])
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	var body struct {
+		Name string `json:"name"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&body)
+	name := models.NormalizeTag(body.Name)
+	if err != nil || name == "" || tag == "" {
+		BadRequestHandler(w, r, "Body format incorrect for tag. Try: { \"name\": \"new-name\" }")
+		return
+	}
+
+	mergeTags(w, r, claims.UserId, []string{tag}, name)
+}
+
+// TagsMergeHandler is the handler function for the /api/tags/merge
+// endpoint. It replaces all of the given tags with a single tag.
+func TagsMergeHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	var body struct {
+		Tags []string `json:"tags"`
+		Into string   `json:"into"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&body)
+	tags := models.NormalizeTags(body.Tags)
+	into := models.NormalizeTag(body.Into)
+	if err != nil || len(tags) == 0 || into == "" {
+		BadRequestHandler(w, r, "Body format incorrect for merge. Try: { \"tags\": [\"a\", \"b\"], \"into\": \"c\" }")
+		return
+	}
+
+	mergeTags(w, r, claims.UserId, tags, into)
+}
+
+func mergeTags(w http.ResponseWriter, r *http.Request, userId string, tags []string, into string) {
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	err := tds.MergeTags(userId, tags, into)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to modify tags")
+		log.Println("Failure to modify tags: " + err.Error())
+		return
+	}
+
+	res := jsonResponse{Result: fmt.Sprintf("Successfully merged %v into tag: %s", tags, into)}
+	msg, err := json.Marshal(res)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to modify tags")
+		log.Println("Failure to modify tags: " + err.Error())
+		return
+	}
+
+	w.WriteHeader(StatusSuccess)
+	w.Header().Set(ContentType, ApplicationJSON)
+	w.Write(msg)
+}
@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"auth"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"log"
+	"models"
+	"net/http"
+	"time"
+)
+
+// TemplatesHandler is a handler function for the /api/templates
+// endpoint it acts as a multiplexer to a respective http method handler.
+func TemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		TemplatesGetHandler(w, r)
+	case "POST":
+		TemplatesPostHandler(w, r)
+	}
+}
+
+// TemplateHandler is a handler function for the /api/templates/{id}
+// endpoint it acts as a multiplexer to a respective http method handler.
+func TemplateHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		TemplateGetHandler(w, r)
+	case "PUT":
+		TemplatePutHandler(w, r)
+	case "DELETE":
+		TemplateDeleteHandler(w, r)
+	}
+}
+
+const templateFormat = "Body format incorrect for template. Try: { \"name\": \"Release\", \"title\": \"Release {{version}}\", \"subtasks\": [{ \"title\": \"Tag {{version}}\", \"due_offset\": \"-1d\" }] }"
+
+// ownTemplate returns the template with the id if the user owns it,
+// otherwise it writes the error response. Templates are private so
+// those of other users are not found.
+func ownTemplate(w http.ResponseWriter, r *http.Request, tts models.TemplateStorage, userId, id string) (*models.Template, bool) {
+	t, err := tts.GetTemplateById(id)
+	if err != nil || t.Ownerid != userId {
+		NotFoundHandler(w, r, fmt.Sprintf("Failed to retrieve template with id: %s", id))
+		return nil, false
+	}
+
+	return t, true
+}
+
+// templateFromRequest decodes and validates the template of the request.
+func templateFromRequest(r *http.Request, t *models.Template) error {
+	if err := json.NewDecoder(r.Body).Decode(t); err != nil {
+		return errors.New(templateFormat)
+	}
+
+	return t.Validate()
+}
+
+// TemplatesGetHandler returns the templates of the user by name.
+func TemplatesGetHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	tts := models.NewTemplateStorage()
+	defer tts.Close()
+
+	ts, err := tts.GetTemplatesForUserId(claims.UserId)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("Failed to get templates: " + err.Error())
+		return
+	}
+
+	writeJSON(w, r, StatusSuccess, ts)
+}
+
+// TemplatesPostHandler creates a template. Its title, note and tags
+// may hold {{variable}} placeholders and the due_offset of its todos,
+// e.g. 3d, -1w or 2d4h, is relative to the anchor date it is
+// instantiated with.
+func TemplatesPostHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	t := models.Template{}
+	if err := templateFromRequest(r, &t); err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+	n := models.NewTemplate()
+	t.Id, t.Ownerid, t.Created = n.Id, claims.UserId, n.Created
+
+	tts := models.NewTemplateStorage()
+	defer tts.Close()
+
+	err = tts.InsertTemplate(t)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to add template")
+		log.Println("Failure to add template: " + err.Error())
+		return
+	}
+
+	writeJSON(w, r, StatusCreation, jsonResponse{
+		Result: fmt.Sprintf("Successfully created template: %s", t.Id.Hex()),
+		Data:   t,
+	})
+}
+
+// TemplateGetHandler returns a template of the user along with the
+// names of its variables.
+func TemplateGetHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	tts := models.NewTemplateStorage()
+	defer tts.Close()
+
+	t, ok := ownTemplate(w, r, tts, claims.UserId, id)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, r, StatusSuccess, struct {
+		*models.Template
+		Variables []string `json:"variables"`
+	}{t, t.Variables()})
+}
+
+// TemplatePutHandler replaces the name and content of a template.
+func TemplatePutHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	tts := models.NewTemplateStorage()
+	defer tts.Close()
+
+	old, ok := ownTemplate(w, r, tts, claims.UserId, id)
+	if !ok {
+		return
+	}
+
+	t := models.Template{}
+	if err := templateFromRequest(r, &t); err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+	t.Id, t.Ownerid, t.Created = old.Id, old.Ownerid, old.Created
+
+	err = tts.ReplaceTemplate(t)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to modify template")
+		log.Println("Failure to modify template: " + err.Error())
+		return
+	}
+
+	writeJSON(w, r, StatusSuccess, jsonResponse{
+		Result: fmt.Sprintf("Successfully modified template: %s", id),
+		Data:   t,
+	})
+}
+
+// TemplateDeleteHandler deletes a template, the todos created from
+// it are kept.
+func TemplateDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	tts := models.NewTemplateStorage()
+	defer tts.Close()
+
+	if _, ok := ownTemplate(w, r, tts, claims.UserId, id); !ok {
+		return
+	}
+
+	err = tts.DeleteTemplate(id)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to delete template")
+		log.Println("Failure to delete template: " + err.Error())
+		return
+	}
+
+	writeJSON(w, r, StatusSuccess, jsonResponse{Result: fmt.Sprintf("Successfully deleted template: %s", id)})
+}
+
+// TemplateInstantiateHandler creates the todos of a template, the
+// template's own todo with its subtasks. The body gives the values of
+// the variables, the anchor date which due offsets are relative to,
+// today in the user's time zone by default, and optionally the list
+// the todos are added to:
+// { "variables": { "version": "1.2" }, "anchor": "2017-01-02", "list_id": "..." }
+// The anchor is either a date, which makes due dates all day, or an
+// RFC 3339 time.
+func TemplateInstantiateHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	body := struct {
+		Variables map[string]string `json:"variables"`
+		Anchor    string            `json:"anchor"`
+		ListId    string            `json:"list_id"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		BadRequestHandler(w, r, "Body format incorrect for instantiation. Try: { \"variables\": { \"version\": \"1.2\" }, \"anchor\": \"2017-01-02\" }")
+		return
+	}
+
+	loc := userLocation(claims.UserId)
+	if body.Anchor == "" {
+		body.Anchor = time.Now().In(loc).Format(models.DateFormat)
+	}
+	anchor, allDay, err := models.ParseDue(body.Anchor, loc)
+	if err != nil {
+		BadRequestHandler(w, r, "anchor must be an RFC 3339 time or a date (2006-01-02)")
+		return
+	}
+
+	if body.ListId != "" && !userCanEditList(body.ListId, claims.UserId) {
+		BadRequestHandler(w, r, fmt.Sprintf("List not found: %s", body.ListId))
+		return
+	}
+
+	tts := models.NewTemplateStorage()
+	defer tts.Close()
+
+	t, ok := ownTemplate(w, r, tts, claims.UserId, id)
+	if !ok {
+		return
+	}
+
+	ts, err := t.Instantiate(body.Variables, anchor, allDay)
+	if err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+
+	for i := range ts {
+		ts[i].ListId = body.ListId
+		if err := setInitialStatus(&ts[i], claims.UserId); err != nil {
+			BadRequestHandler(w, r, err.Error())
+			return
+		}
+	}
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	// The 2Dos are placed after the user's others
+	last, err := tds.LastPosition(claims.UserId)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to add 2Dos")
+		log.Println("Failure to instantiate template: " + err.Error())
+		return
+	}
+
+	for i := range ts {
+		ts[i].Ownerid = claims.UserId
+		ts[i].Position, _ = models.PositionBetween(&last, nil)
+		last = ts[i].Position
+
+		if err := tds.InsertTodo(ts[i]); err != nil {
+			InternalErrorHandler(w, r, "Failure to add 2Dos")
+			log.Println("Failure to instantiate template: " + err.Error())
+			return
+		}
+	}
+	log.Printf("Template: %s instantiated as 2Do: %s\n", id, ts[0].Id)
+
+	writeJSON(w, r, StatusCreation, jsonResponse{
+		Result: fmt.Sprintf("Successfully created 2Do: %s", ts[0].Id),
+		Data:   ts,
+	})
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"models"
+	"testing"
+)
+
+func init() {
+	models.TEMPLATE_STORE_TYPE = models.Test
+}
+
+func TestTemplates(t *testing.T) {
+	_, token := shareSetup("tpl-TestTemplates")
+	_, other := shareSetup("tpl-TestTemplates-other")
+
+	req, rr := handlersSetup("POST", "api/templates", `{"name": "Release", "title": "Release {{version}}", "due_offset": "0d", "subtasks": [{"title": "Tag {{version}}", "due_offset": "-1d"}]}`)
+	req.Header.Set("Authorization", "Bearer "+token)
+	ValidatePath(TemplatesHandler).ServeHTTP(rr, req)
+	testStatus(StatusCreation, rr, t)
+
+	var created struct {
+		Data models.Template `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	id := created.Data.Id.Hex()
+
+	req, rr = handlersSetup("POST", "api/templates", `{"name": "Broken", "title": "Oops", "due_offset": "soon"}`)
+	req.Header.Set("Authorization", "Bearer "+token)
+	ValidatePath(TemplatesHandler).ServeHTTP(rr, req)
+	testStatus(StatusBadRequest, rr, t)
+
+	req, rr = handlersSetup("GET", "api/templates/"+id, "")
+	req.Header.Set("Authorization", "Bearer "+other)
+	req = mux.SetURLVars(req, map[string]string{"id": id})
+	ValidatePath(TemplateHandler).ServeHTTP(rr, req)
+	testStatus(StatusNotFound, rr, t)
+
+	req, rr = handlersSetup("PUT", "api/templates/"+id, `{"name": "Release", "title": "Release {{version}} of {{project}}", "subtasks": [{"title": "Tag {{version}}", "due_offset": "-1d"}]}`)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req = mux.SetURLVars(req, map[string]string{"id": id})
+	ValidatePath(TemplateHandler).ServeHTTP(rr, req)
+	testStatus(StatusSuccess, rr, t)
+
+	req, rr = handlersSetup("GET", "api/templates/"+id, "")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req = mux.SetURLVars(req, map[string]string{"id": id})
+	ValidatePath(TemplateHandler).ServeHTTP(rr, req)
+	testStatus(StatusSuccess, rr, t)
+
+	var got struct {
+		Title     string   `json:"title"`
+		Variables []string `json:"variables"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &got)
+	if got.Title != "Release {{version}} of {{project}}" || len(got.Variables) != 2 {
+		t.Errorf("Template should be replaced: %+v", got)
+	}
+
+	instantiate := func(body string) {
+		req, rr = handlersSetup("POST", "api/templates/"+id+"/instantiate", body)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		ValidatePath(TemplateInstantiateHandler).ServeHTTP(rr, req)
+	}
+
+	instantiate(`{"variables": {"version": "1.2"}}`)
+	testStatus(StatusBadRequest, rr, t)
+
+	instantiate(`{"variables": {"version": "1.2", "project": "2Do"}, "anchor": "2017-01-02"}`)
+	testStatus(StatusCreation, rr, t)
+
+	var res struct {
+		Data []models.Todo `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &res)
+	if len(res.Data) != 2 || res.Data[0].Title != "Release 1.2 of 2Do" || res.Data[1].ParentId != res.Data[0].Id {
+		t.Fatalf("Template should be instantiated: %+v", res.Data)
+	}
+
+	tds := models.NewTodoStorage()
+	sub, err := tds.GetTodoById(res.Data[1].Id)
+	if err != nil || sub.Title != "Tag 1.2" || sub.Due.Format(models.DateFormat) != "2017-01-01" || !sub.AllDay {
+		t.Errorf("Subtask should be stored: %+v %v", sub, err)
+	}
+	if sub.Position <= res.Data[0].Position {
+		t.Errorf("Subtask should be placed after the 2Do: %v %v", sub.Position, res.Data[0].Position)
+	}
+
+	req, rr = handlersSetup("DELETE", "api/templates/"+id, "")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req = mux.SetURLVars(req, map[string]string{"id": id})
+	ValidatePath(TemplateHandler).ServeHTTP(rr, req)
+	testStatus(StatusSuccess, rr, t)
+
+	req, rr = handlersSetup("GET", "api/templates", "")
+	req.Header.Set("Authorization", "Bearer "+token)
+	ValidatePath(TemplatesHandler).ServeHTTP(rr, req)
+	testStatus(StatusSuccess, rr, t)
+
+	var ts []models.Template
+	json.Unmarshal(rr.Body.Bytes(), &ts)
+	if len(ts) != 0 {
+		t.Errorf("Template should be deleted: %v", ts)
+	}
+}
@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"auth"
+	"authz"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"log"
+	"models"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	TextCSV = "text/csv"
+
+	// defaultReportDays is the period of a time report without ?from=.
+	defaultReportDays = 30
+)
+
+// markLogged sets the total time logged on the todos.
+func markLogged(ts []models.Todo) {
+	if len(ts) == 0 {
+		return
+	}
+
+	eds := models.NewTimeEntryStorage()
+	defer eds.Close()
+
+	ids := make([]string, len(ts))
+	for i, t := range ts {
+		ids[i] = t.Id
+	}
+
+	logged, err := eds.GetLoggedSeconds(ids)
+	if err != nil {
+		log.Println("Failure to get logged time: " + err.Error())
+		return
+	}
+
+	for i := range ts {
+		ts[i].Logged = logged[ts[i].Id]
+	}
+}
+
+func removeTimeEntries(ts ...models.Todo) {
+	eds := models.NewTimeEntryStorage()
+	defer eds.Close()
+
+	for _, t := range ts {
+		err := eds.DeleteTimeEntriesForTodoId(t.Id)
+		if err != nil {
+			log.Printf("removeTimeEntries: Failure to delete time entries of 2Do %s: %s\n", t.Id, err)
+		}
+	}
+}
+
+func trackableTodo(w http.ResponseWriter, r *http.Request, id, userId string, a authz.Action) (*models.Todo, bool) {
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	return authorizeTodo(w, r, tds, userId, id, a)
+}
+
+// TimerHandler returns or stops the running timer of the user.
+func TimerHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	eds := models.NewTimeEntryStorage()
+	defer eds.Close()
+
+	var e *models.TimeEntry
+	switch r.Method {
+	case "GET":
+		e, err = eds.GetRunningTimer(claims.UserId)
+	case "DELETE":
+		e, err = eds.StopTimer(claims.UserId, time.Now())
+	}
+	if err == models.TimeEntryNotFoundError {
+		NotFoundHandler(w, r, "No timer running.")
+		return
+	}
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("Failure to get timer: " + err.Error())
+		return
+	}
+
+	writeJSON(w, r, StatusSuccess, e)
+}
+
+// TodoTimerHandler starts a timer on the todo. A user may only run one
+// timer at a time, the running timer must be stopped first.
+func TodoTimerHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	if _, ok := trackableTodo(w, r, id, claims.UserId, authz.TrackTime); !ok {
+		return
+	}
+
+	e := models.NewTimeEntry()
+	e.TodoId = id
+	e.Ownerid = claims.UserId
+	e.Start = time.Now()
+
+	eds := models.NewTimeEntryStorage()
+	defer eds.Close()
+
+	err = eds.StartTimer(e)
+	if err == models.ErrTimerRunning {
+		ConflictHandler(w, r, "A timer is already running, stop it first.")
+		return
+	}
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to start timer")
+		log.Println("Failure to start timer: " + err.Error())
+		return
+	}
+	e.Running = true
+
+	writeJSON(w, r, StatusCreation, jsonResponse{Result: fmt.Sprintf("Successfully started timer: %s", e.Id.Hex()), Data: e})
+}
+
+// TimeEntriesHandler lists the time entries of a todo or adds a manual
+// entry, e.g. { "start": "2017-01-02T15:04:05Z", "seconds": 3600 }.
+func TimeEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	action := authz.View
+	if r.Method == "POST" {
+		action = authz.TrackTime
+	}
+	if _, ok := trackableTodo(w, r, id, claims.UserId, action); !ok {
+		return
+	}
+
+	eds := models.NewTimeEntryStorage()
+	defer eds.Close()
+
+	if r.Method == "GET" {
+		es, err := eds.GetTimeEntriesForTodoId(id)
+		if err != nil {
+			InternalErrorHandler(w, r, "")
+			log.Println("Failure to get time entries: " + err.Error())
+			return
+		}
+
+		writeJSON(w, r, StatusSuccess, es)
+		return
+	}
+
+	var body struct {
+		Start   time.Time  `json:"start"`
+		End     *time.Time `json:"end"`
+		Seconds int64      `json:"seconds"`
+		Note    string     `json:"note"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		BadRequestHandler(w, r, "Body format incorrect for time entry. Try: { \"start\": \"2017-01-02T15:04:05Z\", \"seconds\": 3600 }")
+		return
+	}
+
+	e := models.NewTimeEntry()
+	e.Start, e.End, e.Seconds, e.Note = body.Start, body.End, body.Seconds, body.Note
+	if err := e.ValidateManual(); err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+	e.TodoId = id
+	e.Ownerid = claims.UserId
+
+	err = eds.InsertTimeEntry(e)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to add time entry")
+		log.Println("Failure to add time entry: " + err.Error())
+		return
+	}
+
+	writeJSON(w, r, StatusCreation, jsonResponse{Result: fmt.Sprintf("Successfully added time entry: %s", e.Id.Hex()), Data: e})
+}
+
+// TimeEntryDeleteHandler deletes one of the user's time entries.
+func TimeEntryDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, entryId := vars["id"], vars["entry_id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	if _, ok := trackableTodo(w, r, id, claims.UserId, authz.View); !ok {
+		return
+	}
+
+	eds := models.NewTimeEntryStorage()
+	defer eds.Close()
+
+	e, err := eds.GetTimeEntryById(entryId)
+	if err != nil || e.TodoId != id {
+		NotFoundHandler(w, r, "Time entry not found.")
+		return
+	}
+
+	if e.Ownerid != claims.UserId {
+		ForbiddenHandler(w, r, "Only the user who logged the time may delete it.")
+		return
+	}
+
+	err = eds.DeleteTimeEntry(entryId)
+	if err != nil {
+		NotFoundHandler(w, r, "Time entry not found.")
+		return
+	}
+
+	writeJSON(w, r, StatusSuccess, jsonResponse{Result: fmt.Sprintf("Successfully deleted time entry: %s", entryId)})
+}
+
+// reportTime parses a time of a report, either a date in loc or RFC
+// 3339. Dates of the end of the period include the whole day.
+func reportTime(q url.Values, key string, loc *time.Location, def time.Time, end bool) (time.Time, error) {
+	v := q.Get(key)
+	if v == "" {
+		return def, nil
+	}
+
+	if d, err := time.ParseInLocation(models.TimeReportDayFormat, v, loc); err == nil {
+		if end {
+			d = d.AddDate(0, 0, 1)
+		}
+		return d, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be a date (2006-01-02) or an RFC 3339 time", key)
+	}
+
+	return t, nil
+}
+
+// TimeReportHandler aggregates the time the user logged per todo, per
+// tag and per day of the user's time zone. The period is given by
+// ?from=&to=, by default the last 30 days. With ?format=csv, or when
+// csv is accepted, the report is returned as csv.
+func TimeReportHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	loc := userLocation(claims.UserId)
+	q := r.URL.Query()
+	to, err := reportTime(q, "to", loc, time.Now(), true)
+	if err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+
+	from, err := reportTime(q, "from", loc, to.AddDate(0, 0, -defaultReportDays), false)
+	if err != nil {
+		BadRequestHandler(w, r, err.Error())
+		return
+	}
+
+	if !from.Before(to) {
+		BadRequestHandler(w, r, "from must be before to")
+		return
+	}
+
+	eds := models.NewTimeEntryStorage()
+	defer eds.Close()
+
+	es, err := eds.GetTimeEntriesForUserId(claims.UserId, from, to)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("Failure to get time entries: " + err.Error())
+		return
+	}
+
+	ids := make([]string, 0, len(es))
+	for _, e := range es {
+		ids = append(ids, e.TodoId)
+	}
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	ts, err := tds.GetTodosByIds(ids)
+	if err != nil {
+		InternalErrorHandler(w, r, "")
+		log.Println("Failure to get 2Dos of time entries: " + err.Error())
+		return
+	}
+
+	todos := make(map[string]models.Todo, len(ts))
+	for _, t := range ts {
+		todos[t.Id] = t
+	}
+
+	report := models.NewTimeReport(from, to, es, todos, loc)
+
+	if q.Get("format") == "csv" || (q.Get("format") == "" && r.Header.Get("Accept") == TextCSV) {
+		w.Header().Set(ContentType, TextCSV)
+		w.Header().Set("Content-Disposition", "attachment; filename=\"time-report.csv\"")
+		w.WriteHeader(StatusSuccess)
+		if err := report.WriteCSV(w); err != nil {
+			log.Println("Failure to write time report: " + err.Error())
+		}
+		return
+	}
+
+	writeJSON(w, r, StatusSuccess, report)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func init() {
+	models.TIME_ENTRY_STORE_TYPE = models.Test
+}
+
+func serveTime(h http.HandlerFunc, token, method, path, body string, vars map[string]string) *httptest.ResponseRecorder {
+	req, rr := handlersSetup(method, path, body)
+	req = mux.SetURLVars(req, vars)
+	req.Header.Set("Authorization", "Bearer "+token)
+	ValidatePath(h).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestTimeTracking(t *testing.T) {
+	token, t0 := attachmentSetup()
+	t0.Title = "Invoice"
+	t0.Tags = []string{"acme"}
+	models.NewTodoStorage().InsertTodo(t0)
+	vars := map[string]string{"id": t0.Id}
+
+	rr := serveTime(TodoTimerHandler, token, "POST", "api/todos/"+t0.Id+"/timer", "", vars)
+	testStatus(StatusCreation, rr, t)
+
+	rr = serveTime(TodoTimerHandler, token, "POST", "api/todos/"+t0.Id+"/timer", "", vars)
+	testStatus(StatusConflict, rr, t)
+
+	rr = serveTime(TimerHandler, token, "DELETE", "api/timer", "", nil)
+	testStatus(StatusSuccess, rr, t)
+
+	rr = serveTime(TimerHandler, token, "GET", "api/timer", "", nil)
+	testStatus(StatusNotFound, rr, t)
+
+	body := "{\"start\": \"2017-01-02T09:00:00Z\", \"seconds\": 3600}"
+	rr = serveTime(TimeEntriesHandler, token, "POST", "api/todos/"+t0.Id+"/time", body, vars)
+	testStatus(StatusCreation, rr, t)
+
+	rr = serveTime(TimeEntriesHandler, token, "POST", "api/todos/"+t0.Id+"/time", "{\"seconds\": -1}", vars)
+	testStatus(StatusBadRequest, rr, t)
+
+	rr = serveTime(TodoHandler, token, "GET", "api/todos/"+t0.Id, "", vars)
+	var got models.Todo
+	json.Unmarshal(rr.Body.Bytes(), &got)
+	if got.Logged < 3600 {
+		t.Errorf("2Do should show the logged time: got %d", got.Logged)
+	}
+
+	rr = serveTime(TimeReportHandler, token, "GET", "api/reports/time?from=2017-01-01&to=2017-01-31", "", nil)
+	testStatus(StatusSuccess, rr, t)
+	var report models.TimeReport
+	json.Unmarshal(rr.Body.Bytes(), &report)
+	if report.Total != 3600 || len(report.Tags) != 1 || report.Tags[0].Key != "acme" {
+		t.Errorf("Incorrect time report: %v", report)
+	}
+
+	rr = serveTime(TimeReportHandler, token, "GET", "api/reports/time?from=2017-01-01&to=2017-01-31&format=csv", "", nil)
+	if !strings.Contains(rr.Body.String(), "todo,"+t0.Id+",Invoice,3600") {
+		t.Errorf("Incorrect csv time report: %s", rr.Body.String())
+	}
+
+	rr = serveTime(TimeReportHandler, token, "GET", "api/reports/time?from=2017-02-01&to=2017-01-01", "", nil)
+	testStatus(StatusBadRequest, rr, t)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"models"
+	"testing"
+	"time"
+)
+
+func TestTimeZoneDueDates(t *testing.T) {
+	u, token := shareSetup("tz-TestTimeZoneDueDates")
+	u.TimeZone = "Pacific/Kiritimati"
+	models.NewUserStorage().InsertUser(u)
+	loc := u.Location()
+
+	today := time.Now().In(loc).Format(models.DateFormat)
+	req, rr := handlersSetup("POST", "api/todos", "{\"title\": \"All day\", \"due_date\": \""+today+"\"}")
+	req.Header.Set("Authorization", "Bearer "+token)
+	ValidatePath(TodosHandler).ServeHTTP(rr, req)
+	testStatus(StatusCreation, rr, t)
+
+	var res struct {
+		Data models.Todo `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &res)
+	start, _ := models.DayBounds(time.Now(), loc)
+	if !res.Data.AllDay || !res.Data.Due.Equal(start) {
+		t.Errorf("Due date should be all day in the user's time zone: %v %v", res.Data.Due, res.Data.AllDay)
+	}
+
+	late := models.NewTodo()
+	late.Ownerid = u.Id.Hex()
+	late.Due = start
+	models.NewTodoStorage().InsertTodo(late)
+
+	get := func(due string) []models.Todo {
+		req, rr := handlersSetup("GET", "api/todos?due="+due, "")
+		req.Header.Set("Authorization", "Bearer "+token)
+		ValidatePath(TodosHandler).ServeHTTP(rr, req)
+		testStatus(StatusSuccess, rr, t)
+
+		var ts []models.Todo
+		json.Unmarshal(rr.Body.Bytes(), &ts)
+		return ts
+	}
+
+	if ts := get("overdue"); len(ts) != 1 || ts[0].Id != late.Id {
+		t.Errorf("Only the late 2Do should be overdue: %v", ts)
+	}
+
+	if ts := get("today"); len(ts) != 2 {
+		t.Errorf("Both 2Dos should be due today: %v", ts)
+	}
+
+	req, rr = handlersSetup("GET", "api/todos?due=someday", "")
+	req.Header.Set("Authorization", "Bearer "+token)
+	ValidatePath(TodosHandler).ServeHTTP(rr, req)
+	testStatus(StatusBadRequest, rr, t)
+}
+
+func TestTimeZoneClearDueDate(t *testing.T) {
+	u, token := shareSetup("tz-TestTimeZoneClearDueDate")
+	tds := models.NewTodoStorage()
+
+	for _, cleared := range []string{"\"\"", "null"} {
+		t0 := models.NewTodo()
+		t0.Ownerid = u.Id.Hex()
+		t0.Due = time.Now().AddDate(0, 0, -2)
+		t0.AllDay = true
+		tds.InsertTodo(t0)
+
+		req, rr := handlersSetup("PUT", "api/todos/"+t0.Id, "{\"due_date\": "+cleared+"}")
+		req.Header.Set("Authorization", "Bearer "+token)
+		req = mux.SetURLVars(req, map[string]string{"id": t0.Id})
+		ValidatePath(TodoHandler).ServeHTTP(rr, req)
+		testStatus(StatusSuccess, rr, t)
+
+		t1, _ := tds.GetTodoById(t0.Id)
+		if !t1.Due.IsZero() || t1.AllDay {
+			t.Errorf("Due date should be cleared with %s: %v %v", cleared, t1.Due, t1.AllDay)
+		}
+
+		req, rr = handlersSetup("GET", "api/todos?due=overdue", "")
+		req.Header.Set("Authorization", "Bearer "+token)
+		ValidatePath(TodosHandler).ServeHTTP(rr, req)
+		testStatus(StatusSuccess, rr, t)
+
+		var ts []models.Todo
+		json.Unmarshal(rr.Body.Bytes(), &ts)
+		if len(ts) != 0 {
+			t.Errorf("A 2Do without a due date should not be overdue: %v", ts)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"auth"
+	"authz"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"log"
+	"models"
+	"net/http"
+	"time"
+)
+
+// listWorkflow returns the workflow of the list, nil when there is no
+// list or it has no workflow.
+func listWorkflow(listId string) *models.Workflow {
+	if listId == "" {
+		return nil
+	}
+
+	lds := models.NewListStorage()
+	defer lds.Close()
+
+	l, err := lds.GetListById(listId)
+	if err != nil {
+		log.Printf("listWorkflow: GetListById failed for: %s reason: %s\n", listId, err)
+		return nil
+	}
+
+	return l.Workflow
+}
+
+// setInitialStatus puts a new todo in a state of the workflow of its
+// list, the status it was given or the initial state, and derives
+// whether it is completed from it.
+func setInitialStatus(t *models.Todo, userId string) error {
+	wf := listWorkflow(t.ListId)
+	if wf == nil {
+		if t.Status != "" {
+			return models.ErrNoWorkflow
+		}
+		return nil
+	}
+
+	if t.Status == "" {
+		t.Status = wf.StatusFor(t.Completed)
+	} else if !wf.HasState(t.Status) {
+		return fmt.Errorf("%s: %s", models.ErrUnknownStatus, t.Status)
+	}
+
+	t.Completed = wf.Terminal(t.Status)
+	t.StatusHistory = []models.StatusChange{{To: t.Status, At: time.Now(), By: userId}}
+	return nil
+}
+
+// applyWorkflow adds the status changes of the todo to the changes. A
+// new status must be allowed by the workflow of the list of the todo,
+// completing or reopening it moves it to the first terminal or non
+// terminal state it may transition to, and moving it to another list
+// puts it in a state of that list's workflow. Completed is derived
+// from the state and the change is recorded in the status history.
+// The workflow is returned, otherwise the error response is written.
+func applyWorkflow(w http.ResponseWriter, r *http.Request, changes map[string]interface{}, t models.Todo, userId string) (*models.Workflow, bool) {
+	listId := t.ListId
+	if id, ok := changes["list_id"].(string); ok {
+		listId = id
+	}
+
+	v, hasStatus := changes["status"]
+	status, ok := v.(string)
+	if hasStatus && !ok {
+		BadRequestHandler(w, r, "status must be a string")
+		return nil, false
+	}
+
+	wf := listWorkflow(listId)
+	if wf == nil {
+		if hasStatus && status != "" {
+			BadRequestHandler(w, r, models.ErrNoWorkflow.Error())
+			return nil, false
+		}
+		if t.Status != "" {
+			changes["status"] = ""
+		}
+		return nil, true
+	}
+
+	moved := listId != t.ListId
+	current := t.Status
+	if !moved {
+		current = wf.StatusOf(t)
+	}
+
+	to := current
+	switch completed, completing := changes["completed"].(bool); {
+	case hasStatus:
+		if !wf.HasState(status) {
+			BadRequestHandler(w, r, fmt.Sprintf("%s: %s", models.ErrUnknownStatus, status))
+			return nil, false
+		}
+		if !moved && status != current && !wf.Allows(current, status) {
+			ConflictHandler(w, r, fmt.Sprintf("%s from %s to %s", models.ErrInvalidTransition, current, status))
+			return nil, false
+		}
+		to = status
+	case moved:
+		if !completing {
+			completed = t.Completed
+		}
+		to = wf.StatusFor(completed)
+	case completing && completed != wf.Terminal(current):
+		var err error
+		to, err = wf.CompletionTarget(current, completed)
+		if err != nil {
+			ConflictHandler(w, r, fmt.Sprintf("%s: no state to move to from %s", err, current))
+			return nil, false
+		}
+	}
+
+	if to != t.Status {
+		changes["status"] = to
+	} else {
+		delete(changes, "status")
+	}
+
+	if _, ok := changes["completed"]; ok || to != current {
+		changes["completed"] = wf.Terminal(to)
+	}
+
+	if to != current {
+		c := models.StatusChange{From: current, To: to, At: time.Now(), By: userId}
+		changes["status_history"] = models.AppendStatusChange(t.StatusHistory, c)
+	}
+
+	return wf, true
+}
+
+// WorkflowHandler is the handler function for the
+// /api/lists/{id}/workflow endpoint.
+func WorkflowHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		WorkflowGetHandler(w, r)
+	case "PUT":
+		WorkflowPutHandler(w, r)
+	}
+}
+
+// WorkflowGetHandler returns the workflow of a list, null when it has
+// none.
+func WorkflowGetHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	lds := models.NewListStorage()
+	defer lds.Close()
+
+	l, ok := authorizeList(w, r, lds, claims.UserId, id, authz.View)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, r, StatusSuccess, l.Workflow)
+}
+
+// WorkflowPutHandler replaces the workflow of a list, null removes it.
+// The body is a workflow such as { "states": [{ "name": "todo" },
+// { "name": "done", "terminal": true }], "transitions": [{ "from":
+// "todo", "to": "done" }], "initial": "todo" }. The todos of the list whose status isn't a state of the new workflow
+// are in its initial state, or first terminal state when completed.
+func WorkflowPutHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		UnauthorizedHandler(w, r, "")
+		return
+	}
+
+	var wf *models.Workflow
+	err = json.NewDecoder(r.Body).Decode(&wf)
+	if err != nil {
+		BadRequestHandler(w, r, "Body format incorrect for workflow. Try: { \"states\": [{ \"name\": \"todo\" }, { \"name\": \"done\", \"terminal\": true }], \"transitions\": [{ \"from\": \"todo\", \"to\": \"done\" }] }")
+		return
+	}
+
+	if wf != nil {
+		if err := wf.Validate(); err != nil {
+			BadRequestHandler(w, r, err.Error())
+			return
+		}
+	}
+
+	lds := models.NewListStorage()
+	defer lds.Close()
+
+	if _, ok := authorizeList(w, r, lds, claims.UserId, id, authz.Edit); !ok {
+		return
+	}
+
+	err = lds.SetListWorkflow(id, wf)
+	if err != nil {
+		InternalErrorHandler(w, r, "Failure to modify workflow")
+		log.Println("Failure to modify workflow: " + err.Error())
+		return
+	}
+
+	writeJSON(w, r, StatusSuccess, jsonResponse{
+		Result: fmt.Sprintf("Successfully modified workflow of list: %s", id),
+		Data:   wf,
+	})
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"models"
+	"testing"
+)
+
+const teamWorkflow = `{"states": [{"name": "todo"}, {"name": "in progress"}, {"name": "review"}, {"name": "blocked"}, {"name": "done", "terminal": true}],
+	"transitions": [{"from": "todo", "to": "in progress"}, {"from": "in progress", "to": "review"}, {"from": "in progress", "to": "blocked"},
+		{"from": "blocked", "to": "in progress"}, {"from": "review", "to": "done"}, {"from": "done", "to": "todo"}]}`
+
+// workflowSetup stores a list of the user with the team workflow.
+func workflowSetup(t *testing.T, u models.User, token string) models.List {
+	l := models.NewList()
+	l.Ownerid = u.Id.Hex()
+	models.NewListStorage().InsertList(l)
+
+	req, rr := handlersSetup("PUT", "api/lists/"+l.Id.Hex()+"/workflow", teamWorkflow)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req = mux.SetURLVars(req, map[string]string{"id": l.Id.Hex()})
+	ValidatePath(WorkflowHandler).ServeHTTP(rr, req)
+	testStatus(StatusSuccess, rr, t)
+
+	return l
+}
+
+func TestWorkflow(t *testing.T) {
+	u, token := shareSetup("wf-TestWorkflow")
+	l := workflowSetup(t, u, token)
+
+	req, rr := handlersSetup("PUT", "api/lists/"+l.Id.Hex()+"/workflow", `{"states": [{"name": "done", "terminal": true}]}`)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req = mux.SetURLVars(req, map[string]string{"id": l.Id.Hex()})
+	ValidatePath(WorkflowHandler).ServeHTTP(rr, req)
+	testStatus(StatusBadRequest, rr, t)
+
+	req, rr = handlersSetup("POST", "api/todos", `{"title": "Ship it", "list_id": "`+l.Id.Hex()+`"}`)
+	req.Header.Set("Authorization", "Bearer "+token)
+	ValidatePath(TodosHandler).ServeHTTP(rr, req)
+	testStatus(StatusCreation, rr, t)
+
+	var created struct {
+		Data models.Todo `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	id := created.Data.Id
+	if created.Data.Status != "todo" || len(created.Data.StatusHistory) != 1 {
+		t.Errorf("2Do should start in the initial state: %+v", created.Data)
+	}
+
+	put := func(body string) {
+		req, rr = handlersSetup("PUT", "api/todos/"+id, body)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		ValidatePath(TodoHandler).ServeHTTP(rr, req)
+	}
+
+	put(`{"status": "done"}`)
+	testStatus(StatusConflict, rr, t)
+
+	put(`{"status": "someday"}`)
+	testStatus(StatusBadRequest, rr, t)
+
+	put(`{"status": "in progress"}`)
+	testStatus(StatusSuccess, rr, t)
+
+	put(`{"completed": true}`)
+	testStatus(StatusConflict, rr, t)
+
+	put(`{"status": "review"}`)
+	testStatus(StatusSuccess, rr, t)
+
+	put(`{"completed": true}`)
+	testStatus(StatusSuccess, rr, t)
+
+	tds := models.NewTodoStorage()
+	done, _ := tds.GetTodoById(id)
+	if done.Status != "done" || !done.Completed || done.CompletedAt == nil {
+		t.Errorf("Completing should move the 2Do to the terminal state: %+v", done)
+	}
+	if h := done.StatusHistory; len(h) != 4 || h[3].From != "review" || h[3].To != "done" || h[3].By != u.Id.Hex() {
+		t.Errorf("Incorrect status history: %+v", h)
+	}
+
+	put(`{"status": "todo", "status_history": []}`)
+	testStatus(StatusSuccess, rr, t)
+
+	reopened, _ := tds.GetTodoById(id)
+	if reopened.Completed || reopened.CompletedAt != nil || len(reopened.StatusHistory) != 5 {
+		t.Errorf("Reopening should derive completed from the state: %+v", reopened)
+	}
+
+	put(`{"list_id": ""}`)
+	testStatus(StatusSuccess, rr, t)
+
+	inbox, _ := tds.GetTodoById(id)
+	if inbox.Status != "" {
+		t.Errorf("2Dos of lists without a workflow have no status: %q", inbox.Status)
+	}
+
+	put(`{"status": "todo"}`)
+	testStatus(StatusBadRequest, rr, t)
+
+	req, rr = handlersSetup("GET", "api/lists/"+l.Id.Hex()+"/workflow", "")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req = mux.SetURLVars(req, map[string]string{"id": l.Id.Hex()})
+	ValidatePath(WorkflowHandler).ServeHTTP(rr, req)
+	testStatus(StatusSuccess, rr, t)
+
+	var wf models.Workflow
+	json.Unmarshal(rr.Body.Bytes(), &wf)
+	if wf.Initial != "todo" || len(wf.States) != 5 {
+		t.Errorf("Incorrect workflow: %+v", wf)
+	}
+}
@@ -0,0 +1,119 @@
+// Package ical writes calendars in the iCalendar format of RFC 5545,
+// such as the feeds of todos which calendar apps subscribe to.
+//
+// Only what feeds need is supported: a calendar of events which are
+// either all day or start at an instant, without an end.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ProductId identifies the producer of the calendars.
+const ProductId = "-//2Do//Agenda//EN"
+
+const (
+	dateFormat     = "20060102"
+	dateTimeFormat = "20060102T150405Z"
+	// maxLineLength is the length of lines in octets after which they
+	// are folded.
+	maxLineLength = 75
+)
+
+// Event is an event of a calendar. All day events are on the date of
+// Start in its location.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Categories  []string
+	Start       time.Time
+	AllDay      bool
+}
+
+// Calendar is a calendar of events.
+type Calendar struct {
+	Name   string
+	Events []Event
+	// Stamp is when the calendar was written.
+	Stamp time.Time
+}
+
+// Write writes the calendar to w.
+func (c Calendar) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeLine(bw, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", ProductId)
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		line("X-WR-CALNAME", escape(c.Name))
+	}
+
+	stamp := c.Stamp.UTC().Format(dateTimeFormat)
+	for _, e := range c.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", escape(e.UID))
+		line("DTSTAMP", stamp)
+		if e.AllDay {
+			y, m, d := e.Start.Date()
+			line("DTSTART;VALUE=DATE", time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Format(dateFormat))
+		} else {
+			line("DTSTART", e.Start.UTC().Format(dateTimeFormat))
+		}
+		line("SUMMARY", escape(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION", escape(e.Description))
+		}
+		if len(e.Categories) > 0 {
+			categories := make([]string, len(e.Categories))
+			for i, category := range e.Categories {
+				categories[i] = escape(category)
+			}
+			line("CATEGORIES", strings.Join(categories, ","))
+		}
+		line("TRANSP", "TRANSPARENT")
+		line("END", "VEVENT")
+	}
+
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// escape escapes the text value of a property.
+func escape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(s)
+}
+
+// writeLine writes the content line ended by CRLF, folding it into
+// lines of at most maxLineLength octets without splitting characters.
+func writeLine(w *bufio.Writer, l string) {
+	limit := maxLineLength
+	for len(l) > limit {
+		i := limit
+		for i > 0 && !utf8.RuneStart(l[i]) {
+			i--
+		}
+		w.WriteString(l[:i] + "\r\n ")
+		l = l[i:]
+		// The leading space of the continuation counts
+		limit = maxLineLength - 1
+	}
+	w.WriteString(l + "\r\n")
+}
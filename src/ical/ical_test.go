@@ -0,0 +1,59 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendarWrite(t *testing.T) {
+	loc, _ := time.LoadLocation("America/New_York")
+	c := Calendar{
+		Name:  "2Do",
+		Stamp: time.Date(2017, 1, 2, 15, 4, 5, 0, time.UTC),
+		Events: []Event{
+			{UID: "a@2do", Summary: "Pay rent; now, please", Description: "line\nline", Categories: []string{"home", "a,b"}, Start: time.Date(2017, 1, 3, 0, 0, 0, 0, loc), AllDay: true},
+			{UID: "b@2do", Summary: "Call", Start: time.Date(2017, 1, 3, 21, 30, 0, 0, loc)},
+		},
+	}
+
+	var b bytes.Buffer
+	if err := c.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"X-WR-CALNAME:2Do\r\n",
+		"UID:a@2do\r\nDTSTAMP:20170102T150405Z\r\nDTSTART;VALUE=DATE:20170103\r\n",
+		"SUMMARY:Pay rent\\; now\\, please\r\n",
+		"DESCRIPTION:line\\nline\r\n",
+		"CATEGORIES:home,a\\,b\r\n",
+		"DTSTART:20170104T023000Z\r\n",
+		"END:VEVENT\r\nEND:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Calendar should contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestLinesAreFolded(t *testing.T) {
+	c := Calendar{Events: []Event{{UID: "x", Summary: strings.Repeat("é", 100), Start: time.Now()}}}
+
+	var b bytes.Buffer
+	c.Write(&b)
+
+	unfolded := strings.Replace(b.String(), "\r\n ", "", -1)
+	if !strings.Contains(unfolded, "SUMMARY:"+strings.Repeat("é", 100)+"\r\n") {
+		t.Errorf("Folded lines should unfold to the summary:\n%s", b.String())
+	}
+
+	for _, l := range strings.Split(b.String(), "\r\n") {
+		if len(l) > maxLineLength {
+			t.Errorf("Line longer than %d octets: %q", maxLineLength, l)
+		}
+	}
+}
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"archiver"
 	h "github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"handlers"
 	"log"
 	"logger"
+	"models"
 	"net/http"
+	"notify"
+	"reminders"
 	"time"
 )
 
@@ -20,10 +24,66 @@ const (
 	loginRoute  = "/login"
 	signUpRoute = "/signup"
 
+	// todoIdVar is the id of a todo in the routes, see models.NewPublicId
+	todoIdVar = "{id:" + models.PublicIdPattern + "}"
+
 	todosRoute = "/todos"
-	todoRoute  = "/todos/{id}"
+	todoRoute  = "/todos/" + todoIdVar
+
+	todoQuickAddRoute = "/todos/quick"
+
+	todoOccurrencesRoute = "/todos/" + todoIdVar + "/occurrences"
+	todoGraphRoute       = "/todos/" + todoIdVar + "/graph"
+	todoMoveRoute        = "/todos/" + todoIdVar + "/move"
+	todoTransitionRoute  = "/todos/" + todoIdVar + "/transition"
+	todoSnoozeRoute      = "/todos/" + todoIdVar + "/snooze"
+	todoChecklistRoute   = "/todos/" + todoIdVar + "/checklist"
+	todoTimerRoute       = "/todos/" + todoIdVar + "/timer"
+	timeEntriesRoute     = "/todos/" + todoIdVar + "/time"
+	timeEntryRoute       = "/todos/" + todoIdVar + "/time/{entry_id}"
+	attachmentsRoute     = "/todos/" + todoIdVar + "/attachments"
+	attachmentRoute      = "/todos/" + todoIdVar + "/attachments/{attachment_id}"
+	commentsRoute        = "/todos/" + todoIdVar + "/comments"
+	commentRoute         = "/todos/" + todoIdVar + "/comments/{comment_id}"
+	todoSharesRoute      = "/todos/" + todoIdVar + "/shares"
+	todoShareRoute       = "/todos/" + todoIdVar + "/shares/{username}"
+
+	listsRoute = "/lists"
+	listRoute  = "/lists/{id}"
+
+	listSharesRoute = "/lists/{id}/shares"
+	listShareRoute  = "/lists/{id}/shares/{username}"
+
+	listWorkflowRoute = "/lists/{id}/workflow"
+	listBoardRoute    = "/lists/{id}/board"
+	listFieldsRoute   = "/lists/{id}/fields"
+
+	tagsRoute      = "/tags"
+	tagsMergeRoute = "/tags/merge"
+	tagRoute       = "/tags/{tag}"
 
 	usrAccntRoute = "/account"
+
+	notificationsRoute    = "/notifications"
+	notificationReadRoute = "/notifications/{id}/read"
+
+	timerRoute      = "/timer"
+	timeReportRoute = "/reports/time"
+
+	statsRoute = "/stats"
+
+	archiveRoute = "/archive"
+
+	templatesRoute           = "/templates"
+	templateRoute            = "/templates/{id}"
+	templateInstantiateRoute = "/templates/{id}/instantiate"
+	smartListsRoute          = "/smart-lists"
+	smartListRoute           = "/smart-lists/{id}"
+	smartListTodosRoute      = "/smart-lists/{id}/todos"
+
+	agendaRoute        = "/agenda"
+	agendaFeedRoute    = "/agenda/feed"
+	agendaFeedGetRoute = "/agenda/feed/{token:" + models.FeedTokenPattern + "}.ics"
 )
 
 const addr = "localhost:8000"
@@ -36,16 +96,102 @@ func main() {
 	homeHandler := logger.Logger(handlers.ValidatePath(handlers.HomeHandler), homeRoute)
 	todosHandler := logger.Logger(handlers.ValidatePath(handlers.TodosHandler), todosRoute)
 	todoHandler := logger.Logger(handlers.ValidatePath(handlers.TodoHandler), todoRoute)
+	todoQuickAddHandler := logger.Logger(handlers.ValidatePath(handlers.TodoQuickAddHandler), todoQuickAddRoute)
+	todoOccurrencesHandler := logger.Logger(handlers.ValidatePath(handlers.TodoOccurrencesHandler), todoOccurrencesRoute)
+	todoGraphHandler := logger.Logger(handlers.ValidatePath(handlers.TodoGraphHandler), todoGraphRoute)
+	todoMoveHandler := logger.Logger(handlers.ValidatePath(handlers.TodoMoveHandler), todoMoveRoute)
+	todoTransitionHandler := logger.Logger(handlers.ValidatePath(handlers.TodoTransitionHandler), todoTransitionRoute)
+	todoSnoozeHandler := logger.Logger(handlers.ValidatePath(handlers.TodoSnoozeHandler), todoSnoozeRoute)
+	todoChecklistHandler := logger.Logger(handlers.ValidatePath(handlers.TodoChecklistHandler), todoChecklistRoute)
+	todoTimerHandler := logger.Logger(handlers.ValidatePath(handlers.TodoTimerHandler), todoTimerRoute)
+	timeEntriesHandler := logger.Logger(handlers.ValidatePath(handlers.TimeEntriesHandler), timeEntriesRoute)
+	timeEntryHandler := logger.Logger(handlers.ValidatePath(handlers.TimeEntryDeleteHandler), timeEntryRoute)
+	attachmentsHandler := logger.Logger(handlers.ValidatePath(handlers.AttachmentsHandler), attachmentsRoute)
+	attachmentHandler := logger.Logger(handlers.ValidatePath(handlers.AttachmentHandler), attachmentRoute)
+	commentsHandler := logger.Logger(handlers.ValidatePath(handlers.CommentsHandler), commentsRoute)
+	commentHandler := logger.Logger(handlers.ValidatePath(handlers.CommentHandler), commentRoute)
+	todoSharesHandler := logger.Logger(handlers.ValidatePath(handlers.TodoSharesHandler), todoSharesRoute)
+	todoShareHandler := logger.Logger(handlers.ValidatePath(handlers.TodoShareDeleteHandler), todoShareRoute)
+	listsHandler := logger.Logger(handlers.ValidatePath(handlers.ListsHandler), listsRoute)
+	listHandler := logger.Logger(handlers.ValidatePath(handlers.ListHandler), listRoute)
+	listSharesHandler := logger.Logger(handlers.ValidatePath(handlers.ListSharesHandler), listSharesRoute)
+	listShareHandler := logger.Logger(handlers.ValidatePath(handlers.ListShareDeleteHandler), listShareRoute)
+	listWorkflowHandler := logger.Logger(handlers.ValidatePath(handlers.WorkflowHandler), listWorkflowRoute)
+	listBoardHandler := logger.Logger(handlers.ValidatePath(handlers.BoardHandler), listBoardRoute)
+	listFieldsHandler := logger.Logger(handlers.ValidatePath(handlers.FieldsHandler), listFieldsRoute)
+	tagsHandler := logger.Logger(handlers.ValidatePath(handlers.TagsGetHandler), tagsRoute)
+	tagsMergeHandler := logger.Logger(handlers.ValidatePath(handlers.TagsMergeHandler), tagsMergeRoute)
+	tagHandler := logger.Logger(handlers.ValidatePath(handlers.TagPutHandler), tagRoute)
+	usrAccntHandler := logger.Logger(handlers.ValidatePath(handlers.AccountHandler), usrAccntRoute)
+	notificationsHandler := logger.Logger(handlers.ValidatePath(handlers.NotificationsGetHandler), notificationsRoute)
+	notificationReadHandler := logger.Logger(handlers.ValidatePath(handlers.NotificationReadHandler), notificationReadRoute)
+	timerHandler := logger.Logger(handlers.ValidatePath(handlers.TimerHandler), timerRoute)
+	timeReportHandler := logger.Logger(handlers.ValidatePath(handlers.TimeReportHandler), timeReportRoute)
+	statsHandler := logger.Logger(handlers.ValidatePath(handlers.StatsHandler), statsRoute)
+	archiveHandler := logger.Logger(handlers.ValidatePath(handlers.ArchiveHandler), archiveRoute)
+	templatesHandler := logger.Logger(handlers.ValidatePath(handlers.TemplatesHandler), templatesRoute)
+	templateHandler := logger.Logger(handlers.ValidatePath(handlers.TemplateHandler), templateRoute)
+	templateInstantiateHandler := logger.Logger(handlers.ValidatePath(handlers.TemplateInstantiateHandler), templateInstantiateRoute)
+	smartListsHandler := logger.Logger(handlers.ValidatePath(handlers.SmartListsHandler), smartListsRoute)
+	smartListHandler := logger.Logger(handlers.ValidatePath(handlers.SmartListHandler), smartListRoute)
+	smartListTodosHandler := logger.Logger(handlers.ValidatePath(handlers.SmartListTodosHandler), smartListTodosRoute)
+	agendaHandler := logger.Logger(handlers.ValidatePath(handlers.AgendaHandler), agendaRoute)
+	agendaFeedHandler := logger.Logger(handlers.ValidatePath(handlers.AgendaFeedHandler), agendaFeedRoute)
 
 	signUpHandler := logger.Logger(handlers.SignUpHandler, signUpRoute)
 	logInHandler := logger.Logger(handlers.LogInHandler, loginRoute)
+	// The token of the feed authorizes its requests
+	agendaFeedGetHandler := logger.Logger(handlers.AgendaFeedGetHandler, agendaFeedGetRoute)
 
 	api.HandleFunc(homeRoute, homeHandler).Methods("GET")
 	api.HandleFunc(todosRoute, todosHandler).Methods("GET", "POST")
+	// Registered before todoRoute which would match it otherwise
+	api.HandleFunc(todoQuickAddRoute, todoQuickAddHandler).Methods("POST")
 	api.HandleFunc(todoRoute, todoHandler).Methods("GET", "PUT", "DELETE")
+	api.HandleFunc(todoOccurrencesRoute, todoOccurrencesHandler).Methods("GET")
+	api.HandleFunc(todoGraphRoute, todoGraphHandler).Methods("GET")
+	api.HandleFunc(todoMoveRoute, todoMoveHandler).Methods("POST")
+	api.HandleFunc(todoTransitionRoute, todoTransitionHandler).Methods("POST")
+	api.HandleFunc(todoSnoozeRoute, todoSnoozeHandler).Methods("POST")
+	api.HandleFunc(todoChecklistRoute, todoChecklistHandler).Methods("POST")
+	api.HandleFunc(todoTimerRoute, todoTimerHandler).Methods("POST")
+	api.HandleFunc(timeEntriesRoute, timeEntriesHandler).Methods("GET", "POST")
+	api.HandleFunc(timeEntryRoute, timeEntryHandler).Methods("DELETE")
+	api.HandleFunc(attachmentsRoute, attachmentsHandler).Methods("GET", "POST")
+	api.HandleFunc(attachmentRoute, attachmentHandler).Methods("GET", "DELETE")
+	api.HandleFunc(commentsRoute, commentsHandler).Methods("GET", "POST")
+	api.HandleFunc(commentRoute, commentHandler).Methods("PUT", "DELETE")
+	api.HandleFunc(todoSharesRoute, todoSharesHandler).Methods("GET", "PUT")
+	api.HandleFunc(todoShareRoute, todoShareHandler).Methods("DELETE")
+	api.HandleFunc(listsRoute, listsHandler).Methods("GET", "POST")
+	api.HandleFunc(listRoute, listHandler).Methods("GET", "PUT", "DELETE")
+	api.HandleFunc(listSharesRoute, listSharesHandler).Methods("GET", "PUT")
+	api.HandleFunc(listShareRoute, listShareHandler).Methods("DELETE")
+	api.HandleFunc(listWorkflowRoute, listWorkflowHandler).Methods("GET", "PUT")
+	api.HandleFunc(listBoardRoute, listBoardHandler).Methods("GET")
+	api.HandleFunc(listFieldsRoute, listFieldsHandler).Methods("GET", "PUT")
+	api.HandleFunc(tagsRoute, tagsHandler).Methods("GET")
+	api.HandleFunc(tagsMergeRoute, tagsMergeHandler).Methods("POST")
+	api.HandleFunc(tagRoute, tagHandler).Methods("PUT")
+	api.HandleFunc(usrAccntRoute, usrAccntHandler).Methods("GET", "PUT")
+	api.HandleFunc(notificationsRoute, notificationsHandler).Methods("GET")
+	api.HandleFunc(notificationReadRoute, notificationReadHandler).Methods("POST")
+	api.HandleFunc(timerRoute, timerHandler).Methods("GET", "DELETE")
+	api.HandleFunc(timeReportRoute, timeReportHandler).Methods("GET")
+	api.HandleFunc(statsRoute, statsHandler).Methods("GET")
+	api.HandleFunc(archiveRoute, archiveHandler).Methods("GET")
+	api.HandleFunc(templatesRoute, templatesHandler).Methods("GET", "POST")
+	api.HandleFunc(templateRoute, templateHandler).Methods("GET", "PUT", "DELETE")
+	api.HandleFunc(templateInstantiateRoute, templateInstantiateHandler).Methods("POST")
+	api.HandleFunc(smartListsRoute, smartListsHandler).Methods("GET", "POST")
+	api.HandleFunc(smartListRoute, smartListHandler).Methods("GET", "PUT", "DELETE")
+	api.HandleFunc(smartListTodosRoute, smartListTodosHandler).Methods("GET")
+	api.HandleFunc(agendaRoute, agendaHandler).Methods("GET")
+	api.HandleFunc(agendaFeedRoute, agendaFeedHandler).Methods("POST", "DELETE")
 
 	api.HandleFunc(signUpRoute, signUpHandler).Methods("POST")
 	api.HandleFunc(loginRoute, logInHandler).Methods("POST")
+	api.HandleFunc(agendaFeedGetRoute, agendaFeedGetHandler).Methods("GET")
 
 	srv := &http.Server{
 		Handler:      h.CORS()(r),
@@ -54,6 +200,19 @@ func main() {
 		ReadTimeout:  15 * time.Second,
 	}
 
+	if n, err := models.MigrateTodoIds(); err != nil {
+		log.Fatal("Failure to migrate 2Do ids: " + err.Error())
+	} else if n > 0 {
+		log.Printf("Migrated the ids of %d 2Dos", n)
+	}
+
+	if err := models.EnsureTimeEntryIndexes(); err != nil {
+		log.Fatal("Failure to create the indexes of time entries: " + err.Error())
+	}
+
+	go reminders.NewScheduler(notify.GetDispatcher()).Run(nil)
+	go archiver.NewArchiver().Run(nil)
+
 	log.Printf("Hosting on %s", addr)
 
 	log.Fatal(srv.ListenAndServe())
@@ -0,0 +1,725 @@
+// Package markdown renders the notes of todos, written in Markdown, to
+// HTML which is safe to show in a browser.
+//
+// The common subset of CommonMark is supported: paragraphs, ATX
+// headings, thematic breaks, block quotes, bullet and ordered lists,
+// fenced and indented code blocks, emphasis, code spans, links and
+// autolinks, along with task list items (- [ ] and - [x]), ~~strike
+// through~~, bare http(s) URLs and entity references such as &amp; and
+// &#169;. Raw HTML is escaped rather than passed through, images are
+// rendered as links to them and only links to http, https and mailto
+// URLs, or relative ones, are kept.
+package markdown
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Link is a link of a note.
+type Link struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// Task is a task list item of a note, Line is its line in the note.
+type Task struct {
+	Text    string `json:"text"`
+	Checked bool   `json:"checked"`
+	Line    int    `json:"-"`
+}
+
+// Document is a rendered note.
+type Document struct {
+	HTML  string
+	Links []Link // Without duplicate URLs, in order of appearance
+	Tasks []Task // In order of appearance
+}
+
+var ErrNoTask = errors.New("No checklist item at the index")
+
+var (
+	atxHeadingExp = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	thematicExp   = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	fenceExp      = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ \t]*([^`]*)$")
+	listItemExp   = regexp.MustCompile(`^( {0,3})([-+*]|[0-9]{1,9}[.)])(?:([ \t]+)(.*))?$`)
+	taskBoxExp    = regexp.MustCompile(`^\[([ xX])\](?:[ \t]+|$)`)
+	taskLineExp   = regexp.MustCompile(`^((?:[ \t]*(?:>[ \t]?|(?:[-+*]|[0-9]{1,9}[.)])[ \t]+))+)\[[ xX]\]`)
+	quoteExp      = regexp.MustCompile(`^ {0,3}> ?(.*)$`)
+	autolinkExp   = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9+.-]{1,31}:[^<>\x00-\x20]*)>`)
+	emailExp      = regexp.MustCompile(`^<([a-zA-Z0-9.!#$%&'*+/=?^_{|}~-]+@[a-zA-Z0-9-]+(?:\.[a-zA-Z0-9-]+)*)>`)
+	bareURLExp    = regexp.MustCompile(`^https?://[^\s<]+`)
+	schemeExp     = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9+.-]*):`)
+	tagExp        = regexp.MustCompile(`<[^>]*>`)
+	entityExp     = regexp.MustCompile(`^&(?:#[xX][0-9a-fA-F]{1,6}|#[0-9]{1,7}|[a-zA-Z][a-zA-Z0-9]{1,31});`)
+)
+
+// safeSchemes are the schemes of the URLs which are linked to.
+var safeSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+type line struct {
+	text string
+	n    int // The index of the line in the source
+}
+
+type renderer struct {
+	out    bytes.Buffer
+	links  []Link
+	seen   map[string]bool
+	tasks  []Task
+	inLink bool
+}
+
+// Render renders the Markdown source.
+func Render(src string) Document {
+	r := &renderer{seen: make(map[string]bool)}
+	r.blocks(splitLines(src), false)
+
+	return Document{HTML: r.out.String(), Links: r.links, Tasks: r.tasks}
+}
+
+// ToggleTask checks or unchecks the task list item at the index, in
+// order of appearance, and returns the changed source.
+func ToggleTask(src string, index int, checked bool) (string, error) {
+	tasks := Render(src).Tasks
+	if index < 0 || index >= len(tasks) {
+		return src, ErrNoTask
+	}
+
+	box := "[ ]"
+	if checked {
+		box = "[x]"
+	}
+
+	// The box follows the markers of the list item and its containers
+	lines := strings.Split(src, "\n")
+	l := lines[tasks[index].Line]
+	m := taskLineExp.FindStringSubmatch(l)
+	if m == nil {
+		return src, ErrNoTask
+	}
+	i := len(m[1])
+	lines[tasks[index].Line] = l[:i] + box + l[i+3:]
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func splitLines(src string) []line {
+	texts := strings.Split(src, "\n")
+	ls := make([]line, len(texts))
+	for i, t := range texts {
+		t = strings.TrimSuffix(t, "\r")
+		indent := 0
+		for indent < len(t) && t[indent] == '\t' {
+			indent++
+		}
+		ls[i] = line{strings.Repeat("    ", indent) + t[indent:], i}
+	}
+
+	return ls
+}
+
+func isBlank(s string) bool {
+	return strings.TrimSpace(s) == ""
+}
+
+func indentOf(s string) int {
+	return len(s) - len(strings.TrimLeft(s, " "))
+}
+
+// startsBlock reports whether the line starts a block other than a
+// paragraph, which it would interrupt.
+func startsBlock(s string) bool {
+	if fenceExp.MatchString(s) || atxHeadingExp.MatchString(s) || thematicExp.MatchString(s) || quoteExp.MatchString(s) {
+		return true
+	}
+
+	m := listItemExp.FindStringSubmatch(s)
+	if m == nil || isBlank(m[4]) {
+		return false
+	}
+
+	return !isOrdered(m[2]) || strings.TrimRight(m[2], ".)") == "1"
+}
+
+func isOrdered(marker string) bool {
+	return marker[0] >= '0' && marker[0] <= '9'
+}
+
+// blocks renders the lines as a sequence of blocks. The paragraphs of
+// tight lists aren't wrapped in <p>.
+func (r *renderer) blocks(ls []line, tight bool) {
+	var para []line
+	flush := func() {
+		if len(para) > 0 {
+			r.paragraph(para, tight)
+			para = nil
+		}
+	}
+
+	for i := 0; i < len(ls); {
+		t := ls[i].text
+		switch {
+		case isBlank(t):
+			flush()
+			i++
+		case len(para) == 0 && indentOf(t) >= 4:
+			i = r.indentedCode(ls, i)
+		case fenceExp.MatchString(t):
+			flush()
+			i = r.fencedCode(ls, i)
+		case atxHeadingExp.MatchString(t):
+			flush()
+			m := atxHeadingExp.FindStringSubmatch(t)
+			fmt.Fprintf(&r.out, "<h%d>%s</h%d>\n", len(m[1]), r.inline(strings.TrimSpace(m[2])), len(m[1]))
+			i++
+		case thematicExp.MatchString(t):
+			flush()
+			r.out.WriteString("<hr>\n")
+			i++
+		case quoteExp.MatchString(t):
+			flush()
+			i = r.blockquote(ls, i)
+		case listItemExp.MatchString(t) && (len(para) == 0 || startsBlock(t)):
+			flush()
+			i = r.list(ls, i)
+		default:
+			para = append(para, ls[i])
+			i++
+		}
+	}
+	flush()
+}
+
+func (r *renderer) paragraph(ls []line, tight bool) {
+	texts := make([]string, len(ls))
+	for i, l := range ls {
+		t := strings.TrimLeft(l.text, " ")
+		trimmed := strings.TrimRight(t, " ")
+		// Two trailing spaces are a hard line break
+		if i < len(ls)-1 && len(t)-len(trimmed) >= 2 {
+			trimmed += "\\"
+		}
+		texts[i] = trimmed
+	}
+
+	text := r.inline(strings.Join(texts, "\n"))
+	if tight {
+		r.out.WriteString(text + "\n")
+	} else {
+		r.out.WriteString("<p>" + text + "</p>\n")
+	}
+}
+
+func (r *renderer) indentedCode(ls []line, i int) int {
+	var code []string
+	for ; i < len(ls) && (isBlank(ls[i].text) || indentOf(ls[i].text) >= 4); i++ {
+		if isBlank(ls[i].text) {
+			code = append(code, "")
+		} else {
+			code = append(code, ls[i].text[4:])
+		}
+	}
+
+	for len(code) > 0 && code[len(code)-1] == "" {
+		code = code[:len(code)-1]
+	}
+
+	r.out.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")+"\n") + "</code></pre>\n")
+	return i
+}
+
+func (r *renderer) fencedCode(ls []line, i int) int {
+	m := fenceExp.FindStringSubmatch(ls[i].text)
+	indent, marker := len(m[1]), m[2]
+	closing := regexp.MustCompile("^ {0,3}" + regexp.QuoteMeta(marker[:1]) + "{" + strconv.Itoa(len(marker)) + ",}[ \t]*$")
+
+	class := ""
+	if info := strings.Fields(m[3]); len(info) > 0 {
+		class = fmt.Sprintf(` class="language-%s"`, html.EscapeString(info[0]))
+	}
+
+	var code []string
+	for i++; i < len(ls) && !closing.MatchString(ls[i].text); i++ {
+		t := ls[i].text
+		strip := indentOf(t)
+		if strip > indent {
+			strip = indent
+		}
+		code = append(code, t[strip:])
+	}
+
+	content := ""
+	if len(code) > 0 {
+		content = strings.Join(code, "\n") + "\n"
+	}
+	r.out.WriteString("<pre><code" + class + ">" + html.EscapeString(content) + "</code></pre>\n")
+
+	return i + 1
+}
+
+func (r *renderer) blockquote(ls []line, i int) int {
+	var inner []line
+	for ; i < len(ls); i++ {
+		m := quoteExp.FindStringSubmatch(ls[i].text)
+		if m == nil {
+			break
+		}
+		inner = append(inner, line{m[1], ls[i].n})
+	}
+
+	r.out.WriteString("<blockquote>\n")
+	r.blocks(inner, false)
+	r.out.WriteString("</blockquote>\n")
+	return i
+}
+
+type listItem struct {
+	lines []line
+}
+
+// list renders the list starting at the line i and returns the index
+// of the line following it.
+func (r *renderer) list(ls []line, i int) int {
+	first := listItemExp.FindStringSubmatch(ls[i].text)
+	ordered := isOrdered(first[2])
+	delimiter := first[2][len(first[2])-1]
+
+	var items []listItem
+	loose := false
+	for i < len(ls) {
+		m := listItemExp.FindStringSubmatch(ls[i].text)
+		if m == nil || thematicExp.MatchString(ls[i].text) || isOrdered(m[2]) != ordered || m[2][len(m[2])-1] != delimiter {
+			break
+		}
+
+		spaces := len(m[3])
+		if spaces > 4 || isBlank(m[4]) {
+			spaces = 1
+		}
+		contentIndent := len(m[1]) + len(m[2]) + spaces
+
+		item := listItem{lines: []line{{m[4], ls[i].n}}}
+		lazy := !isBlank(m[4])
+		for i++; i < len(ls); {
+			t := ls[i].text
+			if isBlank(t) {
+				next := i
+				for next < len(ls) && isBlank(ls[next].text) {
+					next++
+				}
+				if next == len(ls) || indentOf(ls[next].text) < contentIndent {
+					break
+				}
+				for ; i < next; i++ {
+					item.lines = append(item.lines, line{"", ls[i].n})
+				}
+				loose, lazy = true, false
+				continue
+			}
+
+			if indentOf(t) >= contentIndent {
+				item.lines = append(item.lines, line{t[contentIndent:], ls[i].n})
+			} else if lazy && !startsBlock(t) && !listItemExp.MatchString(t) {
+				item.lines = append(item.lines, line{strings.TrimLeft(t, " "), ls[i].n})
+			} else {
+				break
+			}
+			i++
+		}
+		items = append(items, item)
+
+		// Blank lines between items make the list loose
+		next := i
+		for next < len(ls) && isBlank(ls[next].text) {
+			next++
+		}
+		if next == i || next == len(ls) {
+			continue
+		}
+		if m := listItemExp.FindStringSubmatch(ls[next].text); m == nil || isOrdered(m[2]) != ordered {
+			break
+		}
+		loose, i = true, next
+	}
+
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+		if start, _ := strconv.Atoi(strings.TrimRight(first[2], ".)")); start != 1 {
+			fmt.Fprintf(&r.out, "<ol start=\"%d\">\n", start)
+		} else {
+			r.out.WriteString("<ol>\n")
+		}
+	} else {
+		r.out.WriteString("<ul>\n")
+	}
+
+	for _, item := range items {
+		r.listItem(item, !loose)
+	}
+
+	r.out.WriteString("</" + tag + ">\n")
+	return i
+}
+
+func (r *renderer) listItem(item listItem, tight bool) {
+	if m := taskBoxExp.FindStringSubmatch(item.lines[0].text); m != nil {
+		rest := item.lines[0].text[len(m[0]):]
+		task := Task{Text: strings.TrimSpace(rest), Checked: m[1] != " ", Line: item.lines[0].n}
+		r.tasks = append(r.tasks, task)
+
+		checked := ""
+		if task.Checked {
+			checked = " checked"
+		}
+		fmt.Fprintf(&r.out, "<li class=\"task-list-item\"><input type=\"checkbox\" disabled data-task=\"%d\"%s> ", len(r.tasks)-1, checked)
+		item.lines[0].text = rest
+	} else {
+		r.out.WriteString("<li>")
+	}
+
+	if !tight {
+		r.out.WriteString("\n")
+	}
+	r.blocks(item.lines, tight)
+	if tight && r.out.Len() > 0 && r.out.Bytes()[r.out.Len()-1] == '\n' {
+		r.out.Truncate(r.out.Len() - 1)
+	}
+	r.out.WriteString("</li>\n")
+}
+
+// inline renders the inline content of a block.
+func (r *renderer) inline(s string) string {
+	var b bytes.Buffer
+	r.inlineTo(&b, s)
+	return b.String()
+}
+
+func (r *renderer) inlineTo(b *bytes.Buffer, s string) {
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch c {
+		case '\\':
+			if i+1 < len(s) && isPunct(s[i+1]) {
+				b.WriteString(html.EscapeString(s[i+1 : i+2]))
+				i += 2
+				continue
+			}
+			if i+1 < len(s) && s[i+1] == '\n' {
+				b.WriteString("<br>\n")
+				i += 2
+				continue
+			}
+		case '`':
+			if n := r.codeSpan(b, s[i:]); n > 0 {
+				i += n
+				continue
+			}
+			n := runLength(s[i:], '`')
+			b.WriteString(s[i : i+n])
+			i += n
+			continue
+		case '<':
+			if n := r.autolink(b, s[i:]); n > 0 {
+				i += n
+				continue
+			}
+		case '!':
+			if i+1 < len(s) && s[i+1] == '[' {
+				if n := r.inlineLink(b, s[i+1:]); n > 0 {
+					i += n + 1
+					continue
+				}
+			}
+		case '[':
+			if n := r.inlineLink(b, s[i:]); n > 0 {
+				i += n
+				continue
+			}
+		case '*', '_', '~':
+			if n := r.emphasis(b, s, i); n > 0 {
+				i += n
+				continue
+			}
+			n := runLength(s[i:], c)
+			b.WriteString(s[i : i+n])
+			i += n
+			continue
+		case 'h':
+			if n := r.bareURL(b, s, i); n > 0 {
+				i += n
+				continue
+			}
+		case '&':
+			// Entities are decoded so that they are escaped only once
+			if m := entityExp.FindString(s[i:]); m != "" {
+				b.WriteString(html.EscapeString(html.UnescapeString(m)))
+				i += len(m)
+				continue
+			}
+		}
+
+		b.WriteString(html.EscapeString(s[i : i+1]))
+		i++
+	}
+}
+
+func isPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+func isAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func runLength(s string, c byte) int {
+	n := 0
+	for n < len(s) && s[n] == c {
+		n++
+	}
+	return n
+}
+
+// codeSpan renders the code span at the start of s and returns its
+// length, 0 if there is none.
+func (r *renderer) codeSpan(b *bytes.Buffer, s string) int {
+	n := runLength(s, '`')
+	for j := n; j < len(s); {
+		k := strings.IndexByte(s[j:], '`')
+		if k < 0 {
+			return 0
+		}
+		j += k
+		m := runLength(s[j:], '`')
+		if m == n {
+			code := strings.Replace(s[n:j], "\n", " ", -1)
+			if len(code) > 1 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+				code = code[1 : len(code)-1]
+			}
+			b.WriteString("<code>" + html.EscapeString(code) + "</code>")
+			return j + m
+		}
+		j += m
+	}
+
+	return 0
+}
+
+// autolink renders the autolink at the start of s e.g. <https://a.b>
+// and returns its length, 0 if there is none.
+func (r *renderer) autolink(b *bytes.Buffer, s string) int {
+	if m := autolinkExp.FindStringSubmatch(s); m != nil {
+		r.link(b, m[1], html.EscapeString(m[1]))
+		return len(m[0])
+	}
+
+	if m := emailExp.FindStringSubmatch(s); m != nil {
+		r.link(b, "mailto:"+m[1], html.EscapeString(m[1]))
+		return len(m[0])
+	}
+
+	return 0
+}
+
+// bareURL renders the http(s) URL at s[i:] if it starts a word.
+func (r *renderer) bareURL(b *bytes.Buffer, s string, i int) int {
+	if i > 0 && !isSpace(s[i-1]) && strings.IndexByte("(*_~", s[i-1]) < 0 {
+		return 0
+	}
+
+	url := bareURLExp.FindString(s[i:])
+	for url != "" {
+		last := url[len(url)-1]
+		if strings.IndexByte(".,:;!?'\"*_~", last) >= 0 ||
+			last == ')' && strings.Count(url, ")") > strings.Count(url, "(") {
+			url = url[:len(url)-1]
+			continue
+		}
+		break
+	}
+	if len(url) <= len("https://") {
+		return 0
+	}
+
+	r.link(b, url, html.EscapeString(url))
+	return len(url)
+}
+
+// inlineLink renders the link [text](destination "title") at the start
+// of s and returns its length, 0 if there is none.
+func (r *renderer) inlineLink(b *bytes.Buffer, s string) int {
+	depth, end := 0, -1
+	for j := 0; j < len(s) && end < 0; j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '[':
+			depth++
+		case ']':
+			if depth--; depth == 0 {
+				end = j
+			}
+		}
+	}
+	if end < 0 || end+1 >= len(s) || s[end+1] != '(' {
+		return 0
+	}
+
+	j := end + 2
+	for j < len(s) && isSpace(s[j]) {
+		j++
+	}
+
+	var dest string
+	if j < len(s) && s[j] == '<' {
+		k := strings.IndexAny(s[j:], ">\n")
+		if k < 0 || s[j+k] != '>' {
+			return 0
+		}
+		dest = strings.Replace(s[j+1:j+k], " ", "%20", -1)
+		j += k + 1
+	} else {
+		start, parens := j, 0
+		for ; j < len(s) && !isSpace(s[j]) && s[j] > 0x1f; j++ {
+			if s[j] == '\\' && j+1 < len(s) && isPunct(s[j+1]) {
+				j++
+			} else if s[j] == '(' {
+				parens++
+			} else if s[j] == ')' {
+				if parens == 0 {
+					break
+				}
+				parens--
+			}
+		}
+		dest = unescapePunct(s[start:j])
+	}
+	dest = html.UnescapeString(dest)
+
+	for j < len(s) && isSpace(s[j]) {
+		j++
+	}
+
+	// The title isn't rendered
+	if j < len(s) && (s[j] == '"' || s[j] == '\'') {
+		k := strings.IndexByte(s[j+1:], s[j])
+		if k < 0 {
+			return 0
+		}
+		j += k + 2
+		for j < len(s) && isSpace(s[j]) {
+			j++
+		}
+	}
+
+	if j >= len(s) || s[j] != ')' {
+		return 0
+	}
+
+	if r.inLink {
+		// Links may not contain other links
+		r.inlineTo(b, s[1:end])
+		return j + 1
+	}
+
+	r.inLink = true
+	text := r.inline(s[1:end])
+	r.inLink = false
+
+	r.link(b, dest, text)
+	return j + 1
+}
+
+func unescapePunct(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// emphasis renders *em*, **strong** or ~~strike through~~ at s[i:] and
+// returns its length, 0 if there is none.
+func (r *renderer) emphasis(b *bytes.Buffer, s string, i int) int {
+	c := s[i]
+	n := runLength(s[i:], c)
+	if n > 3 || (c == '~' && n != 2) {
+		return 0
+	}
+
+	open := i + n
+	if open >= len(s) || isSpace(s[open]) || (c == '_' && i > 0 && isAlnum(s[i-1])) {
+		return 0
+	}
+
+	for j := open + 1; j < len(s); {
+		k := strings.IndexByte(s[j:], c)
+		if k < 0 {
+			return 0
+		}
+		j += k
+
+		m := runLength(s[j:], c)
+		after := j + m
+		if m == n && !isSpace(s[j-1]) && (c != '_' || after >= len(s) || !isAlnum(s[after])) {
+			inner := r.inline(s[open:j])
+			switch {
+			case c == '~':
+				b.WriteString("<del>" + inner + "</del>")
+			case n == 1:
+				b.WriteString("<em>" + inner + "</em>")
+			case n == 2:
+				b.WriteString("<strong>" + inner + "</strong>")
+			default:
+				b.WriteString("<em><strong>" + inner + "</strong></em>")
+			}
+			return after - i
+		}
+		j = after
+	}
+
+	return 0
+}
+
+// link writes the link to the destination with the rendered text, only
+// the text when the destination isn't safe.
+func (r *renderer) link(b *bytes.Buffer, dest, text string) {
+	if r.inLink || !safeURL(dest) {
+		b.WriteString(text)
+		return
+	}
+
+	fmt.Fprintf(b, `<a href="%s" rel="nofollow noopener noreferrer">%s</a>`, html.EscapeString(dest), text)
+
+	if !r.seen[dest] {
+		r.seen[dest] = true
+		plain := html.UnescapeString(tagExp.ReplaceAllString(text, ""))
+		r.links = append(r.links, Link{Text: plain, URL: dest})
+	}
+}
+
+// safeURL reports whether the URL may be linked to: http, https and
+// mailto URLs or relative URLs.
+func safeURL(url string) bool {
+	for i := 0; i < len(url); i++ {
+		if url[i] < 0x20 || url[i] == 0x7f {
+			return false
+		}
+	}
+
+	m := schemeExp.FindStringSubmatch(url)
+	return m == nil || safeSchemes[strings.ToLower(m[1])]
+}
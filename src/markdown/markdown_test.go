@@ -0,0 +1,127 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		src, html string
+	}{
+		{"Hello *world*", "<p>Hello <em>world</em></p>\n"},
+		{"**bold** and __bold__ and ~~gone~~", "<p><strong>bold</strong> and <strong>bold</strong> and <del>gone</del></p>\n"},
+		{"snake_case_name", "<p>snake_case_name</p>\n"},
+		{"# Title #\n\ntext", "<h1>Title</h1>\n<p>text</p>\n"},
+		{"#hashtag", "<p>#hashtag</p>\n"},
+		{"a `<b>` c", "<p>a <code>&lt;b&gt;</code> c</p>\n"},
+		{"line  \nbreak", "<p>line<br>\nbreak</p>\n"},
+		{"```go\nif a < b {}\n```", "<pre><code class=\"language-go\">if a &lt; b {}\n</code></pre>\n"},
+		{"    code", "<pre><code>code\n</code></pre>\n"},
+		{"---", "<hr>\n"},
+		{"> quoted\n> text", "<blockquote>\n<p>quoted\ntext</p>\n</blockquote>\n"},
+		{"- a\n- b", "<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n"},
+		{"- a\n\n- b", "<ul>\n<li>\n<p>a</p>\n</li>\n<li>\n<p>b</p>\n</li>\n</ul>\n"},
+		{"3. a\n4. b", "<ol start=\"3\">\n<li>a</li>\n<li>b</li>\n</ol>\n"},
+		{"- a\n  - b", "<ul>\n<li>a\n<ul>\n<li>b</li>\n</ul></li>\n</ul>\n"},
+		{"- [ ] todo\n- [x] done", "<ul>\n<li class=\"task-list-item\"><input type=\"checkbox\" disabled data-task=\"0\"> todo</li>\n" +
+			"<li class=\"task-list-item\"><input type=\"checkbox\" disabled data-task=\"1\" checked> done</li>\n</ul>\n"},
+		{"[site](https://example.com)", "<p><a href=\"https://example.com\" rel=\"nofollow noopener noreferrer\">site</a></p>\n"},
+		{"![logo](https://example.com/a.png)", "<p><a href=\"https://example.com/a.png\" rel=\"nofollow noopener noreferrer\">logo</a></p>\n"},
+		{"see https://example.com/a.", "<p>see <a href=\"https://example.com/a\" rel=\"nofollow noopener noreferrer\">https://example.com/a</a>.</p>\n"},
+		{"<me@example.com>", "<p><a href=\"mailto:me@example.com\" rel=\"nofollow noopener noreferrer\">me@example.com</a></p>\n"},
+		{"Tom &amp; Jerry &copy; &#169; &#xA9; &bogus; & `&amp;`", "<p>Tom &amp; Jerry © © © &amp;bogus; &amp; <code>&amp;amp;</code></p>\n"},
+		{"[q](https://a.example/?a=1&amp;b=2)", "<p><a href=\"https://a.example/?a=1&amp;b=2\" rel=\"nofollow noopener noreferrer\">q</a></p>\n"},
+	}
+
+	for _, test := range tests {
+		if got := Render(test.src).HTML; got != test.html {
+			t.Errorf("Incorrect html of %q:\nwant %q\ngot  %q", test.src, test.html, got)
+		}
+	}
+}
+
+func TestRenderIsSafe(t *testing.T) {
+	srcs := []string{
+		"<script>alert(1)</script>",
+		"<img src=x onerror=alert(1)>",
+		"[x](javascript:alert(1))",
+		"[x](JavaScript:alert(1))",
+		"[x](data:text/html;base64,PHNjcmlwdD4=)",
+		"<javascript:alert(1)>",
+		"[x](\"onmouseover=alert(1))",
+		"```\"><script>\n```",
+		"``` x\"onload=alert(1)\n```",
+		"- [x] <b onclick=alert(1)>",
+		"[x](javascript&#58;alert(1))",
+		"&lt;script&gt;alert(1)&lt;/script&gt;",
+	}
+
+	for _, src := range srcs {
+		html := Render(src).HTML
+		for _, bad := range []string{"<script", "<img", "<b ", "href=\"javascript", "href=\"data", "\"onmouseover", "\"onload"} {
+			if strings.Contains(strings.ToLower(html), strings.ToLower(bad)) {
+				t.Errorf("Unsafe html of %q: %s", src, html)
+			}
+		}
+	}
+}
+
+func TestRenderLinks(t *testing.T) {
+	d := Render("[Docs](https://a.example) and <https://b.example>, again [docs](https://a.example)\n\n[bad](javascript:x)")
+
+	if len(d.Links) != 2 {
+		t.Fatalf("Incorrect links: %v", d.Links)
+	}
+	if d.Links[0] != (Link{"Docs", "https://a.example"}) || d.Links[1] != (Link{"https://b.example", "https://b.example"}) {
+		t.Errorf("Incorrect links: %v", d.Links)
+	}
+}
+
+func TestRenderTasks(t *testing.T) {
+	d := Render("Shopping\n\n- [ ] milk\n- [X] eggs\n\n```\n- [ ] not a task\n```\n> - [ ] quoted")
+
+	if len(d.Tasks) != 3 {
+		t.Fatalf("Incorrect tasks: %v", d.Tasks)
+	}
+	if d.Tasks[0].Text != "milk" || d.Tasks[0].Checked || d.Tasks[0].Line != 2 {
+		t.Errorf("Incorrect first task: %v", d.Tasks[0])
+	}
+	if !d.Tasks[1].Checked || d.Tasks[2].Line != 8 {
+		t.Errorf("Incorrect tasks: %v", d.Tasks)
+	}
+}
+
+func TestToggleTask(t *testing.T) {
+	src := "- [ ] milk [ ]\n- [x] eggs\n> - [ ] quoted"
+
+	got, err := ToggleTask(src, 0, true)
+	if err != nil || got != "- [x] milk [ ]\n- [x] eggs\n> - [ ] quoted" {
+		t.Errorf("Incorrect toggle of the first task: %q, %v", got, err)
+	}
+
+	got, err = ToggleTask(src, 1, false)
+	if err != nil || got != "- [ ] milk [ ]\n- [ ] eggs\n> - [ ] quoted" {
+		t.Errorf("Incorrect toggle of the second task: %q, %v", got, err)
+	}
+
+	got, err = ToggleTask(src, 2, true)
+	if err != nil || got != "- [ ] milk [ ]\n- [x] eggs\n> - [x] quoted" {
+		t.Errorf("Incorrect toggle of the quoted task: %q, %v", got, err)
+	}
+
+	if _, err := ToggleTask(src, 3, true); err != ErrNoTask {
+		t.Errorf("Expected ErrNoTask, got %v", err)
+	}
+
+	src = "1. [ ] one\n   - [ ] [nested](https://a.example)\n> 2) [x] quoted [ ]"
+	got, err = ToggleTask(src, 1, true)
+	if err != nil || got != "1. [ ] one\n   - [x] [nested](https://a.example)\n> 2) [x] quoted [ ]" {
+		t.Errorf("Incorrect toggle of the nested task: %q, %v", got, err)
+	}
+
+	got, err = ToggleTask(src, 2, false)
+	if err != nil || got != "1. [ ] one\n   - [ ] [nested](https://a.example)\n> 2) [ ] quoted [ ]" {
+		t.Errorf("Incorrect toggle of the quoted ordered task: %q, %v", got, err)
+	}
+}
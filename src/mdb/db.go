@@ -3,10 +3,11 @@ package mdb
 import (
 	"config"
 	"errors"
-	"fmt"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
+	"io"
 	"log"
+	"time"
 )
 
 var Hostname = config.GetConfig().MongodbHostname
@@ -16,12 +17,6 @@ var masterSession *mgo.Session
 
 var NotFoundError = mgo.ErrNotFound
 
-type NotValidObjIndexError error
-
-func notValidObjIndexError(id string) NotValidObjIndexError {
-	return fmt.Errorf("Id is not a valid ObjectIdHex: %s", id)
-}
-
 func init() {
 	var err error
 	masterSession, err = mgo.Dial(Hostname)
@@ -58,21 +53,12 @@ func (d *DataStore) GetAllObjects() ([]bson.Raw, error) {
 	return results, err
 }
 
-func (d *DataStore) GetObjectById(i interface{}) (*bson.Raw, error) {
-
-	id, ok := i.(string)
-	if !ok {
-		return nil, errors.New("Provided id is not of type string")
-	}
-
-	if !bson.IsObjectIdHex(id) {
-		return nil, notValidObjIndexError(id)
-	}
-
+// GetObjectById returns the object whose _id is the id. Ids are
+// opaque to the DataStore, they are matched as they are given e.g. as
+// a bson.ObjectId or a string.
+func (d *DataStore) GetObjectById(id interface{}) (*bson.Raw, error) {
 	var raw bson.Raw
-	oid := bson.ObjectIdHex(id)
-
-	err := d.session.DB(d.Database).C(d.Collection).FindId(oid).One(&raw)
+	err := d.session.DB(d.Database).C(d.Collection).FindId(id).One(&raw)
 	if err != nil {
 		return nil, err
 	}
@@ -114,17 +100,14 @@ func (d *DataStore) InsertObject(obj interface{}) error {
 	return d.session.DB(d.Database).C(d.Collection).Insert(obj)
 }
 
-func (d *DataStore) ModifyObjectForId(params map[string]string, change map[string]interface{}) error {
+// ModifyObjectForId applies the $set change to the object selected
+// by the params, the "id" param is matched against its _id as given.
+func (d *DataStore) ModifyObjectForId(params map[string]interface{}, change map[string]interface{}) error {
 
 	selector := bson.M{}
 	for k, v := range params {
 		if k == "id" {
-			id := params["id"]
-			if !bson.IsObjectIdHex(id) {
-				return notValidObjIndexError(id)
-			}
-			oid := bson.ObjectIdHex(id)
-			selector["_id"] = oid
+			selector["_id"] = v
 		} else {
 			selector[k] = v
 		}
@@ -140,17 +123,12 @@ func (d *DataStore) ModifyObjectForId(params map[string]string, change map[strin
 	return nil
 }
 
-func (d *DataStore) DeleteObjectForSelector(params map[string]string) error {
+func (d *DataStore) DeleteObjectForSelector(params map[string]interface{}) error {
 
 	selector := bson.M{}
 	for k, v := range params {
 		if k == "id" {
-			id := params["id"]
-			if !bson.IsObjectIdHex(id) {
-				return notValidObjIndexError(id)
-			}
-			oid := bson.ObjectIdHex(id)
-			selector["_id"] = oid
+			selector["_id"] = v
 		} else {
 			selector[k] = v
 		}
@@ -158,3 +136,256 @@ func (d *DataStore) DeleteObjectForSelector(params map[string]string) error {
 
 	return d.session.DB(d.Database).C(d.Collection).Remove(selector)
 }
+
+// ModifyObjectsForQuery applies the $set change to every object
+// matching the query.
+func (d *DataStore) ModifyObjectsForQuery(query interface{}, change map[string]interface{}) error {
+	q, ok := query.(bson.M)
+	if !ok {
+		return errors.New("Invalid query structure must be bson.M")
+	}
+
+	_, err := d.session.DB(d.Database).C(d.Collection).UpdateAll(q, bson.M{"$set": change})
+	if err != nil {
+		log.Println("ModifyObjectsForQuery: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeleteObjectsForQuery removes every object matching the query.
+func (d *DataStore) DeleteObjectsForQuery(query interface{}) error {
+	q, ok := query.(bson.M)
+	if !ok {
+		return errors.New("Invalid query structure must be bson.M")
+	}
+
+	_, err := d.session.DB(d.Database).C(d.Collection).RemoveAll(q)
+	return err
+}
+
+// UpdateObjectsForQuery applies the update document, which may use any
+// of the mongodb update operators, to every object matching the query.
+func (d *DataStore) UpdateObjectsForQuery(query interface{}, update interface{}) error {
+	q, ok := query.(bson.M)
+	if !ok {
+		return errors.New("Invalid query structure must be bson.M")
+	}
+
+	u, ok := update.(bson.M)
+	if !ok {
+		return errors.New("Invalid update structure must be bson.M")
+	}
+
+	_, err := d.session.DB(d.Database).C(d.Collection).UpdateAll(q, u)
+	if err != nil {
+		log.Println("UpdateObjectsForQuery: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// AggregateObjects runs the aggregation pipeline against the
+// collection and returns the resulting documents.
+func (d *DataStore) AggregateObjects(pipeline interface{}) ([]bson.Raw, error) {
+	p, ok := pipeline.([]bson.M)
+	if !ok {
+		return nil, errors.New("Invalid pipeline structure must be []bson.M")
+	}
+
+	var results []bson.Raw
+	err := d.session.DB(d.Database).C(d.Collection).Pipe(p).All(&results)
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// CountObjectsForQuery returns the number of objects matching the query.
+func (d *DataStore) CountObjectsForQuery(query interface{}) (int, error) {
+	q, ok := query.(bson.M)
+	if !ok {
+		return 0, errors.New("Invalid query structure must be bson.M")
+	}
+
+	return d.session.DB(d.Database).C(d.Collection).Find(q).Count()
+}
+
+// CountObjectsPerDay counts the objects matching the query per day of
+// their dateField in the time zone (an IANA name). The days are
+// formatted as 2006-01-02.
+func (d *DataStore) CountObjectsPerDay(query bson.M, dateField, timeZone string) (map[string]int, error) {
+	match := bson.M{dateField: bson.M{"$type": "date"}}
+	for k, v := range query {
+		if k != dateField {
+			match[k] = v
+		}
+	}
+
+	day := bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$" + dateField, "timezone": timeZone}}
+	raws, err := d.AggregateObjects([]bson.M{
+		{"$match": match},
+		{"$group": bson.M{"_id": day, "count": bson.M{"$sum": 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(raws))
+	for _, raw := range raws {
+		var r struct {
+			Day   string `bson:"_id"`
+			Count int    `bson:"count"`
+		}
+		if err := raw.Unmarshal(&r); err != nil {
+			return nil, err
+		}
+		counts[r.Day] = r.Count
+	}
+
+	return counts, nil
+}
+
+// AverageDuration returns the average time from startField to endField
+// of the objects matching the query, which have both dates. It reports
+// false when there are no such objects.
+func (d *DataStore) AverageDuration(query bson.M, startField, endField string) (time.Duration, bool, error) {
+	match := bson.M{startField: bson.M{"$type": "date"}, endField: bson.M{"$type": "date"}}
+	for k, v := range query {
+		if k != startField && k != endField {
+			match[k] = v
+		}
+	}
+
+	raws, err := d.AggregateObjects([]bson.M{
+		{"$match": match},
+		{"$group": bson.M{"_id": nil, "avg": bson.M{"$avg": bson.M{"$subtract": []string{"$" + endField, "$" + startField}}}}},
+	})
+	if err != nil || len(raws) == 0 {
+		return 0, false, err
+	}
+
+	var r struct {
+		Avg float64 `bson:"avg"` // milliseconds
+	}
+	if err := raws[0].Unmarshal(&r); err != nil {
+		return 0, false, err
+	}
+
+	return time.Duration(r.Avg * float64(time.Millisecond)), true, nil
+}
+
+// FindAndModifyObject atomically applies the update document to a
+// single object matching the query and returns the modified object.
+// NotFoundError is returned when no object matches.
+func (d *DataStore) FindAndModifyObject(query interface{}, update interface{}) (*bson.Raw, error) {
+	q, ok := query.(bson.M)
+	if !ok {
+		return nil, errors.New("Invalid query structure must be bson.M")
+	}
+
+	u, ok := update.(bson.M)
+	if !ok {
+		return nil, errors.New("Invalid update structure must be bson.M")
+	}
+
+	var raw bson.Raw
+	change := mgo.Change{Update: u, ReturnNew: true}
+	_, err := d.session.DB(d.Database).C(d.Collection).Find(q).Apply(change, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return &raw, nil
+}
+
+// InsertObjectIfNone inserts the object unless an object matching the
+// query exists. It reports whether the object was inserted. The fields
+// of the query are set on the inserted object. Concurrent calls may both
+// insert unless a unique index covers the query, then all but one fail
+// with an error for which IsDuplicateError holds.
+func (d *DataStore) InsertObjectIfNone(query interface{}, obj interface{}) (bool, error) {
+	q, ok := query.(bson.M)
+	if !ok {
+		return false, errors.New("Invalid query structure must be bson.M")
+	}
+
+	change := mgo.Change{Update: bson.M{"$setOnInsert": obj}, Upsert: true}
+	info, err := d.session.DB(d.Database).C(d.Collection).Find(q).Apply(change, nil)
+	if err != nil {
+		return false, err
+	}
+
+	return info.UpsertedId != nil, nil
+}
+
+// EnsureUniqueIndex creates the unique index with the name on the keys
+// unless it exists. With a filter the index is partial, only the objects
+// matching the filter are unique.
+func (d *DataStore) EnsureUniqueIndex(name string, keys bson.D, filter bson.M) error {
+	index := bson.M{"name": name, "key": keys, "unique": true}
+	if filter != nil {
+		index["partialFilterExpression"] = filter
+	}
+
+	cmd := bson.D{{Name: "createIndexes", Value: d.Collection}, {Name: "indexes", Value: []bson.M{index}}}
+	return d.session.DB(d.Database).Run(cmd, nil)
+}
+
+// IsDuplicateError reports whether the error is due to an object which
+// a unique index already has.
+func IsDuplicateError(err error) bool {
+	return mgo.IsDup(err)
+}
+
+// GetObjectsForQueryPage returns the objects matching the query ordered
+// by the sort fields (prefixed with '-' for descending order), skipping
+// the first skip objects. A limit of 0 returns all remaining objects.
+func (d *DataStore) GetObjectsForQueryPage(query interface{}, sort []string, skip, limit int) ([]bson.Raw, error) {
+	q, ok := query.(bson.M)
+	if !ok {
+		return nil, errors.New("Invalid query structure must be bson.M")
+	}
+
+	var results []bson.Raw
+	err := d.session.DB(d.Database).C(d.Collection).Find(q).Sort(sort...).Skip(skip).Limit(limit).All(&results)
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// WriteFile stores the contents of r as the GridFS file with the name,
+// using the Collection as the GridFS prefix. It returns the number of
+// bytes written.
+func (d *DataStore) WriteFile(name string, r io.Reader) (int64, error) {
+	f, err := d.session.DB(d.Database).GridFS(d.Collection).Create(name)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := io.Copy(f, r)
+	if err != nil {
+		f.Abort()
+		f.Close()
+		return n, err
+	}
+
+	return n, f.Close()
+}
+
+// OpenFile opens the most recent GridFS file with the name for reading.
+// NotFoundError is returned when there is no such file. The file must be
+// closed before the DataStore.
+func (d *DataStore) OpenFile(name string) (io.ReadCloser, error) {
+	return d.session.DB(d.Database).GridFS(d.Collection).Open(name)
+}
+
+// RemoveFile removes every GridFS file with the name.
+func (d *DataStore) RemoveFile(name string) error {
+	return d.session.DB(d.Database).GridFS(d.Collection).Remove(name)
+}
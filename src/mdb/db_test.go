@@ -2,9 +2,11 @@ package mdb
 
 import (
 	"fmt"
+	"io/ioutil"
 	"log"
 	"strings"
 	"testing"
+	"time"
 
 	"gopkg.in/mgo.v2/bson"
 )
@@ -166,8 +168,7 @@ func TestGetObjectById(t *testing.T) {
 	defer teardown(d)
 
 	// Main test content
-	var id = ts1.Id.Hex()
-	obj, err := d.GetObjectById(id)
+	obj, err := d.GetObjectById(ts1.Id)
 	if err != nil {
 		t.Error(err)
 	}
@@ -189,10 +190,10 @@ func TestModifyObjectForId(t *testing.T) {
 	defer teardown(d)
 
 	// Main test content
-	id := ts0.Id.Hex()
+	id := ts0.Id
 	change := bson.M{"value0": "Updated Value"}
 
-	m := make(map[string]string)
+	m := make(map[string]interface{})
 	m["id"] = id
 	err := d.ModifyObjectForId(m, change)
 	if err != nil {
@@ -222,7 +223,7 @@ func TestModifyObjectForId(t *testing.T) {
 	m["id"] = "123"
 	err = d.ModifyObjectForId(m, change)
 	if err == nil {
-		t.Error("Did not return NotValidObjIndexError")
+		t.Error("Modified an object which doesn't exist")
 	}
 }
 
@@ -235,20 +236,346 @@ func TestDeleteObjectForId(t *testing.T) {
 	defer teardown(d)
 
 	// Main test content
-	m := make(map[string]string)
-	id := ts0.Id.Hex()
-	m["id"] = id
+	m := make(map[string]interface{})
+	m["id"] = ts0.Id
 	err := d.DeleteObjectForSelector(m)
 	if err != nil {
 		t.Error(err)
 	}
 
-	id = "abc"
-	m["id"] = id
+	m["id"] = "abc"
 	err = d.DeleteObjectForSelector(m)
 	if err == nil {
 		t.Error(err)
 	}
 }
 
+func TestModifyObjectsForQuery(t *testing.T) {
+	// Test setup
+	d := NewDataStore()
+	d.Collection = "2Do_TestModifyObjectsForQuery_Collection"
+	d.getSetup()
+
+	defer teardown(d)
+
+	// Main test content
+	query := bson.M{"value1": bson.M{"$gt": 1200}}
+	change := bson.M{"value0": "Updated Value"}
+	err := d.ModifyObjectsForQuery(query, change)
+	if err != nil {
+		t.Error(err)
+	}
+
+	objs, err := d.GetObjectsForQuery(bson.M{"value0": "Updated Value"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(objs) != 2 {
+		t.Errorf("Incorrect number of modified objects: want %d got %d", 2, len(objs))
+	}
+
+	err = d.ModifyObjectsForQuery("value1", change)
+	if err == nil {
+		t.Error("Did not fail with invalid query structure")
+	}
+}
+
+func TestDeleteObjectsForQuery(t *testing.T) {
+	// Test setup
+	d := NewDataStore()
+	d.Collection = "2Do_TestDeleteObjectsForQuery_Collection"
+	d.getSetup()
+
+	defer teardown(d)
+
+	// Main test content
+	err := d.DeleteObjectsForQuery(bson.M{"value1": bson.M{"$lt": 2000}})
+	if err != nil {
+		t.Error(err)
+	}
+
+	objs, err := d.GetAllObjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(objs) != 1 {
+		t.Errorf("Incorrect number of remaining objects: want %d got %d", 1, len(objs))
+	}
+}
+
+func TestUpdateObjectsForQuery(t *testing.T) {
+	// Test setup
+	d := NewDataStore()
+	d.Collection = "2Do_TestUpdateObjectsForQuery_Collection"
+	d.getSetup()
+
+	defer teardown(d)
+
+	// Main test content
+	update := bson.M{"$inc": bson.M{"value1": 1}}
+	err := d.UpdateObjectsForQuery(bson.M{}, update)
+	if err != nil {
+		t.Error(err)
+	}
+
+	obj, err := d.GetObjectById(ts0.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tstStrct TestStruct
+	if err := obj.Unmarshal(&tstStrct); err != nil {
+		t.Fatal(err)
+	}
+
+	if tstStrct.Val1 != 1112 {
+		t.Errorf("Val1 was not incremented: want %d got %d", 1112, tstStrct.Val1)
+	}
+}
+
+func TestAggregateObjects(t *testing.T) {
+	// Test setup
+	d := NewDataStore()
+	d.Collection = "2Do_TestAggregateObjects_Collection"
+	d.getSetup()
+
+	defer teardown(d)
+
+	// Main test content
+	pipeline := []bson.M{
+		{"$group": bson.M{"_id": nil, "total": bson.M{"$sum": "$value1"}}},
+	}
+	raws, err := d.AggregateObjects(pipeline)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result struct {
+		Total int `bson:"total"`
+	}
+	if len(raws) != 1 {
+		t.Fatalf("Incorrect number of results: want %d got %d", 1, len(raws))
+	}
+	if err := raws[0].Unmarshal(&result); err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Total != 1111+1234+9876 {
+		t.Errorf("Incorrect total: got %d", result.Total)
+	}
+}
+
+func TestCountObjectsForQuery(t *testing.T) {
+	// Test setup
+	d := NewDataStore()
+	d.Collection = "2Do_TestCountObjectsForQuery_Collection"
+	d.getSetup()
+
+	defer teardown(d)
+
+	// Main test content
+	n, err := d.CountObjectsForQuery(bson.M{"value1": bson.M{"$gt": 1200}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 2 {
+		t.Errorf("Incorrect count: want %d got %d", 2, n)
+	}
+}
+
+func TestCountObjectsPerDay(t *testing.T) {
+	// Test setup
+	d := NewDataStore()
+	d.Collection = "2Do_TestCountObjectsPerDay_Collection"
+	d.setup()
+
+	defer teardown(d)
+
+	// 23:30 UTC is already the next day in Paris
+	day := time.Date(2017, 1, 2, 12, 0, 0, 0, time.UTC)
+	for _, at := range []time.Time{day, day.Add(time.Hour), day.Add(11*time.Hour + 30*time.Minute)} {
+		if err := d.InsertObject(bson.M{"kind": "a", "at": at}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d.InsertObject(bson.M{"kind": "b", "at": day})
+
+	// Main test content
+	counts, err := d.CountObjectsPerDay(bson.M{"kind": "a"}, "at", "Europe/Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(counts) != 2 || counts["2017-01-02"] != 2 || counts["2017-01-03"] != 1 {
+		t.Errorf("Incorrect counts per day: %v", counts)
+	}
+}
+
+func TestAverageDuration(t *testing.T) {
+	// Test setup
+	d := NewDataStore()
+	d.Collection = "2Do_TestAverageDuration_Collection"
+	d.setup()
+
+	defer teardown(d)
+
+	start := time.Date(2017, 1, 2, 12, 0, 0, 0, time.UTC)
+	d.InsertObject(bson.M{"start": start, "end": start.Add(time.Hour)})
+	d.InsertObject(bson.M{"start": start, "end": start.Add(3 * time.Hour)})
+	d.InsertObject(bson.M{"start": start})
+
+	// Main test content
+	avg, ok, err := d.AverageDuration(bson.M{}, "start", "end")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ok || avg != 2*time.Hour {
+		t.Errorf("Incorrect average duration: %v %v", avg, ok)
+	}
+
+	_, ok, err = d.AverageDuration(bson.M{"missing": true}, "start", "end")
+	if err != nil || ok {
+		t.Errorf("Should not average without objects: %v %v", ok, err)
+	}
+}
+
+func TestFindAndModifyObject(t *testing.T) {
+	// Test setup
+	d := NewDataStore()
+	d.Collection = "2Do_TestFindAndModifyObject_Collection"
+	d.getSetup()
+
+	defer teardown(d)
+
+	// Main test content
+	query := bson.M{"value1": 1234}
+	update := bson.M{"$set": bson.M{"value1": 4321}}
+	raw, err := d.FindAndModifyObject(query, update)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tstStrct TestStruct
+	if err := raw.Unmarshal(&tstStrct); err != nil {
+		t.Fatal(err)
+	}
+
+	if tstStrct.Id != ts1.Id || tstStrct.Val1 != 4321 {
+		t.Errorf("Incorrect modified object: %v", tstStrct)
+	}
+
+	_, err = d.FindAndModifyObject(query, update)
+	if err != NotFoundError {
+		t.Errorf("Should not find an object: got %v", err)
+	}
+}
+
+func TestInsertObjectIfNone(t *testing.T) {
+	// Test setup
+	d := NewDataStore()
+	d.Collection = "2Do_TestInsertObjectIfNone_Collection"
+	d.getSetup()
+
+	defer teardown(d)
+
+	// Main test content
+	inserted, err := d.InsertObjectIfNone(bson.M{"value1": 1234}, bson.M{"value0": "Other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inserted {
+		t.Error("Should not insert when a matching object exists")
+	}
+
+	inserted, err = d.InsertObjectIfNone(bson.M{"value1": 5678}, bson.M{"value0": "New"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !inserted {
+		t.Error("Should insert when no matching object exists")
+	}
+
+	raw, err := d.GetObjectForQuery(bson.M{"value1": 5678})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tstStrct TestStruct
+	if err := raw.Unmarshal(&tstStrct); err != nil || tstStrct.Val0 != "New" {
+		t.Errorf("Incorrect inserted object: %v %v", tstStrct, err)
+	}
+}
+
+func TestGetObjectsForQueryPage(t *testing.T) {
+	// Test setup
+	d := NewDataStore()
+	d.Collection = "2Do_TestGetObjectsForQueryPage_Collection"
+	d.getSetup()
+
+	defer teardown(d)
+
+	// Main test content
+	objs, err := d.GetObjectsForQueryPage(bson.M{}, []string{"-value1"}, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(objs) != 1 {
+		t.Fatalf("Incorrect number of objects: want %d got %d", 1, len(objs))
+	}
+
+	var tstStrct TestStruct
+	if err := objs[0].Unmarshal(&tstStrct); err != nil {
+		t.Fatal(err)
+	}
+
+	if !tstStrct.Equal(ts1) {
+		t.Errorf("Incorrect object for page: want %v got %v", ts1, tstStrct)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	// Test setup
+	d := NewDataStore()
+	d.Collection = "2Do_TestFiles"
+	defer d.Close()
+
+	// Main test content
+	n, err := d.WriteFile("hello.txt", strings.NewReader("Hello, world!"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 13 {
+		t.Errorf("Incorrect number of bytes written: want %d got %d", 13, n)
+	}
+
+	f, err := d.OpenFile("hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != "Hello, world!" {
+		t.Errorf("Incorrect file contents: %s", b)
+	}
+
+	if err := d.RemoveFile("hello.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.OpenFile("hello.txt"); err != NotFoundError {
+		t.Errorf("File should be removed: got %v", err)
+	}
+}
+
 // END OF TEST FUNCTIONS //
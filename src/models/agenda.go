@@ -0,0 +1,150 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// MaxAgendaDays is the number of days an agenda may span.
+const MaxAgendaDays = 366
+
+const (
+	// maxProjections is the number of occurrences projected per
+	// recurring todo.
+	maxProjections = 1000
+	// maxProjectionSteps bounds the occurrences stepped through to
+	// reach the range of an agenda e.g. from an instance long overdue.
+	maxProjectionSteps = 10000
+)
+
+// AgendaItem is a todo of an agenda.
+type AgendaItem struct {
+	Todo
+	// Projected items are upcoming occurrences of a recurring todo,
+	// which are only created once the previous instance is completed.
+	// They have the id of the instance they are projected from.
+	Projected bool `json:"projected,omitempty"`
+	// Overdue items are open todos carried over from a past day.
+	Overdue bool `json:"overdue,omitempty"`
+}
+
+// AgendaDay is a day of an agenda, Date is in the user's time zone.
+type AgendaDay struct {
+	Date  string       `json:"date"`
+	Todos []AgendaItem `json:"todos"`
+}
+
+// AgendaItems returns the todos due from start until before end along
+// with the occurrences of the open recurring todos projected in that
+// range, in order of due date. Only occurrences due from now on are
+// projected, the instance which is due already stands for the earlier
+// ones.
+func AgendaItems(ts []Todo, start, end, now time.Time) []AgendaItem {
+	from := start
+	if now.After(from) {
+		from = now
+	}
+
+	var items []AgendaItem
+	for _, t := range ts {
+		if t.Due.IsZero() {
+			continue
+		}
+
+		if !t.Due.Before(start) && t.Due.Before(end) {
+			items = append(items, AgendaItem{Todo: t})
+		}
+
+		if t.Recurrence != nil && !t.Completed {
+			items = append(items, projections(t, from, end)...)
+		}
+	}
+
+	sortAgendaItems(items)
+	return items
+}
+
+// projections returns the occurrences of the recurring todo following
+// t which are due from start until before end.
+func projections(t Todo, start, end time.Time) []AgendaItem {
+	id := t.Id
+
+	var items []AgendaItem
+	for steps := 0; steps < maxProjectionSteps && len(items) < maxProjections; steps++ {
+		next, err := t.NextInstance(t.Due)
+		if err != nil || next == nil || !next.Due.Before(end) {
+			break
+		}
+
+		if !next.Due.Before(start) {
+			p := *next
+			p.Id = id
+			items = append(items, AgendaItem{Todo: p, Projected: true})
+		}
+		t = *next
+	}
+
+	return items
+}
+
+// Agenda groups the todos by the day of loc they are due on, from the
+// day of start until the day of end, excluded, see AgendaItems. Open
+// todos which are overdue at now are carried over to today when it is
+// one of the days, ahead of the todos due today.
+func Agenda(ts []Todo, start, end, now time.Time, loc *time.Location) []AgendaDay {
+	start, _ = DayBounds(start, loc)
+	today, _ := DayBounds(now, loc)
+	carry := !today.Before(start) && today.Before(end)
+
+	var items []AgendaItem
+	for _, item := range AgendaItems(ts, start, end, now) {
+		if !carry || item.Projected || !item.Todo.Overdue(now, loc) {
+			items = append(items, item)
+		}
+	}
+
+	if carry {
+		for _, t := range ts {
+			if t.Overdue(now, loc) {
+				items = append(items, AgendaItem{Todo: t, Overdue: true})
+			}
+		}
+		sortAgendaItems(items)
+	}
+
+	var days []AgendaDay
+	index := make(map[string]int)
+	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+		date := d.Format(DateFormat)
+		index[date] = len(days)
+		days = append(days, AgendaDay{Date: date, Todos: []AgendaItem{}})
+	}
+
+	todayDate := today.Format(DateFormat)
+	for _, item := range items {
+		date := item.Due.In(loc).Format(DateFormat)
+		if item.Overdue {
+			date = todayDate
+		}
+
+		if i, ok := index[date]; ok {
+			days[i].Todos = append(days[i].Todos, item)
+		}
+	}
+
+	return days
+}
+
+// sortAgendaItems orders the items by due date, overdue ones first.
+func sortAgendaItems(items []AgendaItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.Overdue != b.Overdue {
+			return a.Overdue
+		}
+		if !a.Due.Equal(b.Due) {
+			return a.Due.Before(b.Due)
+		}
+		return a.Title < b.Title
+	})
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAgenda(t *testing.T) {
+	loc, _ := time.LoadLocation("America/New_York")
+	// Monday 2 January 2017, 15:04 in New York
+	now := time.Date(2017, 1, 2, 15, 4, 0, 0, loc)
+	day := func(d, h int) time.Time { return time.Date(2017, 1, d, h, 0, 0, 0, loc) }
+
+	ts := []Todo{
+		{Id: "late", Title: "late", Due: day(1, 9)},
+		{Id: "done", Title: "done", Due: day(1, 9), Completed: true},
+		{Id: "today", Title: "today", Due: day(2, 0), AllDay: true},
+		{Id: "evening", Title: "evening", Due: day(2, 22)},
+		{Id: "later", Title: "later", Due: day(20, 9)},
+		{Id: "gym", Title: "gym", Due: day(3, 7), Recurrence: &Recurrence{Rule: "FREQ=DAILY;INTERVAL=2", TimeZone: "America/New_York"}},
+		{Id: "undated", Title: "undated"},
+	}
+
+	days := Agenda(ts, day(1, 12), day(6, 0), now, loc)
+	if len(days) != 5 || days[0].Date != "2017-01-01" || days[4].Date != "2017-01-05" {
+		t.Fatalf("Incorrect days: %v", days)
+	}
+
+	titles := func(d AgendaDay) (s string) {
+		for _, item := range d.Todos {
+			s += item.Title
+			if item.Projected {
+				s += "*"
+			}
+			if item.Overdue {
+				s += "!"
+			}
+			s += " "
+		}
+		return s
+	}
+
+	for i, want := range []string{
+		"done ",
+		"late! today evening ",
+		"gym ",
+		"",
+		"gym* ",
+	} {
+		if got := titles(days[i]); got != want {
+			t.Errorf("Incorrect todos of %s: want %q got %q", days[i].Date, want, got)
+		}
+	}
+
+	if p := days[4].Todos[0]; p.Id != "gym" || !p.Due.Equal(day(5, 7)) {
+		t.Errorf("Incorrect projection: %s %v", p.Id, p.Due)
+	}
+
+	// Overdue todos stay on their day when today isn't in the agenda
+	days = Agenda(ts, day(1, 0), day(2, 0), day(3, 12), loc)
+	if got := titles(days[0]); got != "done late " {
+		t.Errorf("Incorrect todos of a past day: %q", got)
+	}
+}
+
+func TestAgendaItemsProjectFromNow(t *testing.T) {
+	now := time.Date(2017, 1, 10, 12, 0, 0, 0, time.UTC)
+	daily := Todo{Id: "d", Due: time.Date(2017, 1, 1, 9, 0, 0, 0, time.UTC), Recurrence: &Recurrence{Rule: "FREQ=DAILY"}}
+
+	items := AgendaItems([]Todo{daily}, time.Date(2017, 1, 5, 0, 0, 0, 0, time.UTC), time.Date(2017, 1, 13, 0, 0, 0, 0, time.UTC), now)
+	if len(items) != 2 || !items[0].Due.Equal(time.Date(2017, 1, 11, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Only occurrences from now on should be projected: %v", items)
+	}
+}
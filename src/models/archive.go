@@ -0,0 +1,98 @@
+package models
+
+import (
+	"fmt"
+	"gopkg.in/mgo.v2/bson"
+	"time"
+)
+
+const (
+	// MaxArchiveRules is the number of archive rules a user may have.
+	MaxArchiveRules = 20
+	// MaxArchiveDays is the longest a rule may wait to archive a todo.
+	MaxArchiveDays = 3650
+)
+
+var ErrTooManyArchiveRules = fmt.Errorf("At most %d archive rules are allowed", MaxArchiveRules)
+
+// archiveSort orders archived todos most recently archived first.
+var archiveSort = []string{"-archived_at", "-_id"}
+
+// ArchiveRule archives the completed todos of a user Days days after
+// they were completed. A rule with a ListId only applies to the todos
+// of that list, InboxListId for those without a list.
+type ArchiveRule struct {
+	Days   int    `json:"days" bson:"days"`
+	ListId string `json:"list_id,omitempty" bson:"list_id,omitempty"`
+}
+
+func (ar ArchiveRule) Validate() error {
+	if ar.Days < 0 || ar.Days > MaxArchiveDays {
+		return fmt.Errorf("Archive rule days must be between 0 and %d", MaxArchiveDays)
+	}
+
+	return nil
+}
+
+// ValidateArchiveRules validates every rule and their number.
+func ValidateArchiveRules(rules []ArchiveRule) error {
+	if len(rules) > MaxArchiveRules {
+		return ErrTooManyArchiveRules
+	}
+
+	for _, ar := range rules {
+		if err := ar.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// before returns the time todos must have been completed before for
+// the rule to archive them at now.
+func (ar ArchiveRule) before(now time.Time) time.Time {
+	return now.AddDate(0, 0, -ar.Days)
+}
+
+// query returns the mongodb query selecting the todos of the user
+// which the rule archives at now.
+func (ar ArchiveRule) query(userId string, now time.Time) bson.M {
+	q := bson.M{
+		"ownerid":      userId,
+		"completed":    true,
+		"archived":     bson.M{"$ne": true},
+		"completed_at": bson.M{"$lte": ar.before(now)},
+	}
+
+	switch ar.ListId {
+	case "":
+	case InboxListId:
+		q["list_id"] = bson.M{"$in": []interface{}{nil, ""}}
+	default:
+		q["list_id"] = ar.ListId
+	}
+
+	return q
+}
+
+// matches reports whether the rule archives t of the user at now. It
+// mirrors query for storage implementations which do not use mongodb.
+func (ar ArchiveRule) matches(userId string, t Todo, now time.Time) bool {
+	if t.Ownerid != userId || !t.Completed || t.Archived || t.CompletedAt == nil {
+		return false
+	}
+
+	if t.CompletedAt.After(ar.before(now)) {
+		return false
+	}
+
+	switch ar.ListId {
+	case "":
+		return true
+	case InboxListId:
+		return t.ListId == ""
+	}
+
+	return t.ListId == ar.ListId
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestArchiveRuleMatches(t *testing.T) {
+	now := time.Date(2017, 1, 10, 12, 0, 0, 0, time.UTC)
+	userId := "archive-TestArchiveRuleMatches"
+	week := now.AddDate(0, 0, -7)
+	day := now.AddDate(0, 0, -1)
+
+	todo := func(completedAt *time.Time, listId string) Todo {
+		t0 := NewTodo()
+		t0.Ownerid = userId
+		t0.Completed = completedAt != nil
+		t0.CompletedAt = completedAt
+		t0.ListId = listId
+		return t0
+	}
+
+	archived := todo(&week, "")
+	archived.Archived = true
+
+	for _, tc := range []struct {
+		rule    ArchiveRule
+		todo    Todo
+		matches bool
+	}{
+		{ArchiveRule{Days: 7}, todo(&week, ""), true},
+		{ArchiveRule{Days: 7}, todo(&day, ""), false},
+		{ArchiveRule{Days: 7}, todo(nil, ""), false},
+		{ArchiveRule{Days: 7}, archived, false},
+		{ArchiveRule{Days: 0, ListId: "l"}, todo(&day, "l"), true},
+		{ArchiveRule{Days: 0, ListId: "l"}, todo(&day, "m"), false},
+		{ArchiveRule{Days: 0, ListId: InboxListId}, todo(&day, ""), true},
+		{ArchiveRule{Days: 0, ListId: InboxListId}, todo(&day, "l"), false},
+	} {
+		if m := tc.rule.matches(userId, tc.todo, now); m != tc.matches {
+			t.Errorf("Rule %+v should match %v: %v", tc.rule, tc.matches, m)
+		}
+	}
+
+	if tc := todo(&week, ""); (ArchiveRule{Days: 1}).matches("someone", tc, now) {
+		t.Error("Rules should only archive the user's 2Dos")
+	}
+}
+
+func TestValidateArchiveRules(t *testing.T) {
+	if err := ValidateArchiveRules([]ArchiveRule{{Days: 0}, {Days: 30, ListId: "l"}}); err != nil {
+		t.Errorf("Rules should be valid: %v", err)
+	}
+
+	if err := ValidateArchiveRules([]ArchiveRule{{Days: -1}}); err == nil {
+		t.Error("Negative days should be rejected")
+	}
+
+	if err := ValidateArchiveRules(make([]ArchiveRule, MaxArchiveRules+1)); err != ErrTooManyArchiveRules {
+		t.Errorf("Too many rules should be rejected: %v", err)
+	}
+}
+
+func TestTodoFilterArchived(t *testing.T) {
+	t0 := NewTodo()
+	t0.Title = "Renew Passport"
+	t0.Note = "Bring two photos"
+
+	if !(TodoFilter{}).matches(t0) || (TodoFilter{Archived: true}).matches(t0) {
+		t.Error("Unarchived 2Dos should only pass the default filter")
+	}
+
+	t0.Archived = true
+	if (TodoFilter{}).matches(t0) || !(TodoFilter{Archived: true}).matches(t0) {
+		t.Error("Archived 2Dos should only pass the archived filter")
+	}
+
+	for search, matches := range map[string]bool{"passport": true, "PHOTOS": true, "visa": false} {
+		if m := (TodoFilter{Archived: true, Search: search}).matches(t0); m != matches {
+			t.Errorf("Search %q should match %v: %v", search, matches, m)
+		}
+	}
+}
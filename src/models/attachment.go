@@ -0,0 +1,70 @@
+package models
+
+import (
+	"errors"
+	"gopkg.in/mgo.v2/bson"
+	"strings"
+	"time"
+)
+
+// The default limits of attachments when they aren't configured.
+const (
+	DefaultMaxUploadSize int64 = 10 << 20  // 10 MiB per attachment
+	DefaultUploadQuota   int64 = 100 << 20 // 100 MiB per user
+)
+
+var AttachmentNotFoundError = errors.New("Attachment not found")
+
+var ErrAttachmentQuota = errors.New("Attachment quota exceeded")
+
+// AttachmentContentTypes are the sniffed content types which may be
+// attached to a todo.
+var AttachmentContentTypes = []string{
+	"application/pdf",
+	"image/gif",
+	"image/jpeg",
+	"image/png",
+	"image/webp",
+	"text/plain",
+}
+
+// Attachment is the metadata of a file attached to a todo. The contents
+// are kept in a BlobStore under the hex of the attachment id.
+type Attachment struct {
+	Id          bson.ObjectId `json:"id" bson:"_id"`
+	Name        string        `json:"name" bson:"name"`
+	ContentType string        `json:"content_type" bson:"content_type"`
+	Size        int64         `json:"size" bson:"size"`
+	Created     time.Time     `json:"created_date" bson:"created_date"`
+}
+
+func NewAttachment() Attachment {
+	a := Attachment{}
+	a.Id = bson.NewObjectId()
+	a.Created = time.Now()
+	return a
+}
+
+// AllowedContentType reports whether files of the content type,
+// ignoring its parameters, may be attached.
+func AllowedContentType(contentType string) bool {
+	ct := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	for _, allowed := range AttachmentContentTypes {
+		if ct == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Attachment returns the attachment of the todo with the id.
+func (t Todo) Attachment(id string) (*Attachment, bool) {
+	for _, a := range t.Attachments {
+		if a.Id.Hex() == id {
+			return &a, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"config"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mdb"
+	"os"
+	"path/filepath"
+)
+
+const BlobCollection = "blobs"
+
+// The kinds of BlobStore which may be configured.
+const (
+	GridFSBlobs = "gridfs"
+	LocalBlobs  = "local"
+)
+
+var BlobNotFoundError = errors.New("Blob not found")
+
+// BlobStore is an interface which details the requirments
+// to keep the contents of files, such as attachments, in
+// long term storage.
+type BlobStore interface {
+	Close()
+	// PutBlob stores the contents of r under the key and returns
+	// the number of bytes stored.
+	PutBlob(key string, r io.Reader) (int64, error)
+	// GetBlob opens the blob with the key for reading. The blob must
+	// be closed before the store.
+	GetBlob(key string) (io.ReadCloser, error)
+	DeleteBlob(key string) error
+}
+
+// NewBlobStore is the abstracted function that returns a BlobStore
+// implementation depending on the value of the BLOB_STORE_TYPE and
+// the configured blob_store.
+func NewBlobStore() BlobStore {
+	switch BLOB_STORE_TYPE {
+	case Test:
+		return newTestBlobStore()
+	}
+
+	c := config.GetConfig()
+	if c.BlobStore == LocalBlobs {
+		return NewLocalBlobStore(c.BlobDir)
+	}
+
+	return NewGridFSBlobStore()
+}
+
+// LocalBlobStore keeps blobs as files in a directory.
+// It implements the BlobStore interface
+type LocalBlobStore struct {
+	Dir string
+}
+
+func NewLocalBlobStore(dir string) *LocalBlobStore {
+	return &LocalBlobStore{Dir: dir}
+}
+
+func (lbs *LocalBlobStore) Close() {}
+
+// path returns the path of the blob, keys can't refer to
+// files outside of the directory.
+func (lbs *LocalBlobStore) path(key string) (string, error) {
+	if key == "" || key != filepath.Base(key) || key == "." || key == ".." {
+		return "", errors.New("Invalid blob key: " + key)
+	}
+
+	return filepath.Join(lbs.Dir, key), nil
+}
+
+// PutBlob writes the blob to a temporary file which is renamed once
+// complete so that a partial blob is never read.
+func (lbs *LocalBlobStore) PutBlob(key string, r io.Reader) (int64, error) {
+	p, err := lbs.path(key)
+	if err != nil {
+		return 0, err
+	}
+
+	err = os.MkdirAll(lbs.Dir, 0700)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := ioutil.TempFile(lbs.Dir, ".upload-")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name())
+
+	n, err := io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return n, err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return n, err
+	}
+
+	return n, os.Rename(f.Name(), p)
+}
+
+func (lbs *LocalBlobStore) GetBlob(key string) (io.ReadCloser, error) {
+	p, err := lbs.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, BlobNotFoundError
+	}
+
+	return f, err
+}
+
+func (lbs *LocalBlobStore) DeleteBlob(key string) error {
+	p, err := lbs.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(p)
+	if os.IsNotExist(err) {
+		return BlobNotFoundError
+	}
+
+	return err
+}
+
+// GridFSBlobStore keeps blobs in mongodb GridFS files.
+// It implements the BlobStore interface
+type GridFSBlobStore struct {
+	d mdb.DataStore
+}
+
+func NewGridFSBlobStore() *GridFSBlobStore {
+	gbs := GridFSBlobStore{}
+	gbs.d = mdb.NewDataStore()
+	gbs.d.Collection = BlobCollection
+	return &gbs
+}
+
+func (gbs *GridFSBlobStore) Close() {
+	gbs.d.Close()
+}
+
+func (gbs *GridFSBlobStore) PutBlob(key string, r io.Reader) (int64, error) {
+	return gbs.d.WriteFile(key, r)
+}
+
+func (gbs *GridFSBlobStore) GetBlob(key string) (io.ReadCloser, error) {
+	f, err := gbs.d.OpenFile(key)
+	if err == mdb.NotFoundError {
+		return nil, BlobNotFoundError
+	}
+
+	return f, err
+}
+
+func (gbs *GridFSBlobStore) DeleteBlob(key string) error {
+	return gbs.d.RemoveFile(key)
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLocalBlobStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "2Do_TestLocalBlobStore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	lbs := NewLocalBlobStore(dir)
+	n, err := lbs.PutBlob("key", strings.NewReader("Hello, world!"))
+	if err != nil || n != 13 {
+		t.Fatalf("Failure to put blob: %d %v", n, err)
+	}
+
+	blob, err := lbs.GetBlob("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadAll(blob)
+	blob.Close()
+	if err != nil || string(b) != "Hello, world!" {
+		t.Errorf("Incorrect blob contents: %s %v", b, err)
+	}
+
+	if err := lbs.DeleteBlob("key"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := lbs.GetBlob("key"); err != BlobNotFoundError {
+		t.Errorf("Blob should be deleted: got %v", err)
+	}
+
+	for _, key := range []string{"", "..", "../key", "dir/key"} {
+		if _, err := lbs.PutBlob(key, strings.NewReader("")); err == nil {
+			t.Errorf("Key should be invalid: %q", key)
+		}
+	}
+}
+
+func TestAllowedContentType(t *testing.T) {
+	if !AllowedContentType("text/plain; charset=utf-8") {
+		t.Error("Plain text should be allowed")
+	}
+
+	if AllowedContentType("application/octet-stream") {
+		t.Error("Binary files should not be allowed")
+	}
+}
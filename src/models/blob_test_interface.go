@@ -0,0 +1,64 @@
+package models
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"log"
+	"sync"
+)
+
+var blobMap = make(map[string][]byte)
+var blobMutex sync.Mutex
+
+// TestBlobStore implements the BlobStore interface
+type TestBlobStore struct {
+	blobs *map[string][]byte
+}
+
+func newTestBlobStore() *TestBlobStore {
+	t := TestBlobStore{}
+	t.blobs = &blobMap
+	return &t
+}
+
+func (tbs *TestBlobStore) Close() {
+	log.Println("Closing TestBlobStore")
+}
+
+func (tbs *TestBlobStore) PutBlob(key string, r io.Reader) (int64, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return int64(len(b)), err
+	}
+
+	blobMutex.Lock()
+	defer blobMutex.Unlock()
+	(*tbs.blobs)[key] = b
+
+	return int64(len(b)), nil
+}
+
+func (tbs *TestBlobStore) GetBlob(key string) (io.ReadCloser, error) {
+	blobMutex.Lock()
+	defer blobMutex.Unlock()
+
+	b, ok := (*tbs.blobs)[key]
+	if !ok {
+		return nil, BlobNotFoundError
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (tbs *TestBlobStore) DeleteBlob(key string) error {
+	blobMutex.Lock()
+	defer blobMutex.Unlock()
+
+	if _, ok := (*tbs.blobs)[key]; !ok {
+		return BlobNotFoundError
+	}
+
+	delete(*tbs.blobs, key)
+	return nil
+}
@@ -0,0 +1,50 @@
+package models
+
+// BoardColumn holds the todos in a state of a workflow by position.
+type BoardColumn struct {
+	State    string `json:"state"`
+	Terminal bool   `json:"terminal,omitempty"`
+	WIPLimit int    `json:"wip_limit,omitempty"`
+	Count    int    `json:"count"`
+	Todos    []Todo `json:"todos"`
+}
+
+// Board shows the todos of a list in a column per workflow state.
+type Board struct {
+	ListId  string        `json:"list_id"`
+	Columns []BoardColumn `json:"columns"`
+}
+
+// NewBoard groups the todos of the list, in their order, by their
+// state in the workflow.
+func NewBoard(listId string, wf Workflow, ts []Todo) Board {
+	b := Board{ListId: listId, Columns: make([]BoardColumn, len(wf.States))}
+
+	columns := make(map[string]*BoardColumn, len(wf.States))
+	for i, s := range wf.States {
+		b.Columns[i] = BoardColumn{State: s.Name, Terminal: s.Terminal, WIPLimit: s.WIPLimit, Todos: make([]Todo, 0)}
+		columns[s.Name] = &b.Columns[i]
+	}
+
+	for _, t := range ts {
+		t.Status = wf.StatusOf(t)
+		c := columns[t.Status]
+		c.Todos = append(c.Todos, t)
+		c.Count++
+	}
+
+	return b
+}
+
+// CountInState returns the number of the todos in the state, leaving
+// out the todo with the id exceptId.
+func (wf Workflow) CountInState(ts []Todo, state, exceptId string) int {
+	n := 0
+	for _, t := range ts {
+		if t.Id != exceptId && wf.StatusOf(t) == state {
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,40 @@
+package models
+
+import "testing"
+
+func TestNewBoard(t *testing.T) {
+	wf := teamWorkflow()
+	wf.States[2].WIPLimit = 2
+	if err := wf.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := []Todo{NewTodo(), NewTodo(), NewTodo(), NewTodo()}
+	ts[0].Status = "review"
+	ts[1].Status = "todo"
+	ts[2].Status = "review"
+	ts[3].Status = "legacy" // not a state of the workflow
+
+	b := NewBoard("l", wf, ts)
+	if len(b.Columns) != len(wf.States) {
+		t.Fatalf("Expected a column per state: %+v", b.Columns)
+	}
+
+	todo, review := b.Columns[0], b.Columns[2]
+	if todo.Count != 2 || todo.Todos[0].Id != ts[1].Id || todo.Todos[1].Id != ts[3].Id {
+		t.Errorf("Incorrect todo column: %+v", todo)
+	}
+	if review.Count != 2 || review.WIPLimit != 2 || review.Todos[0].Id != ts[0].Id || review.Todos[1].Id != ts[2].Id {
+		t.Errorf("Incorrect review column: %+v", review)
+	}
+	if !b.Columns[4].Terminal || b.Columns[4].Todos == nil {
+		t.Errorf("Empty columns should have no todos: %+v", b.Columns[4])
+	}
+
+	if n := wf.CountInState(ts, "review", ts[0].Id); n != 1 {
+		t.Errorf("Expected 1 other todo in review: %d", n)
+	}
+	if wf.WIPLimit("review") != 2 || wf.WIPLimit("todo") != 0 {
+		t.Error("Incorrect WIP limits")
+	}
+}
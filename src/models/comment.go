@@ -0,0 +1,156 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/mgo.v2/bson"
+	"mdb"
+	"strings"
+	"time"
+)
+
+const CommentCollection = "comments"
+
+const MaxCommentLength = 10000
+
+var CommentNotFoundError = mdb.NotFoundError
+
+// Comment is a message in the discussion thread of a todo.
+type Comment struct {
+	Id       bson.ObjectId `json:"id" bson:"_id,omitempty"`
+	TodoId   string        `json:"todo_id" bson:"todo_id"`
+	Authorid string        `json:"author_id" bson:"authorid"`
+	Body     string        `json:"body" bson:"body"`
+	Created  time.Time     `json:"created_date" bson:"created_date"`
+	Edited   *time.Time    `json:"edited_date,omitempty" bson:"edited_date,omitempty"`
+}
+
+func NewComment() Comment {
+	c := Comment{}
+	c.Id = bson.NewObjectId()
+	c.Created = time.Now()
+	return c
+}
+
+// ValidateCommentBody trims the body of a comment and checks that it
+// isn't empty or too long.
+func ValidateCommentBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", errors.New("Comment body must not be empty")
+	}
+
+	if len(body) > MaxCommentLength {
+		return "", fmt.Errorf("Comment body must not exceed %d bytes", MaxCommentLength)
+	}
+
+	return body, nil
+}
+
+// CommentStorage is an interface which details the requirments
+// to interface with retrieval and insertion of comments
+// into long term storage.
+type CommentStorage interface {
+	Close()
+	GetCommentById(id string) (*Comment, error)
+	// GetCommentsForTodoId returns the comments of a todo oldest first,
+	// skipping the first skip. A limit of 0 returns all the remaining.
+	GetCommentsForTodoId(todoId string, skip, limit int) ([]Comment, error)
+	InsertComment(c Comment) error
+	// EditComment replaces the body of a comment by the author.
+	EditComment(id, authorId, body string, edited time.Time) error
+	DeleteComment(id string) error
+	DeleteCommentsForTodoId(todoId string) error
+}
+
+// NewCommentStorage is the abstracted function that returns
+// a CommentStorage implementation depending on the value of
+// the COMMENT_STORE_TYPE.
+func NewCommentStorage() CommentStorage {
+	switch COMMENT_STORE_TYPE {
+	case Regular:
+		return NewCommentDataStore()
+	case Test:
+		return newTestCommentStorage()
+	}
+
+	return NewCommentDataStore()
+}
+
+// CommentDataStore is a wrapper struct for DataStore.
+// It implements the CommentStorage interface
+type CommentDataStore struct {
+	d mdb.DataStore
+}
+
+func NewCommentDataStore() *CommentDataStore {
+	cds := CommentDataStore{}
+	cds.d = mdb.NewDataStore()
+	cds.d.Collection = CommentCollection
+	return &cds
+}
+
+func (cds *CommentDataStore) Close() {
+	cds.d.Close()
+}
+
+func (cds *CommentDataStore) GetCommentById(id string) (*Comment, error) {
+	c := Comment{}
+
+	raw, err := cds.d.GetObjectById(objectId(id))
+	if err != nil {
+		return nil, err
+	}
+
+	err = raw.Unmarshal(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func (cds *CommentDataStore) GetCommentsForTodoId(todoId string, skip, limit int) ([]Comment, error) {
+	cs := make([]Comment, 0)
+
+	raws, err := cds.d.GetObjectsForQueryPage(bson.M{"todo_id": todoId}, []string{"created_date", "_id"}, skip, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, raw := range raws {
+		c := Comment{}
+		err := raw.Unmarshal(&c)
+		if err != nil {
+			return nil, err
+		}
+		cs = append(cs, c)
+	}
+
+	return cs, nil
+}
+
+func (cds *CommentDataStore) InsertComment(c Comment) error {
+	return cds.d.InsertObject(c)
+}
+
+func (cds *CommentDataStore) EditComment(id, authorId, body string, edited time.Time) error {
+	params := make(map[string]interface{})
+	params["id"] = objectId(id)
+	params["authorid"] = authorId
+
+	err := cds.d.ModifyObjectForId(params, map[string]interface{}{"body": body, "edited_date": edited})
+	if err == mdb.NotFoundError {
+		return CommentNotFoundError
+	}
+
+	return err
+}
+
+func (cds *CommentDataStore) DeleteComment(id string) error {
+	return cds.d.DeleteObjectForSelector(map[string]interface{}{"id": objectId(id)})
+}
+
+func (cds *CommentDataStore) DeleteCommentsForTodoId(todoId string) error {
+	return cds.d.DeleteObjectsForQuery(bson.M{"todo_id": todoId})
+}
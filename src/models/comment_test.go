@@ -0,0 +1,19 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateCommentBody(t *testing.T) {
+	body, err := ValidateCommentBody("  Looks good to me \n")
+	if err != nil || body != "Looks good to me" {
+		t.Errorf("Incorrect comment body: %q %v", body, err)
+	}
+
+	for _, invalid := range []string{"", " \n\t", strings.Repeat("a", MaxCommentLength+1)} {
+		if _, err := ValidateCommentBody(invalid); err == nil {
+			t.Errorf("Comment body should be invalid: %q", invalid)
+		}
+	}
+}
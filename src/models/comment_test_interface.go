@@ -0,0 +1,99 @@
+package models
+
+import (
+	"log"
+	"sort"
+	"time"
+)
+
+var commentMap = make(map[string]Comment)
+
+// TestCommentStorage implements the CommentStorage interface
+type TestCommentStorage struct {
+	comments *map[string]Comment
+}
+
+func newTestCommentStorage() *TestCommentStorage {
+	t := TestCommentStorage{}
+	t.comments = &commentMap
+	return &t
+}
+
+func (tcs *TestCommentStorage) Close() {
+	log.Println("Closing TestCommentStorage")
+}
+
+func (tcs *TestCommentStorage) GetCommentById(id string) (*Comment, error) {
+	c, ok := (*tcs.comments)[id]
+	if !ok {
+		return nil, CommentNotFoundError
+	}
+
+	return &c, nil
+}
+
+func (tcs *TestCommentStorage) GetCommentsForTodoId(todoId string, skip, limit int) ([]Comment, error) {
+	cs := make([]Comment, 0)
+	for _, c := range *tcs.comments {
+		if c.TodoId == todoId {
+			cs = append(cs, c)
+		}
+	}
+
+	sort.Slice(cs, func(i, j int) bool {
+		if cs[i].Created.Equal(cs[j].Created) {
+			return cs[i].Id < cs[j].Id
+		}
+		return cs[i].Created.Before(cs[j].Created)
+	})
+
+	if skip > len(cs) {
+		skip = len(cs)
+	}
+	cs = cs[skip:]
+
+	if limit > 0 && limit < len(cs) {
+		cs = cs[:limit]
+	}
+
+	return cs, nil
+}
+
+func (tcs *TestCommentStorage) InsertComment(c Comment) error {
+	(*tcs.comments)[c.Id.Hex()] = c
+	return nil
+}
+
+func (tcs *TestCommentStorage) EditComment(id, authorId, body string, edited time.Time) error {
+	comments := *tcs.comments
+	c, ok := comments[id]
+	if !ok || c.Authorid != authorId {
+		return CommentNotFoundError
+	}
+
+	c.Body = body
+	c.Edited = &edited
+	comments[id] = c
+	return nil
+}
+
+func (tcs *TestCommentStorage) DeleteComment(id string) error {
+	comments := *tcs.comments
+	if _, ok := comments[id]; !ok {
+		return CommentNotFoundError
+	}
+
+	delete(comments, id)
+	return nil
+}
+
+func (tcs *TestCommentStorage) DeleteCommentsForTodoId(todoId string) error {
+	comments := *tcs.comments
+	for id, c := range comments {
+		if c.TodoId == todoId {
+			delete(comments, id)
+		}
+	}
+
+	return nil
+}
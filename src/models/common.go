@@ -1,7 +1,17 @@
 package models
 
+import "gopkg.in/mgo.v2/bson"
+
 var TODO_STORE_TYPE StoreType = Regular
 var USER_STORE_TYPE StoreType = Regular
+var LIST_STORE_TYPE StoreType = Regular
+var REMINDER_STORE_TYPE StoreType = Regular
+var NOTIFICATION_STORE_TYPE StoreType = Regular
+var BLOB_STORE_TYPE StoreType = Regular
+var COMMENT_STORE_TYPE StoreType = Regular
+var TIME_ENTRY_STORE_TYPE StoreType = Regular
+var TEMPLATE_STORE_TYPE StoreType = Regular
+var SMART_LIST_STORE_TYPE StoreType = Regular
 
 // Used to set the the store type for testing purposes.
 type StoreType int
@@ -10,3 +20,15 @@ const (
 	Regular StoreType = 0
 	Test    StoreType = 1
 )
+
+// objectId returns the ObjectId written as the hex id, the _id of the
+// objects of every collection but todos, which are keyed by public ids,
+// see NewPublicId. Ids which aren't ObjectIds are returned as they are
+// so that they match no object.
+func objectId(id string) interface{} {
+	if !bson.IsObjectIdHex(id) {
+		return id
+	}
+
+	return bson.ObjectIdHex(id)
+}
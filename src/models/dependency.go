@@ -0,0 +1,144 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrDependencyCycle = errors.New("Dependency would create a cycle")
+
+// BlockersOpen returns the blockers of the todo which are not yet
+// completed. Blockers which no longer exist are ignored.
+func BlockersOpen(tds TodoStorage, t Todo) ([]Todo, error) {
+	open := make([]Todo, 0)
+	if len(t.BlockedBy) == 0 {
+		return open, nil
+	}
+
+	blockers, err := tds.GetTodosByIds(t.BlockedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, b := range blockers {
+		if !b.Completed {
+			open = append(open, b)
+		}
+	}
+
+	return open, nil
+}
+
+// MarkBlocked sets the computed Blocked flag of the todos.
+func MarkBlocked(tds TodoStorage, ts []Todo) error {
+	ids := make([]string, 0)
+	for _, t := range ts {
+		ids = append(ids, t.BlockedBy...)
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	blockers, err := tds.GetTodosByIds(ids)
+	if err != nil {
+		return err
+	}
+
+	open := make(map[string]bool)
+	for _, b := range blockers {
+		open[b.Id] = !b.Completed
+	}
+
+	for i := range ts {
+		ts[i].Blocked = false
+		for _, id := range ts[i].BlockedBy {
+			if open[id] {
+				ts[i].Blocked = true
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// CheckDependencies returns ErrDependencyCycle if the todo with the id
+// being blocked by the todos blockedBy would create a cycle i.e. if the
+// todo is, directly or transitively, a blocker of one of them.
+func CheckDependencies(tds TodoStorage, todoId string, blockedBy []string) error {
+	seen := make(map[string]bool)
+	next := blockedBy
+
+	for len(next) > 0 {
+		ids := make([]string, 0, len(next))
+		for _, id := range next {
+			if id == todoId {
+				return ErrDependencyCycle
+			}
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+
+		if len(ids) == 0 {
+			break
+		}
+
+		blockers, err := tds.GetTodosByIds(ids)
+		if err != nil {
+			return err
+		}
+
+		next = nil
+		for _, b := range blockers {
+			next = append(next, b.BlockedBy...)
+		}
+	}
+
+	return nil
+}
+
+// blockedByChange accepts an array of todo ids, as decoded from json,
+// and returns them without duplicates.
+func blockedByChange(v interface{}) (interface{}, error) {
+	vs, ok := v.([]interface{})
+	if !ok {
+		if ids, isStrings := v.([]string); isStrings {
+			vs = make([]interface{}, len(ids))
+			for i, id := range ids {
+				vs[i] = id
+			}
+		} else {
+			return nil, errors.New("Value is not an array of strings")
+		}
+	}
+
+	ids := make([]string, 0, len(vs))
+	seen := make(map[string]bool)
+	for _, v := range vs {
+		id, ok := v.(string)
+		if !ok || !IsPublicId(id) {
+			return nil, fmt.Errorf("Invalid 2Do id: %v", v)
+		}
+
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+// NormalizeBlockedBy validates the ids of the blockers of a todo
+// and removes duplicates.
+func NormalizeBlockedBy(ids []string) ([]string, error) {
+	v, err := blockedByChange(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.([]string), nil
+}
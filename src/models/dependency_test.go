@@ -0,0 +1,68 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestCheckDependencies(t *testing.T) {
+	tds := newTestTodoStorage()
+	a, b, c := NewTodo(), NewTodo(), NewTodo()
+	b.BlockedBy = []string{a.Id}
+	c.BlockedBy = []string{b.Id}
+	for _, t0 := range []Todo{a, b, c} {
+		tds.InsertTodo(t0)
+	}
+
+	if err := CheckDependencies(tds, a.Id, []string{c.Id}); err != ErrDependencyCycle {
+		t.Errorf("Transitive cycle should be rejected: %v", err)
+	}
+
+	if err := CheckDependencies(tds, a.Id, []string{a.Id}); err != ErrDependencyCycle {
+		t.Errorf("Self dependency should be rejected: %v", err)
+	}
+
+	if err := CheckDependencies(tds, c.Id, []string{a.Id}); err != nil {
+		t.Errorf("Dependency should be accepted: %v", err)
+	}
+}
+
+func TestMarkBlocked(t *testing.T) {
+	tds := newTestTodoStorage()
+	a, b, c := NewTodo(), NewTodo(), NewTodo()
+	b.Completed = true
+	c.BlockedBy = []string{a.Id, b.Id}
+	for _, t0 := range []Todo{a, b, c} {
+		tds.InsertTodo(t0)
+	}
+
+	ts := []Todo{a, c}
+	if err := MarkBlocked(tds, ts); err != nil {
+		t.Fatal(err)
+	}
+	if ts[0].Blocked || !ts[1].Blocked {
+		t.Errorf("Incorrect blocked flags: %v %v", ts[0].Blocked, ts[1].Blocked)
+	}
+
+	tds.ModifyTodo(a.Id, map[string]interface{}{"completed": true})
+	open, _ := BlockersOpen(tds, c)
+	if len(open) != 0 {
+		t.Errorf("Completed blockers should not be open: %v", open)
+	}
+
+	tds.RemoveBlocker(a.Id)
+	if c0, _ := tds.GetTodoById(c.Id); len(c0.BlockedBy) != 1 {
+		t.Errorf("Blocker should be removed: %v", c0.BlockedBy)
+	}
+}
+
+func TestNormalizeBlockedBy(t *testing.T) {
+	id := NewTodo().Id
+	ids, err := NormalizeBlockedBy([]string{id, id})
+	if err != nil || len(ids) != 1 {
+		t.Errorf("Duplicate blockers should be removed: %v %v", ids, err)
+	}
+
+	if _, err := NormalizeBlockedBy([]string{"not an id"}); err == nil {
+		t.Error("Invalid blocker id should be rejected")
+	}
+}
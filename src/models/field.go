@@ -0,0 +1,336 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Types of custom fields.
+const (
+	FieldText   = "text"
+	FieldNumber = "number"
+	FieldDate   = "date" // A day, stored as midnight UTC
+	FieldEnum   = "enum" // One of the options of the field
+	FieldUser   = "user" // The id of a user
+)
+
+// Operators of field conditions, see FieldCondition.
+const (
+	FieldEq  = "eq"
+	FieldGt  = "gt"
+	FieldGte = "gte"
+	FieldLt  = "lt"
+	FieldLte = "lte"
+)
+
+const (
+	// MaxCustomFields is the number of custom fields a list may define.
+	MaxCustomFields = 20
+	// MaxFieldTextLength is the length of the values of text fields.
+	MaxFieldTextLength = 1000
+)
+
+var ErrUnknownField = errors.New("Unknown field")
+
+var fieldKeyRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// CustomField defines a field which the todos of a list may have a
+// value for e.g. the customer or the estimate. Values are kept in
+// Todo.Fields by the key of the field.
+type CustomField struct {
+	Key     string   `json:"key" bson:"key"`
+	Name    string   `json:"name" bson:"name"`
+	Type    string   `json:"type" bson:"type"`
+	Options []string `json:"options,omitempty" bson:"options,omitempty"`
+}
+
+// ValidateCustomFields normalizes the fields of a list and checks that
+// their keys are unique and that only enums, and all of them, have
+// options. The name of a field defaults to its key.
+func ValidateCustomFields(fields []CustomField) error {
+	if len(fields) > MaxCustomFields {
+		return fmt.Errorf("Lists may have at most %d custom fields", MaxCustomFields)
+	}
+
+	seen := make(map[string]bool, len(fields))
+	for i := range fields {
+		f := &fields[i]
+		f.Name = strings.TrimSpace(f.Name)
+		if !fieldKeyRegexp.MatchString(f.Key) {
+			return fmt.Errorf("Field keys must be lowercase letters, digits or _ and start with a letter: %s", f.Key)
+		}
+		if seen[f.Key] {
+			return fmt.Errorf("Duplicate field: %s", f.Key)
+		}
+		seen[f.Key] = true
+
+		if f.Name == "" {
+			f.Name = f.Key
+		}
+
+		switch f.Type {
+		case FieldText, FieldNumber, FieldDate, FieldUser:
+			if len(f.Options) > 0 {
+				return fmt.Errorf("Only enum fields have options: %s", f.Key)
+			}
+		case FieldEnum:
+			if len(f.Options) == 0 {
+				return fmt.Errorf("Enum fields must have options: %s", f.Key)
+			}
+			options := make(map[string]bool, len(f.Options))
+			for _, o := range f.Options {
+				if strings.TrimSpace(o) == "" || options[o] {
+					return fmt.Errorf("Options of %s must be unique and not empty", f.Key)
+				}
+				options[o] = true
+			}
+		default:
+			return fmt.Errorf("Field type of %s must be one of %s, %s, %s, %s or %s",
+				f.Key, FieldText, FieldNumber, FieldDate, FieldEnum, FieldUser)
+		}
+	}
+
+	return nil
+}
+
+// FindField returns the field with the key, nil if there is none.
+func FindField(fields []CustomField, key string) *CustomField {
+	for i := range fields {
+		if fields[i].Key == key {
+			return &fields[i]
+		}
+	}
+
+	return nil
+}
+
+// Value checks that v, as decoded from json or read from storage, is
+// a value of the field and returns it in its stored type: a string,
+// a float64 for numbers or a time.Time for dates.
+func (f CustomField) Value(v interface{}) (interface{}, error) {
+	switch f.Type {
+	case FieldNumber:
+		switch n := v.(type) {
+		case float64:
+			return n, nil
+		case int:
+			return float64(n), nil
+		case int64:
+			return float64(n), nil
+		}
+		return nil, fmt.Errorf("%s must be a number", f.Key)
+	case FieldDate:
+		switch d := v.(type) {
+		case time.Time:
+			return d.UTC(), nil
+		case string:
+			return f.Parse(d)
+		}
+		return nil, fmt.Errorf("%s must be a date", f.Key)
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a string", f.Key)
+	}
+
+	return f.Parse(s)
+}
+
+// Parse returns the value of the field written as s e.g. in a query.
+// Dates are written as 2006-01-02 or in RFC 3339, of which only the
+// date is kept.
+func (f CustomField) Parse(s string) (interface{}, error) {
+	switch f.Type {
+	case FieldNumber:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number", f.Key)
+		}
+		return n, nil
+	case FieldDate:
+		d, err := time.Parse(DateFormat, s)
+		if err != nil {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be a date e.g. 2006-01-02", f.Key)
+			}
+			d = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		}
+		return d, nil
+	case FieldEnum:
+		for _, o := range f.Options {
+			if o == s {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("%s must be one of: %s", f.Key, strings.Join(f.Options, ", "))
+	case FieldText:
+		if len(s) > MaxFieldTextLength {
+			return nil, fmt.Errorf("%s must be at most %d characters", f.Key, MaxFieldTextLength)
+		}
+	}
+
+	return s, nil
+}
+
+// FieldValues applies the changes to the field values of a todo and
+// checks them against the fields of its list. A nil change removes
+// the value. Values which no longer fit the fields, e.g. those of the
+// todo's former list, are dropped while changes must fit them.
+func FieldValues(fields []CustomField, values, changes map[string]interface{}) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	for k, v := range values {
+		if f := FindField(fields, k); f != nil {
+			if value, err := f.Value(v); err == nil {
+				result[k] = value
+			}
+		}
+	}
+
+	for k, v := range changes {
+		f := FindField(fields, k)
+		if f == nil {
+			return nil, fmt.Errorf("%s: %s", ErrUnknownField, k)
+		}
+
+		if v == nil {
+			delete(result, k)
+			continue
+		}
+
+		value, err := f.Value(v)
+		if err != nil {
+			return nil, err
+		}
+		result[k] = value
+	}
+
+	if len(result) == 0 {
+		return nil, nil
+	}
+
+	return result, nil
+}
+
+// FieldCondition narrows todos down to those whose value of the field
+// with the key compares to Value by Op, one of FieldEq, FieldGt,
+// FieldGte, FieldLt or FieldLte.
+type FieldCondition struct {
+	Key   string
+	Op    string
+	Value interface{}
+}
+
+// ParseFieldCondition returns the condition on the field for the
+// value written as s with the operator op, FieldEq when empty.
+func ParseFieldCondition(f CustomField, op, s string) (FieldCondition, error) {
+	c := FieldCondition{Key: f.Key, Op: op}
+	switch op {
+	case "":
+		c.Op = FieldEq
+	case FieldEq, FieldGt, FieldGte, FieldLt, FieldLte:
+	default:
+		return c, fmt.Errorf("Field operator must be one of %s, %s, %s, %s or %s", FieldEq, FieldGt, FieldGte, FieldLt, FieldLte)
+	}
+
+	var err error
+	c.Value, err = f.Parse(s)
+	return c, err
+}
+
+// query returns the mongodb condition on the field.
+func (c FieldCondition) query() interface{} {
+	if c.Op == FieldEq {
+		return c.Value
+	}
+
+	return map[string]interface{}{"$" + c.Op: c.Value}
+}
+
+// matches reports whether the todo's value of the field satisfies the
+// condition.
+func (c FieldCondition) matches(t Todo) bool {
+	v, ok := t.Fields[c.Key]
+	if !ok {
+		return false
+	}
+
+	cmp, ok := compareFieldValues(v, c.Value)
+	if !ok {
+		return false
+	}
+
+	switch c.Op {
+	case FieldGt:
+		return cmp > 0
+	case FieldGte:
+		return cmp >= 0
+	case FieldLt:
+		return cmp < 0
+	case FieldLte:
+		return cmp <= 0
+	}
+
+	return cmp == 0
+}
+
+// compareFieldValues compares two values of the same field, it reports
+// false when their types differ.
+func compareFieldValues(a, b interface{}) (int, bool) {
+	switch x := a.(type) {
+	case float64:
+		y, ok := b.(float64)
+		switch {
+		case !ok:
+			return 0, false
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	case time.Time:
+		y, ok := b.(time.Time)
+		switch {
+		case !ok:
+			return 0, false
+		case x.Before(y):
+			return -1, true
+		case x.After(y):
+			return 1, true
+		}
+		return 0, true
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(x, y), true
+	}
+
+	return 0, false
+}
+
+// SortByField orders the todos by their value of the field with the
+// key, descending when desc. Todos without a value come last.
+func SortByField(ts []Todo, key string, desc bool) {
+	sort.SliceStable(ts, func(i, j int) bool {
+		a, aok := ts[i].Fields[key]
+		b, bok := ts[j].Fields[key]
+		if !aok || !bok {
+			return aok && !bok
+		}
+
+		cmp, ok := compareFieldValues(a, b)
+		if desc {
+			cmp = -cmp
+		}
+		return ok && cmp < 0
+	})
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func teamFields() []CustomField {
+	return []CustomField{
+		{Key: "customer", Type: FieldText},
+		{Key: "estimate", Type: FieldNumber},
+		{Key: "deadline", Type: FieldDate},
+		{Key: "size", Type: FieldEnum, Options: []string{"S", "M", "L"}},
+		{Key: "reviewer", Type: FieldUser},
+	}
+}
+
+func TestValidateCustomFields(t *testing.T) {
+	fields := teamFields()
+	if err := ValidateCustomFields(fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields[0].Name != "customer" {
+		t.Errorf("Name should default to the key: %q", fields[0].Name)
+	}
+
+	invalid := [][]CustomField{
+		{{Key: "Customer", Type: FieldText}},
+		{{Key: "a", Type: FieldText}, {Key: "a", Type: FieldNumber}},
+		{{Key: "a", Type: "color"}},
+		{{Key: "a", Type: FieldEnum}},
+		{{Key: "a", Type: FieldEnum, Options: []string{"x", "x"}}},
+		{{Key: "a", Type: FieldText, Options: []string{"x"}}},
+	}
+	for _, fs := range invalid {
+		if err := ValidateCustomFields(fs); err == nil {
+			t.Errorf("Expected an error for: %+v", fs)
+		}
+	}
+}
+
+func TestFieldValues(t *testing.T) {
+	fields := teamFields()
+
+	values, err := FieldValues(fields, nil, map[string]interface{}{
+		"customer": "Acme", "estimate": 3.5, "deadline": "2024-02-29", "size": "M",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := values["deadline"]; d != time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC) {
+		t.Errorf("Dates should be stored as midnight UTC: %v", d)
+	}
+
+	values, err = FieldValues(fields, values, map[string]interface{}{"customer": nil, "estimate": 5.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := values["customer"]; ok || values["estimate"] != 5.0 || values["size"] != "M" {
+		t.Errorf("Incorrect values after changes: %v", values)
+	}
+
+	invalid := []map[string]interface{}{
+		{"estimate": "3"},
+		{"deadline": "tomorrow"},
+		{"size": "XL"},
+		{"color": "red"},
+		{"reviewer": 7.0},
+	}
+	for _, changes := range invalid {
+		if _, err := FieldValues(fields, nil, changes); err == nil {
+			t.Errorf("Expected an error for: %v", changes)
+		}
+	}
+
+	// Values which don't fit the fields of another list are dropped
+	other := []CustomField{{Key: "size", Type: FieldNumber}, {Key: "customer", Type: FieldText}}
+	values, err = FieldValues(other, map[string]interface{}{"size": "M", "customer": "Acme", "estimate": 5.0}, nil)
+	if err != nil || len(values) != 1 || values["customer"] != "Acme" {
+		t.Errorf("Incorrect values after moving: %v %v", values, err)
+	}
+}
+
+func TestFieldConditions(t *testing.T) {
+	fields := teamFields()
+	ts := []Todo{NewTodo(), NewTodo(), NewTodo()}
+	ts[0].Fields = map[string]interface{}{"estimate": 8.0, "customer": "Acme"}
+	ts[1].Fields = map[string]interface{}{"estimate": 1.0}
+	// ts[2] has no values
+
+	c, err := ParseFieldCondition(fields[1], FieldLt, "5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.matches(ts[0]) || !c.matches(ts[1]) || c.matches(ts[2]) {
+		t.Error("Incorrect matches for estimate < 5")
+	}
+
+	c, _ = ParseFieldCondition(fields[0], "", "Acme")
+	if !c.matches(ts[0]) || c.matches(ts[1]) {
+		t.Error("Incorrect matches for customer = Acme")
+	}
+
+	if _, err := ParseFieldCondition(fields[1], "like", "5"); err == nil {
+		t.Error("Expected an error for an unknown operator")
+	}
+	if _, err := ParseFieldCondition(fields[1], FieldGt, "five"); err == nil {
+		t.Error("Expected an error for a value which isn't a number")
+	}
+
+	SortByField(ts, "estimate", false)
+	if ts[0].Fields["estimate"] != 1.0 || ts[1].Fields["estimate"] != 8.0 || ts[2].Fields != nil {
+		t.Errorf("Incorrect ascending order: %v %v %v", ts[0].Fields, ts[1].Fields, ts[2].Fields)
+	}
+
+	SortByField(ts, "estimate", true)
+	if ts[0].Fields["estimate"] != 8.0 || ts[2].Fields != nil {
+		t.Error("Todos without a value should be last when descending")
+	}
+}
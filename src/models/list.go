@@ -0,0 +1,251 @@
+package models
+
+import (
+	"errors"
+	"gopkg.in/mgo.v2/bson"
+	"mdb"
+	"time"
+)
+
+const ListCollection = "lists"
+
+var ListNotFoundError = mdb.NotFoundError
+
+// List groups a user's todos together e.g. "Work" or "Groceries".
+// Todos which are not part of any list belong to the inbox.
+type List struct {
+	Id      bson.ObjectId `json:"id" bson:"_id,omitempty"`
+	Name    string        `json:"name" bson:"name"`
+	Created time.Time     `json:"created_date" bson:"created_date,omitempty"`
+	Ownerid string        `json:"-" bson:"ownerid"`
+	Shares  []Share       `json:"shares,omitempty" bson:"shares,omitempty"`
+
+	// Workflow defines the states of the todos of the list, todos of
+	// lists without one are only open or completed.
+	Workflow *Workflow `json:"workflow,omitempty" bson:"workflow,omitempty"`
+
+	// Fields are the custom fields which the todos of the list may
+	// have values for.
+	Fields []CustomField `json:"fields,omitempty" bson:"fields,omitempty"`
+}
+
+func NewList() List {
+	l := List{}
+	l.Id = bson.NewObjectId()
+	return l
+}
+
+// ListStorage is an interface which details the requirments
+// to interface with retrieval and insertion of lists
+// into long term storage. Whether a user may access a list is
+// decided by the authz package, not by the storage.
+type ListStorage interface {
+	Close()
+	GetListById(id string) (*List, error)
+	// GetListsForUserId returns the lists owned by or shared with the user.
+	GetListsForUserId(id string) ([]List, error)
+	InsertList(l List) error
+	ModifyList(listId string, changes map[string]interface{}) error
+	SetListShares(listId string, shares []Share) error
+	// SetListWorkflow replaces the workflow of the list, nil removes it.
+	SetListWorkflow(listId string, wf *Workflow) error
+	// SetListFields replaces the custom fields of the list.
+	SetListFields(listId string, fields []CustomField) error
+	// LockList claims the list for the holder until the lease expires.
+	// It reports false when another holder's lease hasn't expired.
+	LockList(listId, holder string, now time.Time, lease time.Duration) (bool, error)
+	// UnlockList releases the claim of the holder on the list.
+	UnlockList(listId, holder string) error
+	DeleteList(id string) error
+}
+
+// NewListStorage is the abstracted function that returns
+// a ListStorage implementation depending on the value of
+// the LIST_STORE_TYPE.
+func NewListStorage() ListStorage {
+	switch LIST_STORE_TYPE {
+	case Regular:
+		return NewListDataStore()
+	case Test:
+		return newTestListStorage()
+	}
+
+	return NewListDataStore()
+}
+
+// ListDataStore is a wrapper struct for DataStore.
+// It implements the ListStorage interface
+type ListDataStore struct {
+	d mdb.DataStore
+}
+
+func NewListDataStore() *ListDataStore {
+	lds := ListDataStore{}
+	lds.d = mdb.NewDataStore()
+	lds.d.Collection = ListCollection
+	return &lds
+}
+
+func (lds *ListDataStore) Close() {
+	lds.d.Close()
+}
+
+func (lds *ListDataStore) SetDB(db string) {
+	lds.d.Database = db
+}
+
+func (lds *ListDataStore) GetDB() string {
+	return lds.d.Database
+}
+
+func (lds *ListDataStore) SetCollection(coll string) {
+	lds.d.Collection = coll
+}
+
+func (lds *ListDataStore) GetCollection() string {
+	return lds.d.Collection
+}
+
+func (lds *ListDataStore) GetListById(id string) (*List, error) {
+	l := List{}
+
+	raw, err := lds.d.GetObjectById(objectId(id))
+	if err != nil {
+		return nil, err
+	}
+
+	err = raw.Unmarshal(&l)
+	if err != nil {
+		return nil, err
+	}
+
+	return &l, nil
+}
+
+func (lds *ListDataStore) GetListsForUserId(id string) ([]List, error) {
+	ls := make([]List, 0)
+	query := bson.M{"$or": []bson.M{{"ownerid": id}, {"shares.user_id": id}}}
+
+	raws, err := lds.d.GetObjectsForQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, raw := range raws {
+		l := List{}
+		err := raw.Unmarshal(&l)
+		if err != nil {
+			return nil, err
+		}
+		ls = append(ls, l)
+	}
+
+	return ls, nil
+}
+
+func (lds *ListDataStore) InsertList(l List) error {
+	return lds.d.InsertObject(l)
+}
+
+func (lds *ListDataStore) ModifyList(listId string, changes map[string]interface{}) error {
+	params := make(map[string]interface{})
+	params["id"] = objectId(listId)
+
+	// See TodoDataStore.ModifyTodo for why the keys are whitelisted.
+	for k, v := range changes {
+		if k != "name" {
+			delete(changes, k)
+			continue
+		}
+
+		if _, ok := v.(string); !ok {
+			return errors.New("Incorrect format for key: " + k)
+		}
+	}
+
+	err := lds.d.ModifyObjectForId(params, changes)
+	if err != nil {
+		if err == mdb.NotFoundError {
+			return ListNotFoundError
+		} else {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetListShares replaces the shares of the list.
+func (lds *ListDataStore) SetListShares(listId string, shares []Share) error {
+	params := make(map[string]interface{})
+	params["id"] = objectId(listId)
+
+	err := lds.d.ModifyObjectForId(params, map[string]interface{}{"shares": shares})
+	if err == mdb.NotFoundError {
+		return ListNotFoundError
+	}
+
+	return err
+}
+
+func (lds *ListDataStore) SetListWorkflow(listId string, wf *Workflow) error {
+	params := make(map[string]interface{})
+	params["id"] = objectId(listId)
+
+	err := lds.d.ModifyObjectForId(params, map[string]interface{}{"workflow": wf})
+	if err == mdb.NotFoundError {
+		return ListNotFoundError
+	}
+
+	return err
+}
+
+func (lds *ListDataStore) SetListFields(listId string, fields []CustomField) error {
+	params := make(map[string]interface{})
+	params["id"] = objectId(listId)
+
+	err := lds.d.ModifyObjectForId(params, map[string]interface{}{"fields": fields})
+	if err == mdb.NotFoundError {
+		return ListNotFoundError
+	}
+
+	return err
+}
+
+func (lds *ListDataStore) LockList(listId, holder string, now time.Time, lease time.Duration) (bool, error) {
+	if !bson.IsObjectIdHex(listId) {
+		return false, ListNotFoundError
+	}
+
+	query := bson.M{
+		"_id": bson.ObjectIdHex(listId),
+		"$or": []bson.M{
+			{"lock_until": bson.M{"$exists": false}},
+			{"lock_until": bson.M{"$lte": now}},
+			{"lock_holder": holder},
+		},
+	}
+	update := bson.M{"$set": bson.M{"lock_holder": holder, "lock_until": now.Add(lease)}}
+
+	_, err := lds.d.FindAndModifyObject(query, update)
+	if err == mdb.NotFoundError {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (lds *ListDataStore) UnlockList(listId, holder string) error {
+	if !bson.IsObjectIdHex(listId) {
+		return ListNotFoundError
+	}
+
+	query := bson.M{"_id": bson.ObjectIdHex(listId), "lock_holder": holder}
+	return lds.d.UpdateObjectsForQuery(query, bson.M{"$unset": bson.M{"lock_holder": "", "lock_until": ""}})
+}
+
+func (lds *ListDataStore) DeleteList(id string) error {
+	m := make(map[string]interface{})
+	m["id"] = objectId(id)
+	return lds.d.DeleteObjectForSelector(m)
+}
@@ -0,0 +1,153 @@
+package models
+
+import (
+	"gopkg.in/mgo.v2"
+	"log"
+	"mdb"
+	"testing"
+)
+
+// SETUP AND TEARDOWN METHODS //
+
+func ldsTeardown(lds *ListDataStore) {
+	defer lds.Close()
+
+	session, err := mgo.Dial(mdb.Hostname)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer session.Close()
+
+	err = session.DB(lds.d.Database).C(lds.d.Collection).DropCollection()
+	if err != nil {
+		log.Fatalf("Error in ldsTeardown: %s", err.Error())
+	}
+}
+
+// END OF SETUP AND TEARDOWN METHODS //
+
+func TestInsertList(t *testing.T) {
+	// Test setup
+	lds := NewListDataStore()
+	lds.d.Collection = "2Do_TestInsertList_Collection"
+
+	defer ldsTeardown(lds)
+
+	// Main test content
+	l := NewList()
+	l.Name = "Groceries"
+	err := lds.InsertList(l)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetListById(t *testing.T) {
+	// Test setup
+	lds := NewListDataStore()
+	lds.d.Collection = "2Do_TestGetListById_Collection"
+
+	defer ldsTeardown(lds)
+
+	l0 := NewList()
+	l0.Name = "Work"
+	lds.InsertList(l0)
+
+	// Main test content
+	l1, err := lds.GetListById(l0.Id.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l1.Name != l0.Name {
+		t.Errorf("List names do not match: want %s got %s", l0.Name, l1.Name)
+	}
+}
+
+func TestGetListsForUserId(t *testing.T) {
+	// Test setup
+	lds := NewListDataStore()
+	lds.d.Collection = "2Do_TestGetListsForUserId_Collection"
+
+	defer ldsTeardown(lds)
+
+	ownerId := "123456"
+	l0 := NewList()
+	l0.Ownerid = ownerId
+	l1 := NewList()
+	l1.Ownerid = ownerId
+	l2 := NewList()
+	l2.Ownerid = "abcde"
+
+	lds.InsertList(l0)
+	lds.InsertList(l1)
+	lds.InsertList(l2)
+
+	// Main test content
+	ls, err := lds.GetListsForUserId(ownerId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ls) != 2 {
+		t.Errorf("Incorrect number of lists: want %d got %d", 2, len(ls))
+	}
+}
+
+func TestModifyList(t *testing.T) {
+	// Test setup
+	lds := NewListDataStore()
+	lds.d.Collection = "2Do_TestModifyList_Collection"
+
+	defer ldsTeardown(lds)
+
+	ownerId := "12345"
+	l0 := NewList()
+	l0.Name = "Work"
+	l0.Ownerid = ownerId
+	lds.InsertList(l0)
+
+	// Main test content
+	changes := map[string]interface{}{"name": "Office", "ownerid": "abcde"}
+	err := lds.ModifyList(l0.Id.Hex(), changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l1, err := lds.GetListById(l0.Id.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l1.Name != "Office" || l1.Ownerid != ownerId {
+		t.Errorf("List modified incorrectly: %v", l1)
+	}
+
+	err = lds.ModifyList(l0.Id.Hex(), map[string]interface{}{"name": 1})
+	if err == nil {
+		t.Error("Should fail for a non string name")
+	}
+}
+
+func TestDeleteList(t *testing.T) {
+	// Test setup
+	lds := NewListDataStore()
+	lds.d.Collection = "2Do_TestDeleteList_Collection"
+
+	defer ldsTeardown(lds)
+
+	userId := "12345"
+	l0 := NewList()
+	l0.Ownerid = userId
+	lds.InsertList(l0)
+
+	// Main test content
+	err := lds.DeleteList(l0.Id.Hex())
+	if err != nil {
+		t.Error(err)
+	}
+
+	if _, err := lds.GetListById(l0.Id.Hex()); err == nil {
+		t.Error("List was not deleted")
+	}
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+var listMap = make(map[string]List)
+
+// listLease is the claim of a holder on a list, see LockList.
+type listLease struct {
+	holder string
+	until  time.Time
+}
+
+var (
+	listLeases   = make(map[string]listLease)
+	listLeasesMu sync.Mutex
+)
+
+// TestListStorage implements the ListStorage interface
+type TestListStorage struct {
+	lists *map[string]List
+}
+
+func newTestListStorage() *TestListStorage {
+	t := TestListStorage{}
+	t.lists = &listMap
+	return &t
+}
+
+func (tls *TestListStorage) Close() {
+	log.Println("Closing TestListStorage")
+}
+
+func (tls *TestListStorage) GetListById(id string) (*List, error) {
+	lists := *tls.lists
+	if l, ok := lists[id]; ok {
+		return &l, nil
+	}
+
+	return nil, ListNotFoundError
+}
+
+func (tls *TestListStorage) GetListsForUserId(id string) ([]List, error) {
+	ls := make([]List, 0)
+	for _, l := range *tls.lists {
+		if l.RoleFor(id) != RoleNone {
+			ls = append(ls, l)
+		}
+	}
+
+	return ls, nil
+}
+
+func (tls *TestListStorage) InsertList(l List) error {
+	(*tls.lists)[l.Id.Hex()] = l
+	return nil
+}
+
+func (tls *TestListStorage) ModifyList(listId string, changes map[string]interface{}) error {
+	lists := *tls.lists
+	l, ok := lists[listId]
+	if !ok {
+		return ListNotFoundError
+	}
+
+	if name, ok := changes["name"].(string); ok {
+		l.Name = name
+	}
+
+	lists[listId] = l
+	return nil
+}
+
+func (tls *TestListStorage) SetListShares(listId string, shares []Share) error {
+	lists := *tls.lists
+	l, ok := lists[listId]
+	if !ok {
+		return ListNotFoundError
+	}
+
+	l.Shares = shares
+	lists[listId] = l
+	return nil
+}
+
+func (tls *TestListStorage) SetListWorkflow(listId string, wf *Workflow) error {
+	lists := *tls.lists
+	l, ok := lists[listId]
+	if !ok {
+		return ListNotFoundError
+	}
+
+	l.Workflow = wf
+	lists[listId] = l
+	return nil
+}
+
+func (tls *TestListStorage) SetListFields(listId string, fields []CustomField) error {
+	lists := *tls.lists
+	l, ok := lists[listId]
+	if !ok {
+		return ListNotFoundError
+	}
+
+	l.Fields = fields
+	lists[listId] = l
+	return nil
+}
+
+func (tls *TestListStorage) LockList(listId, holder string, now time.Time, lease time.Duration) (bool, error) {
+	listLeasesMu.Lock()
+	defer listLeasesMu.Unlock()
+
+	if l, ok := listLeases[listId]; ok && l.holder != holder && l.until.After(now) {
+		return false, nil
+	}
+
+	listLeases[listId] = listLease{holder, now.Add(lease)}
+	return true, nil
+}
+
+func (tls *TestListStorage) UnlockList(listId, holder string) error {
+	listLeasesMu.Lock()
+	defer listLeasesMu.Unlock()
+
+	if l, ok := listLeases[listId]; ok && l.holder == holder {
+		delete(listLeases, listId)
+	}
+
+	return nil
+}
+
+func (tls *TestListStorage) DeleteList(id string) error {
+	lists := *tls.lists
+	if _, ok := lists[id]; !ok {
+		return ListNotFoundError
+	}
+
+	delete(lists, id)
+	return nil
+}
@@ -0,0 +1,17 @@
+package models
+
+import "markdown"
+
+// RenderNotes sets the sanitized html of the Markdown notes of the todos
+// and the links in them.
+func RenderNotes(ts []Todo) {
+	for i := range ts {
+		if ts[i].Note == "" {
+			continue
+		}
+
+		d := markdown.Render(ts[i].Note)
+		ts[i].NoteHTML = d.HTML
+		ts[i].Links = d.Links
+	}
+}
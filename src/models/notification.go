@@ -0,0 +1,113 @@
+package models
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"mdb"
+	"time"
+)
+
+const NotificationCollection = "notifications"
+
+var NotificationNotFoundError = mdb.NotFoundError
+
+// Notification is an entry of a user's in-app notifications feed.
+type Notification struct {
+	Id      bson.ObjectId `json:"id" bson:"_id,omitempty"`
+	Ownerid string        `json:"-" bson:"ownerid"`
+	TodoId  string        `json:"todo_id,omitempty" bson:"todo_id,omitempty"`
+	Kind    string        `json:"kind" bson:"kind"`
+	Title   string        `json:"title" bson:"title"`
+	Message string        `json:"message" bson:"message"`
+	Created time.Time     `json:"created_date" bson:"created_date"`
+	Read    bool          `json:"read" bson:"read"`
+}
+
+func NewNotification() Notification {
+	n := Notification{}
+	n.Id = bson.NewObjectId()
+	n.Created = time.Now()
+	return n
+}
+
+// NotificationStorage is an interface which details the requirments
+// to interface with retrieval and insertion of notifications
+// into long term storage.
+type NotificationStorage interface {
+	Close()
+	// GetNotificationsForUserId returns the newest notifications first.
+	GetNotificationsForUserId(id string, unreadOnly bool, limit int) ([]Notification, error)
+	InsertNotification(n Notification) error
+	MarkNotificationRead(id, userId string) error
+}
+
+// NewNotificationStorage is the abstracted function that returns
+// a NotificationStorage implementation depending on the value of
+// the NOTIFICATION_STORE_TYPE.
+func NewNotificationStorage() NotificationStorage {
+	switch NOTIFICATION_STORE_TYPE {
+	case Regular:
+		return NewNotificationDataStore()
+	case Test:
+		return newTestNotificationStorage()
+	}
+
+	return NewNotificationDataStore()
+}
+
+// NotificationDataStore is a wrapper struct for DataStore.
+// It implements the NotificationStorage interface
+type NotificationDataStore struct {
+	d mdb.DataStore
+}
+
+func NewNotificationDataStore() *NotificationDataStore {
+	nds := NotificationDataStore{}
+	nds.d = mdb.NewDataStore()
+	nds.d.Collection = NotificationCollection
+	return &nds
+}
+
+func (nds *NotificationDataStore) Close() {
+	nds.d.Close()
+}
+
+func (nds *NotificationDataStore) GetNotificationsForUserId(id string, unreadOnly bool, limit int) ([]Notification, error) {
+	ns := make([]Notification, 0)
+	query := bson.M{"ownerid": id}
+	if unreadOnly {
+		query["read"] = false
+	}
+
+	raws, err := nds.d.GetObjectsForQueryPage(query, []string{"-created_date"}, 0, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, raw := range raws {
+		n := Notification{}
+		err := raw.Unmarshal(&n)
+		if err != nil {
+			return nil, err
+		}
+		ns = append(ns, n)
+	}
+
+	return ns, nil
+}
+
+func (nds *NotificationDataStore) InsertNotification(n Notification) error {
+	return nds.d.InsertObject(n)
+}
+
+func (nds *NotificationDataStore) MarkNotificationRead(id, userId string) error {
+	params := make(map[string]interface{})
+	params["id"] = objectId(id)
+	params["ownerid"] = userId
+
+	err := nds.d.ModifyObjectForId(params, map[string]interface{}{"read": true})
+	if err == mdb.NotFoundError {
+		return NotificationNotFoundError
+	}
+
+	return err
+}
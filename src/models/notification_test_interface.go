@@ -0,0 +1,56 @@
+package models
+
+import (
+	"log"
+	"sort"
+)
+
+var notificationMap = make(map[string]Notification)
+
+// TestNotificationStorage implements the NotificationStorage interface
+type TestNotificationStorage struct {
+	notifications *map[string]Notification
+}
+
+func newTestNotificationStorage() *TestNotificationStorage {
+	t := TestNotificationStorage{}
+	t.notifications = &notificationMap
+	return &t
+}
+
+func (tns *TestNotificationStorage) Close() {
+	log.Println("Closing TestNotificationStorage")
+}
+
+func (tns *TestNotificationStorage) GetNotificationsForUserId(id string, unreadOnly bool, limit int) ([]Notification, error) {
+	ns := make([]Notification, 0)
+	for _, n := range *tns.notifications {
+		if n.Ownerid == id && !(unreadOnly && n.Read) {
+			ns = append(ns, n)
+		}
+	}
+
+	sort.Slice(ns, func(i, j int) bool { return ns[i].Created.After(ns[j].Created) })
+	if limit > 0 && len(ns) > limit {
+		ns = ns[:limit]
+	}
+
+	return ns, nil
+}
+
+func (tns *TestNotificationStorage) InsertNotification(n Notification) error {
+	(*tns.notifications)[n.Id.Hex()] = n
+	return nil
+}
+
+func (tns *TestNotificationStorage) MarkNotificationRead(id, userId string) error {
+	notifications := *tns.notifications
+	n, ok := notifications[id]
+	if !ok || n.Ownerid != userId {
+		return NotificationNotFoundError
+	}
+
+	n.Read = true
+	notifications[id] = n
+	return nil
+}
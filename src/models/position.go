@@ -0,0 +1,111 @@
+package models
+
+import (
+	"errors"
+	"sort"
+)
+
+// Todos are ordered manually by their position. Moving a todo sets its
+// position between those of its new neighbours so that only the moved
+// todo is written. When a move finds its neighbours closer than
+// MinPositionGap, the todos of the user who moves it are rebalanced,
+// spacing them apart again. There is no rebalancing otherwise.
+const (
+	PositionStep   = 1024.0
+	MinPositionGap = 1e-6
+)
+
+// positionSort is the order in which todos are returned by storage.
+var positionSort = []string{"position", "created_date"}
+
+// PositionBetween returns a position between the positions lo and hi,
+// either of which may be nil for the start or end of the todos. It
+// returns false if they are too dense and the todos need rebalancing.
+func PositionBetween(lo, hi *float64) (float64, bool) {
+	switch {
+	case lo == nil && hi == nil:
+		return PositionStep, true
+	case lo == nil:
+		return *hi - PositionStep, true
+	case hi == nil:
+		return *lo + PositionStep, true
+	}
+
+	if *hi-*lo < MinPositionGap {
+		return 0, false
+	}
+
+	return *lo + (*hi-*lo)/2, true
+}
+
+// Rebalance returns new positions for the todos, in their order, which
+// space them evenly. The todos for which keep holds, such as those of
+// other users, keep their positions and the others are spread between
+// them, PositionStep apart before the first and after the last. It
+// returns false if the todos kept are too dense to spread the others.
+func Rebalance(ts []Todo, keep func(Todo) bool) (map[string]float64, bool) {
+	positions := make(map[string]float64, len(ts))
+	var run []Todo
+	var lo *float64
+
+	// spread places the run of todos between lo and hi
+	spread := func(hi *float64) bool {
+		n := float64(len(run))
+		for i, t := range run {
+			k := float64(i + 1)
+			switch {
+			case lo == nil && hi == nil:
+				positions[t.Id] = k * PositionStep
+			case lo == nil:
+				positions[t.Id] = *hi - (n+1-k)*PositionStep
+			case hi == nil:
+				positions[t.Id] = *lo + k*PositionStep
+			default:
+				gap := (*hi - *lo) / (n + 1)
+				if gap < MinPositionGap {
+					return false
+				}
+				positions[t.Id] = *lo + k*gap
+			}
+		}
+
+		run = nil
+		return true
+	}
+
+	for i := range ts {
+		if !keep(ts[i]) {
+			run = append(run, ts[i])
+			continue
+		}
+
+		if !spread(&ts[i].Position) {
+			return nil, false
+		}
+		lo = &ts[i].Position
+	}
+
+	if !spread(nil) {
+		return nil, false
+	}
+
+	return positions, true
+}
+
+// SortByPosition orders the todos as storage returns them.
+func SortByPosition(ts []Todo) {
+	sort.SliceStable(ts, func(i, j int) bool {
+		if ts[i].Position != ts[j].Position {
+			return ts[i].Position < ts[j].Position
+		}
+		return ts[i].Created.Before(ts[j].Created)
+	})
+}
+
+func positionChange(v interface{}) (interface{}, error) {
+	if _, ok := v.(float64); !ok {
+		return nil, errors.New("Value is not a number")
+	}
+
+	return v, nil
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestPositionBetween(t *testing.T) {
+	lo, hi := 1.0, 2.0
+	if p, ok := PositionBetween(&lo, &hi); !ok || p <= lo || p >= hi {
+		t.Errorf("Position should be between: got %v", p)
+	}
+
+	if p, _ := PositionBetween(nil, &lo); p >= lo {
+		t.Errorf("Position should be before: got %v", p)
+	}
+
+	if p, _ := PositionBetween(&hi, nil); p <= hi {
+		t.Errorf("Position should be after: got %v", p)
+	}
+
+	// Repeatedly moving between the same todos eventually requires rebalancing
+	moves := 0
+	for {
+		p, ok := PositionBetween(&lo, &hi)
+		if !ok {
+			break
+		}
+		hi = p
+		moves++
+	}
+	if moves < 10 {
+		t.Errorf("Too few moves before rebalancing: %d", moves)
+	}
+}
+
+func TestRebalance(t *testing.T) {
+	ts := []Todo{NewTodo(), NewTodo(), NewTodo()}
+	positions, _ := Rebalance(ts, func(Todo) bool { return false })
+	for i, t0 := range ts {
+		ts[i].Position = positions[t0.Id]
+	}
+
+	for i := 1; i < len(ts); i++ {
+		if ts[i].Position-ts[i-1].Position != PositionStep {
+			t.Errorf("Positions should be spaced evenly: %v", positions)
+		}
+	}
+
+	ts[0], ts[2] = ts[2], ts[0]
+	SortByPosition(ts)
+	if ts[0].Position > ts[1].Position || ts[1].Position > ts[2].Position {
+		t.Error("2Dos should be sorted by position")
+	}
+}
+
+func TestRebalanceKeep(t *testing.T) {
+	ts := []Todo{NewTodo(), NewTodo(), NewTodo(), NewTodo(), NewTodo()}
+	for i := range ts {
+		ts[i].Ownerid = "me"
+		ts[i].Position = 1 + float64(i)*1e-9
+	}
+	ts[2].Ownerid, ts[2].Position = "other", 2
+	ts[3].Position, ts[4].Position = 2+1e-9, 2+2e-9
+
+	mine := func(t Todo) bool { return t.Ownerid != "me" }
+	positions, ok := Rebalance(ts, mine)
+	if !ok || len(positions) != 4 {
+		t.Fatalf("Only the owner's 2Dos should be rebalanced: %v %v", positions, ok)
+	}
+
+	last := 0.0
+	for i, t0 := range ts {
+		p, found := positions[t0.Id]
+		if !found {
+			p = t0.Position
+		}
+		if i > 0 && p-last < PositionStep/2 {
+			t.Errorf("Positions should be spaced and in order: %v", positions)
+		}
+		last = p
+	}
+
+	// The kept 2Dos leave no room
+	ts[0].Ownerid, ts[0].Position = "other", 2-1e-9
+	if _, ok := Rebalance(ts[:3], mine); ok {
+		t.Error("Rebalancing between dense kept 2Dos should fail")
+	}
+}
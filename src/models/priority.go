@@ -0,0 +1,54 @@
+package models
+
+import (
+	"fmt"
+)
+
+// Priority marks the urgency of a todo.
+type Priority string
+
+const (
+	PriorityNone   Priority = "none"
+	PriorityLow    Priority = "low"
+	PriorityMedium Priority = "medium"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+var priorityLevels = map[Priority]int{
+	PriorityNone:   0,
+	PriorityLow:    1,
+	PriorityMedium: 2,
+	PriorityHigh:   3,
+	PriorityUrgent: 4,
+}
+
+// ParsePriority validates the priority name. An empty name is
+// PriorityNone.
+func ParsePriority(name string) (Priority, error) {
+	if name == "" {
+		return PriorityNone, nil
+	}
+
+	p := Priority(name)
+	if _, ok := priorityLevels[p]; !ok {
+		return PriorityNone, fmt.Errorf("Unknown priority: %s", name)
+	}
+
+	return p, nil
+}
+
+// Level returns the priority as a number between 0 (none)
+// and 4 (urgent).
+func (p Priority) Level() int {
+	return priorityLevels[p]
+}
+
+func priorityChange(v interface{}) (interface{}, error) {
+	name, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("Value is not a string")
+	}
+
+	return ParsePriority(name)
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"regexp"
+)
+
+// Todos are identified by public ids rather than ObjectIds, which
+// reveal when and on which machine they were created. Public ids are
+// random, URL-safe and stored as strings so that any backend can store
+// them. Todos created before public ids keep the hex of their ObjectId
+// as their id, see MigrateTodoIds.
+
+// publicIdBytes is the number of random bytes of a public id, 16
+// characters once encoded.
+const publicIdBytes = 12
+
+// feedTokenBytes is the number of random bytes of a feed token, 32
+// characters once encoded.
+const feedTokenBytes = 24
+
+// PublicIdPattern matches public ids, including the hex ObjectIds of
+// migrated todos, e.g. in the routes of the api.
+const PublicIdPattern = `[0-9A-Za-z_-]{1,32}`
+
+var publicIdRegexp = regexp.MustCompile(`^` + PublicIdPattern + `$`)
+
+// FeedTokenPattern matches the tokens of calendar feeds.
+const FeedTokenPattern = `[0-9A-Za-z_-]{32}`
+
+// NewPublicId returns a new random public id.
+func NewPublicId() string {
+	return randomId(publicIdBytes)
+}
+
+// NewFeedToken returns a new random token for the calendar feed of a
+// user, see User.FeedToken.
+func NewFeedToken() string {
+	return randomId(feedTokenBytes)
+}
+
+func randomId(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic("randomId: " + err.Error())
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// IsPublicId reports whether id could be a public id.
+func IsPublicId(id string) bool {
+	return publicIdRegexp.MatchString(id)
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"testing"
+)
+
+func TestNewPublicId(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id := NewPublicId()
+		if len(id) != 16 || !IsPublicId(id) {
+			t.Fatalf("Incorrect public id: %q", id)
+		}
+		if seen[id] {
+			t.Fatalf("Duplicate public id: %q", id)
+		}
+		seen[id] = true
+	}
+
+	if !IsPublicId(bson.NewObjectId().Hex()) {
+		t.Error("The hex of ObjectIds of migrated todos should be public ids")
+	}
+	for _, id := range []string{"", "a/b", "a b", "ünicode"} {
+		if IsPublicId(id) {
+			t.Errorf("Should not be a public id: %q", id)
+		}
+	}
+}
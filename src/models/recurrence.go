@@ -0,0 +1,180 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"recurrence"
+	"time"
+)
+
+// Recurrence repeats a todo according to an RFC 5545 recurrence rule.
+// Completing an instance of a recurring todo creates the next instance.
+type Recurrence struct {
+	Rule string `json:"rrule" bson:"rrule"`
+	// TimeZone is the IANA time zone in which the occurrences are
+	// computed. UTC is used when it is empty.
+	TimeZone string `json:"tzid,omitempty" bson:"tzid,omitempty"`
+	// FromCompletion repeats the todo relative to when an instance was
+	// completed instead of on a fixed schedule e.g. water the plants 3
+	// days after they were last watered.
+	FromCompletion bool `json:"from_completion,omitempty" bson:"from_completion,omitempty"`
+	// Start is the due date of the first instance of the series.
+	Start time.Time `json:"dtstart" bson:"dtstart,omitempty"`
+	// Index is the position of the instance in the series starting at 1.
+	Index int `json:"index" bson:"index,omitempty"`
+}
+
+var ErrNoDueDate = errors.New("Recurring 2Dos require a due_date")
+
+// parse returns the parsed rule and time zone of the recurrence.
+func (rc Recurrence) parse() (*recurrence.Rule, *time.Location, error) {
+	rule, err := recurrence.Parse(rc.Rule)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	loc, err := time.LoadLocation(rc.TimeZone)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return rule, loc, nil
+}
+
+// Validate checks the rule and time zone of the recurrence.
+func (rc Recurrence) Validate() error {
+	_, _, err := rc.parse()
+	return err
+}
+
+// seriesStart returns the start of the series of which t is an instance.
+func (t Todo) seriesStart() time.Time {
+	if t.Recurrence.Start.IsZero() {
+		return t.Due
+	}
+
+	return t.Recurrence.Start
+}
+
+// nextDue returns the due date of the instance following t when t is
+// completed at completedAt, or false once the series has ended.
+func (t Todo) nextDue(completedAt time.Time) (time.Time, bool, error) {
+	rc := t.Recurrence
+	if rc == nil {
+		return time.Time{}, false, nil
+	}
+
+	if t.Due.IsZero() {
+		return time.Time{}, false, ErrNoDueDate
+	}
+
+	rule, loc, err := rc.parse()
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	if !rc.FromCompletion {
+		next, ok := rule.Next(t.seriesStart(), t.Due, loc)
+		return next, ok, nil
+	}
+
+	// The series is restarted on the day of completion, at the time of
+	// day the todo was due, so the count is tracked with the index.
+	index := rc.Index
+	if index < 1 {
+		index = 1
+	}
+	if rule.Count > 0 && index >= rule.Count {
+		return time.Time{}, false, nil
+	}
+	rule.Count = 0
+
+	y, m, d := completedAt.In(loc).Date()
+	hh, mm, ss := t.Due.In(loc).Clock()
+	base := time.Date(y, m, d, hh, mm, ss, 0, loc)
+
+	next, ok := rule.Next(base, base, loc)
+	return next, ok, nil
+}
+
+// NextInstance returns the instance of a recurring todo which follows t
+// when t is completed at completedAt. It returns nil if t doesn't recur
+// or its series has ended.
+func (t Todo) NextInstance(completedAt time.Time) (*Todo, error) {
+	next, ok, err := t.nextDue(completedAt)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	n := t
+	n.Id = NewPublicId()
+	n.Created = completedAt
+	n.Due = next
+	n.Completed = false
+	n.CompletedAt = nil
+	n.CompletedBy = ""
+	n.Status = ""
+	n.StatusHistory = nil
+	n.DeferUntil = nil
+	n.Tags = append([]string(nil), t.Tags...)
+	n.Shares = append([]Share(nil), t.Shares...)
+	// Attachments stay with the completed instance, which owns their blobs.
+	n.Attachments = nil
+
+	rc := *t.Recurrence
+	rc.Start = t.seriesStart()
+	if rc.Index < 1 {
+		rc.Index = 1
+	}
+	rc.Index++
+	n.Recurrence = &rc
+
+	return &n, nil
+}
+
+// Occurrences previews the due dates of the next n instances of a
+// recurring todo. Todos which repeat from completion are assumed to be
+// completed on their due date.
+func (t Todo) Occurrences(n int) ([]time.Time, error) {
+	ts := make([]time.Time, 0, n)
+	for len(ts) < n {
+		next, err := t.NextInstance(t.Due)
+		if err != nil {
+			return nil, err
+		}
+		if next == nil {
+			break
+		}
+
+		ts = append(ts, next.Due)
+		t = *next
+	}
+
+	return ts, nil
+}
+
+// recurrenceChange converts a recurrence, as decoded from json, for
+// ModifyTodo. A nil value removes the recurrence.
+func recurrenceChange(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	rc := Recurrence{}
+	err = json.Unmarshal(b, &rc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = rc.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return rc, nil
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextInstance(t *testing.T) {
+	due := time.Date(2017, time.March, 1, 9, 0, 0, 0, time.UTC)
+	t0 := NewTodo()
+	t0.Title = "Take out the trash"
+	t0.Due = due
+	t0.Tags = []string{"home"}
+	t0.Recurrence = &Recurrence{Rule: "FREQ=WEEKLY;BYDAY=WE;COUNT=2"}
+
+	// Completing late doesn't change a fixed schedule
+	t1, err := t0.NextInstance(due.Add(48 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if t1 == nil || !t1.Due.Equal(due.AddDate(0, 0, 7)) {
+		t.Fatalf("Incorrect next instance: %v", t1)
+	}
+
+	if t1.Id == t0.Id || t1.Completed || t1.Recurrence.Index != 2 || !t1.Recurrence.Start.Equal(due) {
+		t.Errorf("Next instance not set up correctly: %v", t1)
+	}
+
+	t2, err := t1.NextInstance(t1.Due)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if t2 != nil {
+		t.Errorf("Series should end after COUNT instances: %v", t2)
+	}
+}
+
+func TestNextInstanceFromCompletion(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	due := time.Date(2017, time.March, 20, 18, 0, 0, 0, loc)
+	t0 := NewTodo()
+	t0.Due = due
+	t0.Recurrence = &Recurrence{Rule: "FREQ=DAILY;INTERVAL=3", TimeZone: "Europe/Berlin", FromCompletion: true}
+
+	// Completed on the 25th, DST starts in Berlin on the 26th
+	completed := time.Date(2017, time.March, 25, 10, 0, 0, 0, loc)
+	t1, err := t0.NextInstance(completed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := time.Date(2017, time.March, 28, 18, 0, 0, 0, loc)
+	if t1 == nil || !t1.Due.Equal(expected) {
+		t.Errorf("Incorrect next instance: want %v got %v", expected, t1)
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	t0 := NewTodo()
+	t0.Due = time.Date(2017, time.January, 31, 9, 0, 0, 0, time.UTC)
+	t0.Recurrence = &Recurrence{Rule: "FREQ=MONTHLY;BYMONTHDAY=-1"}
+
+	ts, err := t0.Occurrences(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []int{28, 31, 30}
+	if len(ts) != len(expected) {
+		t.Fatalf("Incorrect number of occurrences: %v", ts)
+	}
+	for i, d := range expected {
+		if ts[i].Day() != d {
+			t.Errorf("Incorrect occurrence %d: want day %d got %v", i, d, ts[i])
+		}
+	}
+
+	t0.Recurrence.TimeZone = "Not/AZone"
+	if _, err := t0.Occurrences(3); err == nil {
+		t.Error("Should fail for an invalid time zone")
+	}
+}
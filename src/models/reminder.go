@@ -0,0 +1,278 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/mgo.v2/bson"
+	"mdb"
+	"time"
+)
+
+const ReminderJobCollection = "reminder_jobs"
+
+// The channels through which a reminder can be delivered.
+const (
+	ChannelInApp   = "in_app"
+	ChannelWebhook = "webhook"
+	ChannelEmail   = "email"
+)
+
+var Channels = []string{ChannelInApp, ChannelWebhook, ChannelEmail}
+
+// The states of a ReminderJob.
+const (
+	JobPending   = "pending"
+	JobSent      = "sent"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Reminder is a notification about a todo at a given time. The time
+// is either absolute (At) or relative to the due date of the todo
+// (BeforeMinutes before it).
+type Reminder struct {
+	At            *time.Time `json:"at,omitempty" bson:"at,omitempty"`
+	BeforeMinutes int        `json:"before_minutes,omitempty" bson:"before_minutes,omitempty"`
+	// Channels the reminder is delivered through, in app when empty.
+	Channels []string `json:"channels,omitempty" bson:"channels,omitempty"`
+}
+
+// Validate checks the offset and channels of the reminder.
+func (rm Reminder) Validate() error {
+	if rm.At != nil && rm.BeforeMinutes != 0 {
+		return errors.New("Reminder can't have both at and before_minutes")
+	}
+
+	if rm.BeforeMinutes < 0 {
+		return errors.New("Reminder before_minutes must not be negative")
+	}
+
+	for _, c := range rm.Channels {
+		found := false
+		for _, channel := range Channels {
+			if c == channel {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("Unknown reminder channel: %s", c)
+		}
+	}
+
+	return nil
+}
+
+// fireAt returns when the reminder of the todo is due. It returns
+// false for relative reminders of a todo without a due date.
+func (rm Reminder) fireAt(t Todo) (time.Time, bool) {
+	if rm.At != nil {
+		return *rm.At, true
+	}
+
+	if t.Due.IsZero() {
+		return time.Time{}, false
+	}
+
+	return t.Due.Add(-time.Duration(rm.BeforeMinutes) * time.Minute), true
+}
+
+func remindersChange(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	rms := []Reminder{}
+	err = json.Unmarshal(b, &rms)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rm := range rms {
+		if err := rm.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return rms, nil
+}
+
+// ReminderJob is a reminder of a todo scheduled for delivery. Jobs are
+// kept in long term storage so that they survive restarts, and are
+// claimed with a lease so that a job is only fired by one instance.
+type ReminderJob struct {
+	Id       bson.ObjectId `bson:"_id,omitempty"`
+	TodoId   string        `bson:"todo_id"`
+	Ownerid  string        `bson:"ownerid"`
+	FireAt   time.Time     `bson:"fire_at"`
+	Channels []string      `bson:"channels"`
+	// Delivered are the channels through which the job was delivered
+	// so that a retry does not deliver it twice.
+	Delivered   []string  `bson:"delivered,omitempty"`
+	Status      string    `bson:"status"`
+	Attempts    int       `bson:"attempts"`
+	LockedBy    string    `bson:"locked_by,omitempty"`
+	LockedUntil time.Time `bson:"locked_until,omitempty"`
+}
+
+// ReminderJobs returns the jobs of the todo's reminders which are
+// due after the time now. A completed or archived todo has no jobs.
+func (t Todo) ReminderJobs(now time.Time) []ReminderJob {
+	jobs := make([]ReminderJob, 0)
+	if t.Completed || t.Archived {
+		return jobs
+	}
+
+	for _, rm := range t.Reminders {
+		at, ok := rm.fireAt(t)
+		if !ok || !at.After(now) {
+			continue
+		}
+
+		channels := rm.Channels
+		if len(channels) == 0 {
+			channels = []string{ChannelInApp}
+		}
+
+		jobs = append(jobs, ReminderJob{
+			Id:       bson.NewObjectId(),
+			TodoId:   t.Id,
+			Ownerid:  t.Ownerid,
+			FireAt:   at,
+			Channels: channels,
+			Status:   JobPending,
+		})
+	}
+
+	return jobs
+}
+
+// ReminderJobStorage is an interface which details the requirments
+// to schedule reminder jobs in long term storage.
+type ReminderJobStorage interface {
+	Close()
+	// ReplaceJobsForTodo replaces the pending jobs of a todo. Jobs
+	// which are leased are kept, they are due and being delivered.
+	ReplaceJobsForTodo(todoId string, jobs []ReminderJob) error
+	// ClaimDueJob leases a pending job due at the time now to the
+	// instance. It returns nil when there is no such job.
+	ClaimDueJob(instance string, now time.Time, lease time.Duration) (*ReminderJob, error)
+	// FinishJob sets the final status of a job claimed by the instance.
+	FinishJob(id, instance, status string, delivered []string) error
+	// RetryJob releases a job claimed by the instance to be retried at.
+	RetryJob(id, instance string, at time.Time, delivered []string) error
+}
+
+// NewReminderJobStorage is the abstracted function that returns
+// a ReminderJobStorage implementation depending on the value of
+// the REMINDER_STORE_TYPE.
+func NewReminderJobStorage() ReminderJobStorage {
+	switch REMINDER_STORE_TYPE {
+	case Regular:
+		return NewReminderJobDataStore()
+	case Test:
+		return newTestReminderJobStorage()
+	}
+
+	return NewReminderJobDataStore()
+}
+
+// ReminderJobDataStore is a wrapper struct for DataStore.
+// It implements the ReminderJobStorage interface
+type ReminderJobDataStore struct {
+	d mdb.DataStore
+}
+
+func NewReminderJobDataStore() *ReminderJobDataStore {
+	rds := ReminderJobDataStore{}
+	rds.d = mdb.NewDataStore()
+	rds.d.Collection = ReminderJobCollection
+	return &rds
+}
+
+func (rds *ReminderJobDataStore) Close() {
+	rds.d.Close()
+}
+
+func (rds *ReminderJobDataStore) ReplaceJobsForTodo(todoId string, jobs []ReminderJob) error {
+	query := bson.M{
+		"todo_id": todoId,
+		"status":  JobPending,
+		"$or": []bson.M{
+			{"locked_until": bson.M{"$exists": false}},
+			{"locked_until": bson.M{"$lt": time.Now()}},
+		},
+	}
+	err := rds.d.DeleteObjectsForQuery(query)
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		err := rds.d.InsertObject(job)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (rds *ReminderJobDataStore) ClaimDueJob(instance string, now time.Time, lease time.Duration) (*ReminderJob, error) {
+	query := bson.M{
+		"status":  JobPending,
+		"fire_at": bson.M{"$lte": now},
+		"$or": []bson.M{
+			{"locked_until": bson.M{"$exists": false}},
+			{"locked_until": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"locked_by": instance, "locked_until": now.Add(lease)}}
+
+	raw, err := rds.d.FindAndModifyObject(query, update)
+	if err != nil {
+		if err == mdb.NotFoundError {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	job := ReminderJob{}
+	err = raw.Unmarshal(&job)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (rds *ReminderJobDataStore) FinishJob(id, instance, status string, delivered []string) error {
+	if !bson.IsObjectIdHex(id) {
+		return fmt.Errorf("Id is not a valid ObjectIdHex: %s", id)
+	}
+
+	query := bson.M{"_id": bson.ObjectIdHex(id), "locked_by": instance}
+	update := bson.M{
+		"$set":   bson.M{"status": status, "delivered": delivered},
+		"$unset": bson.M{"locked_by": "", "locked_until": ""},
+		"$inc":   bson.M{"attempts": 1},
+	}
+	return rds.d.UpdateObjectsForQuery(query, update)
+}
+
+func (rds *ReminderJobDataStore) RetryJob(id, instance string, at time.Time, delivered []string) error {
+	if !bson.IsObjectIdHex(id) {
+		return fmt.Errorf("Id is not a valid ObjectIdHex: %s", id)
+	}
+
+	query := bson.M{"_id": bson.ObjectIdHex(id), "locked_by": instance}
+	update := bson.M{
+		"$set":   bson.M{"fire_at": at, "delivered": delivered},
+		"$unset": bson.M{"locked_by": "", "locked_until": ""},
+		"$inc":   bson.M{"attempts": 1},
+	}
+	return rds.d.UpdateObjectsForQuery(query, update)
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReminderJobs(t *testing.T) {
+	now := time.Date(2017, time.March, 1, 9, 0, 0, 0, time.UTC)
+	at := now.Add(time.Hour)
+	past := now.Add(-time.Hour)
+
+	t0 := NewTodo()
+	t0.Ownerid = "12345"
+	t0.Due = now.Add(24 * time.Hour)
+	t0.Reminders = []Reminder{
+		{At: &at, Channels: []string{ChannelEmail}},
+		{BeforeMinutes: 30},
+		{At: &past},
+	}
+
+	jobs := t0.ReminderJobs(now)
+	if len(jobs) != 2 {
+		t.Fatalf("Incorrect number of jobs: want %d got %d", 2, len(jobs))
+	}
+
+	if !jobs[0].FireAt.Equal(at) || jobs[0].Channels[0] != ChannelEmail {
+		t.Errorf("Incorrect absolute reminder job: %v", jobs[0])
+	}
+
+	if !jobs[1].FireAt.Equal(t0.Due.Add(-30*time.Minute)) || jobs[1].Channels[0] != ChannelInApp {
+		t.Errorf("Incorrect relative reminder job: %v", jobs[1])
+	}
+
+	if jobs[1].TodoId != t0.Id || jobs[1].Ownerid != t0.Ownerid || jobs[1].Status != JobPending {
+		t.Errorf("Reminder job not set up correctly: %v", jobs[1])
+	}
+
+	t0.Completed = true
+	if len(t0.ReminderJobs(now)) != 0 {
+		t.Error("Completed todos should have no reminder jobs")
+	}
+
+	t0.Completed = false
+	t0.Archived = true
+	if len(t0.ReminderJobs(now)) != 0 {
+		t.Error("Archived todos should have no reminder jobs")
+	}
+
+	t0.Archived = false
+	t0.Due = time.Time{}
+	if len(t0.ReminderJobs(now)) != 1 {
+		t.Error("Relative reminders need a due date")
+	}
+}
+
+func TestReminderValidate(t *testing.T) {
+	at := time.Now()
+	invalid := []Reminder{
+		{At: &at, BeforeMinutes: 10},
+		{BeforeMinutes: -10},
+		{Channels: []string{"pigeon"}},
+	}
+
+	for _, rm := range invalid {
+		if err := rm.Validate(); err == nil {
+			t.Errorf("Reminder should be invalid: %v", rm)
+		}
+	}
+
+	if err := (Reminder{BeforeMinutes: 10, Channels: Channels}).Validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+var reminderJobMap = make(map[string]ReminderJob)
+var reminderJobMutex sync.Mutex
+
+// TestReminderJobStorage implements the ReminderJobStorage interface
+type TestReminderJobStorage struct {
+	jobs *map[string]ReminderJob
+}
+
+func newTestReminderJobStorage() *TestReminderJobStorage {
+	t := TestReminderJobStorage{}
+	t.jobs = &reminderJobMap
+	return &t
+}
+
+func (trs *TestReminderJobStorage) Close() {
+	log.Println("Closing TestReminderJobStorage")
+}
+
+func (trs *TestReminderJobStorage) ReplaceJobsForTodo(todoId string, jobs []ReminderJob) error {
+	reminderJobMutex.Lock()
+	defer reminderJobMutex.Unlock()
+
+	now := time.Now()
+	all := *trs.jobs
+	for id, job := range all {
+		if job.TodoId == todoId && job.Status == JobPending && !job.LockedUntil.After(now) {
+			delete(all, id)
+		}
+	}
+
+	for _, job := range jobs {
+		all[job.Id.Hex()] = job
+	}
+
+	return nil
+}
+
+func (trs *TestReminderJobStorage) ClaimDueJob(instance string, now time.Time, lease time.Duration) (*ReminderJob, error) {
+	reminderJobMutex.Lock()
+	defer reminderJobMutex.Unlock()
+
+	all := *trs.jobs
+	for id, job := range all {
+		if job.Status != JobPending || job.FireAt.After(now) || job.LockedUntil.After(now) {
+			continue
+		}
+
+		job.LockedBy = instance
+		job.LockedUntil = now.Add(lease)
+		all[id] = job
+		return &job, nil
+	}
+
+	return nil, nil
+}
+
+func (trs *TestReminderJobStorage) release(id, instance string, update func(*ReminderJob)) error {
+	reminderJobMutex.Lock()
+	defer reminderJobMutex.Unlock()
+
+	all := *trs.jobs
+	job, ok := all[id]
+	if !ok || job.LockedBy != instance {
+		return nil
+	}
+
+	update(&job)
+	job.Attempts++
+	job.LockedBy = ""
+	job.LockedUntil = time.Time{}
+	all[id] = job
+	return nil
+}
+
+func (trs *TestReminderJobStorage) FinishJob(id, instance, status string, delivered []string) error {
+	return trs.release(id, instance, func(job *ReminderJob) {
+		job.Status = status
+		job.Delivered = delivered
+	})
+}
+
+func (trs *TestReminderJobStorage) RetryJob(id, instance string, at time.Time, delivered []string) error {
+	return trs.release(id, instance, func(job *ReminderJob) {
+		job.FireAt = at
+		job.Delivered = delivered
+	})
+}
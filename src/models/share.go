@@ -0,0 +1,114 @@
+package models
+
+import (
+	"fmt"
+)
+
+// Role is the level of access a user has to a shared todo or list.
+type Role string
+
+const (
+	RoleNone   Role = ""
+	RoleViewer Role = "viewer"
+	RoleEditor Role = "editor"
+	RoleOwner  Role = "owner"
+)
+
+var roleLevels = map[Role]int{
+	RoleNone:   0,
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+// ParseRole validates the role name a todo or list is shared with.
+func ParseRole(name string) (Role, error) {
+	r := Role(name)
+	if _, ok := roleLevels[r]; !ok || r == RoleNone {
+		return RoleNone, fmt.Errorf("Unknown role: %s", name)
+	}
+
+	return r, nil
+}
+
+// Level returns the role as a number between 0 (none) and 3 (owner).
+func (r Role) Level() int {
+	return roleLevels[r]
+}
+
+// MaxRole returns the highest of the roles.
+func MaxRole(roles ...Role) Role {
+	max := RoleNone
+	for _, r := range roles {
+		if r.Level() > max.Level() {
+			max = r
+		}
+	}
+
+	return max
+}
+
+// Share grants a user a role on a todo or list. The username is kept
+// alongside the user id so that shares can be listed without looking
+// up every user.
+type Share struct {
+	UserId   string `json:"user_id" bson:"user_id"`
+	Username string `json:"username" bson:"username"`
+	Role     Role   `json:"role" bson:"role"`
+}
+
+// roleIn returns the role the shares grant to the user.
+func roleIn(shares []Share, userId string) Role {
+	for _, s := range shares {
+		if s.UserId == userId {
+			return s.Role
+		}
+	}
+
+	return RoleNone
+}
+
+// SetShare returns the shares with the share of the user added, or
+// replaced if the user already had one.
+func SetShare(shares []Share, share Share) []Share {
+	updated := make([]Share, 0, len(shares)+1)
+	for _, s := range shares {
+		if s.UserId != share.UserId {
+			updated = append(updated, s)
+		}
+	}
+
+	return append(updated, share)
+}
+
+// RemoveShare returns the shares without the share of the user,
+// and whether the user had one.
+func RemoveShare(shares []Share, userId string) ([]Share, bool) {
+	updated := make([]Share, 0, len(shares))
+	for _, s := range shares {
+		if s.UserId != userId {
+			updated = append(updated, s)
+		}
+	}
+
+	return updated, len(updated) != len(shares)
+}
+
+// RoleFor returns the role the user has on the todo itself. The role
+// granted through the list of the todo is not taken into account.
+func (t Todo) RoleFor(userId string) Role {
+	if t.Ownerid == userId {
+		return RoleOwner
+	}
+
+	return roleIn(t.Shares, userId)
+}
+
+// RoleFor returns the role the user has on the list.
+func (l List) RoleFor(userId string) Role {
+	if l.Ownerid == userId {
+		return RoleOwner
+	}
+
+	return roleIn(l.Shares, userId)
+}
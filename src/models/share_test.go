@@ -0,0 +1,52 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestRoleFor(t *testing.T) {
+	t0 := NewTodo()
+	t0.Ownerid = "12345"
+	t0.Shares = []Share{{UserId: "abcde", Role: RoleEditor}}
+
+	tests := map[string]Role{
+		"12345": RoleOwner,
+		"abcde": RoleEditor,
+		"00000": RoleNone,
+	}
+
+	for userId, want := range tests {
+		if got := t0.RoleFor(userId); got != want {
+			t.Errorf("Incorrect role for %s: want %s got %s", userId, want, got)
+		}
+	}
+}
+
+func TestSetShare(t *testing.T) {
+	shares := SetShare(nil, Share{UserId: "abcde", Role: RoleViewer})
+	shares = SetShare(shares, Share{UserId: "abcde", Role: RoleOwner})
+	if len(shares) != 1 || shares[0].Role != RoleOwner {
+		t.Errorf("Share should be replaced: %v", shares)
+	}
+
+	shares, ok := RemoveShare(shares, "abcde")
+	if !ok || len(shares) != 0 {
+		t.Errorf("Share should be removed: %v", shares)
+	}
+
+	if _, ok := RemoveShare(shares, "abcde"); ok {
+		t.Error("Should not remove a missing share")
+	}
+}
+
+func TestParseRole(t *testing.T) {
+	for _, name := range []string{"", "admin"} {
+		if _, err := ParseRole(name); err == nil {
+			t.Errorf("Role should be invalid: %q", name)
+		}
+	}
+
+	if MaxRole(RoleViewer, RoleOwner, RoleEditor) != RoleOwner {
+		t.Error("Incorrect highest role")
+	}
+}
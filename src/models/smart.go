@@ -0,0 +1,94 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// SmartWeights are the weights of each factor in the smart order of
+// todos. A user can store their own weights, otherwise the
+// DefaultSmartWeights are used.
+type SmartWeights struct {
+	Priority float64 `json:"priority" bson:"priority"`
+	DueSoon  float64 `json:"due_soon" bson:"due_soon"`
+	Overdue  float64 `json:"overdue" bson:"overdue"`
+	Age      float64 `json:"age" bson:"age"`
+}
+
+var DefaultSmartWeights = SmartWeights{
+	Priority: 1,
+	DueSoon:  1,
+	Overdue:  1,
+	Age:      0.25,
+}
+
+const (
+	day = 24 * time.Hour
+
+	// Todos older than maxAge get the full age score.
+	maxAge = 30 * day
+)
+
+// Validate checks that none of the weights are negative.
+func (w SmartWeights) Validate() error {
+	for _, v := range []float64{w.Priority, w.DueSoon, w.Overdue, w.Age} {
+		if v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return errors.New("Smart weights must be non-negative numbers")
+		}
+	}
+
+	return nil
+}
+
+// Score rates how pressing the todo is at the time now. Each factor
+// is scaled to [0, 1] before being weighted:
+//   - priority is the priority level divided by the highest level
+//   - due soon is 1 / (1 + days until due), or 1 once overdue
+//   - overdue is 1 if the due date has passed
+//   - age is the time since creation divided by 30 days, capped at 1
+func (w SmartWeights) Score(t Todo, now time.Time) float64 {
+	score := w.Priority * float64(t.Priority.Level()) / float64(PriorityUrgent.Level())
+
+	if !t.Due.IsZero() {
+		until := t.Due.Sub(now)
+		if until < 0 {
+			score += w.DueSoon + w.Overdue
+		} else {
+			score += w.DueSoon / (1 + float64(until)/float64(day))
+		}
+	}
+
+	if !t.Created.IsZero() && now.After(t.Created) {
+		score += w.Age * math.Min(float64(now.Sub(t.Created))/float64(maxAge), 1)
+	}
+
+	return score
+}
+
+// SmartSort orders the todos from most to least pressing. Completed
+// todos are placed after all open todos.
+func SmartSort(ts []Todo, w SmartWeights, now time.Time) {
+	scores := make([]float64, len(ts))
+	idx := make([]int, len(ts))
+	for i, t := range ts {
+		idx[i] = i
+		scores[i] = w.Score(t, now)
+	}
+
+	sort.SliceStable(idx, func(i, j int) bool {
+		a, b := ts[idx[i]], ts[idx[j]]
+		if a.Completed != b.Completed {
+			return !a.Completed
+		}
+
+		return scores[idx[i]] > scores[idx[j]]
+	})
+
+	sorted := make([]Todo, len(ts))
+	for i, j := range idx {
+		sorted[i] = ts[j]
+	}
+	copy(ts, sorted)
+}
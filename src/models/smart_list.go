@@ -0,0 +1,423 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/mgo.v2/bson"
+	"mdb"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const SmartListCollection = "smart_lists"
+
+var SmartListNotFoundError = mdb.NotFoundError
+
+var ErrNoSmartListName = errors.New("Smart list name must not be empty")
+
+// Sorts of smart lists besides those by custom fields, fields.{key}.
+// Sorts but SortSmart are descending when prefixed with -.
+const (
+	SortSmart    = "smart" // See SmartSort
+	SortDue      = "due"
+	SortPriority = "priority"
+	SortCreated  = "created"
+	SortTitle    = "title"
+)
+
+// relativeDateRegexp matches relative dates such as today, now-3h or
+// today+7d, see ParseRelativeDate.
+var relativeDateRegexp = regexp.MustCompile(`^(now|today|tomorrow|yesterday)(?:([+-])(\d{1,4})([hdwm]))?$`)
+
+// ParseRelativeDate returns the time written as a date relative to now
+// in loc: now, today, tomorrow or yesterday optionally followed by an
+// offset of hours, days, weeks or months e.g. today+7d or now-12h.
+// Absolute dates and times are accepted as by ParseDue. It reports
+// whether the time is the start of a day rather than an instant.
+func ParseRelativeDate(s string, now time.Time, loc *time.Location) (time.Time, bool, error) {
+	m := relativeDateRegexp.FindStringSubmatch(strings.ToLower(strings.TrimSpace(s)))
+	if m == nil {
+		t, allDay, err := ParseDue(s, loc)
+		if err != nil || t.IsZero() {
+			return time.Time{}, false, fmt.Errorf("Invalid date: %q, try today, today+7d, now-12h or 2006-01-02", s)
+		}
+		return t, allDay, nil
+	}
+
+	t, day := now.In(loc), true
+	start, _ := DayBounds(now, loc)
+	switch m[1] {
+	case "now":
+		day = false
+	case "today":
+		t = start
+	case "tomorrow":
+		t = start.AddDate(0, 0, 1)
+	case "yesterday":
+		t = start.AddDate(0, 0, -1)
+	}
+
+	if m[2] == "" {
+		return t, day, nil
+	}
+
+	n, _ := strconv.Atoi(m[3])
+	if m[2] == "-" {
+		n = -n
+	}
+
+	switch m[4] {
+	case "h":
+		return t.Add(time.Duration(n) * time.Hour), false, nil
+	case "d":
+		t = t.AddDate(0, 0, n)
+	case "w":
+		t = t.AddDate(0, 0, 7*n)
+	case "m":
+		t = t.AddDate(0, n, 0)
+	}
+
+	return t, day, nil
+}
+
+// SmartFieldCondition is a condition on a custom field of the list of
+// a smart list, see FieldCondition.
+type SmartFieldCondition struct {
+	Key   string `json:"key" bson:"key"`
+	Op    string `json:"op,omitempty" bson:"op,omitempty"`
+	Value string `json:"value" bson:"value"`
+}
+
+// SmartFilter defines the todos of a smart list, fields which are left
+// out are not filtered on as in TodoFilter. DueFrom and DueTo are
+// relative dates, see ParseRelativeDate, and DueTo includes its day.
+type SmartFilter struct {
+	ListId          string                `json:"list_id,omitempty" bson:"list_id,omitempty"`
+	Tags            []string              `json:"tags,omitempty" bson:"tags,omitempty"`
+	TagMode         string                `json:"tag_mode,omitempty" bson:"tag_mode,omitempty"`
+	MinPriority     Priority              `json:"min_priority,omitempty" bson:"min_priority,omitempty"`
+	Assigned        string                `json:"assigned,omitempty" bson:"assigned,omitempty"` // me
+	Due             string                `json:"due,omitempty" bson:"due,omitempty"`           // DueToday or DueOverdue
+	DueFrom         string                `json:"due_from,omitempty" bson:"due_from,omitempty"`
+	DueTo           string                `json:"due_to,omitempty" bson:"due_to,omitempty"`
+	Completed       *bool                 `json:"completed,omitempty" bson:"completed,omitempty"`
+	Search          string                `json:"search,omitempty" bson:"search,omitempty"`
+	Fields          []SmartFieldCondition `json:"fields,omitempty" bson:"fields,omitempty"`
+	IncludeDeferred bool                  `json:"include_deferred,omitempty" bson:"include_deferred,omitempty"`
+}
+
+// SmartList is a saved search of a user, its todos are those passing
+// its filter at the time they are asked for in the order of Sort.
+type SmartList struct {
+	Id      bson.ObjectId `json:"id" bson:"_id,omitempty"`
+	Ownerid string        `json:"-" bson:"ownerid"`
+	Name    string        `json:"name" bson:"name"`
+	Created time.Time     `json:"created_date" bson:"created_date,omitempty"`
+	Filter  SmartFilter   `json:"filter" bson:"filter"`
+	Sort    string        `json:"sort,omitempty" bson:"sort,omitempty"`
+}
+
+func NewSmartList() SmartList {
+	s := SmartList{}
+	s.Id = bson.NewObjectId()
+	s.Created = time.Now()
+	return s
+}
+
+// Validate normalizes the smart list and checks its definition. The
+// custom fields it uses are checked against those of its list by
+// TodoFilter.
+func (s *SmartList) Validate() error {
+	s.Name = strings.TrimSpace(s.Name)
+	if s.Name == "" {
+		return ErrNoSmartListName
+	}
+
+	f := &s.Filter
+	f.Tags = NormalizeTags(f.Tags)
+	f.Search = strings.TrimSpace(f.Search)
+
+	switch f.TagMode {
+	case "", TagModeAny, TagModeAll:
+	default:
+		return fmt.Errorf("tag_mode must be either %s or %s", TagModeAny, TagModeAll)
+	}
+
+	switch f.Due {
+	case "", DueToday, DueOverdue:
+	default:
+		return fmt.Errorf("due must be either %s or %s", DueToday, DueOverdue)
+	}
+
+	if f.Assigned != "" && f.Assigned != "me" {
+		return errors.New("assigned must be me")
+	}
+
+	var err error
+	if f.MinPriority, err = ParsePriority(string(f.MinPriority)); err != nil {
+		return err
+	}
+	if f.MinPriority == PriorityNone {
+		f.MinPriority = ""
+	}
+
+	for _, d := range []string{f.DueFrom, f.DueTo} {
+		if d == "" {
+			continue
+		}
+		if _, _, err := ParseRelativeDate(d, time.Now(), time.UTC); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range f.Fields {
+		if !fieldKeyRegexp.MatchString(c.Key) {
+			return fmt.Errorf("%s: %s", ErrUnknownField, c.Key)
+		}
+	}
+
+	by, _ := s.SortBy()
+	switch {
+	case s.Sort == "":
+	case strings.HasPrefix(by, fieldsPrefix):
+	case by == SortSmart && strings.HasPrefix(s.Sort, "-"):
+		return errors.New("sort smart can't be descending")
+	case by == SortSmart, by == SortDue, by == SortPriority, by == SortCreated, by == SortTitle:
+	default:
+		return fmt.Errorf("sort must be one of %s, %s, %s, %s, %s or fields.{key}, - for descending",
+			SortSmart, SortDue, SortPriority, SortCreated, SortTitle)
+	}
+
+	if (len(f.Fields) > 0 || strings.HasPrefix(by, fieldsPrefix)) && (f.ListId == "" || f.ListId == InboxListId) {
+		return errors.New("Filtering or sorting by fields requires a list_id")
+	}
+
+	return nil
+}
+
+// fieldsPrefix prefixes the sorts by custom fields.
+const fieldsPrefix = "fields."
+
+// SortBy returns the sort of the smart list without its - prefix and
+// whether it is descending.
+func (s SmartList) SortBy() (string, bool) {
+	return strings.TrimPrefix(s.Sort, "-"), strings.HasPrefix(s.Sort, "-")
+}
+
+// SortField returns the key of the custom field the todos are sorted
+// by, if any.
+func (s SmartList) SortField() string {
+	by, _ := s.SortBy()
+	if !strings.HasPrefix(by, fieldsPrefix) {
+		return ""
+	}
+
+	return strings.TrimPrefix(by, fieldsPrefix)
+}
+
+// TodoFilter returns the filter of the todos of the smart list for the
+// user at now, relative dates being days of loc. fields are the custom
+// fields of the list of the smart list which its conditions and sort
+// must refer to.
+func (s SmartList) TodoFilter(userId string, fields []CustomField, now time.Time, loc *time.Location) (TodoFilter, error) {
+	sf := s.Filter
+	f := TodoFilter{
+		ListId:       sf.ListId,
+		Tags:         sf.Tags,
+		TagMode:      sf.TagMode,
+		MinPriority:  sf.MinPriority,
+		Completed:    sf.Completed,
+		Due:          sf.Due,
+		Search:       sf.Search,
+		HideDeferred: !sf.IncludeDeferred,
+		Now:          now,
+		Location:     loc,
+	}
+
+	if sf.Assigned == "me" {
+		f.AssigneeId = userId
+	}
+
+	if sf.DueFrom != "" {
+		t, _, err := ParseRelativeDate(sf.DueFrom, now, loc)
+		if err != nil {
+			return f, err
+		}
+		f.DueFrom = t
+	}
+
+	if sf.DueTo != "" {
+		t, day, err := ParseRelativeDate(sf.DueTo, now, loc)
+		if err != nil {
+			return f, err
+		}
+		if day {
+			t = t.AddDate(0, 0, 1)
+		} else {
+			t = t.Add(time.Nanosecond)
+		}
+		f.DueBefore = t
+	}
+
+	for _, c := range sf.Fields {
+		field := FindField(fields, c.Key)
+		if field == nil {
+			return f, fmt.Errorf("%s: %s", ErrUnknownField, c.Key)
+		}
+
+		fc, err := ParseFieldCondition(*field, c.Op, c.Value)
+		if err != nil {
+			return f, err
+		}
+		f.Fields = append(f.Fields, fc)
+	}
+
+	if key := s.SortField(); key != "" && FindField(fields, key) == nil {
+		return f, fmt.Errorf("%s: %s", ErrUnknownField, key)
+	}
+
+	return f, nil
+}
+
+// SortTodos orders the todos of the smart list by due date, priority,
+// creation or title. Todos without a due date come last. Sorts by
+// custom fields and the smart sort are left to SortByField and
+// SmartSort.
+func (s SmartList) SortTodos(ts []Todo) {
+	by, desc := s.SortBy()
+
+	var less func(a, b Todo) bool
+	switch by {
+	case SortDue:
+		less = func(a, b Todo) bool { return a.Due.Before(b.Due) }
+	case SortPriority:
+		less = func(a, b Todo) bool { return a.Priority.Level() < b.Priority.Level() }
+	case SortCreated:
+		less = func(a, b Todo) bool { return a.Created.Before(b.Created) }
+	case SortTitle:
+		less = func(a, b Todo) bool { return strings.ToLower(a.Title) < strings.ToLower(b.Title) }
+	default:
+		return
+	}
+
+	sort.SliceStable(ts, func(i, j int) bool {
+		a, b := ts[i], ts[j]
+		if by == SortDue && (a.Due.IsZero() || b.Due.IsZero()) {
+			return !a.Due.IsZero() && b.Due.IsZero()
+		}
+		if desc {
+			return less(b, a)
+		}
+		return less(a, b)
+	})
+}
+
+// SmartListStorage is an interface which details the requirments
+// to interface with retrieval and insertion of smart lists
+// into long term storage.
+type SmartListStorage interface {
+	Close()
+	GetSmartListById(id string) (*SmartList, error)
+	GetSmartListsForUserId(id string) ([]SmartList, error)
+	InsertSmartList(s SmartList) error
+	// ReplaceSmartList replaces the name and definition of a smart list.
+	ReplaceSmartList(s SmartList) error
+	DeleteSmartList(id string) error
+}
+
+// NewSmartListStorage is the abstracted function that returns
+// a SmartListStorage implementation depending on the value of
+// the SMART_LIST_STORE_TYPE.
+func NewSmartListStorage() SmartListStorage {
+	switch SMART_LIST_STORE_TYPE {
+	case Regular:
+		return NewSmartListDataStore()
+	case Test:
+		return newTestSmartListStorage()
+	}
+
+	return NewSmartListDataStore()
+}
+
+// SmartListDataStore is a wrapper struct for DataStore.
+// It implements the SmartListStorage interface
+type SmartListDataStore struct {
+	d mdb.DataStore
+}
+
+func NewSmartListDataStore() *SmartListDataStore {
+	sds := SmartListDataStore{}
+	sds.d = mdb.NewDataStore()
+	sds.d.Collection = SmartListCollection
+	return &sds
+}
+
+func (sds *SmartListDataStore) Close() {
+	sds.d.Close()
+}
+
+func (sds *SmartListDataStore) GetSmartListById(id string) (*SmartList, error) {
+	s := SmartList{}
+
+	raw, err := sds.d.GetObjectById(objectId(id))
+	if err != nil {
+		return nil, err
+	}
+
+	err = raw.Unmarshal(&s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func (sds *SmartListDataStore) GetSmartListsForUserId(id string) ([]SmartList, error) {
+	ss := make([]SmartList, 0)
+
+	raws, err := sds.d.GetObjectsForQueryPage(bson.M{"ownerid": id}, []string{"name", "_id"}, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, raw := range raws {
+		s := SmartList{}
+		if err := raw.Unmarshal(&s); err != nil {
+			return nil, err
+		}
+		ss = append(ss, s)
+	}
+
+	return ss, nil
+}
+
+func (sds *SmartListDataStore) InsertSmartList(s SmartList) error {
+	return sds.d.InsertObject(s)
+}
+
+func (sds *SmartListDataStore) ReplaceSmartList(s SmartList) error {
+	params := make(map[string]interface{})
+	params["id"] = s.Id
+
+	changes := map[string]interface{}{
+		"name":   s.Name,
+		"filter": s.Filter,
+		"sort":   s.Sort,
+	}
+
+	err := sds.d.ModifyObjectForId(params, changes)
+	if err == mdb.NotFoundError {
+		return SmartListNotFoundError
+	}
+
+	return err
+}
+
+func (sds *SmartListDataStore) DeleteSmartList(id string) error {
+	params := make(map[string]interface{})
+	params["id"] = objectId(id)
+	return sds.d.DeleteObjectForSelector(params)
+}
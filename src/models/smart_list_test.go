@@ -0,0 +1,141 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRelativeDate(t *testing.T) {
+	loc, _ := time.LoadLocation("America/New_York")
+	// Monday 2 January 2017, 15:04 in New York
+	now := time.Date(2017, 1, 2, 15, 4, 0, 0, loc)
+	today := time.Date(2017, 1, 2, 0, 0, 0, 0, loc)
+
+	for s, want := range map[string]struct {
+		t   time.Time
+		day bool
+	}{
+		"today":      {today, true},
+		"Today+7d":   {today.AddDate(0, 0, 7), true},
+		"tomorrow":   {today.AddDate(0, 0, 1), true},
+		"yesterday":  {today.AddDate(0, 0, -1), true},
+		"today-2w":   {today.AddDate(0, 0, -14), true},
+		"today+1m":   {today.AddDate(0, 1, 0), true},
+		"now":        {now, false},
+		"now-12h":    {now.Add(-12 * time.Hour), false},
+		"2017-03-01": {time.Date(2017, 3, 1, 0, 0, 0, 0, loc), true},
+	} {
+		got, day, err := ParseRelativeDate(s, now, loc)
+		if err != nil || !got.Equal(want.t) || day != want.day {
+			t.Errorf("Incorrect date %q: want %v %v got %v %v %v", s, want.t, want.day, got, day, err)
+		}
+	}
+
+	for _, s := range []string{"", "soon", "today+", "today+7", "today+7y", "next week"} {
+		if _, _, err := ParseRelativeDate(s, now, loc); err == nil {
+			t.Errorf("Invalid date should be rejected: %q", s)
+		}
+	}
+}
+
+func TestSmartListValidate(t *testing.T) {
+	valid := []SmartList{
+		{Name: " Work ", Filter: SmartFilter{Tags: []string{"Work"}, MinPriority: PriorityHigh, DueTo: "today+7d"}, Sort: "due"},
+		{Name: "Estimates", Filter: SmartFilter{ListId: "abc", Fields: []SmartFieldCondition{{Key: "estimate", Op: FieldLt, Value: "3"}}}, Sort: "-fields.estimate"},
+		{Name: "Smart", Sort: SortSmart},
+	}
+	for _, s := range valid {
+		if err := s.Validate(); err != nil {
+			t.Errorf("Valid smart list %q rejected: %v", s.Name, err)
+		}
+	}
+
+	invalid := []SmartList{
+		{Name: " "},
+		{Name: "a", Filter: SmartFilter{TagMode: "some"}},
+		{Name: "a", Filter: SmartFilter{Due: "later"}},
+		{Name: "a", Filter: SmartFilter{Assigned: "you"}},
+		{Name: "a", Filter: SmartFilter{MinPriority: "huge"}},
+		{Name: "a", Filter: SmartFilter{DueFrom: "whenever"}},
+		{Name: "a", Filter: SmartFilter{Fields: []SmartFieldCondition{{Key: "estimate", Value: "3"}}}},
+		{Name: "a", Sort: "-smart"},
+		{Name: "a", Sort: "size"},
+		{Name: "a", Sort: "fields.estimate"},
+	}
+	for _, s := range invalid {
+		if err := s.Validate(); err == nil {
+			t.Errorf("Invalid smart list should be rejected: %+v", s)
+		}
+	}
+}
+
+func TestSmartListTodoFilter(t *testing.T) {
+	loc := time.UTC
+	now := time.Date(2017, 1, 2, 15, 4, 0, 0, loc)
+	fields := []CustomField{{Key: "estimate", Type: FieldNumber}}
+
+	s := SmartList{Name: "Work this week", Filter: SmartFilter{
+		ListId:      "work",
+		Tags:        []string{"work"},
+		MinPriority: PriorityHigh,
+		DueTo:       "today+7d",
+		Fields:      []SmartFieldCondition{{Key: "estimate", Op: FieldLte, Value: "3"}},
+	}}
+	if err := s.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := s.TodoFilter("u", fields, now, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	todo := func(due time.Time, p Priority, estimate float64) Todo {
+		return Todo{ListId: "work", Tags: []string{"work"}, Priority: p, Due: due, Fields: map[string]interface{}{"estimate": estimate}}
+	}
+	in7 := time.Date(2017, 1, 9, 23, 0, 0, 0, loc)
+	for _, c := range []struct {
+		t    Todo
+		want bool
+	}{
+		{todo(in7, PriorityHigh, 2), true},
+		{todo(in7, PriorityUrgent, 3), true},
+		{todo(in7.Add(2*time.Hour), PriorityHigh, 2), false},
+		{todo(in7, PriorityMedium, 2), false},
+		{todo(in7, PriorityHigh, 5), false},
+		{todo(time.Time{}, PriorityHigh, 2), false},
+	} {
+		if got := f.matches(c.t); got != c.want {
+			t.Errorf("Incorrect match of %v %s %v: got %v", c.t.Due, c.t.Priority, c.t.Fields, got)
+		}
+	}
+
+	s.Filter.Fields[0].Key = "size"
+	if _, err := s.TodoFilter("u", fields, now, loc); err == nil {
+		t.Errorf("Conditions on unknown fields should be rejected")
+	}
+}
+
+func TestSmartListSortTodos(t *testing.T) {
+	day := time.Date(2017, 1, 2, 0, 0, 0, 0, time.UTC)
+	ts := []Todo{
+		{Title: "b", Priority: PriorityLow},
+		{Title: "a", Priority: PriorityHigh, Due: day.AddDate(0, 0, 2)},
+		{Title: "c", Priority: PriorityMedium, Due: day},
+	}
+
+	titles := func() string {
+		s := ""
+		for _, t := range ts {
+			s += t.Title
+		}
+		return s
+	}
+
+	for sort, want := range map[string]string{"due": "cab", "-priority": "acb", "title": "abc", "-due": "acb"} {
+		SmartList{Sort: sort}.SortTodos(ts)
+		if got := titles(); got != want {
+			t.Errorf("Incorrect order by %s: want %s got %s", sort, want, got)
+		}
+	}
+}
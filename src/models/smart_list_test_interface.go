@@ -0,0 +1,77 @@
+package models
+
+import (
+	"log"
+	"sort"
+)
+
+var smartListMap = make(map[string]SmartList)
+
+// TestSmartListStorage implements the SmartListStorage interface
+type TestSmartListStorage struct {
+	smartLists *map[string]SmartList
+}
+
+func newTestSmartListStorage() *TestSmartListStorage {
+	s := TestSmartListStorage{}
+	s.smartLists = &smartListMap
+	return &s
+}
+
+func (tss *TestSmartListStorage) Close() {
+	log.Println("Closing TestSmartListStorage")
+}
+
+func (tss *TestSmartListStorage) GetSmartListById(id string) (*SmartList, error) {
+	s, ok := (*tss.smartLists)[id]
+	if !ok {
+		return nil, SmartListNotFoundError
+	}
+
+	return &s, nil
+}
+
+func (tss *TestSmartListStorage) GetSmartListsForUserId(id string) ([]SmartList, error) {
+	ss := make([]SmartList, 0)
+	for _, s := range *tss.smartLists {
+		if s.Ownerid == id {
+			ss = append(ss, s)
+		}
+	}
+
+	sort.Slice(ss, func(i, j int) bool {
+		if ss[i].Name == ss[j].Name {
+			return ss[i].Id < ss[j].Id
+		}
+		return ss[i].Name < ss[j].Name
+	})
+
+	return ss, nil
+}
+
+func (tss *TestSmartListStorage) InsertSmartList(s SmartList) error {
+	(*tss.smartLists)[s.Id.Hex()] = s
+	return nil
+}
+
+func (tss *TestSmartListStorage) ReplaceSmartList(s SmartList) error {
+	smartLists := *tss.smartLists
+	old, ok := smartLists[s.Id.Hex()]
+	if !ok {
+		return SmartListNotFoundError
+	}
+
+	s.Ownerid, s.Created = old.Ownerid, old.Created
+	smartLists[s.Id.Hex()] = s
+	return nil
+}
+
+func (tss *TestSmartListStorage) DeleteSmartList(id string) error {
+	smartLists := *tss.smartLists
+	if _, ok := smartLists[id]; !ok {
+		return SmartListNotFoundError
+	}
+
+	delete(smartLists, id)
+	return nil
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePriority(t *testing.T) {
+	p, err := ParsePriority("")
+	if err != nil || p != PriorityNone {
+		t.Errorf("Empty priority should be none: got %s %v", p, err)
+	}
+
+	p, err = ParsePriority("urgent")
+	if err != nil || p.Level() != 4 {
+		t.Errorf("Incorrect urgent priority: got %s %v", p, err)
+	}
+
+	_, err = ParsePriority("critical")
+	if err == nil {
+		t.Error("Should fail for an unknown priority")
+	}
+}
+
+func TestSmartSort(t *testing.T) {
+	now := time.Date(2017, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	overdue := NewTodo()
+	overdue.Title = "overdue"
+	overdue.Due = now.Add(-2 * time.Hour)
+
+	urgent := NewTodo()
+	urgent.Title = "urgent"
+	urgent.Priority = PriorityUrgent
+
+	dueTomorrow := NewTodo()
+	dueTomorrow.Title = "due tomorrow"
+	dueTomorrow.Due = now.Add(24 * time.Hour)
+
+	done := NewTodo()
+	done.Title = "done"
+	done.Priority = PriorityUrgent
+	done.Due = now.Add(-time.Hour)
+	done.Completed = true
+
+	nothing := NewTodo()
+	nothing.Title = "nothing"
+
+	ts := []Todo{nothing, done, dueTomorrow, urgent, overdue}
+	SmartSort(ts, DefaultSmartWeights, now)
+
+	expected := []string{"overdue", "urgent", "due tomorrow", "nothing", "done"}
+	for i, title := range expected {
+		if ts[i].Title != title {
+			t.Errorf("Incorrect smart order at %d: want %s got %s", i, title, ts[i].Title)
+		}
+	}
+
+	// Only caring about the priority puts the urgent todo first
+	SmartSort(ts, SmartWeights{Priority: 1}, now)
+	if ts[0].Title != "urgent" {
+		t.Errorf("Incorrect smart order for priority weights: got %s first", ts[0].Title)
+	}
+}
+
+func TestSmartWeightsValidate(t *testing.T) {
+	if err := DefaultSmartWeights.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	if err := (SmartWeights{Age: -1}).Validate(); err == nil {
+		t.Error("Should fail for negative weights")
+	}
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Presets of SnoozeUntil.
+const (
+	SnoozeLaterToday = "later_today"
+	SnoozeTomorrow   = "tomorrow"
+	SnoozeNextWeek   = "next_week"
+)
+
+const (
+	// snoozeLater is how much later a todo snoozed for later today
+	// resurfaces.
+	snoozeLater = 3 * time.Hour
+	// snoozeMorning is the hour at which todos snoozed for another day
+	// resurface.
+	snoozeMorning = 9
+)
+
+var ErrUnknownSnoozePreset = fmt.Errorf("Snooze preset must be one of %s, %s or %s",
+	SnoozeLaterToday, SnoozeTomorrow, SnoozeNextWeek)
+
+// SnoozeUntil returns when a todo snoozed at now with the preset
+// resurfaces, in the time zone loc:
+//   - later today is 3 hours later on the hour, at the latest midnight
+//   - tomorrow is 9:00 tomorrow
+//   - next week is 9:00 next Monday
+func SnoozeUntil(preset string, now time.Time, loc *time.Location) (time.Time, error) {
+	local := now.In(loc)
+	y, m, d := local.Date()
+
+	switch preset {
+	case SnoozeLaterToday:
+		later := local.Add(snoozeLater)
+		until := time.Date(later.Year(), later.Month(), later.Day(), later.Hour(), 0, 0, 0, loc)
+		if until.Before(later) {
+			until = until.Add(time.Hour)
+		}
+		if _, end := DayBounds(now, loc); until.After(end) {
+			until = end
+		}
+		return until, nil
+	case SnoozeTomorrow:
+		return time.Date(y, m, d+1, snoozeMorning, 0, 0, 0, loc), nil
+	case SnoozeNextWeek:
+		days := (8 - int(local.Weekday())) % 7
+		if days == 0 {
+			days = 7
+		}
+		return time.Date(y, m, d+days, snoozeMorning, 0, 0, 0, loc), nil
+	}
+
+	return time.Time{}, ErrUnknownSnoozePreset
+}
+
+// ParseDeferUntil parses when a todo is deferred until, either an RFC
+// 3339 time or a date such as 2017-01-02 which starts at midnight in
+// loc. An empty string doesn't defer the todo.
+func ParseDeferUntil(s string, loc *time.Location) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	until, _, err := ParseDue(s, loc)
+	if err != nil {
+		return nil, errors.New("defer_until must be an RFC 3339 time or a date (2006-01-02)")
+	}
+
+	return &until, nil
+}
+
+// Deferred reports whether the todo is hidden at now because it is
+// deferred until later.
+func (t Todo) Deferred(now time.Time) bool {
+	return t.DeferUntil != nil && t.DeferUntil.After(now)
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSnoozeUntil(t *testing.T) {
+	loc, _ := time.LoadLocation("America/New_York")
+	at := func(d, h, m int) time.Time { return time.Date(2024, time.March, d, h, m, 0, 0, loc) }
+
+	tests := []struct {
+		preset string
+		now    time.Time
+		want   time.Time
+	}{
+		{SnoozeLaterToday, at(6, 10, 20), at(6, 14, 0)},
+		{SnoozeLaterToday, at(6, 10, 0), at(6, 13, 0)},
+		{SnoozeLaterToday, at(6, 22, 30), at(7, 0, 0)},
+		{SnoozeTomorrow, at(6, 23, 59), at(7, 9, 0)},
+		{SnoozeNextWeek, at(6, 8, 0), at(11, 9, 0)},  // Wednesday
+		{SnoozeNextWeek, at(10, 8, 0), at(11, 9, 0)}, // Sunday, across the change to daylight saving time
+		{SnoozeNextWeek, at(11, 8, 0), at(18, 9, 0)}, // Monday
+	}
+
+	for _, tt := range tests {
+		got, err := SnoozeUntil(tt.preset, tt.now.UTC(), loc)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("%s at %v: want %v got %v %v", tt.preset, tt.now, tt.want, got, err)
+		}
+	}
+
+	if _, err := SnoozeUntil("someday", time.Now(), loc); err != ErrUnknownSnoozePreset {
+		t.Errorf("Expected ErrUnknownSnoozePreset: %v", err)
+	}
+}
+
+func TestHideDeferred(t *testing.T) {
+	now := time.Date(2024, time.March, 6, 12, 0, 0, 0, time.UTC)
+	later, earlier := now.Add(time.Hour), now.Add(-time.Hour)
+
+	ts := []Todo{NewTodo(), NewTodo(), NewTodo()}
+	ts[0].DeferUntil = &later
+	ts[1].DeferUntil = &earlier
+
+	f := TodoFilter{HideDeferred: true, Now: now}
+	if f.matches(ts[0]) || !f.matches(ts[1]) || !f.matches(ts[2]) {
+		t.Error("Only todos deferred until after now should be hidden")
+	}
+
+	f.HideDeferred = false
+	if !f.matches(ts[0]) {
+		t.Error("Deferred todos should be included on demand")
+	}
+
+	until, err := ParseDeferUntil("2024-03-07", time.UTC)
+	if err != nil || !until.Equal(time.Date(2024, time.March, 7, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Incorrect defer until: %v %v", until, err)
+	}
+	if _, err := ParseDeferUntil("next tuesday", time.UTC); err == nil {
+		t.Error("Expected an error for an invalid defer until")
+	}
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// CompletionStats are the completions of a user aggregated by storage.
+type CompletionStats struct {
+	// PerDay is the number of todos completed per day of the user's
+	// time zone, formatted with DateFormat.
+	PerDay map[string]int
+	// AverageCompletion is the average time from the creation to the
+	// completion of a todo. HasAverage is false without completions.
+	AverageCompletion time.Duration
+	HasAverage        bool
+}
+
+// PeriodCount is the number of todos completed in a day (2006-01-02)
+// or an ISO week (2006-W01).
+type PeriodCount struct {
+	Period string `json:"period"`
+	Count  int    `json:"count"`
+}
+
+// Stats are the productivity statistics of a user.
+type Stats struct {
+	CompletedPerDay  []PeriodCount `json:"completed_per_day"`
+	CompletedPerWeek []PeriodCount `json:"completed_per_week"`
+	// CurrentStreak is the number of consecutive days up to today, or
+	// yesterday while nothing is completed today, with completions.
+	CurrentStreak int `json:"current_streak"`
+	LongestStreak int `json:"longest_streak"`
+	// AverageCompletionSeconds is the average time from the creation
+	// to the completion of a todo, omitted without completions.
+	AverageCompletionSeconds *int64 `json:"average_completion_seconds,omitempty"`
+	Overdue                  int    `json:"overdue"`
+}
+
+// isoWeek formats the ISO week of t e.g. 2017-W01.
+func isoWeek(t time.Time) string {
+	y, w := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", y, w)
+}
+
+// NewStats computes the statistics at now in loc with series of the
+// last days days and weeks weeks, including the current ones.
+func NewStats(cs CompletionStats, overdue int, now time.Time, loc *time.Location, days, weeks int) Stats {
+	s := Stats{Overdue: overdue}
+	today, _ := DayBounds(now, loc)
+
+	for i := days - 1; i >= 0; i-- {
+		day := today.AddDate(0, 0, -i).Format(DateFormat)
+		s.CompletedPerDay = append(s.CompletedPerDay, PeriodCount{day, cs.PerDay[day]})
+	}
+
+	perWeek := make(map[string]int)
+	for day, n := range cs.PerDay {
+		if d, err := time.ParseInLocation(DateFormat, day, loc); err == nil {
+			perWeek[isoWeek(d)] += n
+		}
+	}
+	for i := weeks - 1; i >= 0; i-- {
+		week := isoWeek(today.AddDate(0, 0, -7*i))
+		s.CompletedPerWeek = append(s.CompletedPerWeek, PeriodCount{week, perWeek[week]})
+	}
+
+	// The current streak may still be extended today
+	day := today
+	if cs.PerDay[day.Format(DateFormat)] == 0 {
+		day = day.AddDate(0, 0, -1)
+	}
+	for cs.PerDay[day.Format(DateFormat)] > 0 {
+		s.CurrentStreak++
+		day = day.AddDate(0, 0, -1)
+	}
+
+	for d := range cs.PerDay {
+		start, err := time.ParseInLocation(DateFormat, d, loc)
+		if err != nil || cs.PerDay[start.AddDate(0, 0, -1).Format(DateFormat)] > 0 {
+			continue // Not the first day of a streak
+		}
+
+		n := 0
+		for cs.PerDay[start.AddDate(0, 0, n).Format(DateFormat)] > 0 {
+			n++
+		}
+		if n > s.LongestStreak {
+			s.LongestStreak = n
+		}
+	}
+
+	if cs.HasAverage {
+		seconds := int64(cs.AverageCompletion / time.Second)
+		s.AverageCompletionSeconds = &seconds
+	}
+
+	return s
+}
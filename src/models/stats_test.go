@@ -0,0 +1,65 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewStats(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	// Wednesday 2017-01-11 in Tokyo
+	now := time.Date(2017, 1, 11, 9, 0, 0, 0, loc)
+
+	cs := CompletionStats{
+		PerDay: map[string]int{
+			"2016-12-30": 1,
+			"2016-12-31": 2,
+			"2017-01-01": 1,
+			"2017-01-02": 4,
+			"2017-01-09": 1,
+			"2017-01-10": 3,
+		},
+		AverageCompletion: 90 * time.Minute,
+		HasAverage:        true,
+	}
+
+	s := NewStats(cs, 2, now, loc, 3, 2)
+
+	days := []PeriodCount{{"2017-01-09", 1}, {"2017-01-10", 3}, {"2017-01-11", 0}}
+	if len(s.CompletedPerDay) != len(days) {
+		t.Fatalf("Incorrect days: %v", s.CompletedPerDay)
+	}
+	for i, d := range days {
+		if s.CompletedPerDay[i] != d {
+			t.Errorf("Incorrect day: %v != %v", s.CompletedPerDay[i], d)
+		}
+	}
+
+	weeks := []PeriodCount{{"2017-W01", 4}, {"2017-W02", 4}}
+	if len(s.CompletedPerWeek) != len(weeks) {
+		t.Fatalf("Incorrect weeks: %v", s.CompletedPerWeek)
+	}
+	for i, w := range weeks {
+		if s.CompletedPerWeek[i] != w {
+			t.Errorf("Incorrect week: %v != %v", s.CompletedPerWeek[i], w)
+		}
+	}
+
+	if s.CurrentStreak != 2 {
+		t.Errorf("Current streak should continue from yesterday: %d", s.CurrentStreak)
+	}
+	if s.LongestStreak != 4 {
+		t.Errorf("Incorrect longest streak: %d", s.LongestStreak)
+	}
+	if s.AverageCompletionSeconds == nil || *s.AverageCompletionSeconds != 5400 {
+		t.Errorf("Incorrect average completion: %v", s.AverageCompletionSeconds)
+	}
+	if s.Overdue != 2 {
+		t.Errorf("Incorrect overdue count: %d", s.Overdue)
+	}
+
+	s = NewStats(CompletionStats{}, 0, now, loc, 1, 1)
+	if s.CurrentStreak != 0 || s.LongestStreak != 0 || s.AverageCompletionSeconds != nil {
+		t.Errorf("Stats without completions should be empty: %+v", s)
+	}
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"strings"
+)
+
+const (
+	TagModeAny = "any" // Todos with at least one of the tags
+	TagModeAll = "all" // Todos with every one of the tags
+)
+
+// TagCount is the number of todos of a user which carry a tag.
+type TagCount struct {
+	Tag   string `json:"tag" bson:"_id"`
+	Count int    `json:"count" bson:"count"`
+}
+
+// NormalizeTag lowercases the tag and strips surrounding
+// whitespace and a leading '#'.
+func NormalizeTag(tag string) string {
+	tag = strings.TrimSpace(tag)
+	tag = strings.TrimPrefix(tag, "#")
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// NormalizeTags normalizes every tag and removes empty and
+// duplicate tags while keeping the original order.
+func NormalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	ts := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+
+		seen[tag] = true
+		ts = append(ts, tag)
+	}
+
+	return ts
+}
+
+// hasTag reports whether the todo carries the tag.
+func (t Todo) hasTag(tag string) bool {
+	for _, tt := range t.Tags {
+		if tt == tag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags := NormalizeTags([]string{" Home", "#home", "", "work ", "#"})
+	expected := []string{"home", "work"}
+
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("Tags incorrectly normalized: want %v got %v", expected, tags)
+	}
+
+	if NormalizeTags(nil) != nil {
+		t.Error("Normalizing nil tags should return nil")
+	}
+}
+
+func TestTodoFilterMatchesTags(t *testing.T) {
+	t0 := NewTodo()
+	t0.Tags = []string{"home", "errand"}
+
+	if !(TodoFilter{Tags: []string{"home", "work"}}).matches(t0) {
+		t.Error("Todo should match any of the tags")
+	}
+
+	if (TodoFilter{Tags: []string{"home", "work"}, TagMode: TagModeAll}).matches(t0) {
+		t.Error("Todo should not match all of the tags")
+	}
+
+	if !(TodoFilter{Tags: []string{"home", "errand"}, TagMode: TagModeAll}).matches(t0) {
+		t.Error("Todo should match all of the tags")
+	}
+}
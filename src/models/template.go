@@ -0,0 +1,319 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/mgo.v2/bson"
+	"mdb"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const TemplateCollection = "templates"
+
+// MaxTemplateSubtasks is the number of subtasks a template may have.
+const MaxTemplateSubtasks = 100
+
+var TemplateNotFoundError = mdb.NotFoundError
+
+var (
+	ErrNoTemplateName  = errors.New("Template name must not be empty")
+	ErrNoTemplateTitle = errors.New("Template title must not be empty")
+	ErrTooManySubtasks = fmt.Errorf("Templates may have at most %d subtasks", MaxTemplateSubtasks)
+)
+
+// placeholderRegexp matches the {{variable}} placeholders of templates.
+var placeholderRegexp = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// offsetRegexp matches due offsets such as 3d, -1w or 2d4h.
+var offsetRegexp = regexp.MustCompile(`^([+-])?(?:(\d+)w)?(?:(\d+)d)?(?:(\d+)h)?$`)
+
+// TemplateTodo is a todo of a template. Its title, note and tags may
+// hold {{variable}} placeholders and its due date is an offset from
+// the anchor date the template is instantiated with, see ParseOffset.
+type TemplateTodo struct {
+	Title     string   `json:"title" bson:"title"`
+	Note      string   `json:"note,omitempty" bson:"note,omitempty"`
+	Tags      []string `json:"tags,omitempty" bson:"tags,omitempty"`
+	Priority  Priority `json:"priority,omitempty" bson:"priority,omitempty"`
+	DueOffset string   `json:"due_offset,omitempty" bson:"due_offset,omitempty"`
+}
+
+// Template is a reusable todo along with its subtasks e.g. a release
+// checklist. Instantiate creates the todos of the template.
+type Template struct {
+	Id           bson.ObjectId `json:"id" bson:"_id,omitempty"`
+	Ownerid      string        `json:"-" bson:"ownerid"`
+	Name         string        `json:"name" bson:"name"`
+	Created      time.Time     `json:"created_date" bson:"created_date,omitempty"`
+	TemplateTodo `bson:",inline"`
+	Subtasks     []TemplateTodo `json:"subtasks,omitempty" bson:"subtasks,omitempty"`
+}
+
+func NewTemplate() Template {
+	t := Template{}
+	t.Id = bson.NewObjectId()
+	t.Created = time.Now()
+	return t
+}
+
+// ParseOffset parses a due offset made of weeks, days and hours in that
+// order e.g. 3d, -1w or 2d4h. Weeks and days are calendar days.
+func ParseOffset(s string) (int, time.Duration, error) {
+	m := offsetRegexp.FindStringSubmatch(s)
+	if m == nil || m[2]+m[3]+m[4] == "" {
+		return 0, 0, fmt.Errorf("Invalid due offset: %q, try 3d, -1w or 2d4h", s)
+	}
+
+	n := func(s string) int {
+		i, _ := strconv.Atoi(s)
+		return i
+	}
+
+	days, hours := 7*n(m[2])+n(m[3]), n(m[4])
+	if m[1] == "-" {
+		days, hours = -days, -hours
+	}
+
+	return days, time.Duration(hours) * time.Hour, nil
+}
+
+// Validate normalizes the template and checks that it is complete.
+func (t *Template) Validate() error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return ErrNoTemplateName
+	}
+
+	if len(t.Subtasks) > MaxTemplateSubtasks {
+		return ErrTooManySubtasks
+	}
+
+	if err := t.TemplateTodo.validate(); err != nil {
+		return err
+	}
+
+	for i := range t.Subtasks {
+		if err := t.Subtasks[i].validate(); err != nil {
+			return fmt.Errorf("Subtask %d: %s", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+func (tt *TemplateTodo) validate() error {
+	tt.Title = strings.TrimSpace(tt.Title)
+	if tt.Title == "" {
+		return ErrNoTemplateTitle
+	}
+
+	var err error
+	tt.Priority, err = ParsePriority(string(tt.Priority))
+	if err != nil {
+		return err
+	}
+
+	if tt.DueOffset != "" {
+		if _, _, err := ParseOffset(tt.DueOffset); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// todos returns the todos of the template, the template's own first.
+func (t Template) todos() []TemplateTodo {
+	return append([]TemplateTodo{t.TemplateTodo}, t.Subtasks...)
+}
+
+// Variables returns the names of the placeholders of the template.
+func (t Template) Variables() []string {
+	found := make(map[string]bool)
+	for _, tt := range t.todos() {
+		for _, s := range append([]string{tt.Title, tt.Note}, tt.Tags...) {
+			for _, m := range placeholderRegexp.FindAllStringSubmatch(s, -1) {
+				found[m[1]] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Instantiate creates the todos of the template with the variables
+// substituted for its placeholders, the template's own todo first and
+// its subtasks after. Due dates are offset from the anchor, which is
+// all day when allDay is set and the offset is a number of days.
+func (t Template) Instantiate(vars map[string]string, anchor time.Time, allDay bool) ([]Todo, error) {
+	var missing []string
+	for _, name := range t.Variables() {
+		if _, ok := vars[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("Missing template variables: %s", strings.Join(missing, ", "))
+	}
+
+	substitute := func(s string) string {
+		return placeholderRegexp.ReplaceAllStringFunc(s, func(p string) string {
+			return vars[placeholderRegexp.FindStringSubmatch(p)[1]]
+		})
+	}
+
+	now := time.Now()
+	ts := make([]Todo, 0, len(t.Subtasks)+1)
+	for i, tt := range t.todos() {
+		todo := NewTodo()
+		todo.Title = substitute(tt.Title)
+		todo.Note = substitute(tt.Note)
+		todo.Priority = tt.Priority
+		todo.Created = now
+		for _, tag := range tt.Tags {
+			todo.Tags = append(todo.Tags, substitute(tag))
+		}
+		todo.Tags = NormalizeTags(todo.Tags)
+
+		if tt.DueOffset != "" {
+			if anchor.IsZero() {
+				return nil, errors.New("Template due offsets require an anchor date")
+			}
+
+			days, d, err := ParseOffset(tt.DueOffset)
+			if err != nil {
+				return nil, err
+			}
+			todo.Due = anchor.AddDate(0, 0, days).Add(d)
+			todo.AllDay = allDay && d == 0
+		}
+
+		if i > 0 {
+			todo.ParentId = ts[0].Id
+		}
+		ts = append(ts, todo)
+	}
+
+	return ts, nil
+}
+
+// TemplateStorage is an interface which details the requirments
+// to interface with retrieval and insertion of templates
+// into long term storage.
+type TemplateStorage interface {
+	Close()
+	GetTemplateById(id string) (*Template, error)
+	GetTemplatesForUserId(id string) ([]Template, error)
+	InsertTemplate(t Template) error
+	// ReplaceTemplate replaces the name and content of a template.
+	ReplaceTemplate(t Template) error
+	DeleteTemplate(id string) error
+}
+
+// NewTemplateStorage is the abstracted function that returns
+// a TemplateStorage implementation depending on the value of
+// the TEMPLATE_STORE_TYPE.
+func NewTemplateStorage() TemplateStorage {
+	switch TEMPLATE_STORE_TYPE {
+	case Regular:
+		return NewTemplateDataStore()
+	case Test:
+		return newTestTemplateStorage()
+	}
+
+	return NewTemplateDataStore()
+}
+
+// TemplateDataStore is a wrapper struct for DataStore.
+// It implements the TemplateStorage interface
+type TemplateDataStore struct {
+	d mdb.DataStore
+}
+
+func NewTemplateDataStore() *TemplateDataStore {
+	tds := TemplateDataStore{}
+	tds.d = mdb.NewDataStore()
+	tds.d.Collection = TemplateCollection
+	return &tds
+}
+
+func (tds *TemplateDataStore) Close() {
+	tds.d.Close()
+}
+
+func (tds *TemplateDataStore) GetTemplateById(id string) (*Template, error) {
+	t := Template{}
+
+	raw, err := tds.d.GetObjectById(objectId(id))
+	if err != nil {
+		return nil, err
+	}
+
+	err = raw.Unmarshal(&t)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+func (tds *TemplateDataStore) GetTemplatesForUserId(id string) ([]Template, error) {
+	ts := make([]Template, 0)
+
+	raws, err := tds.d.GetObjectsForQueryPage(bson.M{"ownerid": id}, []string{"name", "_id"}, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, raw := range raws {
+		t := Template{}
+		if err := raw.Unmarshal(&t); err != nil {
+			return nil, err
+		}
+		ts = append(ts, t)
+	}
+
+	return ts, nil
+}
+
+func (tds *TemplateDataStore) InsertTemplate(t Template) error {
+	return tds.d.InsertObject(t)
+}
+
+func (tds *TemplateDataStore) ReplaceTemplate(t Template) error {
+	params := make(map[string]interface{})
+	params["id"] = t.Id
+
+	changes := map[string]interface{}{
+		"name":       t.Name,
+		"title":      t.Title,
+		"note":       t.Note,
+		"tags":       t.Tags,
+		"priority":   t.Priority,
+		"due_offset": t.DueOffset,
+		"subtasks":   t.Subtasks,
+	}
+
+	err := tds.d.ModifyObjectForId(params, changes)
+	if err == mdb.NotFoundError {
+		return TemplateNotFoundError
+	}
+
+	return err
+}
+
+func (tds *TemplateDataStore) DeleteTemplate(id string) error {
+	params := make(map[string]interface{})
+	params["id"] = objectId(id)
+	return tds.d.DeleteObjectForSelector(params)
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseOffset(t *testing.T) {
+	for s, want := range map[string]struct {
+		days int
+		d    time.Duration
+	}{
+		"3d":      {3, 0},
+		"-1w":     {-7, 0},
+		"+2d4h":   {2, 4 * time.Hour},
+		"-1w1d2h": {-8, -2 * time.Hour},
+		"0d":      {0, 0},
+	} {
+		days, d, err := ParseOffset(s)
+		if err != nil || days != want.days || d != want.d {
+			t.Errorf("Incorrect offset %q: %d %v %v", s, days, d, err)
+		}
+	}
+
+	for _, s := range []string{"", "-", "3", "1d1w", "tomorrow"} {
+		if _, _, err := ParseOffset(s); err == nil {
+			t.Errorf("Invalid offset should be rejected: %q", s)
+		}
+	}
+}
+
+func releaseTemplate() Template {
+	tp := NewTemplate()
+	tp.Name = " Release "
+	tp.Title = "Release {{version}}"
+	tp.Note = "Ship {{ version }} of {{project}}"
+	tp.DueOffset = "0d"
+	tp.Subtasks = []TemplateTodo{
+		{Title: "Tag {{version}}", Tags: []string{"{{project}}"}, DueOffset: "-1d"},
+		{Title: "Announce", DueOffset: "1d10h"},
+		{Title: "Celebrate"},
+	}
+	return tp
+}
+
+func TestTemplateValidate(t *testing.T) {
+	tp := releaseTemplate()
+	if err := tp.Validate(); err != nil || tp.Name != "Release" {
+		t.Errorf("Template should be valid: %q %v", tp.Name, err)
+	}
+
+	tp.Subtasks[1].DueOffset = "soon"
+	if err := tp.Validate(); err == nil {
+		t.Error("Invalid due offset should be rejected")
+	}
+
+	tp = releaseTemplate()
+	tp.Subtasks[2].Title = " "
+	if err := tp.Validate(); err == nil {
+		t.Error("Subtasks without a title should be rejected")
+	}
+
+	tp = releaseTemplate()
+	tp.Name = ""
+	if err := tp.Validate(); err != ErrNoTemplateName {
+		t.Errorf("Template without a name should be rejected: %v", err)
+	}
+}
+
+func TestTemplateInstantiate(t *testing.T) {
+	tp := releaseTemplate()
+	if vs := tp.Variables(); !reflect.DeepEqual(vs, []string{"project", "version"}) {
+		t.Errorf("Incorrect variables: %v", vs)
+	}
+
+	loc, _ := time.LoadLocation("Europe/Paris")
+	anchor := time.Date(2017, 3, 27, 0, 0, 0, 0, loc)
+
+	if _, err := tp.Instantiate(map[string]string{"version": "1.2"}, anchor, true); err == nil {
+		t.Error("Missing variables should be rejected")
+	}
+
+	ts, err := tp.Instantiate(map[string]string{"version": "1.2", "project": "2Do"}, anchor, true)
+	if err != nil || len(ts) != 4 {
+		t.Fatalf("Template should be instantiated: %v %v", ts, err)
+	}
+
+	if ts[0].Title != "Release 1.2" || ts[0].Note != "Ship 1.2 of 2Do" || ts[0].ParentId != "" {
+		t.Errorf("Incorrect 2Do: %+v", ts[0])
+	}
+	if !ts[0].Due.Equal(anchor) || !ts[0].AllDay {
+		t.Errorf("2Do should be due all day on the anchor: %v %v", ts[0].Due, ts[0].AllDay)
+	}
+
+	for _, sub := range ts[1:] {
+		if sub.ParentId != ts[0].Id {
+			t.Errorf("Subtask should belong to the 2Do: %+v", sub)
+		}
+	}
+
+	if ts[1].Title != "Tag 1.2" || !reflect.DeepEqual(ts[1].Tags, []string{"2do"}) {
+		t.Errorf("Incorrect subtask: %+v", ts[1])
+	}
+	if !ts[1].Due.Equal(time.Date(2017, 3, 26, 0, 0, 0, 0, loc)) || !ts[1].AllDay {
+		t.Errorf("Subtask should be due the day before: %v", ts[1].Due)
+	}
+	if !ts[2].Due.Equal(time.Date(2017, 3, 28, 10, 0, 0, 0, loc)) || ts[2].AllDay {
+		t.Errorf("Subtask should be due at a time: %v %v", ts[2].Due, ts[2].AllDay)
+	}
+	if !ts[3].Due.IsZero() {
+		t.Errorf("Subtask without an offset should not be due: %v", ts[3].Due)
+	}
+
+	if _, err := tp.Instantiate(map[string]string{"version": "1.2", "project": "2Do"}, time.Time{}, false); err == nil {
+		t.Error("Due offsets without an anchor should be rejected")
+	}
+}
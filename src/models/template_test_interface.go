@@ -0,0 +1,77 @@
+package models
+
+import (
+	"log"
+	"sort"
+)
+
+var templateMap = make(map[string]Template)
+
+// TestTemplateStorage implements the TemplateStorage interface
+type TestTemplateStorage struct {
+	templates *map[string]Template
+}
+
+func newTestTemplateStorage() *TestTemplateStorage {
+	t := TestTemplateStorage{}
+	t.templates = &templateMap
+	return &t
+}
+
+func (tts *TestTemplateStorage) Close() {
+	log.Println("Closing TestTemplateStorage")
+}
+
+func (tts *TestTemplateStorage) GetTemplateById(id string) (*Template, error) {
+	t, ok := (*tts.templates)[id]
+	if !ok {
+		return nil, TemplateNotFoundError
+	}
+
+	return &t, nil
+}
+
+func (tts *TestTemplateStorage) GetTemplatesForUserId(id string) ([]Template, error) {
+	ts := make([]Template, 0)
+	for _, t := range *tts.templates {
+		if t.Ownerid == id {
+			ts = append(ts, t)
+		}
+	}
+
+	sort.Slice(ts, func(i, j int) bool {
+		if ts[i].Name == ts[j].Name {
+			return ts[i].Id < ts[j].Id
+		}
+		return ts[i].Name < ts[j].Name
+	})
+
+	return ts, nil
+}
+
+func (tts *TestTemplateStorage) InsertTemplate(t Template) error {
+	(*tts.templates)[t.Id.Hex()] = t
+	return nil
+}
+
+func (tts *TestTemplateStorage) ReplaceTemplate(t Template) error {
+	templates := *tts.templates
+	old, ok := templates[t.Id.Hex()]
+	if !ok {
+		return TemplateNotFoundError
+	}
+
+	t.Ownerid, t.Created = old.Ownerid, old.Created
+	templates[t.Id.Hex()] = t
+	return nil
+}
+
+func (tts *TestTemplateStorage) DeleteTemplate(id string) error {
+	templates := *tts.templates
+	if _, ok := templates[id]; !ok {
+		return TemplateNotFoundError
+	}
+
+	delete(templates, id)
+	return nil
+}
@@ -0,0 +1,283 @@
+package models
+
+import (
+	"errors"
+	"gopkg.in/mgo.v2/bson"
+	"mdb"
+	"time"
+)
+
+const TimeEntryCollection = "time_entries"
+
+// MaxTimeEntry is the longest manual time entry.
+const MaxTimeEntry = 24 * time.Hour
+
+var TimeEntryNotFoundError = mdb.NotFoundError
+
+var ErrTimerRunning = errors.New("A timer is already running")
+
+// TimeEntry is time a user logged on a todo, either by running a timer
+// or manually. Each user has at most one running timer.
+type TimeEntry struct {
+	Id      bson.ObjectId `json:"id" bson:"_id,omitempty"`
+	TodoId  string        `json:"todo_id" bson:"todo_id"`
+	Ownerid string        `json:"user_id" bson:"ownerid"`
+	Start   time.Time     `json:"start" bson:"start"`
+	End     *time.Time    `json:"end,omitempty" bson:"end,omitempty"`
+	Seconds int64         `json:"seconds" bson:"seconds"`
+	Note    string        `json:"note,omitempty" bson:"note,omitempty"`
+	Running bool          `json:"running" bson:"running"`
+}
+
+func NewTimeEntry() TimeEntry {
+	e := TimeEntry{}
+	e.Id = bson.NewObjectId()
+	return e
+}
+
+// Stop ends the entry at end.
+func (e *TimeEntry) Stop(end time.Time) {
+	if end.Before(e.Start) {
+		end = e.Start
+	}
+
+	e.End = &end
+	e.Seconds = int64(end.Sub(e.Start) / time.Second)
+	e.Running = false
+}
+
+// ValidateManual checks a manual time entry, which has a start and
+// either an end or its length in seconds, and completes the other.
+func (e *TimeEntry) ValidateManual() error {
+	if e.Start.IsZero() {
+		return errors.New("Time entry requires a start")
+	}
+
+	if e.End != nil {
+		if !e.End.After(e.Start) {
+			return errors.New("Time entry must end after its start")
+		}
+		e.Seconds = int64(e.End.Sub(e.Start) / time.Second)
+	} else {
+		if e.Seconds <= 0 {
+			return errors.New("Time entry requires an end or a positive number of seconds")
+		}
+		end := e.Start.Add(time.Duration(e.Seconds) * time.Second)
+		e.End = &end
+	}
+
+	if time.Duration(e.Seconds)*time.Second > MaxTimeEntry {
+		return errors.New("Time entry must not exceed 24 hours")
+	}
+
+	e.Running = false
+	return nil
+}
+
+// TimeEntryStorage is an interface which details the requirments
+// to interface with retrieval and insertion of time entries
+// into long term storage.
+type TimeEntryStorage interface {
+	Close()
+	GetTimeEntryById(id string) (*TimeEntry, error)
+	// GetRunningTimer returns TimeEntryNotFoundError when the user has
+	// no running timer.
+	GetRunningTimer(userId string) (*TimeEntry, error)
+	GetTimeEntriesForTodoId(todoId string) ([]TimeEntry, error)
+	// GetTimeEntriesForUserId returns the stopped entries of the user
+	// which started in [from, to).
+	GetTimeEntriesForUserId(userId string, from, to time.Time) ([]TimeEntry, error)
+	// GetLoggedSeconds returns the seconds logged on each of the todos
+	// by stopped entries.
+	GetLoggedSeconds(todoIds []string) (map[string]int64, error)
+	InsertTimeEntry(e TimeEntry) error
+	// StartTimer inserts the running entry unless the user already has
+	// a running timer, then it returns ErrTimerRunning.
+	StartTimer(e TimeEntry) error
+	// StopTimer stops the running timer of the user at end.
+	StopTimer(userId string, end time.Time) (*TimeEntry, error)
+	DeleteTimeEntry(id string) error
+	DeleteTimeEntriesForTodoId(todoId string) error
+}
+
+// NewTimeEntryStorage is the abstracted function that returns
+// a TimeEntryStorage implementation depending on the value of
+// the TIME_ENTRY_STORE_TYPE.
+func NewTimeEntryStorage() TimeEntryStorage {
+	switch TIME_ENTRY_STORE_TYPE {
+	case Regular:
+		return NewTimeEntryDataStore()
+	case Test:
+		return newTestTimeEntryStorage()
+	}
+
+	return NewTimeEntryDataStore()
+}
+
+// TimeEntryDataStore is a wrapper struct for DataStore.
+// It implements the TimeEntryStorage interface
+type TimeEntryDataStore struct {
+	d mdb.DataStore
+}
+
+func NewTimeEntryDataStore() *TimeEntryDataStore {
+	eds := TimeEntryDataStore{}
+	eds.d = mdb.NewDataStore()
+	eds.d.Collection = TimeEntryCollection
+	return &eds
+}
+
+func (eds *TimeEntryDataStore) Close() {
+	eds.d.Close()
+}
+
+func (eds *TimeEntryDataStore) GetTimeEntryById(id string) (*TimeEntry, error) {
+	e := TimeEntry{}
+
+	raw, err := eds.d.GetObjectById(objectId(id))
+	if err != nil {
+		return nil, err
+	}
+
+	err = raw.Unmarshal(&e)
+	if err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+func (eds *TimeEntryDataStore) GetRunningTimer(userId string) (*TimeEntry, error) {
+	e := TimeEntry{}
+
+	raw, err := eds.d.GetObjectForQuery(bson.M{"ownerid": userId, "running": true})
+	if err != nil {
+		return nil, err
+	}
+
+	err = raw.Unmarshal(&e)
+	if err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+func (eds *TimeEntryDataStore) GetTimeEntriesForTodoId(todoId string) ([]TimeEntry, error) {
+	return eds.getTimeEntriesForQuery(bson.M{"todo_id": todoId})
+}
+
+func (eds *TimeEntryDataStore) GetTimeEntriesForUserId(userId string, from, to time.Time) ([]TimeEntry, error) {
+	return eds.getTimeEntriesForQuery(bson.M{
+		"ownerid": userId,
+		"running": false,
+		"start":   bson.M{"$gte": from, "$lt": to},
+	})
+}
+
+func (eds *TimeEntryDataStore) getTimeEntriesForQuery(query bson.M) ([]TimeEntry, error) {
+	es := make([]TimeEntry, 0)
+
+	raws, err := eds.d.GetObjectsForQueryPage(query, []string{"start"}, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, raw := range raws {
+		e := TimeEntry{}
+		err := raw.Unmarshal(&e)
+		if err != nil {
+			return nil, err
+		}
+		es = append(es, e)
+	}
+
+	return es, nil
+}
+
+func (eds *TimeEntryDataStore) GetLoggedSeconds(todoIds []string) (map[string]int64, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"todo_id": bson.M{"$in": todoIds}, "running": false}},
+		{"$group": bson.M{"_id": "$todo_id", "seconds": bson.M{"$sum": "$seconds"}}},
+	}
+
+	raws, err := eds.d.AggregateObjects(pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	logged := make(map[string]int64)
+	for _, raw := range raws {
+		var r struct {
+			TodoId  string `bson:"_id"`
+			Seconds int64  `bson:"seconds"`
+		}
+		if err := raw.Unmarshal(&r); err != nil {
+			return nil, err
+		}
+		logged[r.TodoId] = r.Seconds
+	}
+
+	return logged, nil
+}
+
+func (eds *TimeEntryDataStore) InsertTimeEntry(e TimeEntry) error {
+	return eds.d.InsertObject(e)
+}
+
+// runningTimerIndex is the unique index which keeps starts of timers
+// from racing, see EnsureTimeEntryIndexes.
+const runningTimerIndex = "running_timer"
+
+func (eds *TimeEntryDataStore) StartTimer(e TimeEntry) error {
+	e.Running = true
+	inserted, err := eds.d.InsertObjectIfNone(bson.M{"ownerid": e.Ownerid, "running": true}, e)
+	if mdb.IsDuplicateError(err) {
+		// A concurrent start won
+		return ErrTimerRunning
+	}
+	if err != nil {
+		return err
+	}
+
+	if !inserted {
+		return ErrTimerRunning
+	}
+
+	return nil
+}
+
+func (eds *TimeEntryDataStore) StopTimer(userId string, end time.Time) (*TimeEntry, error) {
+	e, err := eds.GetRunningTimer(userId)
+	if err != nil {
+		return nil, err
+	}
+	e.Stop(end)
+
+	// Only stops the timer if it wasn't stopped concurrently
+	query := bson.M{"_id": e.Id, "running": true}
+	update := bson.M{"$set": bson.M{"running": false, "end": e.End, "seconds": e.Seconds}}
+	if _, err := eds.d.FindAndModifyObject(query, update); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (eds *TimeEntryDataStore) DeleteTimeEntry(id string) error {
+	return eds.d.DeleteObjectForSelector(map[string]interface{}{"id": objectId(id)})
+}
+
+func (eds *TimeEntryDataStore) DeleteTimeEntriesForTodoId(todoId string) error {
+	return eds.d.DeleteObjectsForQuery(bson.M{"todo_id": todoId})
+}
+
+// EnsureTimeEntryIndexes creates the index which makes the running
+// timer of each user unique, so that concurrent starts of timers cannot
+// both succeed.
+func EnsureTimeEntryIndexes() error {
+	eds := NewTimeEntryDataStore()
+	defer eds.Close()
+
+	return eds.d.EnsureUniqueIndex(runningTimerIndex, bson.D{{Name: "ownerid", Value: 1}}, bson.M{"running": true})
+}
@@ -0,0 +1,149 @@
+package models
+
+import (
+	"bytes"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"mdb"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestValidateManual(t *testing.T) {
+	start := time.Date(2017, 1, 2, 9, 0, 0, 0, time.UTC)
+
+	e := TimeEntry{Start: start, Seconds: 1800}
+	if err := e.ValidateManual(); err != nil || e.End == nil || !e.End.Equal(start.Add(30*time.Minute)) {
+		t.Errorf("End should be computed: %v %v", e.End, err)
+	}
+
+	end := start.Add(time.Hour)
+	e = TimeEntry{Start: start, End: &end}
+	if err := e.ValidateManual(); err != nil || e.Seconds != 3600 {
+		t.Errorf("Seconds should be computed: %d %v", e.Seconds, err)
+	}
+
+	invalid := []TimeEntry{
+		{Seconds: 60},
+		{Start: start},
+		{Start: start, End: &start},
+		{Start: start, Seconds: int64(25 * time.Hour / time.Second)},
+	}
+	for _, e := range invalid {
+		if err := e.ValidateManual(); err == nil {
+			t.Errorf("Time entry should be invalid: %v", e)
+		}
+	}
+}
+
+func TestStartTimer(t *testing.T) {
+	eds := newTestTimeEntryStorage()
+	e0 := NewTimeEntry()
+	e0.Ownerid = "TestStartTimer"
+	e0.Start = time.Now().Add(-time.Minute)
+	if err := eds.StartTimer(e0); err != nil {
+		t.Fatal(err)
+	}
+
+	e1 := NewTimeEntry()
+	e1.Ownerid = e0.Ownerid
+	if err := eds.StartTimer(e1); err != ErrTimerRunning {
+		t.Errorf("Only one timer should run: got %v", err)
+	}
+
+	e, err := eds.StopTimer(e0.Ownerid, time.Now())
+	if err != nil || e.Running || e.Seconds < 59 {
+		t.Errorf("Timer should be stopped: %v %v", e, err)
+	}
+
+	if _, err := eds.StopTimer(e0.Ownerid, time.Now()); err != TimeEntryNotFoundError {
+		t.Errorf("No timer should be running: got %v", err)
+	}
+}
+
+func TestStartTimerConcurrently(t *testing.T) {
+	eds := NewTimeEntryDataStore()
+	eds.d.Database = testDB
+	eds.d.Collection = "2Do_TestStartTimerConcurrently_Collection"
+	defer eds.Close()
+
+	session, err := mgo.Dial(mdb.Hostname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+	defer session.DB(eds.d.Database).C(eds.d.Collection).DropCollection()
+
+	err = eds.d.EnsureUniqueIndex(runningTimerIndex, bson.D{{Name: "ownerid", Value: 1}}, bson.M{"running": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e := NewTimeEntry()
+			e.Ownerid = "TestStartTimerConcurrently"
+			e.Start = time.Now()
+			errs <- eds.StartTimer(e)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	started := 0
+	for err := range errs {
+		switch err {
+		case nil:
+			started++
+		case ErrTimerRunning:
+		default:
+			t.Error(err)
+		}
+	}
+	if started != 1 {
+		t.Errorf("Only one timer should start: %d", started)
+	}
+}
+
+func TestTimeReport(t *testing.T) {
+	t0, t1 := NewTodo(), NewTodo()
+	t0.Title, t0.Tags = "Invoice", []string{"acme", "billing"}
+	t1.Title, t1.Tags = "Call", []string{"acme"}
+	todos := map[string]Todo{t0.Id: t0, t1.Id: t1}
+
+	day := time.Date(2017, 1, 2, 9, 0, 0, 0, time.UTC)
+	es := []TimeEntry{
+		{TodoId: t0.Id, Start: day, Seconds: 600},
+		{TodoId: t0.Id, Start: day.AddDate(0, 0, 1), Seconds: 300},
+		{TodoId: t1.Id, Start: day, Seconds: 60},
+	}
+
+	tr := NewTimeReport(day, day.AddDate(0, 0, 7), es, todos, time.UTC)
+	if tr.Total != 960 {
+		t.Errorf("Incorrect total: %d", tr.Total)
+	}
+	if len(tr.Todos) != 2 || tr.Todos[0].Title != "Invoice" || tr.Todos[0].Seconds != 900 {
+		t.Errorf("Incorrect todos: %v", tr.Todos)
+	}
+	if len(tr.Tags) != 2 || tr.Tags[0].Key != "acme" || tr.Tags[0].Seconds != 960 {
+		t.Errorf("Incorrect tags: %v", tr.Tags)
+	}
+	if len(tr.Days) != 2 || tr.Days[0].Key != "2017-01-02" || tr.Days[0].Seconds != 660 {
+		t.Errorf("Incorrect days: %v", tr.Days)
+	}
+
+	var b bytes.Buffer
+	if err := tr.WriteCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 8 || lines[0] != "group,key,title,seconds" || lines[7] != "total,,,960" {
+		t.Errorf("Incorrect csv: %q", lines)
+	}
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"log"
+	"sort"
+	"time"
+)
+
+var timeEntryMap = make(map[string]TimeEntry)
+
+// TestTimeEntryStorage implements the TimeEntryStorage interface
+type TestTimeEntryStorage struct {
+	entries *map[string]TimeEntry
+}
+
+func newTestTimeEntryStorage() *TestTimeEntryStorage {
+	t := TestTimeEntryStorage{}
+	t.entries = &timeEntryMap
+	return &t
+}
+
+func (tes *TestTimeEntryStorage) Close() {
+	log.Println("Closing TestTimeEntryStorage")
+}
+
+func (tes *TestTimeEntryStorage) GetTimeEntryById(id string) (*TimeEntry, error) {
+	e, ok := (*tes.entries)[id]
+	if !ok {
+		return nil, TimeEntryNotFoundError
+	}
+
+	return &e, nil
+}
+
+func (tes *TestTimeEntryStorage) GetRunningTimer(userId string) (*TimeEntry, error) {
+	for _, e := range *tes.entries {
+		if e.Ownerid == userId && e.Running {
+			return &e, nil
+		}
+	}
+
+	return nil, TimeEntryNotFoundError
+}
+
+func (tes *TestTimeEntryStorage) GetTimeEntriesForTodoId(todoId string) ([]TimeEntry, error) {
+	return tes.filter(func(e TimeEntry) bool { return e.TodoId == todoId }), nil
+}
+
+func (tes *TestTimeEntryStorage) GetTimeEntriesForUserId(userId string, from, to time.Time) ([]TimeEntry, error) {
+	return tes.filter(func(e TimeEntry) bool {
+		return e.Ownerid == userId && !e.Running && !e.Start.Before(from) && e.Start.Before(to)
+	}), nil
+}
+
+func (tes *TestTimeEntryStorage) filter(keep func(TimeEntry) bool) []TimeEntry {
+	es := make([]TimeEntry, 0)
+	for _, e := range *tes.entries {
+		if keep(e) {
+			es = append(es, e)
+		}
+	}
+
+	sort.Slice(es, func(i, j int) bool { return es[i].Start.Before(es[j].Start) })
+	return es
+}
+
+func (tes *TestTimeEntryStorage) GetLoggedSeconds(todoIds []string) (map[string]int64, error) {
+	logged := make(map[string]int64)
+	for _, id := range todoIds {
+		for _, e := range *tes.entries {
+			if e.TodoId == id && !e.Running {
+				logged[id] += e.Seconds
+			}
+		}
+	}
+
+	return logged, nil
+}
+
+func (tes *TestTimeEntryStorage) InsertTimeEntry(e TimeEntry) error {
+	(*tes.entries)[e.Id.Hex()] = e
+	return nil
+}
+
+func (tes *TestTimeEntryStorage) StartTimer(e TimeEntry) error {
+	if _, err := tes.GetRunningTimer(e.Ownerid); err == nil {
+		return ErrTimerRunning
+	}
+
+	e.Running = true
+	return tes.InsertTimeEntry(e)
+}
+
+func (tes *TestTimeEntryStorage) StopTimer(userId string, end time.Time) (*TimeEntry, error) {
+	e, err := tes.GetRunningTimer(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	e.Stop(end)
+	(*tes.entries)[e.Id.Hex()] = *e
+	return e, nil
+}
+
+func (tes *TestTimeEntryStorage) DeleteTimeEntry(id string) error {
+	entries := *tes.entries
+	if _, ok := entries[id]; !ok {
+		return TimeEntryNotFoundError
+	}
+
+	delete(entries, id)
+	return nil
+}
+
+func (tes *TestTimeEntryStorage) DeleteTimeEntriesForTodoId(todoId string) error {
+	entries := *tes.entries
+	for id, e := range entries {
+		if e.TodoId == todoId {
+			delete(entries, id)
+		}
+	}
+
+	return nil
+}
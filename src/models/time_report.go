@@ -0,0 +1,97 @@
+package models
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const TimeReportDayFormat = "2006-01-02"
+
+// TimeReportRow is the time logged for a todo, tag or day.
+type TimeReportRow struct {
+	Key     string `json:"key"`
+	Title   string `json:"title,omitempty"`
+	Seconds int64  `json:"seconds"`
+}
+
+// TimeReport aggregates the time logged in [From, To) per todo, per tag
+// and per day. The time of a todo counts towards each of its tags and
+// entries count towards the day they started.
+type TimeReport struct {
+	From  time.Time       `json:"from"`
+	To    time.Time       `json:"to"`
+	Total int64           `json:"total_seconds"`
+	Todos []TimeReportRow `json:"todos"`
+	Tags  []TimeReportRow `json:"tags"`
+	Days  []TimeReportRow `json:"days"`
+}
+
+// NewTimeReport aggregates the entries. todos holds the todos of the
+// entries by id, days are those of the location loc.
+func NewTimeReport(from, to time.Time, es []TimeEntry, todos map[string]Todo, loc *time.Location) TimeReport {
+	tr := TimeReport{From: from, To: to}
+	perTodo := make(map[string]int64)
+	perTag := make(map[string]int64)
+	perDay := make(map[string]int64)
+
+	for _, e := range es {
+		tr.Total += e.Seconds
+		perTodo[e.TodoId] += e.Seconds
+		perDay[e.Start.In(loc).Format(TimeReportDayFormat)] += e.Seconds
+		for _, tag := range todos[e.TodoId].Tags {
+			perTag[tag] += e.Seconds
+		}
+	}
+
+	tr.Todos = reportRows(perTodo, func(id string) string { return todos[id].Title })
+	tr.Tags = reportRows(perTag, nil)
+	tr.Days = reportRows(perDay, nil)
+
+	// Todos and tags are ordered by the time spent, days chronologically
+	sortBySeconds := func(rows []TimeReportRow) {
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].Seconds > rows[j].Seconds })
+	}
+	sortBySeconds(tr.Todos)
+	sortBySeconds(tr.Tags)
+
+	return tr
+}
+
+// reportRows returns the rows ordered by key.
+func reportRows(seconds map[string]int64, title func(string) string) []TimeReportRow {
+	rows := make([]TimeReportRow, 0, len(seconds))
+	for k, s := range seconds {
+		row := TimeReportRow{Key: k, Seconds: s}
+		if title != nil {
+			row.Title = title(k)
+		}
+		rows = append(rows, row)
+	}
+
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Key < rows[j].Key })
+	return rows
+}
+
+// WriteCSV writes the report as csv with the columns group, key, title
+// and seconds. The group is one of todo, tag, day or total.
+func (tr TimeReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"group", "key", "title", "seconds"})
+
+	groups := []struct {
+		name string
+		rows []TimeReportRow
+	}{{"todo", tr.Todos}, {"tag", tr.Tags}, {"day", tr.Days}}
+	for _, g := range groups {
+		for _, row := range g.rows {
+			cw.Write([]string{g.name, row.Key, row.Title, strconv.FormatInt(row.Seconds, 10)})
+		}
+	}
+	cw.Write([]string{"total", "", "", strconv.FormatInt(tr.Total, 10)})
+
+	cw.Flush()
+	return cw.Error()
+}
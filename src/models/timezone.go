@@ -0,0 +1,114 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// DateFormat is the format of date-only, all day, due dates.
+const DateFormat = "2006-01-02"
+
+// Due filters of TodoFilter.
+const (
+	DueToday   = "today"
+	DueOverdue = "overdue"
+)
+
+// localeExp matches BCP 47 language tags such as en, en-GB or zh-Hant-TW.
+var localeExp = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// ValidateTimeZone checks that the name is an IANA time zone. An empty
+// name is UTC.
+func ValidateTimeZone(name string) error {
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("Unknown time zone: %s", name)
+	}
+
+	return nil
+}
+
+// ValidateLocale checks that the locale is a language tag e.g. en-GB.
+// An empty locale is the default locale of clients.
+func ValidateLocale(locale string) error {
+	if locale != "" && !localeExp.MatchString(locale) {
+		return fmt.Errorf("Invalid locale: %s", locale)
+	}
+
+	return nil
+}
+
+// Location returns the time zone of the user, UTC by default.
+func (u User) Location() *time.Location {
+	loc, err := time.LoadLocation(u.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
+}
+
+// ParseDue parses a due date which is either an RFC 3339 time or a date
+// such as 2017-01-02. A date is all day and starts at midnight in loc.
+func ParseDue(s string, loc *time.Location) (time.Time, bool, error) {
+	if s == "" {
+		return time.Time{}, false, nil
+	}
+
+	if d, err := time.ParseInLocation(DateFormat, s, loc); err == nil {
+		return d, true, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, false, errors.New("due_date must be an RFC 3339 time or a date (2006-01-02)")
+	}
+
+	return t, false, nil
+}
+
+// DayBounds returns the start of the day of now in loc and the start
+// of the next day.
+func DayBounds(now time.Time, loc *time.Location) (time.Time, time.Time) {
+	y, m, d := now.In(loc).Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// Overdue reports whether the todo is past due at now. An all day todo
+// is overdue once its day has ended in loc.
+func (t Todo) Overdue(now time.Time, loc *time.Location) bool {
+	if t.Completed || t.Due.IsZero() {
+		return false
+	}
+
+	if t.AllDay {
+		start, _ := DayBounds(now, loc)
+		return t.Due.Before(start)
+	}
+
+	return t.Due.Before(now)
+}
+
+// dueChange converts a due date for ModifyTodo, either a time or an
+// RFC 3339 string. A cleared due date, nil, empty or zero, is stored as
+// null rather than the zero time, which queries would find overdue.
+func dueChange(v interface{}) (interface{}, error) {
+	switch due := v.(type) {
+	case nil:
+		return nil, nil
+	case time.Time:
+		if due.IsZero() {
+			return nil, nil
+		}
+		return due, nil
+	case string:
+		if due == "" {
+			return nil, nil
+		}
+		return time.Parse(time.RFC3339, due)
+	}
+
+	return nil, errors.New("Value is not a time")
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDue(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+
+	due, allDay, err := ParseDue("2017-01-02", loc)
+	if err != nil || !allDay || !due.Equal(time.Date(2017, 1, 2, 0, 0, 0, 0, loc)) {
+		t.Errorf("Incorrect all day due date: %v %v %v", due, allDay, err)
+	}
+
+	due, allDay, err = ParseDue("2017-01-02T15:04:05Z", loc)
+	if err != nil || allDay || !due.Equal(time.Date(2017, 1, 2, 15, 4, 5, 0, time.UTC)) {
+		t.Errorf("Incorrect due date: %v %v %v", due, allDay, err)
+	}
+
+	if _, _, err := ParseDue("tomorrow", loc); err == nil {
+		t.Error("Invalid due date should be rejected")
+	}
+
+	for _, cleared := range []interface{}{nil, "", time.Time{}} {
+		if v, err := dueChange(cleared); err != nil || v != nil {
+			t.Errorf("Cleared due date should be stored as null: %#v %v %v", cleared, v, err)
+		}
+	}
+}
+
+func TestValidateProfile(t *testing.T) {
+	for _, tz := range []string{"", "Europe/Paris"} {
+		if err := ValidateTimeZone(tz); err != nil {
+			t.Errorf("Time zone should be valid: %q %v", tz, err)
+		}
+	}
+	if err := ValidateTimeZone("Mars/Base"); err == nil {
+		t.Error("Unknown time zone should be rejected")
+	}
+
+	for _, locale := range []string{"", "en", "en-GB", "zh-Hant-TW"} {
+		if err := ValidateLocale(locale); err != nil {
+			t.Errorf("Locale should be valid: %q %v", locale, err)
+		}
+	}
+	if err := ValidateLocale("en_GB!"); err == nil {
+		t.Error("Invalid locale should be rejected")
+	}
+}
+
+func TestDueFilter(t *testing.T) {
+	loc, _ := time.LoadLocation("America/Los_Angeles")
+	// 23:00 on the 2nd in Los Angeles is already the 3rd in UTC
+	now := time.Date(2017, 1, 2, 23, 0, 0, 0, loc)
+
+	allDayToday := Todo{Due: time.Date(2017, 1, 2, 0, 0, 0, 0, loc), AllDay: true}
+	allDayYesterday := Todo{Due: time.Date(2017, 1, 1, 0, 0, 0, 0, loc), AllDay: true}
+	earlier := Todo{Due: now.Add(-time.Hour)}
+	tomorrow := Todo{Due: now.Add(2 * time.Hour)}
+	done := Todo{Due: now.Add(-time.Hour), Completed: true}
+
+	today := TodoFilter{Due: DueToday, Now: now, Location: loc}
+	overdue := TodoFilter{Due: DueOverdue, Now: now, Location: loc}
+
+	tests := []struct {
+		t                Todo
+		isToday, overdue bool
+	}{
+		{allDayToday, true, false},
+		{allDayYesterday, false, true},
+		{earlier, true, true},
+		{tomorrow, false, false},
+		{done, true, false},
+	}
+
+	for i, test := range tests {
+		if got := today.matches(test.t); got != test.isToday {
+			t.Errorf("%d: Incorrect today match: want %v got %v", i, test.isToday, got)
+		}
+		if got := overdue.matches(test.t); got != test.overdue {
+			t.Errorf("%d: Incorrect overdue match: want %v got %v", i, test.overdue, got)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"errors"
 	"gopkg.in/mgo.v2/bson"
+	"markdown"
 	"mdb"
 	"time"
 )
@@ -13,34 +14,121 @@ var TodoConvertError = errors.New("Failed to convert to Todo type")
 var TodoNotFoundError = mdb.NotFoundError
 
 type Todo struct {
-	Id bson.ObjectId `json:"id" bson:"_id,omitempty"` // MongoDBId
-	// TODO: Add id which would be exposed and hide the mongodb id
-	// see http://stackoverflow.com/a/13740114/2812587 for more info
+	Id        string    `json:"id" bson:"_id,omitempty"` // A public id, see NewPublicId
 	Title     string    `json:"title" bson:"title"`
-	Note      string    `json:"note" bson:"note"`
+	Note      string    `json:"note" bson:"note"` // Markdown, see RenderNotes
 	Created   time.Time `json:"created_date" bson:"created_date,omitempty"`
 	Due       time.Time `json:"due_date" bson:"due_date,omitempty"`
+	AllDay    bool      `json:"all_day,omitempty" bson:"all_day,omitempty"` // Due is a date, see ParseDue
 	Ownerid   string    `json:"-" bson:"ownerid"`
+	ListId    string    `json:"list_id" bson:"list_id,omitempty"`
+	Tags      []string  `json:"tags,omitempty" bson:"tags,omitempty"`
+	Priority  Priority  `json:"priority,omitempty" bson:"priority,omitempty"`
 	Completed bool      `json:"completed" bson:"completed"`
+
+	// CompletedAt and CompletedBy record when and by whom the todo
+	// was completed.
+	CompletedAt *time.Time `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+	CompletedBy string     `json:"completed_by,omitempty" bson:"completed_by,omitempty"`
+
+	// Status is the state of the todo in the workflow of its list,
+	// Completed is derived from it. StatusHistory records its changes.
+	Status        string         `json:"status,omitempty" bson:"status,omitempty"`
+	StatusHistory []StatusChange `json:"status_history,omitempty" bson:"status_history,omitempty"`
+
+	// Archived todos are left out of the todos of a user unless they
+	// are asked for, see TodoFilter.
+	Archived   bool       `json:"archived,omitempty" bson:"archived,omitempty"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty" bson:"archived_at,omitempty"`
+
+	// DeferUntil hides the todo until then, see TodoFilter.HideDeferred.
+	DeferUntil *time.Time `json:"defer_until,omitempty" bson:"defer_until,omitempty"`
+
+	// ParentId is the todo which this todo is a subtask of.
+	ParentId string `json:"parent_id,omitempty" bson:"parent_id,omitempty"`
+
+	// AssigneeId is the user who is to do the todo.
+	AssigneeId string `json:"assignee_id,omitempty" bson:"assignee_id,omitempty"`
+
+	// BlockedBy are the ids of the todos which must be completed first.
+	// Blocked is computed from them, see MarkBlocked.
+	BlockedBy []string `json:"blocked_by,omitempty" bson:"blocked_by,omitempty"`
+	Blocked   bool     `json:"blocked" bson:"-"`
+
+	// Position orders the todos manually, see PositionBetween.
+	Position float64 `json:"position" bson:"position"`
+
+	// Logged is the total time logged on the todo in seconds, it is
+	// computed from the time entries.
+	Logged int64 `json:"logged_seconds" bson:"-"`
+
+	// NoteHTML and Links are rendered from the note when asked for,
+	// see RenderNotes.
+	NoteHTML string          `json:"note_html,omitempty" bson:"-"`
+	Links    []markdown.Link `json:"links,omitempty" bson:"-"`
+
+	// Fields are the values of the custom fields of the list of the
+	// todo by their key, see CustomField.
+	Fields map[string]interface{} `json:"fields,omitempty" bson:"fields,omitempty"`
+
+	Recurrence *Recurrence `json:"recurrence,omitempty" bson:"recurrence,omitempty"`
+	Reminders  []Reminder  `json:"reminders,omitempty" bson:"reminders,omitempty"`
+
+	Attachments []Attachment `json:"attachments,omitempty" bson:"attachments,omitempty"`
+	Shares      []Share      `json:"shares,omitempty" bson:"shares,omitempty"`
 }
 
 func NewTodo() Todo {
 	t := Todo{}
-	t.Id = bson.NewObjectId()
+	t.Id = NewPublicId()
 	return t
 }
 
 // TodoStorage is an interface which details the requirments
 // to interface with retrieval and insertion of todos
-// into long term storage.
+// into long term storage. Whether a user may access a todo is
+// decided by the authz package, not by the storage.
 type TodoStorage interface {
 	Close()
 	GetAllTodos() ([]Todo, error)
 	GetTodoById(id string) (*Todo, error)
+	GetTodosByIds(ids []string) ([]Todo, error)
+	// GetTodosBlockedBy returns the todos which the todo with the id blocks.
+	GetTodosBlockedBy(id string) ([]Todo, error)
+	// GetTodosForUserId and GetTodosForFilter return the todos ordered
+	// by their position.
 	GetTodosForUserId(id string) ([]Todo, error)
+	GetTodosForFilter(userId string, f TodoFilter) ([]Todo, error)
+	CountTodosForFilter(userId string, f TodoFilter) (int, error)
+	// GetArchivedTodos returns a page of the archived todos accessible
+	// to the user which pass the filter, most recently archived first.
+	GetArchivedTodos(userId string, f TodoFilter, skip, limit int) ([]Todo, error)
+	// ArchiveTodos archives the user's todos which the rule applies to.
+	ArchiveTodos(userId string, rule ArchiveRule, now time.Time) error
+	// GetCompletionStats aggregates the todos the user completed, days
+	// are those of loc.
+	GetCompletionStats(userId string, loc *time.Location) (CompletionStats, error)
 	InsertTodo(t Todo) error
-	ModifyTodo(todoId, userId string, changes map[string]interface{}) error
-	DeleteTodo(id, userId string) error
+	// LastPosition returns the greatest position of the user's todos.
+	LastPosition(userId string) (float64, error)
+	// SetTodoPositions writes the positions of the todos with the ids.
+	SetTodoPositions(positions map[string]float64) error
+	ModifyTodo(todoId string, changes map[string]interface{}) error
+	SetTodoShares(todoId string, shares []Share) error
+	MoveTodosToList(fromListId, toListId string) error
+	GetTagsForUserId(id string) ([]TagCount, error)
+	MergeTags(userId string, tags []string, into string) error
+	// AddAttachment links the attachment to the todo unless the
+	// attachments of the owner of the todo would exceed the quota in
+	// bytes, then it returns ErrAttachmentQuota.
+	AddAttachment(todoId string, a Attachment, quota int64) error
+	RemoveAttachment(todoId, attachmentId string) error
+	GetAttachmentsSize(userId string) (int64, error)
+	DeleteTodo(id string) error
+	// RemoveBlocker removes the todo with the id from the blockers
+	// of every todo.
+	RemoveBlocker(id string) error
+	DeleteTodosInList(listId string) error
 }
 
 // NewTodoStorage is the abstracted function that returns
@@ -127,11 +215,18 @@ func (tds *TodoDataStore) GetTodoById(id string) (*Todo, error) {
 	return &t, nil
 }
 
-func (tds *TodoDataStore) GetTodosForUserId(id string) ([]Todo, error) {
+func (tds *TodoDataStore) GetTodosByIds(ids []string) ([]Todo, error) {
+	return tds.getTodosForQuery(bson.M{"_id": bson.M{"$in": ids}})
+}
+
+func (tds *TodoDataStore) GetTodosBlockedBy(id string) ([]Todo, error) {
+	return tds.getTodosForQuery(bson.M{"blocked_by": id})
+}
+
+func (tds *TodoDataStore) getTodosForQuery(query bson.M) ([]Todo, error) {
 	ts := make([]Todo, 0)
-	query := bson.M{"ownerid": id}
 
-	raws, err := tds.d.GetObjectsForQuery(query)
+	raws, err := tds.d.GetObjectsForQueryPage(query, positionSort, 0, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -148,38 +243,181 @@ func (tds *TodoDataStore) GetTodosForUserId(id string) ([]Todo, error) {
 	return ts, nil
 }
 
+func (tds *TodoDataStore) GetTodosForUserId(id string) ([]Todo, error) {
+	return tds.GetTodosForFilter(id, TodoFilter{})
+}
+
+// GetTodosForFilter returns the todos accessible to the user
+// which pass the filter.
+func (tds *TodoDataStore) GetTodosForFilter(userId string, f TodoFilter) ([]Todo, error) {
+	return tds.getTodosForQuery(f.query(userId))
+}
+
+func (tds *TodoDataStore) CountTodosForFilter(userId string, f TodoFilter) (int, error) {
+	return tds.d.CountObjectsForQuery(f.query(userId))
+}
+
+func (tds *TodoDataStore) GetArchivedTodos(userId string, f TodoFilter, skip, limit int) ([]Todo, error) {
+	f.Archived = true
+	ts := make([]Todo, 0)
+
+	raws, err := tds.d.GetObjectsForQueryPage(f.query(userId), archiveSort, skip, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, raw := range raws {
+		t := Todo{}
+		if err := raw.Unmarshal(&t); err != nil {
+			return nil, err
+		}
+		ts = append(ts, t)
+	}
+
+	return ts, nil
+}
+
+func (tds *TodoDataStore) ArchiveTodos(userId string, rule ArchiveRule, now time.Time) error {
+	return tds.d.ModifyObjectsForQuery(rule.query(userId, now), bson.M{"archived": true, "archived_at": now})
+}
+
+func (tds *TodoDataStore) GetCompletionStats(userId string, loc *time.Location) (CompletionStats, error) {
+	cs := CompletionStats{}
+	query := bson.M{"completed": true, "completed_by": userId}
+
+	var err error
+	cs.PerDay, err = tds.d.CountObjectsPerDay(query, "completed_at", loc.String())
+	if err != nil {
+		return cs, err
+	}
+
+	cs.AverageCompletion, cs.HasAverage, err = tds.d.AverageDuration(query, "created_date", "completed_at")
+	return cs, err
+}
+
 func (tds *TodoDataStore) InsertTodo(t Todo) error {
 	return tds.d.InsertObject(t)
 }
 
-func (tds *TodoDataStore) ModifyTodo(todoId, userId string, changes map[string]interface{}) error {
-	params := make(map[string]string)
+// modifiableTodoKeys maps the keys which may be changed with ModifyTodo
+// to a function validating, and if needed converting, the new value.
+var modifiableTodoKeys = map[string]func(interface{}) (interface{}, error){
+	"title":          stringChange,
+	"note":           stringChange,
+	"due_date":       dueChange,
+	"all_day":        boolChange,
+	"created_date":   stringChange,
+	"list_id":        stringChange,
+	"assignee_id":    stringChange,
+	"blocked_by":     blockedByChange,
+	"position":       positionChange,
+	"tags":           tagsChange,
+	"priority":       priorityChange,
+	"completed":      boolChange,
+	"completed_at":   optionalTimeChange,
+	"completed_by":   stringChange,
+	"archived":       boolChange,
+	"status":         stringChange,
+	"status_history": statusHistoryChange,
+	"archived_at":    optionalTimeChange,
+	"defer_until":    optionalTimeChange,
+	"fields":         fieldsChange,
+	"recurrence":     recurrenceChange,
+	"reminders":      remindersChange,
+}
+
+func stringChange(v interface{}) (interface{}, error) {
+	if _, ok := v.(string); !ok {
+		return nil, errors.New("Value is not a string")
+	}
+
+	return v, nil
+}
+
+func boolChange(v interface{}) (interface{}, error) {
+	if _, ok := v.(bool); !ok {
+		return nil, errors.New("Value is not a boolean")
+	}
+
+	return v, nil
+}
+
+// optionalTimeChange accepts a time, or nil when it is unset e.g. the
+// completion time of a todo which is no longer completed.
+func optionalTimeChange(v interface{}) (interface{}, error) {
+	switch at := v.(type) {
+	case nil:
+		return nil, nil
+	case time.Time:
+		return at, nil
+	case *time.Time:
+		if at == nil {
+			return nil, nil
+		}
+		return *at, nil
+	}
+
+	return nil, errors.New("Value is not a time")
+}
+
+// fieldsChange accepts the values of the custom fields of a todo, as
+// returned by FieldValues, or nil when it has none.
+func fieldsChange(v interface{}) (interface{}, error) {
+	switch fs := v.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		if len(fs) == 0 {
+			return nil, nil
+		}
+		return fs, nil
+	}
+
+	return nil, errors.New("Value is not an object")
+}
+
+// tagsChange accepts an array of strings, as decoded from json,
+// and returns the normalized tags.
+func tagsChange(v interface{}) (interface{}, error) {
+	switch vs := v.(type) {
+	case []string:
+		return NormalizeTags(vs), nil
+	case []interface{}:
+		tags := make([]string, 0, len(vs))
+		for _, tag := range vs {
+			s, ok := tag.(string)
+			if !ok {
+				return nil, errors.New("Tag is not a string")
+			}
+			tags = append(tags, s)
+		}
+		return NormalizeTags(tags), nil
+	}
+
+	return nil, errors.New("Value is not an array of strings")
+}
+
+func (tds *TodoDataStore) ModifyTodo(todoId string, changes map[string]interface{}) error {
+	params := make(map[string]interface{})
 	params["id"] = todoId
-	params["ownerid"] = userId
 
 	// This is required because we're using the $set operator to replace values
 	// of a specified field. It will create the field in the db lest we
 	// remove it explicitly from the changes map. (TODO: Find a better way of
 	// doing this)
 	// https://docs.mongodb.com/manual/reference/operator/update/set/
-	allowedKeys := []string{"title", "note", "due_date", "created_date"}
 	for k, v := range changes {
-		found := false
-
-		for _, modifiableVal := range allowedKeys {
-			if k == modifiableVal {
-				found = true
-				break
-			}
-		}
-
+		convert, found := modifiableTodoKeys[k]
 		if !found {
 			delete(changes, k)
+			continue
 		}
 
-		if _, ok := v.(string); !ok {
+		value, err := convert(v)
+		if err != nil {
 			return errors.New("Incorrect format for key: " + k)
 		}
+		changes[k] = value
 	}
 
 	err := tds.d.ModifyObjectForId(params, changes)
@@ -194,9 +432,235 @@ func (tds *TodoDataStore) ModifyTodo(todoId, userId string, changes map[string]i
 	return nil
 }
 
-func (tds *TodoDataStore) DeleteTodo(id, userId string) error {
-	m := make(map[string]string)
+func (tds *TodoDataStore) DeleteTodo(id string) error {
+	m := make(map[string]interface{})
 	m["id"] = id
-	m["ownerid"] = userId
 	return tds.d.DeleteObjectForSelector(m)
 }
+
+func (tds *TodoDataStore) LastPosition(userId string) (float64, error) {
+	raws, err := tds.d.GetObjectsForQueryPage(bson.M{"ownerid": userId}, []string{"-position"}, 0, 1)
+	if err != nil || len(raws) == 0 {
+		return 0, err
+	}
+
+	t := Todo{}
+	err = raws[0].Unmarshal(&t)
+	return t.Position, err
+}
+
+func (tds *TodoDataStore) SetTodoPositions(positions map[string]float64) error {
+	for id, position := range positions {
+		err := tds.d.UpdateObjectsForQuery(todoSelector(id), bson.M{"$set": bson.M{"position": position}})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (tds *TodoDataStore) RemoveBlocker(id string) error {
+	return tds.d.UpdateObjectsForQuery(bson.M{"blocked_by": id}, bson.M{"$pull": bson.M{"blocked_by": id}})
+}
+
+// SetTodoShares replaces the shares of the todo.
+func (tds *TodoDataStore) SetTodoShares(todoId string, shares []Share) error {
+	params := make(map[string]interface{})
+	params["id"] = todoId
+
+	err := tds.d.ModifyObjectForId(params, map[string]interface{}{"shares": shares})
+	if err == mdb.NotFoundError {
+		return TodoNotFoundError
+	}
+
+	return err
+}
+
+// MoveTodosToList moves all of the todos in the list fromListId
+// to the list toListId. An empty toListId moves them to the inbox.
+func (tds *TodoDataStore) MoveTodosToList(fromListId, toListId string) error {
+	query := bson.M{"list_id": fromListId}
+	change := map[string]interface{}{"list_id": toListId}
+	return tds.d.ModifyObjectsForQuery(query, change)
+}
+
+// DeleteTodosInList deletes all of the todos in the list.
+func (tds *TodoDataStore) DeleteTodosInList(listId string) error {
+	query := bson.M{"list_id": listId}
+	return tds.d.DeleteObjectsForQuery(query)
+}
+
+// GetTagsForUserId returns every tag used by the user along
+// with the number of todos carrying it.
+func (tds *TodoDataStore) GetTagsForUserId(id string) ([]TagCount, error) {
+	tcs := make([]TagCount, 0)
+	pipeline := []bson.M{
+		{"$match": bson.M{"ownerid": id}},
+		{"$unwind": "$tags"},
+		{"$group": bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}},
+		{"$sort": bson.M{"_id": 1}},
+	}
+
+	raws, err := tds.d.AggregateObjects(pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, raw := range raws {
+		tc := TagCount{}
+		err := raw.Unmarshal(&tc)
+		if err != nil {
+			return nil, err
+		}
+		tcs = append(tcs, tc)
+	}
+
+	return tcs, nil
+}
+
+// MergeTags replaces the tags with the tag into on every todo of the
+// user. Renaming a tag is merging it into its new name.
+func (tds *TodoDataStore) MergeTags(userId string, tags []string, into string) error {
+	from := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag != into {
+			from = append(from, tag)
+		}
+	}
+
+	if len(from) == 0 {
+		return nil
+	}
+
+	// A field can't be the target of $addToSet and $pull in the
+	// same update so the new tag is added before the old are removed.
+	query := bson.M{"ownerid": userId, "tags": bson.M{"$in": from}}
+	err := tds.d.UpdateObjectsForQuery(query, bson.M{"$addToSet": bson.M{"tags": into}})
+	if err != nil {
+		return err
+	}
+
+	return tds.d.UpdateObjectsForQuery(query, bson.M{"$pull": bson.M{"tags": bson.M{"$in": from}}})
+}
+
+// MigrateTodoIds rekeys the todos stored with an ObjectId as their _id
+// by the hex of the ObjectId, the public id which they are known by.
+// It returns the number of todos migrated.
+func MigrateTodoIds() (int, error) {
+	tds := NewTodoDataStore()
+	defer tds.Close()
+
+	raws, err := tds.d.GetObjectsForQuery(bson.M{"_id": bson.M{"$type": 7}}) // 7 is the type of ObjectIds
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, raw := range raws {
+		var doc bson.M
+		if err := raw.Unmarshal(&doc); err != nil {
+			return n, err
+		}
+
+		oid, ok := doc["_id"].(bson.ObjectId)
+		if !ok {
+			continue
+		}
+
+		// The todo may have been inserted by an interrupted migration
+		doc["_id"] = oid.Hex()
+		if err := tds.d.InsertObject(doc); err != nil {
+			if _, missing := tds.d.GetObjectById(oid.Hex()); missing != nil {
+				return n, err
+			}
+		}
+
+		if err := tds.d.DeleteObjectsForQuery(bson.M{"_id": oid}); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+// todoSelector returns the query selecting the todo.
+func todoSelector(todoId string) bson.M {
+	return bson.M{"_id": todoId}
+}
+
+// AddAttachment links the attachment metadata to the todo. The quota is
+// checked once the attachment is linked, and the attachment unlinked
+// if it is exceeded. The quota is therefore never exceeded for long,
+// but concurrent uploads which together exceed it may all be refused
+// even if some of them would fit alone.
+func (tds *TodoDataStore) AddAttachment(todoId string, a Attachment, quota int64) error {
+	query := todoSelector(todoId)
+
+	raw, err := tds.d.FindAndModifyObject(query, bson.M{"$push": bson.M{"attachments": a}})
+	if err == mdb.NotFoundError {
+		return TodoNotFoundError
+	}
+	if err != nil {
+		return err
+	}
+
+	t := Todo{}
+	if err := raw.Unmarshal(&t); err != nil {
+		return err
+	}
+
+	used, err := tds.GetAttachmentsSize(t.Ownerid)
+	if err == nil && used <= quota {
+		return nil
+	}
+
+	// Unlinked as well when the size is unknown
+	if rmErr := tds.RemoveAttachment(todoId, a.Id.Hex()); rmErr != nil {
+		return rmErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return ErrAttachmentQuota
+}
+
+// RemoveAttachment unlinks the attachment from the todo.
+func (tds *TodoDataStore) RemoveAttachment(todoId, attachmentId string) error {
+	query := todoSelector(todoId)
+	if !bson.IsObjectIdHex(attachmentId) {
+		return AttachmentNotFoundError
+	}
+	query["attachments._id"] = bson.ObjectIdHex(attachmentId)
+
+	update := bson.M{"$pull": bson.M{"attachments": bson.M{"_id": bson.ObjectIdHex(attachmentId)}}}
+	_, err := tds.d.FindAndModifyObject(query, update)
+	if err == mdb.NotFoundError {
+		return AttachmentNotFoundError
+	}
+
+	return err
+}
+
+// GetAttachmentsSize returns the total size in bytes of the
+// attachments of the todos owned by the user.
+func (tds *TodoDataStore) GetAttachmentsSize(userId string) (int64, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"ownerid": userId}},
+		{"$unwind": "$attachments"},
+		{"$group": bson.M{"_id": nil, "size": bson.M{"$sum": "$attachments.size"}}},
+	}
+
+	raws, err := tds.d.AggregateObjects(pipeline)
+	if err != nil || len(raws) == 0 {
+		return 0, err
+	}
+
+	total := struct {
+		Size int64 `bson:"size"`
+	}{}
+	err = raws[0].Unmarshal(&total)
+	return total.Size, err
+}
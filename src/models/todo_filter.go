@@ -0,0 +1,262 @@
+package models
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// InboxListId is the list id used to filter for todos which
+// do not belong to any list.
+const InboxListId = "inbox"
+
+// TodoFilter narrows down the todos returned for a user.
+// Fields which hold their zero value are not filtered on.
+type TodoFilter struct {
+	ListId  string   // A list id or InboxListId
+	Tags    []string // Normalized tags
+	TagMode string   // TagModeAny (default) or TagModeAll
+
+	// AssigneeId narrows the todos down to those assigned to the user.
+	AssigneeId string
+
+	// MinPriority narrows the todos down to those of the priority or a
+	// higher one.
+	MinPriority Priority
+
+	// Completed narrows the todos down to the completed or the open
+	// ones.
+	Completed *bool
+
+	// SharedListIds are the lists shared with the user, their todos
+	// are accessible along with the user's own and shared todos.
+	SharedListIds []string
+
+	// Due narrows the todos down to those due on the day of Now
+	// (DueToday) or past due at Now (DueOverdue). Days are those of
+	// Location, the user's time zone.
+	Due      string
+	Now      time.Time
+	Location *time.Location
+
+	// DueFrom and DueBefore narrow the todos down to those due from
+	// DueFrom on and before DueBefore.
+	DueFrom   time.Time
+	DueBefore time.Time
+
+	// HideDeferred leaves out the todos which are deferred until after
+	// Now, see Todo.DeferUntil.
+	HideDeferred bool
+
+	// Archived narrows the todos down to the archived ones, which are
+	// left out otherwise.
+	Archived bool
+
+	// Search narrows the todos down to those whose title or note
+	// contains it, ignoring case.
+	Search string
+
+	// Fields narrows the todos down to those whose custom field values
+	// satisfy every condition.
+	Fields []FieldCondition
+}
+
+func (f TodoFilter) location() *time.Location {
+	if f.Location == nil {
+		return time.UTC
+	}
+
+	return f.Location
+}
+
+// query returns the mongodb query selecting the todos accessible
+// to the user which pass the filter.
+func (f TodoFilter) query(userId string) bson.M {
+	access := []bson.M{{"ownerid": userId}, {"shares.user_id": userId}, {"assignee_id": userId}}
+	if len(f.SharedListIds) > 0 {
+		access = append(access, bson.M{"list_id": bson.M{"$in": f.SharedListIds}})
+	}
+	q := bson.M{"$or": access}
+	var and []bson.M
+
+	if f.Archived {
+		q["archived"] = true
+	} else {
+		q["archived"] = bson.M{"$ne": true}
+	}
+
+	switch f.ListId {
+	case "":
+	case InboxListId:
+		q["list_id"] = bson.M{"$in": []interface{}{nil, ""}}
+	default:
+		q["list_id"] = f.ListId
+	}
+
+	if f.AssigneeId != "" {
+		q["assignee_id"] = f.AssigneeId
+	}
+
+	if f.MinPriority.Level() > 0 {
+		var ps []Priority
+		for p, level := range priorityLevels {
+			if level >= f.MinPriority.Level() {
+				ps = append(ps, p)
+			}
+		}
+		q["priority"] = bson.M{"$in": ps}
+	}
+
+	if f.Completed != nil {
+		and = append(and, bson.M{"completed": *f.Completed})
+	}
+
+	if len(f.Tags) > 0 {
+		if f.TagMode == TagModeAll {
+			q["tags"] = bson.M{"$all": f.Tags}
+		} else {
+			q["tags"] = bson.M{"$in": f.Tags}
+		}
+	}
+
+	start, end := DayBounds(f.Now, f.location())
+	switch f.Due {
+	case DueToday:
+		q["due_date"] = bson.M{"$gte": start, "$lt": end}
+	case DueOverdue:
+		q["completed"] = false
+		and = append(and, bson.M{"$or": []bson.M{
+			{"all_day": bson.M{"$ne": true}, "due_date": bson.M{"$lt": f.Now}},
+			{"all_day": true, "due_date": bson.M{"$lt": start}},
+		}})
+	}
+
+	if !f.DueFrom.IsZero() {
+		and = append(and, bson.M{"due_date": bson.M{"$gte": f.DueFrom}})
+	}
+	if !f.DueBefore.IsZero() {
+		and = append(and, bson.M{"due_date": bson.M{"$lt": f.DueBefore}})
+	}
+
+	if f.HideDeferred {
+		and = append(and, bson.M{"$or": []bson.M{{"defer_until": nil}, {"defer_until": bson.M{"$lte": f.Now}}}})
+	}
+
+	if f.Search != "" {
+		search := bson.M{"$regex": regexp.QuoteMeta(f.Search), "$options": "i"}
+		and = append(and, bson.M{"$or": []bson.M{{"title": search}, {"note": search}}})
+	}
+
+	for _, c := range f.Fields {
+		and = append(and, bson.M{"fields." + c.Key: c.query()})
+	}
+
+	if len(and) > 0 {
+		q["$and"] = and
+	}
+
+	return q
+}
+
+// accessible reports whether t is accessible to the user. Along with
+// matches it mirrors query for storage implementations which do not
+// use mongodb.
+func (f TodoFilter) accessible(userId string, t Todo) bool {
+	if t.RoleFor(userId) != RoleNone || t.AssigneeId == userId {
+		return true
+	}
+
+	for _, id := range f.SharedListIds {
+		if t.ListId == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matches reports whether t passes the filter.
+func (f TodoFilter) matches(t Todo) bool {
+	if t.Archived != f.Archived {
+		return false
+	}
+
+	if f.HideDeferred && t.Deferred(f.Now) {
+		return false
+	}
+
+	if f.Search != "" {
+		search := strings.ToLower(f.Search)
+		if !strings.Contains(strings.ToLower(t.Title), search) && !strings.Contains(strings.ToLower(t.Note), search) {
+			return false
+		}
+	}
+
+	switch f.ListId {
+	case "":
+	case InboxListId:
+		if t.ListId != "" {
+			return false
+		}
+	default:
+		if t.ListId != f.ListId {
+			return false
+		}
+	}
+
+	if f.AssigneeId != "" && t.AssigneeId != f.AssigneeId {
+		return false
+	}
+
+	if t.Priority.Level() < f.MinPriority.Level() {
+		return false
+	}
+
+	if f.Completed != nil && t.Completed != *f.Completed {
+		return false
+	}
+
+	if (!f.DueFrom.IsZero() || !f.DueBefore.IsZero()) && t.Due.IsZero() {
+		return false
+	}
+	if !f.DueFrom.IsZero() && t.Due.Before(f.DueFrom) {
+		return false
+	}
+	if !f.DueBefore.IsZero() && !t.Due.Before(f.DueBefore) {
+		return false
+	}
+
+	if len(f.Tags) > 0 {
+		found := 0
+		for _, tag := range f.Tags {
+			if t.hasTag(tag) {
+				found++
+			}
+		}
+
+		if found == 0 || (f.TagMode == TagModeAll && found != len(f.Tags)) {
+			return false
+		}
+	}
+
+	for _, c := range f.Fields {
+		if !c.matches(t) {
+			return false
+		}
+	}
+
+	switch f.Due {
+	case DueToday:
+		start, end := DayBounds(f.Now, f.location())
+		if t.Due.Before(start) || !t.Due.Before(end) {
+			return false
+		}
+	case DueOverdue:
+		if !t.Overdue(f.Now, f.location()) {
+			return false
+		}
+	}
+
+	return true
+}
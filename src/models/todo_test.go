@@ -1,7 +1,9 @@
 package models
 
 import (
+	"reflect"
 	"testing"
+	"time"
 
 	"gopkg.in/mgo.v2"
 
@@ -21,7 +23,7 @@ func (tds TodoDataStore) setup() {
 	tds.d.Database = testDB
 }
 
-func tdsTeardown(tds TodoDataStore) {
+func tdsTeardown(tds *TodoDataStore) {
 	defer tds.Close()
 
 	session, err := mgo.Dial(mdb.Hostname)
@@ -44,7 +46,7 @@ func setComparison(ts0, ts1 []Todo) bool {
 	for _, t0 := range ts0 {
 		found := false
 		for _, t1 := range ts1 {
-			if t0 == t1 {
+			if reflect.DeepEqual(t0, t1) {
 				found = true
 			}
 		}
@@ -123,12 +125,12 @@ func TestGetTodoById(t *testing.T) {
 	tds.InsertTodo(t0)
 
 	// Main test content
-	t_0, err := tds.GetTodoById(t0.Id.Hex())
+	t_0, err := tds.GetTodoById(t0.Id)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if *t_0 != t0 {
+	if !reflect.DeepEqual(*t_0, t0) {
 		t.Error("t_0 not equal to t0")
 	}
 }
@@ -189,12 +191,12 @@ func TestModifyTodo(t *testing.T) {
 	changes := make(map[string]interface{})
 	changes["title"] = "Changed Title"
 	changes["note"] = "Example Note"
-	err := tds.ModifyTodo(t0.Id.Hex(), ownerId, changes)
+	err := tds.ModifyTodo(t0.Id, changes)
 	if err != nil {
 		t.Error(err)
 	}
 
-	err = tds.ModifyTodo("1234", ownerId, changes)
+	err = tds.ModifyTodo("1234", changes)
 	if err == nil {
 		t.Error("Error: Should be not found error")
 	}
@@ -214,9 +216,280 @@ func TestDeleteTodo(t *testing.T) {
 	tds.InsertTodo(t0)
 
 	// Main test content
-	err := tds.DeleteTodo(t0.Id.Hex(), userId)
+	err := tds.DeleteTodo(t0.Id)
 	if err != nil {
 		t.Error(err)
 	}
 
 }
+
+func TestGetTodosForFilter(t *testing.T) {
+	// Test setup
+	tds := NewTodoDataStore()
+	tds.d.Collection = "2Do_TestGetTodosForFilter_Collection"
+	tds.setup()
+
+	defer tdsTeardown(tds)
+
+	ownerId := "123456"
+	t0 := NewTodo()
+	t0.Ownerid = ownerId
+	t0.ListId = "work"
+
+	t1 := NewTodo()
+	t1.Ownerid = ownerId
+
+	tds.InsertTodo(t0)
+	tds.InsertTodo(t1)
+
+	// Main test content
+	ts, err := tds.GetTodosForFilter(ownerId, TodoFilter{ListId: "work"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !setComparison([]Todo{t0}, ts) {
+		t.Error("Sets not equal for list filter")
+	}
+
+	ts, err = tds.GetTodosForFilter(ownerId, TodoFilter{ListId: InboxListId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !setComparison([]Todo{t1}, ts) {
+		t.Error("Sets not equal for inbox filter")
+	}
+}
+
+func TestGetTodosForFilterShared(t *testing.T) {
+	// Test setup
+	tds := NewTodoDataStore()
+	tds.d.Collection = "2Do_TestGetTodosForFilterShared_Collection"
+	tds.setup()
+
+	defer tdsTeardown(tds)
+
+	ownerId, userId := "123456", "abcdef"
+	t0 := NewTodo()
+	t0.Ownerid = ownerId
+	t0.Shares = []Share{{UserId: userId, Username: "bob", Role: RoleViewer}}
+
+	t1 := NewTodo()
+	t1.Ownerid = ownerId
+	t1.ListId = "shared"
+
+	t2 := NewTodo()
+	t2.Ownerid = ownerId
+
+	tds.InsertTodo(t0)
+	tds.InsertTodo(t1)
+	tds.InsertTodo(t2)
+
+	// Main test content
+	ts, err := tds.GetTodosForFilter(userId, TodoFilter{SharedListIds: []string{"shared"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !setComparison([]Todo{t0, t1}, ts) {
+		t.Error("Sets not equal for shared todos")
+	}
+}
+
+func TestMoveTodosToList(t *testing.T) {
+	// Test setup
+	tds := NewTodoDataStore()
+	tds.d.Collection = "2Do_TestMoveTodosToList_Collection"
+	tds.setup()
+
+	defer tdsTeardown(tds)
+
+	ownerId := "123456"
+	t0 := NewTodo()
+	t0.Ownerid = ownerId
+	t0.ListId = "work"
+	tds.InsertTodo(t0)
+
+	// Main test content
+	err := tds.MoveTodosToList("work", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts, err := tds.GetTodosForFilter(ownerId, TodoFilter{ListId: InboxListId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ts) != 1 {
+		t.Errorf("Todo was not moved to the inbox: %v", ts)
+	}
+}
+
+func TestDeleteTodosInList(t *testing.T) {
+	// Test setup
+	tds := NewTodoDataStore()
+	tds.d.Collection = "2Do_TestDeleteTodosInList_Collection"
+	tds.setup()
+
+	defer tdsTeardown(tds)
+
+	ownerId := "123456"
+	t0 := NewTodo()
+	t0.Ownerid = ownerId
+	t0.ListId = "work"
+	t1 := NewTodo()
+	t1.Ownerid = ownerId
+	tds.InsertTodo(t0)
+	tds.InsertTodo(t1)
+
+	// Main test content
+	err := tds.DeleteTodosInList("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts, err := tds.GetTodosForUserId(ownerId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !setComparison([]Todo{t1}, ts) {
+		t.Error("Only the todos in the list should be deleted")
+	}
+}
+
+func TestModifyTodoClearDue(t *testing.T) {
+	// Test setup
+	tds := NewTodoDataStore()
+	tds.d.Collection = "2Do_TestModifyTodoClearDue_Collection"
+	tds.setup()
+
+	defer tdsTeardown(tds)
+
+	ownerId := "12345"
+	t0 := NewTodo()
+	t0.Ownerid = ownerId
+	t0.Due = time.Now().Add(-time.Hour)
+	tds.InsertTodo(t0)
+
+	// Main test content
+	f := TodoFilter{Due: DueOverdue, Now: time.Now()}
+	ts, err := tds.GetTodosForFilter(ownerId, f)
+	if err != nil || len(ts) != 1 {
+		t.Fatalf("The 2Do should be overdue: %v %v", ts, err)
+	}
+
+	err = tds.ModifyTodo(t0.Id, map[string]interface{}{"due_date": nil, "all_day": false})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts, err = tds.GetTodosForFilter(ownerId, f)
+	if err != nil || len(ts) != 0 {
+		t.Errorf("The 2Do without a due date should not be overdue: %v %v", ts, err)
+	}
+
+	t1, err := tds.GetTodoById(t0.Id)
+	if err != nil || !t1.Due.IsZero() {
+		t.Errorf("Due date should be cleared: %v %v", t1, err)
+	}
+}
+
+func TestModifyTodoTags(t *testing.T) {
+	// Test setup
+	tds := NewTodoDataStore()
+	tds.d.Collection = "2Do_TestModifyTodoTags_Collection"
+	tds.setup()
+
+	defer tdsTeardown(tds)
+
+	ownerId := "12345"
+	t0 := NewTodo()
+	t0.Ownerid = ownerId
+	tds.InsertTodo(t0)
+
+	// Main test content
+	changes := map[string]interface{}{"tags": []interface{}{"#Home", "work"}}
+	err := tds.ModifyTodo(t0.Id, changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t1, err := tds.GetTodoById(t0.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(t1.Tags, []string{"home", "work"}) {
+		t.Errorf("Incorrect tags: %v", t1.Tags)
+	}
+
+	changes = map[string]interface{}{"tags": []interface{}{"home", 1}}
+	err = tds.ModifyTodo(t0.Id, changes)
+	if err == nil {
+		t.Error("Should fail for non string tags")
+	}
+}
+
+func TestAddAttachmentQuota(t *testing.T) {
+	// Test setup
+	tds := NewTodoDataStore()
+	tds.d.Collection = "2Do_TestAddAttachmentQuota_Collection"
+	tds.setup()
+
+	defer tdsTeardown(tds)
+
+	ownerId := "12345"
+	t0, t1 := NewTodo(), NewTodo()
+	t0.Ownerid, t1.Ownerid = ownerId, ownerId
+	tds.InsertTodo(t0)
+	tds.InsertTodo(t1)
+
+	// Main test content
+	a0, a1 := NewAttachment(), NewAttachment()
+	a0.Size, a1.Size = 60, 50
+	if err := tds.AddAttachment(t0.Id, a0, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tds.AddAttachment(t1.Id, a1, 100); err != ErrAttachmentQuota {
+		t.Errorf("Attachment over the quota should be refused: %v", err)
+	}
+
+	used, err := tds.GetAttachmentsSize(ownerId)
+	if err != nil || used != a0.Size {
+		t.Errorf("Refused attachment should not be kept: %d %v", used, err)
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	// Test setup
+	tds := NewTodoDataStore()
+	tds.d.Collection = "2Do_TestMergeTags_Collection"
+	tds.setup()
+
+	defer tdsTeardown(tds)
+
+	ownerId := "12345"
+	t0 := NewTodo()
+	t0.Ownerid = ownerId
+	t0.Tags = []string{"home", "house"}
+	t1 := NewTodo()
+	t1.Ownerid = ownerId
+	t1.Tags = []string{"house", "work"}
+	tds.InsertTodo(t0)
+	tds.InsertTodo(t1)
+
+	// Main test content
+	err := tds.MergeTags(ownerId, []string{"house"}, "home")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcs, err := tds.GetTagsForUserId(ownerId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []TagCount{{Tag: "home", Count: 2}, {Tag: "work", Count: 1}}
+	if !reflect.DeepEqual(tcs, expected) {
+		t.Errorf("Incorrect tag counts: want %v got %v", expected, tcs)
+	}
+}
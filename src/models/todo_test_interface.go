@@ -2,7 +2,10 @@ package models
 
 import (
 	"fmt"
+	"gopkg.in/mgo.v2/bson"
 	"log"
+	"sort"
+	"time"
 )
 
 var todoMap = make(map[string]Todo)
@@ -26,10 +29,89 @@ func (tus *TestTodoStorage) GetAllTodos() ([]Todo, error) {
 }
 
 func (tus *TestTodoStorage) GetTodoById(id string) (*Todo, error) {
-	return nil, nil
+	t, ok := (*tus.todos)[id]
+	if !ok {
+		return nil, TodoNotFoundError
+	}
+
+	return &t, nil
+}
+
+func (tus *TestTodoStorage) GetTodosByIds(ids []string) ([]Todo, error) {
+	ts := make([]Todo, 0)
+	for _, id := range ids {
+		if t, ok := (*tus.todos)[id]; ok {
+			ts = append(ts, t)
+		}
+	}
+
+	return ts, nil
+}
+
+func (tus *TestTodoStorage) GetTodosBlockedBy(id string) ([]Todo, error) {
+	ts := make([]Todo, 0)
+	for _, t := range *tus.todos {
+		for _, blocker := range t.BlockedBy {
+			if blocker == id {
+				ts = append(ts, t)
+				break
+			}
+		}
+	}
+
+	return ts, nil
+}
+
+func (tus *TestTodoStorage) LastPosition(userId string) (float64, error) {
+	var last float64
+	for _, t := range *tus.todos {
+		if t.Ownerid == userId && t.Position > last {
+			last = t.Position
+		}
+	}
+
+	return last, nil
+}
+
+func (tus *TestTodoStorage) SetTodoPositions(positions map[string]float64) error {
+	todos := *tus.todos
+	for id, position := range positions {
+		t, ok := todos[id]
+		if !ok {
+			return TodoNotFoundError
+		}
+
+		t.Position = position
+		todos[id] = t
+	}
+
+	return nil
+}
+
+func (tus *TestTodoStorage) RemoveBlocker(id string) error {
+	todos := *tus.todos
+	for todoId, t := range todos {
+		blockedBy := make([]string, 0, len(t.BlockedBy))
+		for _, blocker := range t.BlockedBy {
+			if blocker != id {
+				blockedBy = append(blockedBy, blocker)
+			}
+		}
+
+		if len(blockedBy) != len(t.BlockedBy) {
+			t.BlockedBy = blockedBy
+			todos[todoId] = t
+		}
+	}
+
+	return nil
 }
 
 func (tus *TestTodoStorage) GetTodosForUserId(id string) ([]Todo, error) {
+	return tus.GetTodosForFilter(id, TodoFilter{})
+}
+
+func (tus *TestTodoStorage) GetTodosForFilter(userId string, f TodoFilter) ([]Todo, error) {
 	todos := *tus.todos
 	if todos == nil {
 		log.Fatal("todos map was not assigned in TestTodoStorage!")
@@ -37,34 +119,276 @@ func (tus *TestTodoStorage) GetTodosForUserId(id string) ([]Todo, error) {
 
 	ts := make([]Todo, 0)
 	for _, t := range todos {
-		if t.Ownerid == id {
+		if f.accessible(userId, t) && f.matches(t) {
 			ts = append(ts, t)
 		}
 	}
+	SortByPosition(ts)
+
+	return ts, nil
+}
+
+func (tus *TestTodoStorage) CountTodosForFilter(userId string, f TodoFilter) (int, error) {
+	ts, err := tus.GetTodosForFilter(userId, f)
+	return len(ts), err
+}
+
+func (tus *TestTodoStorage) GetArchivedTodos(userId string, f TodoFilter, skip, limit int) ([]Todo, error) {
+	f.Archived = true
+	ts, err := tus.GetTodosForFilter(userId, f)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(ts, func(i, j int) bool {
+		a, b := ts[i].ArchivedAt, ts[j].ArchivedAt
+		if a == nil || b == nil || a.Equal(*b) {
+			return ts[i].Id > ts[j].Id
+		}
+		return a.After(*b)
+	})
+
+	if skip > len(ts) {
+		skip = len(ts)
+	}
+	ts = ts[skip:]
+	if limit > 0 && limit < len(ts) {
+		ts = ts[:limit]
+	}
 
 	return ts, nil
 }
 
+func (tus *TestTodoStorage) ArchiveTodos(userId string, rule ArchiveRule, now time.Time) error {
+	todos := *tus.todos
+	for id, t := range todos {
+		if rule.matches(userId, t, now) {
+			t.Archived, t.ArchivedAt = true, &now
+			todos[id] = t
+		}
+	}
+
+	return nil
+}
+
+func (tus *TestTodoStorage) GetCompletionStats(userId string, loc *time.Location) (CompletionStats, error) {
+	cs := CompletionStats{PerDay: make(map[string]int)}
+
+	var total time.Duration
+	n := 0
+	for _, t := range *tus.todos {
+		if !t.Completed || t.CompletedBy != userId || t.CompletedAt == nil {
+			continue
+		}
+
+		cs.PerDay[t.CompletedAt.In(loc).Format(DateFormat)]++
+		total += t.CompletedAt.Sub(t.Created)
+		n++
+	}
+
+	if n > 0 {
+		cs.AverageCompletion = total / time.Duration(n)
+		cs.HasAverage = true
+	}
+
+	return cs, nil
+}
+
 func (tus *TestTodoStorage) InsertTodo(t Todo) error {
-	(*tus.todos)[t.Id.Hex()] = t
+	(*tus.todos)[t.Id] = t
+	return nil
+}
+
+// ModifyTodo mirrors the $set of TodoDataStore.ModifyTodo by
+// round tripping the todo through bson.
+func (tus *TestTodoStorage) ModifyTodo(todoId string, changes map[string]interface{}) error {
+	todos := *tus.todos
+	t, ok := todos[todoId]
+	if !ok {
+		return TodoNotFoundError
+	}
+
+	doc := bson.M{}
+	if err := marshalInto(t, &doc); err != nil {
+		return err
+	}
+
+	for k, v := range changes {
+		convert, found := modifiableTodoKeys[k]
+		if !found {
+			continue
+		}
+
+		value, err := convert(v)
+		if err != nil {
+			return fmt.Errorf("Incorrect format for key: %s", k)
+		}
+		doc[k] = value
+	}
+
+	modified := Todo{}
+	if err := marshalInto(doc, &modified); err != nil {
+		return err
+	}
+
+	todos[todoId] = modified
+	return nil
+}
+
+func marshalInto(in, out interface{}) error {
+	b, err := bson.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	return bson.Unmarshal(b, out)
+}
+
+func (tus *TestTodoStorage) SetTodoShares(todoId string, shares []Share) error {
+	todos := *tus.todos
+	t, ok := todos[todoId]
+	if !ok {
+		return TodoNotFoundError
+	}
+
+	t.Shares = shares
+	todos[todoId] = t
 	return nil
 }
 
-func (tus *TestTodoStorage) ModifyTodo(todoId, userId string, changes map[string]interface{}) error {
+func (tus *TestTodoStorage) MoveTodosToList(fromListId, toListId string) error {
+	todos := *tus.todos
+	for id, t := range todos {
+		if t.ListId == fromListId {
+			t.ListId = toListId
+			todos[id] = t
+		}
+	}
+
 	return nil
 }
 
-func (tus *TestTodoStorage) DeleteTodo(id, userId string) error {
+func (tus *TestTodoStorage) DeleteTodo(id string) error {
 	todos := (*tus.todos)
-	t, ok := todos[id]
+	if _, ok := todos[id]; !ok {
+		return TodoNotFoundError
+	}
+
+	delete(todos, id)
+	return nil
+}
+
+func (tus *TestTodoStorage) DeleteTodosInList(listId string) error {
+	todos := *tus.todos
+	for id, t := range todos {
+		if t.ListId == listId {
+			delete(todos, id)
+		}
+	}
+
+	return nil
+}
+
+func (tus *TestTodoStorage) GetTagsForUserId(id string) ([]TagCount, error) {
+	counts := make(map[string]int)
+	for _, t := range *tus.todos {
+		if t.Ownerid != id {
+			continue
+		}
+
+		for _, tag := range t.Tags {
+			counts[tag]++
+		}
+	}
+
+	tcs := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tcs = append(tcs, TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(tcs, func(i, j int) bool { return tcs[i].Tag < tcs[j].Tag })
+
+	return tcs, nil
+}
+
+func (tus *TestTodoStorage) MergeTags(userId string, tags []string, into string) error {
+	todos := *tus.todos
+	for id, t := range todos {
+		if t.Ownerid != userId {
+			continue
+		}
+
+		merged := make([]string, 0, len(t.Tags))
+		for _, tag := range t.Tags {
+			for _, from := range tags {
+				if tag == from {
+					tag = into
+					break
+				}
+			}
+			merged = append(merged, tag)
+		}
+
+		t.Tags = NormalizeTags(merged)
+		todos[id] = t
+	}
+
+	return nil
+}
+
+func (tus *TestTodoStorage) AddAttachment(todoId string, a Attachment, quota int64) error {
+	todos := *tus.todos
+	t, ok := todos[todoId]
 	if !ok {
 		return TodoNotFoundError
 	}
 
-	if t.Ownerid != userId {
-		return fmt.Errorf("User: %s does not own: %s", userId, id)
+	used, err := tus.GetAttachmentsSize(t.Ownerid)
+	if err != nil {
+		return err
+	}
+	if used+a.Size > quota {
+		return ErrAttachmentQuota
 	}
 
-	delete(todos, id)
+	t.Attachments = append(t.Attachments, a)
+	todos[todoId] = t
 	return nil
 }
+
+func (tus *TestTodoStorage) RemoveAttachment(todoId, attachmentId string) error {
+	todos := *tus.todos
+	t, ok := todos[todoId]
+	if !ok {
+		return TodoNotFoundError
+	}
+
+	as := make([]Attachment, 0, len(t.Attachments))
+	for _, a := range t.Attachments {
+		if a.Id.Hex() != attachmentId {
+			as = append(as, a)
+		}
+	}
+
+	if len(as) == len(t.Attachments) {
+		return AttachmentNotFoundError
+	}
+
+	t.Attachments = as
+	todos[todoId] = t
+	return nil
+}
+
+func (tus *TestTodoStorage) GetAttachmentsSize(userId string) (int64, error) {
+	var size int64
+	for _, t := range *tus.todos {
+		if t.Ownerid != userId {
+			continue
+		}
+
+		for _, a := range t.Attachments {
+			size += a.Size
+		}
+	}
+
+	return size, nil
+}
@@ -15,6 +15,24 @@ type User struct {
 	Username string        `json:"username" bson:"username"`
 	Password string        `json:"-" bson:"password"` // hashed password
 	Blocked  bool          `json:"-" bson:"blocked"`
+
+	SmartWeights *SmartWeights `json:"smart_weights,omitempty" bson:"smart_weights,omitempty"`
+
+	// Addresses reminders and other notifications are delivered to.
+	Email      string `json:"email,omitempty" bson:"email,omitempty"`
+	WebhookURL string `json:"webhook_url,omitempty" bson:"webhook_url,omitempty"`
+
+	// TimeZone is the IANA time zone of the user, UTC when empty. It
+	// sets the boundaries of the user's days e.g. for all day todos.
+	TimeZone string `json:"time_zone,omitempty" bson:"time_zone,omitempty"`
+	Locale   string `json:"locale,omitempty" bson:"locale,omitempty"`
+
+	// ArchiveRules archive the user's completed todos, see ArchiveRule.
+	ArchiveRules []ArchiveRule `json:"archive_rules,omitempty" bson:"archive_rules,omitempty"`
+
+	// FeedToken grants read access to the user's calendar feed, see
+	// NewFeedToken. There is no feed when it is empty.
+	FeedToken string `json:"-" bson:"feed_token,omitempty"`
 }
 
 type UserDataStore struct {
@@ -25,6 +43,11 @@ type UserStorage interface {
 	Close()
 	GetUserById(id string) (*User, error)
 	GetUserByName(name string) (*User, error)
+	// GetUserByFeedToken returns the user whose calendar feed the
+	// token grants access to.
+	GetUserByFeedToken(token string) (*User, error)
+	// GetUsersWithArchiveRules returns the users who have archive rules.
+	GetUsersWithArchiveRules() ([]User, error)
 	InsertUser(u User) error
 	ModifyUser(id string, change map[string]interface{}) error
 	DeleteUser(id string) error
@@ -53,6 +76,15 @@ func NewUser() User {
 	return u
 }
 
+// Weights returns the smart order weights of the user.
+func (u User) Weights() SmartWeights {
+	if u.SmartWeights == nil {
+		return DefaultSmartWeights
+	}
+
+	return *u.SmartWeights
+}
+
 // UserDataStore Methods - Implements UserStorage Interface //
 func NewUserDataStore() *UserDataStore {
 	uds := UserDataStore{}
@@ -82,7 +114,7 @@ func (uds *UserDataStore) Close() {
 }
 
 func (uds *UserDataStore) GetUserById(id string) (*User, error) {
-	return getUser(id, uds.d.GetObjectById)
+	return getUser(objectId(id), uds.d.GetObjectById)
 }
 
 func (uds *UserDataStore) GetUserByName(name string) (*User, error) {
@@ -90,6 +122,15 @@ func (uds *UserDataStore) GetUserByName(name string) (*User, error) {
 	return getUser(q, uds.d.GetObjectForQuery)
 }
 
+func (uds *UserDataStore) GetUserByFeedToken(token string) (*User, error) {
+	if token == "" {
+		return nil, ErrUserNotFound
+	}
+
+	q := bson.M{"feed_token": token}
+	return getUser(q, uds.d.GetObjectForQuery)
+}
+
 // getUser is a wrapper method that handles converting the bson raw result
 // to a User type.
 func getUser(param interface{}, queryFunc func(interface{}) (*bson.Raw, error)) (*User, error) {
@@ -108,18 +149,37 @@ func getUser(param interface{}, queryFunc func(interface{}) (*bson.Raw, error))
 	return &u, nil
 }
 
+func (uds *UserDataStore) GetUsersWithArchiveRules() ([]User, error) {
+	us := make([]User, 0)
+
+	raws, err := uds.d.GetObjectsForQuery(bson.M{"archive_rules.0": bson.M{"$exists": true}})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, raw := range raws {
+		u := User{}
+		if err := raw.Unmarshal(&u); err != nil {
+			return nil, err
+		}
+		us = append(us, u)
+	}
+
+	return us, nil
+}
+
 func (uds *UserDataStore) InsertUser(u User) error {
 	return uds.d.InsertObject(u)
 }
 
 func (uds *UserDataStore) ModifyUser(id string, change map[string]interface{}) error {
-	params := make(map[string]string)
-	params["id"] = id
+	params := make(map[string]interface{})
+	params["id"] = objectId(id)
 	return uds.d.ModifyObjectForId(params, change)
 }
 
 func (uds *UserDataStore) DeleteUser(id string) error {
-	params := make(map[string]string)
-	params["id"] = id
+	params := make(map[string]interface{})
+	params["id"] = objectId(id)
 	return uds.d.DeleteObjectForSelector(params)
 }
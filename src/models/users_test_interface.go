@@ -43,6 +43,27 @@ func (tus *TestUserStorage) GetUserByName(name string) (*User, error) {
 	return nil, ErrUserNotFound
 
 }
+func (tus *TestUserStorage) GetUserByFeedToken(token string) (*User, error) {
+	for _, usr := range *tus.users {
+		if token != "" && usr.FeedToken == token {
+			return &usr, nil
+		}
+	}
+
+	return nil, ErrUserNotFound
+}
+
+func (tus *TestUserStorage) GetUsersWithArchiveRules() ([]User, error) {
+	us := make([]User, 0)
+	for _, u := range *tus.users {
+		if len(u.ArchiveRules) > 0 {
+			us = append(us, u)
+		}
+	}
+
+	return us, nil
+}
+
 func (tus *TestUserStorage) InsertUser(u User) error {
 	(*tus.users)[u.Id.Hex()] = u
 	return nil
@@ -0,0 +1,210 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxWorkflowStates is the number of states a workflow may have.
+	MaxWorkflowStates = 20
+	// MaxStatusHistory is the number of status changes kept per todo.
+	MaxStatusHistory = 100
+)
+
+var (
+	ErrInvalidTransition = errors.New("Invalid status transition")
+	ErrUnknownStatus     = errors.New("Unknown status")
+	ErrNoWorkflow        = errors.New("The list of the 2Do has no workflow")
+)
+
+// WorkflowState is a named state of a workflow e.g. "in progress".
+// Todos in a terminal state are completed. A WIPLimit caps the number
+// of todos in the state, 0 is unlimited.
+type WorkflowState struct {
+	Name     string `json:"name" bson:"name"`
+	Terminal bool   `json:"terminal,omitempty" bson:"terminal,omitempty"`
+	WIPLimit int    `json:"wip_limit,omitempty" bson:"wip_limit,omitempty"`
+}
+
+// Transition allows todos to move from one state to another.
+type Transition struct {
+	From string `json:"from" bson:"from"`
+	To   string `json:"to" bson:"to"`
+}
+
+// Workflow defines the states the todos of a list go through and the
+// transitions between them. New todos start in the Initial state.
+type Workflow struct {
+	States      []WorkflowState `json:"states" bson:"states"`
+	Transitions []Transition    `json:"transitions" bson:"transitions"`
+	Initial     string          `json:"initial" bson:"initial"`
+}
+
+// StatusChange records a todo moving from one state to another.
+type StatusChange struct {
+	From string    `json:"from,omitempty" bson:"from,omitempty"`
+	To   string    `json:"to" bson:"to"`
+	At   time.Time `json:"at" bson:"at"`
+	By   string    `json:"by" bson:"by"`
+}
+
+// Validate normalizes the workflow and checks that it is consistent.
+// Initial defaults to the first state which is not terminal.
+func (wf *Workflow) Validate() error {
+	if len(wf.States) == 0 || len(wf.States) > MaxWorkflowStates {
+		return fmt.Errorf("Workflows must have between 1 and %d states", MaxWorkflowStates)
+	}
+
+	seen := make(map[string]bool)
+	terminal, open := false, ""
+	for i := range wf.States {
+		s := &wf.States[i]
+		s.Name = strings.TrimSpace(s.Name)
+		if s.Name == "" {
+			return errors.New("Workflow states must have a name")
+		}
+		if seen[s.Name] {
+			return fmt.Errorf("Duplicate workflow state: %s", s.Name)
+		}
+		if s.WIPLimit < 0 {
+			return fmt.Errorf("WIP limit of %s must not be negative", s.Name)
+		}
+		seen[s.Name] = true
+
+		if s.Terminal {
+			terminal = true
+		} else if open == "" {
+			open = s.Name
+		}
+	}
+
+	if !terminal || open == "" {
+		return errors.New("Workflows must have terminal and non terminal states")
+	}
+
+	wf.Initial = strings.TrimSpace(wf.Initial)
+	if wf.Initial == "" {
+		wf.Initial = open
+	}
+	if !seen[wf.Initial] || wf.Terminal(wf.Initial) {
+		return fmt.Errorf("Initial state must be a non terminal state: %s", wf.Initial)
+	}
+
+	for i := range wf.Transitions {
+		tr := &wf.Transitions[i]
+		tr.From, tr.To = strings.TrimSpace(tr.From), strings.TrimSpace(tr.To)
+		if !seen[tr.From] || !seen[tr.To] || tr.From == tr.To {
+			return fmt.Errorf("Invalid transition from %q to %q", tr.From, tr.To)
+		}
+	}
+
+	return nil
+}
+
+// HasState reports whether the workflow has a state with the name.
+func (wf Workflow) HasState(name string) bool {
+	for _, s := range wf.States {
+		if s.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Terminal reports whether the state with the name is terminal.
+func (wf Workflow) Terminal(name string) bool {
+	for _, s := range wf.States {
+		if s.Name == name {
+			return s.Terminal
+		}
+	}
+
+	return false
+}
+
+// WIPLimit returns the WIP limit of the state with the name.
+func (wf Workflow) WIPLimit(name string) int {
+	for _, s := range wf.States {
+		if s.Name == name {
+			return s.WIPLimit
+		}
+	}
+
+	return 0
+}
+
+// Allows reports whether todos may move from one state to the other.
+func (wf Workflow) Allows(from, to string) bool {
+	for _, tr := range wf.Transitions {
+		if tr.From == from && tr.To == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StatusOf returns the state of the todo. Todos whose status is not a
+// state of the workflow, e.g. those added before it was defined, are
+// in the initial state or, when completed, the first terminal state.
+func (wf Workflow) StatusOf(t Todo) string {
+	if wf.HasState(t.Status) {
+		return t.Status
+	}
+
+	return wf.StatusFor(t.Completed)
+}
+
+// StatusFor returns the state of a new todo, the initial state or,
+// when it is completed, the first terminal state.
+func (wf Workflow) StatusFor(completed bool) string {
+	if !completed {
+		return wf.Initial
+	}
+
+	for _, s := range wf.States {
+		if s.Terminal {
+			return s.Name
+		}
+	}
+
+	return wf.Initial
+}
+
+// CompletionTarget returns the state a todo in the state from moves to
+// when it is completed or reopened: the first state it may transition
+// to which is terminal, or not terminal.
+func (wf Workflow) CompletionTarget(from string, completed bool) (string, error) {
+	for _, s := range wf.States {
+		if s.Terminal == completed && wf.Allows(from, s.Name) {
+			return s.Name, nil
+		}
+	}
+
+	return "", ErrInvalidTransition
+}
+
+// AppendStatusChange appends the change to the status history, only
+// keeping the most recent MaxStatusHistory changes.
+func AppendStatusChange(history []StatusChange, c StatusChange) []StatusChange {
+	history = append(append([]StatusChange(nil), history...), c)
+	if len(history) > MaxStatusHistory {
+		history = history[len(history)-MaxStatusHistory:]
+	}
+
+	return history
+}
+
+// statusHistoryChange accepts a status history for ModifyTodo. Only
+// the server records status changes so json arrays are rejected.
+func statusHistoryChange(v interface{}) (interface{}, error) {
+	if _, ok := v.([]StatusChange); !ok {
+		return nil, errors.New("Value is not a status history")
+	}
+
+	return v, nil
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func teamWorkflow() Workflow {
+	return Workflow{
+		States: []WorkflowState{
+			{Name: "todo"}, {Name: "in progress"}, {Name: "review"}, {Name: "blocked"}, {Name: "done", Terminal: true},
+		},
+		Transitions: []Transition{
+			{"todo", "in progress"}, {"in progress", "review"}, {"in progress", "blocked"},
+			{"blocked", "in progress"}, {"review", "in progress"}, {"review", "done"}, {"done", "todo"},
+		},
+	}
+}
+
+func TestWorkflowValidate(t *testing.T) {
+	wf := teamWorkflow()
+	if err := wf.Validate(); err != nil || wf.Initial != "todo" {
+		t.Errorf("Workflow should be valid: %q %v", wf.Initial, err)
+	}
+
+	for name, modify := range map[string]func(*Workflow){
+		"no states":          func(wf *Workflow) { wf.States = nil },
+		"duplicate state":    func(wf *Workflow) { wf.States[1].Name = "todo" },
+		"no terminal state":  func(wf *Workflow) { wf.States[4].Terminal = false },
+		"terminal initial":   func(wf *Workflow) { wf.Initial = "done" },
+		"unknown initial":    func(wf *Workflow) { wf.Initial = "later" },
+		"unknown transition": func(wf *Workflow) { wf.Transitions[0].To = "later" },
+		"self transition":    func(wf *Workflow) { wf.Transitions[0].To = "todo" },
+	} {
+		wf := teamWorkflow()
+		modify(&wf)
+		if err := wf.Validate(); err == nil {
+			t.Errorf("Workflow with %s should be rejected", name)
+		}
+	}
+}
+
+func TestWorkflowStatus(t *testing.T) {
+	wf := teamWorkflow()
+	wf.Validate()
+
+	t0 := NewTodo()
+	if s := wf.StatusOf(t0); s != "todo" {
+		t.Errorf("2Do without a status should be in the initial state: %q", s)
+	}
+
+	t0.Completed = true
+	if s := wf.StatusOf(t0); s != "done" {
+		t.Errorf("Completed 2Do should be in the terminal state: %q", s)
+	}
+
+	t0.Status = "review"
+	if s := wf.StatusOf(t0); s != "review" {
+		t.Errorf("2Do should keep its status: %q", s)
+	}
+
+	if to, err := wf.CompletionTarget("review", true); err != nil || to != "done" {
+		t.Errorf("Review should complete to done: %q %v", to, err)
+	}
+	if _, err := wf.CompletionTarget("todo", true); err != ErrInvalidTransition {
+		t.Errorf("Todo should not complete directly: %v", err)
+	}
+	if to, err := wf.CompletionTarget("done", false); err != nil || to != "todo" {
+		t.Errorf("Done should reopen to todo: %q %v", to, err)
+	}
+}
+
+func TestAppendStatusChange(t *testing.T) {
+	var history []StatusChange
+	for i := 0; i < MaxStatusHistory+5; i++ {
+		history = AppendStatusChange(history, StatusChange{To: "todo", At: time.Unix(int64(i), 0)})
+	}
+
+	if len(history) != MaxStatusHistory || history[0].At.Unix() != 5 {
+		t.Errorf("Only the most recent changes should be kept: %d %v", len(history), history[0].At)
+	}
+}
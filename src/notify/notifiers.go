@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"models"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// InAppNotifier stores notifications in the user's notifications feed.
+type InAppNotifier struct{}
+
+func (InAppNotifier) Channel() string {
+	return models.ChannelInApp
+}
+
+func (InAppNotifier) Notify(u models.User, n models.Notification) error {
+	nds := models.NewNotificationStorage()
+	defer nds.Close()
+
+	n.Ownerid = u.Id.Hex()
+	return nds.InsertNotification(n)
+}
+
+// WebhookNotifier posts notifications as json to the user's webhook.
+type WebhookNotifier struct {
+	Client *http.Client
+}
+
+func NewWebhookNotifier() WebhookNotifier {
+	return WebhookNotifier{Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (WebhookNotifier) Channel() string {
+	return models.ChannelWebhook
+}
+
+func (wn WebhookNotifier) Notify(u models.User, n models.Notification) error {
+	if u.WebhookURL == "" {
+		return ErrNotConfigured
+	}
+
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	res, err := wn.Client.Post(u.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("Webhook responded with status: %s", res.Status)
+	}
+
+	return nil
+}
+
+// SMTPNotifier emails notifications through the mail server at Addr.
+type SMTPNotifier struct {
+	Addr string // host:port
+	From string
+}
+
+func (SMTPNotifier) Channel() string {
+	return models.ChannelEmail
+}
+
+func (sn SMTPNotifier) Notify(u models.User, n models.Notification) error {
+	if u.Email == "" || sn.Addr == "" {
+		return ErrNotConfigured
+	}
+
+	return smtp.SendMail(sn.Addr, nil, sn.From, []string{u.Email}, sn.message(u, n))
+}
+
+// message formats the notification as a plain text email.
+func (sn SMTPNotifier) message(u models.User, n models.Notification) []byte {
+	header := strings.NewReplacer("\r", "", "\n", "")
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", header.Replace(sn.From))
+	fmt.Fprintf(&b, "To: %s\r\n", header.Replace(u.Email))
+	fmt.Fprintf(&b, "Subject: %s\r\n", header.Replace(n.Title))
+	fmt.Fprintf(&b, "Date: %s\r\n", n.Created.Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.Replace(n.Message, "\n", "\r\n", -1))
+	b.WriteString("\r\n")
+
+	return b.Bytes()
+}
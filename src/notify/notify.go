@@ -0,0 +1,94 @@
+// Package notify delivers notifications to users through pluggable
+// channels such as the in-app feed, webhooks and email.
+package notify
+
+import (
+	"config"
+	"errors"
+	"fmt"
+	"log"
+	"models"
+	"strings"
+)
+
+// Notifier delivers a notification through a single channel.
+type Notifier interface {
+	// Channel returns the channel name e.g. models.ChannelEmail.
+	Channel() string
+	// Notify delivers the notification to the user. Notifiers return
+	// ErrNotConfigured when the user has no address for the channel.
+	Notify(u models.User, n models.Notification) error
+}
+
+var ErrNotConfigured = errors.New("Notification channel not configured for user")
+
+// Dispatcher delivers notifications through its registered notifiers.
+type Dispatcher struct {
+	notifiers map[string]Notifier
+}
+
+func NewDispatcher(notifiers ...Notifier) *Dispatcher {
+	d := &Dispatcher{notifiers: make(map[string]Notifier)}
+	for _, n := range notifiers {
+		d.Register(n)
+	}
+	return d
+}
+
+// Register adds the notifier, replacing any notifier of the same channel.
+func (d *Dispatcher) Register(n Notifier) {
+	d.notifiers[n.Channel()] = n
+}
+
+// Dispatch delivers the notification through each of the channels and
+// returns the channels it was delivered through. Channels which aren't
+// registered or configured for the user are skipped, the error reports
+// every channel which failed.
+func (d *Dispatcher) Dispatch(u models.User, n models.Notification, channels []string) ([]string, error) {
+	delivered := make([]string, 0, len(channels))
+	var failures []string
+
+	for _, channel := range channels {
+		notifier, ok := d.notifiers[channel]
+		if !ok {
+			log.Printf("Dispatch: No notifier registered for channel: %s\n", channel)
+			continue
+		}
+
+		err := notifier.Notify(u, n)
+		if err == ErrNotConfigured {
+			continue
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", channel, err))
+			continue
+		}
+
+		delivered = append(delivered, channel)
+	}
+
+	if len(failures) > 0 {
+		return delivered, fmt.Errorf("Failure to notify: %s", strings.Join(failures, ", "))
+	}
+
+	return delivered, nil
+}
+
+var dispatcher *Dispatcher // Global dispatcher instance
+
+// GetDispatcher returns the global dispatcher or if it does not
+// exist it creates it with the notifiers of every channel.
+func GetDispatcher() *Dispatcher {
+	if dispatcher != nil {
+		return dispatcher
+	}
+
+	c := config.GetConfig()
+	dispatcher = NewDispatcher(
+		InAppNotifier{},
+		NewWebhookNotifier(),
+		SMTPNotifier{Addr: c.SMTPHostname, From: c.SMTPFrom},
+	)
+
+	return dispatcher
+}
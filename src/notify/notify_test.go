@@ -0,0 +1,146 @@
+package notify
+
+import (
+	"bufio"
+	"errors"
+	"models"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeNotifier struct {
+	channel string
+	err     error
+	sent    []models.Notification
+}
+
+func (fn *fakeNotifier) Channel() string {
+	return fn.channel
+}
+
+func (fn *fakeNotifier) Notify(u models.User, n models.Notification) error {
+	if fn.err != nil {
+		return fn.err
+	}
+
+	fn.sent = append(fn.sent, n)
+	return nil
+}
+
+func TestDispatch(t *testing.T) {
+	inApp := &fakeNotifier{channel: models.ChannelInApp}
+	email := &fakeNotifier{channel: models.ChannelEmail, err: ErrNotConfigured}
+	webhook := &fakeNotifier{channel: models.ChannelWebhook, err: errors.New("timeout")}
+	d := NewDispatcher(inApp, email, webhook)
+
+	n := models.NewNotification()
+	delivered, err := d.Dispatch(models.NewUser(), n, models.Channels)
+	if err == nil || !strings.Contains(err.Error(), "webhook") {
+		t.Errorf("Should report the failed webhook: got %v", err)
+	}
+
+	if len(delivered) != 1 || delivered[0] != models.ChannelInApp || len(inApp.sent) != 1 {
+		t.Errorf("Should only deliver in app: got %v", delivered)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	u := models.NewUser()
+	wn := NewWebhookNotifier()
+	if err := wn.Notify(u, models.NewNotification()); err != ErrNotConfigured {
+		t.Errorf("Should not be configured without a webhook url: got %v", err)
+	}
+
+	u.WebhookURL = srv.URL
+	if err := wn.Notify(u, models.NewNotification()); err != nil {
+		t.Fatal(err)
+	}
+
+	if contentType != "application/json" {
+		t.Errorf("Incorrect content type: %s", contentType)
+	}
+}
+
+// serveSMTP is a minimal stand-in mail server which accepts a single
+// message and sends its data on the returned channel.
+func serveSMTP(t *testing.T) (string, <-chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := make(chan string, 1)
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost")
+
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case cmd == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var msg []string
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					msg = append(msg, l)
+				}
+				data <- strings.Join(msg, "")
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	return l.Addr().String(), data
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	addr, data := serveSMTP(t)
+
+	u := models.NewUser()
+	u.Email = "someone@example.com"
+	n := models.NewNotification()
+	n.Title = "Reminder: Pay rent"
+	n.Message = "Your 2Do is due."
+
+	sn := SMTPNotifier{Addr: addr, From: "2do@localhost"}
+	if err := sn.Notify(u, n); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := <-data
+	if !strings.Contains(msg, "Subject: Reminder: Pay rent") || !strings.Contains(msg, "Your 2Do is due.") {
+		t.Errorf("Incorrect email: %s", msg)
+	}
+}
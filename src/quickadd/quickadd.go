@@ -0,0 +1,586 @@
+// Package quickadd parses a single line such as
+// "Pay rent tomorrow 9am #home !high every month" into a todo.
+//
+// Recognized are #tags, !priorities (!low, !medium, !high, !urgent or
+// !1 to !4), dates (today, tomorrow, weekdays, next week, in 3 days,
+// 2017-01-02, jan 5), times (9am, 9:30pm, 21:00, noon) and recurrences
+// (daily, every 2 weeks, every monday, every weekday). The remaining
+// words are the title. Dates and times are those of the time zone of
+// the user, a date without a time is all day.
+package quickadd
+
+import (
+	"errors"
+	"fmt"
+	"models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of the parts of the line.
+const (
+	KindTag        = "tag"
+	KindPriority   = "priority"
+	KindDue        = "due_date"
+	KindRecurrence = "recurrence"
+)
+
+// Token is a part of the line which was interpreted as something other
+// than the title, so that clients can show what was understood.
+type Token struct {
+	Text  string `json:"text"`
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// Result is the todo parsed from a line along with its interpretation.
+type Result struct {
+	Todo   models.Todo `json:"todo"`
+	Tokens []Token     `json:"tokens"`
+}
+
+var ErrNoTitle = errors.New("Quick add requires a title")
+
+var priorities = map[string]models.Priority{
+	"low":    models.PriorityLow,
+	"1":      models.PriorityLow,
+	"medium": models.PriorityMedium,
+	"med":    models.PriorityMedium,
+	"2":      models.PriorityMedium,
+	"high":   models.PriorityHigh,
+	"3":      models.PriorityHigh,
+	"urgent": models.PriorityUrgent,
+	"4":      models.PriorityUrgent,
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+var rruleDays = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+var months = map[string]time.Month{
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+var units = map[string]string{
+	"day": "DAILY", "days": "DAILY",
+	"week": "WEEKLY", "weeks": "WEEKLY",
+	"month": "MONTHLY", "months": "MONTHLY",
+	"year": "YEARLY", "years": "YEARLY",
+}
+
+var frequencies = map[string]string{
+	"daily":    "DAILY",
+	"weekly":   "WEEKLY",
+	"monthly":  "MONTHLY",
+	"yearly":   "YEARLY",
+	"annually": "YEARLY",
+}
+
+// date is a calendar day without a time zone.
+type date struct {
+	y int
+	m time.Month
+	d int
+}
+
+// parser holds the state of parsing a line. words are the lower cased
+// words, with trailing punctuation removed, of the original words.
+type parser struct {
+	now   time.Time // in the user's time zone
+	words []string
+	orig  []string
+
+	title  []string
+	tokens []Token
+
+	day        *date
+	hour, min  int
+	hasTime    bool
+	rule       string
+	firstDay   time.Weekday // first weekday of a weekly rule
+	hasWeekday bool
+	tags       []string
+	priority   models.Priority
+
+	dueEnd int // index following the last date or time
+}
+
+// Parse parses the line relative to now in the time zone loc.
+func Parse(line string, now time.Time, loc *time.Location) (*Result, error) {
+	p := &parser{now: now.In(loc), orig: strings.Fields(line), dueEnd: -1}
+	for _, w := range p.orig {
+		p.words = append(p.words, strings.TrimRight(strings.ToLower(w), ",.;"))
+	}
+
+	for i := 0; i < len(p.words); {
+		n := p.match(i)
+		if n == 0 {
+			p.title = append(p.title, p.orig[i])
+			n = 1
+		}
+		i += n
+	}
+
+	title := strings.TrimSpace(strings.Join(p.title, " "))
+	if title == "" {
+		return nil, ErrNoTitle
+	}
+
+	t := models.NewTodo()
+	t.Title = title
+	t.Tags = models.NormalizeTags(p.tags)
+	t.Priority = p.priority
+
+	if p.day == nil && (p.hasTime || p.rule != "") {
+		p.defaultDay()
+	}
+
+	if p.day != nil {
+		t.Due = time.Date(p.day.y, p.day.m, p.day.d, p.hour, p.min, 0, 0, loc)
+		t.AllDay = !p.hasTime
+		p.setToken(KindDue, t.Due.Format(time.RFC3339))
+	}
+
+	if p.rule != "" {
+		t.Recurrence = &models.Recurrence{Rule: p.rule, TimeZone: loc.String(), Start: t.Due, Index: 1}
+		if err := t.Recurrence.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Result{Todo: t, Tokens: p.tokens}, nil
+}
+
+// match tries to interpret the words starting at i and returns the
+// number of words consumed, 0 when they are part of the title.
+func (p *parser) match(i int) int {
+	w := p.words[i]
+
+	switch {
+	case strings.HasPrefix(w, "#") && len(w) > 1:
+		p.tags = append(p.tags, w[1:])
+		p.addToken(i, 1, KindTag, w[1:])
+		return 1
+	case strings.HasPrefix(w, "!") && p.priority == "":
+		if pr, ok := priorities[w[1:]]; ok {
+			p.priority = pr
+			p.addToken(i, 1, KindPriority, string(pr))
+			return 1
+		}
+		return 0
+	}
+
+	if p.rule == "" {
+		if n := p.matchRecurrence(i); n > 0 {
+			p.addToken(i, n, KindRecurrence, p.rule)
+			return n
+		}
+	}
+
+	// Prepositions are only consumed along with a date or time
+	skip := 0
+	switch w {
+	case "on", "at", "by", "due":
+		skip = 1
+	}
+	if i+skip >= len(p.words) {
+		return 0
+	}
+	prep := skip > 0
+
+	n := 0
+	if p.day == nil {
+		n = p.matchDate(i+skip, prep)
+	}
+	if n == 0 && !p.hasTime {
+		n = p.matchTime(i+skip, prep)
+	}
+	if n == 0 {
+		return 0
+	}
+
+	p.addToken(i, skip+n, KindDue, "")
+	p.dueEnd = i + skip + n
+	return skip + n
+}
+
+// addToken records the original words [i, i+n) as a token.
+func (p *parser) addToken(i, n int, kind, value string) {
+	text := strings.Join(p.orig[i:i+n], " ")
+
+	// Dates and times next to each other form a single token
+	if kind == KindDue && i == p.dueEnd {
+		last := &p.tokens[len(p.tokens)-1]
+		last.Text += " " + text
+		return
+	}
+
+	p.tokens = append(p.tokens, Token{Text: text, Kind: kind, Value: value})
+}
+
+// setToken sets the value of the tokens of the kind.
+func (p *parser) setToken(kind, value string) {
+	for i := range p.tokens {
+		if p.tokens[i].Kind == kind {
+			p.tokens[i].Value = value
+		}
+	}
+}
+
+func (p *parser) word(i int) string {
+	if i < len(p.words) {
+		return p.words[i]
+	}
+
+	return ""
+}
+
+func (p *parser) today() date {
+	y, m, d := p.now.Date()
+	return date{y, m, d}
+}
+
+func (p *parser) addDays(d date, n int) date {
+	y, m, dd := time.Date(d.y, d.m, d.d+n, 0, 0, 0, 0, time.UTC).Date()
+	return date{y, m, dd}
+}
+
+// nextWeekday returns the next day, after today, on the weekday.
+func (p *parser) nextWeekday(wd time.Weekday) date {
+	n := (int(wd) - int(p.now.Weekday()) + 7) % 7
+	if n == 0 {
+		n = 7
+	}
+
+	return p.addDays(p.today(), n)
+}
+
+// defaultDay sets the day of a todo which only has a time or recurs.
+// A time which has passed is tomorrow, a weekly rule starts on its
+// first weekday.
+func (p *parser) defaultDay() {
+	d := p.today()
+
+	if p.hasWeekday {
+		if p.now.Weekday() != p.firstDay {
+			d = p.nextWeekday(p.firstDay)
+		}
+	} else if p.hasTime {
+		due := time.Date(d.y, d.m, d.d, p.hour, p.min, 0, 0, p.now.Location())
+		if due.Before(p.now) {
+			d = p.addDays(d, 1)
+		}
+	}
+
+	p.day = &d
+}
+
+func (p *parser) matchRecurrence(i int) int {
+	w := p.word(i)
+	if freq, ok := frequencies[w]; ok {
+		p.rule = "FREQ=" + freq
+		return 1
+	}
+
+	if w == "weekdays" {
+		p.setWeekdays([]time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday})
+		return 1
+	}
+
+	if w != "every" {
+		return 0
+	}
+
+	next := p.word(i + 1)
+	if freq, ok := units[next]; ok {
+		p.rule = "FREQ=" + freq
+		return 2
+	}
+
+	interval := 0
+	if next == "other" {
+		interval = 2
+	} else if n, err := strconv.Atoi(next); err == nil && n > 0 {
+		interval = n
+	}
+	if freq, ok := units[p.word(i+2)]; ok && interval > 0 {
+		p.rule = fmt.Sprintf("FREQ=%s;INTERVAL=%d", freq, interval)
+		return 3
+	}
+
+	if next == "weekday" || next == "weekdays" {
+		p.setWeekdays([]time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday})
+		return 2
+	}
+
+	// every monday, every mon,wed and fri
+	var wds []time.Weekday
+	n := 1
+	for {
+		names := strings.Split(p.word(i+n), ",")
+		found := false
+		for _, name := range names {
+			if wd, ok := weekdays[name]; ok {
+				wds = append(wds, wd)
+				found = true
+			}
+		}
+		if !found {
+			break
+		}
+		n++
+
+		if p.word(i+n) == "and" {
+			if _, ok := weekdays[p.word(i+n+1)]; ok {
+				n++
+			}
+		}
+	}
+
+	if len(wds) == 0 {
+		return 0
+	}
+
+	p.setWeekdays(wds)
+	return n
+}
+
+func (p *parser) setWeekdays(wds []time.Weekday) {
+	days := make([]string, len(wds))
+	for i, wd := range wds {
+		days[i] = rruleDays[wd]
+	}
+
+	p.rule = "FREQ=WEEKLY;BYDAY=" + strings.Join(days, ",")
+
+	// The first instance is on the next of the weekdays from today
+	best := 7
+	for _, wd := range wds {
+		n := (int(wd) - int(p.now.Weekday()) + 7) % 7
+		if n < best {
+			best = n
+			p.firstDay = wd
+		}
+	}
+	p.hasWeekday = true
+}
+
+// matchDate matches a date at i. Abbreviated weekdays are only dates
+// after a preposition, "sun" alone is more likely part of the title.
+func (p *parser) matchDate(i int, prep bool) int {
+	w := p.word(i)
+	today := p.today()
+
+	var d date
+	n := 1
+	switch w {
+	case "today":
+		d = today
+	case "tonight":
+		d = today
+		if !p.hasTime {
+			p.hour, p.min, p.hasTime = 20, 0, true
+		}
+	case "tomorrow", "tmr", "tmrw":
+		d = p.addDays(today, 1)
+	case "next":
+		switch next := p.word(i + 1); {
+		case next == "week":
+			d = p.nextWeekday(time.Monday)
+		case next == "month":
+			y, m, _ := time.Date(today.y, today.m+1, 1, 0, 0, 0, 0, time.UTC).Date()
+			d = date{y, m, 1}
+		case next == "year":
+			d = date{today.y + 1, time.January, 1}
+		default:
+			wd, ok := weekdays[next]
+			if !ok {
+				return 0
+			}
+			d = p.nextWeekday(wd)
+		}
+		n = 2
+	case "in":
+		count, err := strconv.Atoi(p.word(i + 1))
+		if err != nil || count < 1 {
+			return 0
+		}
+
+		switch units[p.word(i+2)] {
+		case "DAILY":
+			d = p.addDays(today, count)
+		case "WEEKLY":
+			d = p.addDays(today, 7*count)
+		case "MONTHLY":
+			y, m, dd := time.Date(today.y, today.m+time.Month(count), today.d, 0, 0, 0, 0, time.UTC).Date()
+			d = date{y, m, dd}
+		default:
+			return 0
+		}
+		n = 3
+	default:
+		if wd, ok := weekdays[w]; ok && (prep || len(w) > 4) {
+			d = p.nextWeekday(wd)
+			break
+		}
+
+		if t, err := time.Parse("2006-01-02", w); err == nil {
+			d = date{t.Year(), t.Month(), t.Day()}
+			break
+		}
+
+		var ok bool
+		d, n, ok = p.matchMonthDay(i)
+		if !ok {
+			return 0
+		}
+	}
+
+	p.day = &d
+	return n
+}
+
+// matchMonthDay matches "jan 5", "january 5th", "5 jan" with an
+// optional year. Without a year the next such day is used.
+func (p *parser) matchMonthDay(i int) (date, int, bool) {
+	var m time.Month
+	var day int
+	var ok bool
+
+	if m, ok = months[p.word(i)]; ok {
+		day, ok = dayOfMonth(p.word(i + 1))
+	} else if day, ok = dayOfMonth(p.word(i)); ok {
+		m, ok = months[p.word(i+1)]
+	}
+	if !ok {
+		return date{}, 0, false
+	}
+
+	n := 2
+	y := p.now.Year()
+	if year, err := strconv.Atoi(p.word(i + 2)); err == nil && year >= 1970 && year < 10000 {
+		y = year
+		n = 3
+	} else if time.Date(y, m, day, 23, 59, 59, 0, p.now.Location()).Before(p.now) {
+		y++
+	}
+
+	if time.Date(y, m, day, 0, 0, 0, 0, time.UTC).Month() != m {
+		return date{}, 0, false
+	}
+
+	return date{y, m, day}, n, true
+}
+
+// dayOfMonth parses 5, 5th, 1st, 2nd or 3rd.
+func dayOfMonth(w string) (int, bool) {
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		w = strings.TrimSuffix(w, suffix)
+	}
+
+	d, err := strconv.Atoi(w)
+	return d, err == nil && d >= 1 && d <= 31
+}
+
+// matchTime matches a time at i. A bare hour is only a time after a
+// preposition e.g. at 9.
+func (p *parser) matchTime(i int, prep bool) int {
+	w := p.word(i)
+
+	switch w {
+	case "noon", "midday":
+		p.hour, p.min, p.hasTime = 12, 0, true
+		return 1
+	case "midnight":
+		p.hour, p.min, p.hasTime = 0, 0, true
+		return 1
+	}
+
+	// 9 am is two words
+	n := 1
+	if next := p.word(i + 1); next == "am" || next == "pm" {
+		w += next
+		n = 2
+	}
+
+	h, m, ok := clock(w)
+	if !ok && prep && n == 1 {
+		var err error
+		h, err = strconv.Atoi(w)
+		m, ok = 0, err == nil && h >= 0 && h <= 23
+	}
+	if !ok {
+		return 0
+	}
+
+	p.hour, p.min, p.hasTime = h, m, true
+	return n
+}
+
+// clock parses 9am, 9:30pm, 12am or 21:00.
+func clock(w string) (int, int, bool) {
+	meridiem := ""
+	if strings.HasSuffix(w, "am") || strings.HasSuffix(w, "pm") {
+		meridiem = w[len(w)-2:]
+		w = w[:len(w)-2]
+	}
+
+	hs, ms := w, "0"
+	if j := strings.Index(w, ":"); j >= 0 {
+		hs, ms = w[:j], w[j+1:]
+		if len(ms) != 2 {
+			return 0, 0, false
+		}
+	} else if meridiem == "" {
+		// A bare number is not a time
+		return 0, 0, false
+	}
+
+	h, err := strconv.Atoi(hs)
+	if err != nil {
+		return 0, 0, false
+	}
+	m, err := strconv.Atoi(ms)
+	if err != nil || m < 0 || m > 59 {
+		return 0, 0, false
+	}
+
+	switch meridiem {
+	case "":
+		if h < 0 || h > 23 {
+			return 0, 0, false
+		}
+	default:
+		if h < 1 || h > 12 {
+			return 0, 0, false
+		}
+		if h == 12 {
+			h = 0
+		}
+		if meridiem == "pm" {
+			h += 12
+		}
+	}
+
+	return h, m, true
+}
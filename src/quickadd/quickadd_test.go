@@ -0,0 +1,121 @@
+package quickadd
+
+import (
+	"models"
+	"testing"
+	"time"
+)
+
+// Monday 2 January 2017, 15:04 in New York
+var loc, _ = time.LoadLocation("America/New_York")
+var now = time.Date(2017, 1, 2, 15, 4, 0, 0, loc)
+
+func mustParse(t *testing.T, line string) *Result {
+	r, err := Parse(line, now, loc)
+	if err != nil {
+		t.Fatalf("Failure to parse %q: %v", line, err)
+	}
+
+	return r
+}
+
+func TestParse(t *testing.T) {
+	r := mustParse(t, "Pay rent tomorrow 9am #home !high every month")
+	td := r.Todo
+
+	if td.Title != "Pay rent" {
+		t.Errorf("Incorrect title: %q", td.Title)
+	}
+	if want := time.Date(2017, 1, 3, 9, 0, 0, 0, loc); !td.Due.Equal(want) {
+		t.Errorf("Incorrect due date: want %v got %v", want, td.Due)
+	}
+	if len(td.Tags) != 1 || td.Tags[0] != "home" {
+		t.Errorf("Incorrect tags: %v", td.Tags)
+	}
+	if td.Priority != models.PriorityHigh {
+		t.Errorf("Incorrect priority: %s", td.Priority)
+	}
+	if td.Recurrence == nil || td.Recurrence.Rule != "FREQ=MONTHLY" || td.Recurrence.TimeZone != "America/New_York" {
+		t.Errorf("Incorrect recurrence: %v", td.Recurrence)
+	}
+
+	want := []Token{
+		{"tomorrow 9am", KindDue, "2017-01-03T09:00:00-05:00"},
+		{"#home", KindTag, "home"},
+		{"!high", KindPriority, "high"},
+		{"every month", KindRecurrence, "FREQ=MONTHLY"},
+	}
+	if len(r.Tokens) != len(want) {
+		t.Fatalf("Incorrect tokens: %v", r.Tokens)
+	}
+	for i, tok := range want {
+		if r.Tokens[i] != tok {
+			t.Errorf("Incorrect token: want %v got %v", tok, r.Tokens[i])
+		}
+	}
+}
+
+func TestParseDates(t *testing.T) {
+	tests := map[string]time.Time{
+		"Call mom at 9":               time.Date(2017, 1, 3, 9, 0, 0, 0, loc),
+		"Call mom at 5:30pm":          time.Date(2017, 1, 2, 17, 30, 0, 0, loc),
+		"Call mom today":              time.Date(2017, 1, 2, 0, 0, 0, 0, loc),
+		"Call mom friday noon":        time.Date(2017, 1, 6, 12, 0, 0, 0, loc),
+		"Call mom on mon":             time.Date(2017, 1, 9, 0, 0, 0, 0, loc),
+		"Call mom next week":          time.Date(2017, 1, 9, 0, 0, 0, 0, loc),
+		"Call mom in 2 weeks":         time.Date(2017, 1, 16, 0, 0, 0, 0, loc),
+		"Call mom 2017-03-04 21:00":   time.Date(2017, 3, 4, 21, 0, 0, 0, loc),
+		"Call mom jan 1st":            time.Date(2018, 1, 1, 0, 0, 0, 0, loc),
+		"Call mom by 14 feb 2018 8am": time.Date(2018, 2, 14, 8, 0, 0, 0, loc),
+	}
+
+	for line, want := range tests {
+		r := mustParse(t, line)
+		if r.Todo.Title != "Call mom" || !r.Todo.Due.Equal(want) {
+			t.Errorf("Incorrect parse of %q: %q %v", line, r.Todo.Title, r.Todo.Due)
+		}
+	}
+
+	// A date without a time is all day
+	if r := mustParse(t, "Call mom today"); !r.Todo.AllDay {
+		t.Error("2Do should be all day")
+	}
+	if r := mustParse(t, "Call mom tonight"); r.Todo.AllDay {
+		t.Error("2Do should not be all day")
+	}
+
+	// Words which only look like dates stay in the title
+	r := mustParse(t, "Buy sun screen at the mall 2")
+	if r.Todo.Title != "Buy sun screen at the mall 2" || !r.Todo.Due.IsZero() {
+		t.Errorf("Incorrect parse: %q %v", r.Todo.Title, r.Todo.Due)
+	}
+}
+
+func TestParseRecurrence(t *testing.T) {
+	tests := map[string]string{
+		"Water plants every 3 days":       "FREQ=DAILY;INTERVAL=3",
+		"Water plants every other week":   "FREQ=WEEKLY;INTERVAL=2",
+		"Water plants weekdays":           "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+		"Water plants every tue and fri":  "FREQ=WEEKLY;BYDAY=TU,FR",
+		"Water plants every wed,sat 10am": "FREQ=WEEKLY;BYDAY=WE,SA",
+	}
+
+	for line, want := range tests {
+		r := mustParse(t, line)
+		if r.Todo.Title != "Water plants" || r.Todo.Recurrence == nil || r.Todo.Recurrence.Rule != want {
+			t.Errorf("Incorrect parse of %q: %q %v", line, r.Todo.Title, r.Todo.Recurrence)
+		}
+	}
+
+	// A weekly todo is first due on the next of its weekdays
+	r := mustParse(t, "Water plants every wed,sat 10am")
+	if want := time.Date(2017, 1, 4, 10, 0, 0, 0, loc); !r.Todo.Due.Equal(want) {
+		t.Errorf("Incorrect first due date: want %v got %v", want, r.Todo.Due)
+	}
+}
+
+func TestParseNoTitle(t *testing.T) {
+	if _, err := Parse("tomorrow #home", now, loc); err != ErrNoTitle {
+		t.Errorf("Line without a title should be rejected: got %v", err)
+	}
+}
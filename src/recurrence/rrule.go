@@ -0,0 +1,449 @@
+// Package recurrence implements the subset of RFC 5545 recurrence
+// rules (RRULE) used to repeat todos.
+//
+// Supported rule parts are FREQ (DAILY, WEEKLY, MONTHLY or YEARLY),
+// INTERVAL, COUNT, UNTIL, WKST, BYDAY and BYMONTHDAY. Occurrences are
+// computed on the wall clock of a time zone so a todo due at 9:00 stays
+// due at 9:00 across daylight saving time transitions.
+package recurrence
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency int
+
+const (
+	Daily Frequency = iota
+	Weekly
+	Monthly
+	Yearly
+)
+
+var frequencies = map[string]Frequency{
+	"DAILY":   Daily,
+	"WEEKLY":  Weekly,
+	"MONTHLY": Monthly,
+	"YEARLY":  Yearly,
+}
+
+func (f Frequency) String() string {
+	for name, freq := range frequencies {
+		if freq == f {
+			return name
+		}
+	}
+
+	return fmt.Sprintf("Frequency(%d)", int(f))
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+var weekdayNames = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// WeekdayNum is a BYDAY entry e.g. MO, 2TU or -1FR. An Ordinal of 0
+// means every such weekday of the period.
+type WeekdayNum struct {
+	Ordinal int
+	Weekday time.Weekday
+}
+
+func (wn WeekdayNum) String() string {
+	if wn.Ordinal == 0 {
+		return weekdayNames[wn.Weekday]
+	}
+
+	return strconv.Itoa(wn.Ordinal) + weekdayNames[wn.Weekday]
+}
+
+// Rule is a parsed recurrence rule.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int       // 0 when the rule is not limited by a count
+	Until      time.Time // Zero when the rule is not limited by a date
+	WeekStart  time.Weekday
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+
+	// FloatingUntil is set when UNTIL is a date or a local time, then
+	// Until has its wall clock in UTC and it is read in the location of
+	// the occurrences.
+	FloatingUntil bool
+}
+
+// maxPeriods bounds the search for the next occurrence so rules which
+// can never match again (e.g. BYMONTHDAY=31 every other February)
+// terminate.
+const maxPeriods = 1000
+
+// Parse parses a recurrence rule such as "FREQ=WEEKLY;BYDAY=MO,WE".
+// A leading "RRULE:" is ignored.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return nil, fmt.Errorf("Empty recurrence rule")
+	}
+
+	r := &Rule{Interval: 1, WeekStart: time.Monday}
+	hasFreq := false
+
+	for _, part := range strings.Split(s, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("Invalid recurrence rule part: %s", part)
+		}
+		key, value := strings.ToUpper(kv[0]), strings.ToUpper(kv[1])
+
+		var err error
+		switch key {
+		case "FREQ":
+			var ok bool
+			r.Freq, ok = frequencies[value]
+			if !ok {
+				return nil, fmt.Errorf("Unsupported recurrence frequency: %s", value)
+			}
+			hasFreq = true
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(value)
+			if err != nil || r.Interval < 1 {
+				return nil, fmt.Errorf("Invalid recurrence interval: %s", value)
+			}
+		case "COUNT":
+			r.Count, err = strconv.Atoi(value)
+			if err != nil || r.Count < 1 {
+				return nil, fmt.Errorf("Invalid recurrence count: %s", value)
+			}
+		case "UNTIL":
+			r.Until, r.FloatingUntil, err = parseUntil(value)
+			if err != nil {
+				return nil, err
+			}
+		case "WKST":
+			var ok bool
+			r.WeekStart, ok = weekdays[value]
+			if !ok {
+				return nil, fmt.Errorf("Invalid recurrence week start: %s", value)
+			}
+		case "BYDAY":
+			r.ByDay, err = parseByDay(value)
+			if err != nil {
+				return nil, err
+			}
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseByMonthDay(value)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("Unsupported recurrence rule part: %s", key)
+		}
+	}
+
+	if !hasFreq {
+		return nil, fmt.Errorf("Recurrence rule requires FREQ")
+	}
+
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, fmt.Errorf("Recurrence rule can't have both COUNT and UNTIL")
+	}
+
+	switch r.Freq {
+	case Daily:
+		if len(r.ByDay) > 0 || len(r.ByMonthDay) > 0 {
+			return nil, fmt.Errorf("BYDAY and BYMONTHDAY are not supported for DAILY rules")
+		}
+	case Weekly:
+		if len(r.ByMonthDay) > 0 {
+			return nil, fmt.Errorf("BYMONTHDAY is not supported for WEEKLY rules")
+		}
+		for _, wn := range r.ByDay {
+			if wn.Ordinal != 0 {
+				return nil, fmt.Errorf("BYDAY ordinals are not supported for WEEKLY rules")
+			}
+		}
+	case Monthly:
+		if len(r.ByDay) > 0 && len(r.ByMonthDay) > 0 {
+			return nil, fmt.Errorf("BYDAY and BYMONTHDAY can't be combined")
+		}
+	case Yearly:
+		if len(r.ByDay) > 0 || len(r.ByMonthDay) > 0 {
+			return nil, fmt.Errorf("BYDAY and BYMONTHDAY are not supported for YEARLY rules")
+		}
+	}
+
+	return r, nil
+}
+
+// parseUntil parses UNTIL, which is floating unless it is a UTC time.
+func parseUntil(value string) (time.Time, bool, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				// A date includes the whole day
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, layout != "20060102T150405Z", nil
+		}
+	}
+
+	return time.Time{}, false, fmt.Errorf("Invalid recurrence until: %s", value)
+}
+
+// until returns the end of the series of occurrences in loc.
+func (r Rule) until(loc *time.Location) time.Time {
+	if !r.FloatingUntil || r.Until.IsZero() {
+		return r.Until
+	}
+
+	y, m, d := r.Until.Date()
+	hh, mm, ss := r.Until.Clock()
+	return time.Date(y, m, d, hh, mm, ss, 0, loc)
+}
+
+func parseByDay(value string) ([]WeekdayNum, error) {
+	var wns []WeekdayNum
+	for _, v := range strings.Split(value, ",") {
+		if len(v) < 2 {
+			return nil, fmt.Errorf("Invalid recurrence weekday: %s", v)
+		}
+
+		wd, ok := weekdays[v[len(v)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("Invalid recurrence weekday: %s", v)
+		}
+
+		wn := WeekdayNum{Weekday: wd}
+		if ord := v[:len(v)-2]; ord != "" {
+			n, err := strconv.Atoi(ord)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, fmt.Errorf("Invalid recurrence weekday: %s", v)
+			}
+			wn.Ordinal = n
+		}
+
+		wns = append(wns, wn)
+	}
+
+	return wns, nil
+}
+
+func parseByMonthDay(value string) ([]int, error) {
+	var days []int
+	for _, v := range strings.Split(value, ",") {
+		d, err := strconv.Atoi(v)
+		if err != nil || d == 0 || d < -31 || d > 31 {
+			return nil, fmt.Errorf("Invalid recurrence month day: %s", v)
+		}
+		days = append(days, d)
+	}
+
+	return days, nil
+}
+
+// String formats the rule in its RFC 5545 form.
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq.String()}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		if r.FloatingUntil {
+			parts = append(parts, "UNTIL="+r.Until.Format("20060102T150405"))
+		} else {
+			parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+		}
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+weekdayNames[r.WeekStart])
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, wn := range r.ByDay {
+			days[i] = wn.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// Occurrences returns at most n occurrences of the series which starts
+// at dtstart and which fall strictly after the time after. Occurrences
+// are computed on the wall clock of loc, dtstart itself is always the
+// first occurrence of the series.
+func (r Rule) Occurrences(dtstart, after time.Time, loc *time.Location, n int) []time.Time {
+	var ts []time.Time
+	if n <= 0 {
+		return ts
+	}
+
+	start := dtstart.In(loc)
+	until := r.until(loc)
+	count := 0
+	emit := func(t time.Time) bool {
+		if !until.IsZero() && t.After(until) {
+			return false
+		}
+
+		count++
+		if r.Count > 0 && count > r.Count {
+			return false
+		}
+
+		if t.After(after) {
+			ts = append(ts, t)
+		}
+
+		return len(ts) < n
+	}
+
+	if !emit(start) {
+		return ts
+	}
+
+	empty := 0
+	for period := 0; empty < maxPeriods; period++ {
+		candidates := r.candidates(start, period, loc)
+		if len(candidates) == 0 {
+			empty++
+			continue
+		}
+		empty = 0
+
+		for _, c := range candidates {
+			if !c.After(start) {
+				continue
+			}
+
+			if !emit(c) {
+				return ts
+			}
+		}
+	}
+
+	return ts
+}
+
+// Next returns the first occurrence of the series after the time
+// after, or false once the series has ended.
+func (r Rule) Next(dtstart, after time.Time, loc *time.Location) (time.Time, bool) {
+	ts := r.Occurrences(dtstart, after, loc, 1)
+	if len(ts) == 0 {
+		return time.Time{}, false
+	}
+
+	return ts[0], true
+}
+
+// candidates returns the sorted occurrences within the nth period
+// (day, week, month or year) of the series which starts at start.
+func (r Rule) candidates(start time.Time, period int, loc *time.Location) []time.Time {
+	y, m, d := start.Date()
+	hh, mm, ss := start.Clock()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, hh, mm, ss, 0, loc)
+	}
+	step := period * r.Interval
+
+	var ts []time.Time
+	switch r.Freq {
+	case Daily:
+		ts = append(ts, at(y, m, d+step))
+	case Weekly:
+		offset := (int(start.Weekday()) - int(r.WeekStart) + 7) % 7
+		weekStart := d - offset + 7*step
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []WeekdayNum{{Weekday: start.Weekday()}}
+		}
+		for _, wn := range days {
+			ts = append(ts, at(y, m, weekStart+(int(wn.Weekday)-int(r.WeekStart)+7)%7))
+		}
+	case Monthly:
+		first := time.Date(y, m+time.Month(step), 1, 0, 0, 0, 0, time.UTC)
+		for _, day := range r.monthDays(first, d) {
+			ts = append(ts, at(first.Year(), first.Month(), day))
+		}
+	case Yearly:
+		if t := at(y+step, m, d); t.Day() == d {
+			ts = append(ts, t)
+		}
+	}
+
+	sort.Slice(ts, func(i, j int) bool { return ts[i].Before(ts[j]) })
+
+	unique := ts[:0]
+	for i, t := range ts {
+		if i == 0 || !t.Equal(ts[i-1]) {
+			unique = append(unique, t)
+		}
+	}
+
+	return unique
+}
+
+// monthDays returns the days of the month starting at first matched by
+// the rule. Days which don't exist in the month are skipped.
+func (r Rule) monthDays(first time.Time, startDay int) []int {
+	last := first.AddDate(0, 1, -1).Day()
+	var days []int
+
+	if len(r.ByDay) > 0 {
+		for _, wn := range r.ByDay {
+			firstMatch := 1 + (int(wn.Weekday)-int(first.Weekday())+7)%7
+			var matches []int
+			for day := firstMatch; day <= last; day += 7 {
+				matches = append(matches, day)
+			}
+
+			switch {
+			case wn.Ordinal == 0:
+				days = append(days, matches...)
+			case wn.Ordinal > 0 && wn.Ordinal <= len(matches):
+				days = append(days, matches[wn.Ordinal-1])
+			case wn.Ordinal < 0 && -wn.Ordinal <= len(matches):
+				days = append(days, matches[len(matches)+wn.Ordinal])
+			}
+		}
+		return days
+	}
+
+	monthDays := r.ByMonthDay
+	if len(monthDays) == 0 {
+		monthDays = []int{startDay}
+	}
+
+	for _, day := range monthDays {
+		if day < 0 {
+			day = last + day + 1
+		}
+		if day >= 1 && day <= last {
+			days = append(days, day)
+		}
+	}
+
+	return days
+}
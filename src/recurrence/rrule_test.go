@@ -0,0 +1,203 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+)
+
+func mustParse(t *testing.T, s string) *Rule {
+	r, err := Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return r
+}
+
+func testOccurrences(t *testing.T, rule string, dtstart time.Time, expected []time.Time) {
+	r := mustParse(t, rule)
+	ts := r.Occurrences(dtstart, dtstart.Add(-time.Second), dtstart.Location(), len(expected)+1)
+
+	if len(ts) < len(expected) {
+		t.Fatalf("%s: Too few occurrences: want %v got %v", rule, expected, ts)
+	}
+
+	for i, e := range expected {
+		if !ts[i].Equal(e) {
+			t.Errorf("%s: Incorrect occurrence %d: want %v got %v", rule, i, e, ts[i])
+		}
+	}
+}
+
+func date(y int, m time.Month, d, hh int, loc *time.Location) time.Time {
+	return time.Date(y, m, d, hh, 0, 0, 0, loc)
+}
+
+func TestParse(t *testing.T) {
+	r := mustParse(t, "RRULE:FREQ=weekly;INTERVAL=2;BYDAY=MO,FR;COUNT=4")
+	if r.Freq != Weekly || r.Interval != 2 || r.Count != 4 || len(r.ByDay) != 2 {
+		t.Errorf("Incorrectly parsed rule: %v", r)
+	}
+
+	if r.String() != "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=MO,FR" {
+		t.Errorf("Incorrect rule string: %s", r.String())
+	}
+
+	invalid := []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=WEEKLY;BYDAY=2MO",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;COUNT=2;UNTIL=20170101",
+		"FREQ=DAILY;BYSETPOS=1",
+	}
+	for _, s := range invalid {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Should fail to parse: %s", s)
+		}
+	}
+}
+
+func TestDaily(t *testing.T) {
+	start := date(2017, time.February, 27, 9, time.UTC)
+	testOccurrences(t, "FREQ=DAILY;INTERVAL=3", start, []time.Time{
+		start,
+		date(2017, time.March, 2, 9, time.UTC),
+		date(2017, time.March, 5, 9, time.UTC),
+	})
+}
+
+func TestWeeklyByDay(t *testing.T) {
+	// Wednesday
+	start := date(2017, time.March, 1, 9, time.UTC)
+	testOccurrences(t, "FREQ=WEEKLY;BYDAY=MO,WE,FR", start, []time.Time{
+		start,
+		date(2017, time.March, 3, 9, time.UTC),
+		date(2017, time.March, 6, 9, time.UTC),
+		date(2017, time.March, 8, 9, time.UTC),
+	})
+
+	// The Tuesday of the first week is before the start
+	testOccurrences(t, "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU", start, []time.Time{
+		start,
+		date(2017, time.March, 14, 9, time.UTC),
+		date(2017, time.March, 28, 9, time.UTC),
+	})
+}
+
+func TestMonthlyByMonthDay(t *testing.T) {
+	start := date(2017, time.January, 31, 9, time.UTC)
+	// Months without a 31st are skipped
+	testOccurrences(t, "FREQ=MONTHLY", start, []time.Time{
+		start,
+		date(2017, time.March, 31, 9, time.UTC),
+		date(2017, time.May, 31, 9, time.UTC),
+	})
+
+	testOccurrences(t, "FREQ=MONTHLY;BYMONTHDAY=-1", start, []time.Time{
+		start,
+		date(2017, time.February, 28, 9, time.UTC),
+		date(2017, time.March, 31, 9, time.UTC),
+	})
+
+	testOccurrences(t, "FREQ=MONTHLY;BYMONTHDAY=1,15", start, []time.Time{
+		start,
+		date(2017, time.February, 1, 9, time.UTC),
+		date(2017, time.February, 15, 9, time.UTC),
+	})
+}
+
+func TestMonthlyByDay(t *testing.T) {
+	start := date(2017, time.January, 10, 9, time.UTC)
+	testOccurrences(t, "FREQ=MONTHLY;BYDAY=2TU", start, []time.Time{
+		start,
+		date(2017, time.February, 14, 9, time.UTC),
+		date(2017, time.March, 14, 9, time.UTC),
+	})
+
+	testOccurrences(t, "FREQ=MONTHLY;BYDAY=-1FR", start, []time.Time{
+		start,
+		date(2017, time.January, 27, 9, time.UTC),
+		date(2017, time.February, 24, 9, time.UTC),
+	})
+}
+
+func TestYearlyLeapDay(t *testing.T) {
+	start := date(2016, time.February, 29, 9, time.UTC)
+	testOccurrences(t, "FREQ=YEARLY", start, []time.Time{
+		start,
+		date(2020, time.February, 29, 9, time.UTC),
+	})
+}
+
+func TestCountAndUntil(t *testing.T) {
+	start := date(2017, time.March, 1, 9, time.UTC)
+
+	r := mustParse(t, "FREQ=DAILY;COUNT=3")
+	ts := r.Occurrences(start, start, time.UTC, 10)
+	if len(ts) != 2 {
+		t.Errorf("COUNT=3 should leave two occurrences after the start: got %v", ts)
+	}
+
+	r = mustParse(t, "FREQ=DAILY;UNTIL=20170303")
+	ts = r.Occurrences(start, start, time.UTC, 10)
+	if len(ts) != 2 {
+		t.Errorf("UNTIL should include the whole last day: got %v", ts)
+	}
+
+	if _, ok := r.Next(start, date(2017, time.March, 3, 9, time.UTC), time.UTC); ok {
+		t.Error("Series should have ended")
+	}
+}
+
+func TestFloatingUntil(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip(err)
+	}
+	start := date(2017, time.March, 1, 9, loc)
+
+	// 10:00 in Tokyo is 01:00 UTC, before the occurrence of March 3
+	for _, rule := range []string{"FREQ=DAILY;UNTIL=20170303T100000", "FREQ=DAILY;UNTIL=20170303"} {
+		r := mustParse(t, rule)
+		ts := r.Occurrences(start, start, loc, 10)
+		if len(ts) != 2 || !ts[1].Equal(date(2017, time.March, 3, 9, loc)) {
+			t.Errorf("%s: UNTIL should be read in the time zone: got %v", rule, ts)
+		}
+
+		if s := mustParse(t, r.String()).String(); s != r.String() {
+			t.Errorf("%s: Floating UNTIL should be kept: got %s", rule, s)
+		}
+	}
+
+	r := mustParse(t, "FREQ=DAILY;UNTIL=20170302T230000Z")
+	if ts := r.Occurrences(start, start, loc, 10); len(ts) != 1 {
+		t.Errorf("UTC UNTIL should end before the occurrence of March 3: got %v", ts)
+	}
+}
+
+func TestDaylightSavingTime(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	// DST starts on March 12th 2017 in New York
+	start := date(2017, time.March, 11, 9, loc)
+	r := mustParse(t, "FREQ=DAILY")
+	next, ok := r.Next(start, start, loc)
+	if !ok {
+		t.Fatal("No next occurrence")
+	}
+
+	if next.Hour() != 9 || next.Day() != 12 {
+		t.Errorf("Occurrence should stay at 9:00 local time: got %v", next)
+	}
+
+	if next.Sub(start) != 23*time.Hour {
+		t.Errorf("Occurrences should be 23 hours apart across DST: got %v", next.Sub(start))
+	}
+}
@@ -0,0 +1,169 @@
+// Package reminders fires the reminders of todos when they are due.
+//
+// Reminder jobs are kept in long term storage so that they survive
+// restarts of the server. Every instance of the server runs a Scheduler
+// which claims due jobs with a lease, so a job is only fired by one
+// instance. Should an instance die while holding a lease the job is
+// picked up by another instance once the lease expires.
+package reminders
+
+import (
+	"fmt"
+	"gopkg.in/mgo.v2/bson"
+	"log"
+	"models"
+	"notify"
+	"os"
+	"time"
+)
+
+const ReminderKind = "reminder"
+
+// Scheduler periodically fires the reminder jobs which are due.
+type Scheduler struct {
+	Instance     string        // Unique name of the server instance
+	PollInterval time.Duration // Time between checks for due jobs
+	Lease        time.Duration // Time a job is claimed for while it is fired
+	MaxAttempts  int           // Attempts before a job is marked as failed
+	RetryDelay   time.Duration // Delay before the first retry, doubled for each attempt
+	Dispatcher   *notify.Dispatcher
+}
+
+func NewScheduler(d *notify.Dispatcher) *Scheduler {
+	host, _ := os.Hostname()
+	return &Scheduler{
+		Instance:     fmt.Sprintf("%s-%d-%s", host, os.Getpid(), bson.NewObjectId().Hex()),
+		PollInterval: 15 * time.Second,
+		Lease:        time.Minute,
+		MaxAttempts:  5,
+		RetryDelay:   time.Minute,
+		Dispatcher:   d,
+	}
+}
+
+// Run fires due jobs every PollInterval until stop is closed.
+func (s *Scheduler) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.RunDue(time.Now())
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue fires every job which is due at the time now and returns
+// the number of jobs fired.
+func (s *Scheduler) RunDue(now time.Time) int {
+	rjs := models.NewReminderJobStorage()
+	defer rjs.Close()
+
+	fired := 0
+	for {
+		job, err := rjs.ClaimDueJob(s.Instance, now, s.Lease)
+		if err != nil {
+			log.Printf("RunDue: ClaimDueJob failure: %s\n", err)
+			return fired
+		}
+
+		if job == nil {
+			return fired
+		}
+
+		s.fire(rjs, *job, now)
+		fired++
+	}
+}
+
+// fire delivers a claimed job through the channels it has not yet been
+// delivered through and records the outcome.
+func (s *Scheduler) fire(rjs models.ReminderJobStorage, job models.ReminderJob, now time.Time) {
+	id := job.Id.Hex()
+	finish := func(status string, delivered []string) {
+		if err := rjs.FinishJob(id, s.Instance, status, delivered); err != nil {
+			log.Printf("fire: FinishJob failure for job %s: %s\n", id, err)
+		}
+	}
+
+	tds := models.NewTodoStorage()
+	defer tds.Close()
+
+	t, err := tds.GetTodoById(job.TodoId)
+	if err == models.TodoNotFoundError || (err == nil && (t.Completed || t.Archived)) {
+		finish(models.JobCancelled, job.Delivered)
+		return
+	}
+
+	uds := models.NewUserStorage()
+	defer uds.Close()
+
+	var u *models.User
+	if err == nil {
+		u, err = uds.GetUserById(job.Ownerid)
+		if err == models.ErrUserNotFound {
+			finish(models.JobCancelled, job.Delivered)
+			return
+		}
+	}
+
+	var delivered []string
+	if err == nil {
+		delivered, err = s.Dispatcher.Dispatch(*u, reminderNotification(*t), remaining(job))
+	}
+	delivered = append(job.Delivered, delivered...)
+
+	if err == nil {
+		finish(models.JobSent, delivered)
+		return
+	}
+
+	log.Printf("fire: Failure to deliver job %s: %s\n", id, err)
+	if job.Attempts+1 >= s.MaxAttempts {
+		finish(models.JobFailed, delivered)
+		return
+	}
+
+	retryAt := now.Add(s.RetryDelay << uint(job.Attempts))
+	if err := rjs.RetryJob(id, s.Instance, retryAt, delivered); err != nil {
+		log.Printf("fire: RetryJob failure for job %s: %s\n", id, err)
+	}
+}
+
+// remaining returns the channels the job has not been delivered through.
+func remaining(job models.ReminderJob) []string {
+	channels := make([]string, 0, len(job.Channels))
+	for _, c := range job.Channels {
+		delivered := false
+		for _, d := range job.Delivered {
+			if c == d {
+				delivered = true
+				break
+			}
+		}
+
+		if !delivered {
+			channels = append(channels, c)
+		}
+	}
+
+	return channels
+}
+
+func reminderNotification(t models.Todo) models.Notification {
+	n := models.NewNotification()
+	n.Kind = ReminderKind
+	n.TodoId = t.Id
+	n.Title = fmt.Sprintf("Reminder: %s", t.Title)
+	if t.Due.IsZero() {
+		n.Message = fmt.Sprintf("Reminder for your 2Do \"%s\".", t.Title)
+	} else {
+		n.Message = fmt.Sprintf("Your 2Do \"%s\" is due %s.", t.Title, t.Due.Format(time.RFC1123))
+	}
+
+	return n
+}
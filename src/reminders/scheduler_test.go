@@ -0,0 +1,164 @@
+package reminders
+
+import (
+	"errors"
+	"models"
+	"notify"
+	"testing"
+	"time"
+)
+
+func init() {
+	models.TODO_STORE_TYPE = models.Test
+	models.USER_STORE_TYPE = models.Test
+	models.REMINDER_STORE_TYPE = models.Test
+	models.NOTIFICATION_STORE_TYPE = models.Test
+}
+
+type fakeNotifier struct {
+	channel string
+	fail    bool
+	sent    int
+}
+
+func (fn *fakeNotifier) Channel() string {
+	return fn.channel
+}
+
+func (fn *fakeNotifier) Notify(u models.User, n models.Notification) error {
+	if fn.fail {
+		return errors.New("Delivery failed")
+	}
+
+	fn.sent++
+	return nil
+}
+
+// schedulerSetup stores a user with a todo whose single reminder is
+// due at the time at.
+func schedulerSetup(at time.Time) (models.User, models.Todo) {
+	u := models.NewUser()
+	models.NewUserStorage().InsertUser(u)
+
+	t := models.NewTodo()
+	t.Ownerid = u.Id.Hex()
+	t.Title = "Pay rent"
+	t.Reminders = []models.Reminder{{At: &at, Channels: []string{models.ChannelInApp, models.ChannelEmail}}}
+	models.NewTodoStorage().InsertTodo(t)
+	models.NewReminderJobStorage().ReplaceJobsForTodo(t.Id, t.ReminderJobs(at.Add(-time.Minute)))
+
+	return u, t
+}
+
+func TestRunDue(t *testing.T) {
+	now := time.Now()
+	_, todo := schedulerSetup(now)
+	defer models.NewTodoStorage().DeleteTodo(todo.Id)
+
+	inApp := &fakeNotifier{channel: models.ChannelInApp}
+	email := &fakeNotifier{channel: models.ChannelEmail, fail: true}
+	s := NewScheduler(notify.NewDispatcher(inApp, email))
+
+	if fired := s.RunDue(now.Add(-time.Second)); fired != 0 {
+		t.Errorf("No job should be due yet: fired %d", fired)
+	}
+
+	if fired := s.RunDue(now); fired != 1 {
+		t.Fatalf("Job should be fired: fired %d", fired)
+	}
+
+	// The failed email is retried without delivering in app again
+	if fired := s.RunDue(now); fired != 0 {
+		t.Errorf("Job should wait to be retried: fired %d", fired)
+	}
+
+	email.fail = false
+	if fired := s.RunDue(now.Add(s.RetryDelay)); fired != 1 {
+		t.Fatalf("Job should be retried: fired %d", fired)
+	}
+
+	if inApp.sent != 1 || email.sent != 1 {
+		t.Errorf("Each channel should be delivered once: in app %d email %d", inApp.sent, email.sent)
+	}
+
+	if fired := s.RunDue(now.Add(time.Hour)); fired != 0 {
+		t.Errorf("Sent job should not be fired again: fired %d", fired)
+	}
+}
+
+func TestRunDueCompletedTodo(t *testing.T) {
+	now := time.Now()
+	_, todo := schedulerSetup(now)
+	defer models.NewTodoStorage().DeleteTodo(todo.Id)
+
+	models.NewTodoStorage().ModifyTodo(todo.Id, map[string]interface{}{"completed": true})
+
+	inApp := &fakeNotifier{channel: models.ChannelInApp}
+	s := NewScheduler(notify.NewDispatcher(inApp))
+	s.RunDue(now)
+
+	if inApp.sent != 0 {
+		t.Error("Reminders of completed 2Dos should not be delivered")
+	}
+}
+
+func TestRunDueArchivedTodo(t *testing.T) {
+	now := time.Now()
+	_, todo := schedulerSetup(now)
+	defer models.NewTodoStorage().DeleteTodo(todo.Id)
+
+	models.NewTodoStorage().ModifyTodo(todo.Id, map[string]interface{}{"archived": true})
+
+	inApp := &fakeNotifier{channel: models.ChannelInApp}
+	s := NewScheduler(notify.NewDispatcher(inApp))
+	s.RunDue(now)
+
+	if inApp.sent != 0 {
+		t.Error("Reminders of archived 2Dos should not be delivered")
+	}
+}
+
+func TestRunDueSingleInstance(t *testing.T) {
+	now := time.Now()
+	_, todo := schedulerSetup(now)
+	defer models.NewTodoStorage().DeleteTodo(todo.Id)
+
+	// A job claimed by one instance is not fired by another
+	rjs := models.NewReminderJobStorage()
+	job, err := rjs.ClaimDueJob("other-instance", now, time.Minute)
+	if err != nil || job == nil {
+		t.Fatalf("Failure to claim job: %v", err)
+	}
+
+	inApp := &fakeNotifier{channel: models.ChannelInApp}
+	s := NewScheduler(notify.NewDispatcher(inApp))
+	if fired := s.RunDue(now); fired != 0 {
+		t.Errorf("Claimed job should not be fired: fired %d", fired)
+	}
+
+	// Until the lease of the other instance expires
+	if fired := s.RunDue(now.Add(2 * time.Minute)); fired != 1 {
+		t.Errorf("Job with an expired lease should be fired: fired %d", fired)
+	}
+}
+
+func TestReplaceJobsKeepsClaimedJob(t *testing.T) {
+	now := time.Now()
+	_, todo := schedulerSetup(now)
+	defer models.NewTodoStorage().DeleteTodo(todo.Id)
+
+	rjs := models.NewReminderJobStorage()
+	job, err := rjs.ClaimDueJob("other-instance", now, time.Minute)
+	if err != nil || job == nil {
+		t.Fatalf("Failure to claim job: %v", err)
+	}
+
+	// The todo changes while the other instance delivers the job
+	rjs.ReplaceJobsForTodo(todo.Id, nil)
+
+	inApp := &fakeNotifier{channel: models.ChannelInApp}
+	s := NewScheduler(notify.NewDispatcher(inApp))
+	if fired := s.RunDue(now.Add(2 * time.Minute)); fired != 1 {
+		t.Errorf("Claimed job should be kept: fired %d", fired)
+	}
+}